@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/actorbuf/iotaer/wizard"
+	"github.com/spf13/cobra"
+)
+
+func addCommand() *cobra.Command {
+	a := &wizard.Answers{}
+	cmd := &cobra.Command{
+		Use:   "add [route|api|svc|rpc|task|errcode]",
+		Short: "交互式添加路由组、接口、服务、rpc、定时任务或错误码文件",
+		Long: "在终端中依次选择 proto 文件、路由组或 service 并填写名称, 支持 Tab 补全, 确认后执行对应的 add* 命令. " +
+			"已通过参数填写的内容不再询问. 标准输入不是终端时不进入交互, 直接按参数执行",
+		Example: "builder add\nbuilder add api\nbuilder add api --path model/user.proto --svc UserApi --name GetUser --method GET < /dev/null",
+		Args:    cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 1 {
+				a.Kind = args[0]
+			}
+			proj, err := wizard.Scan(".")
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			var run []string
+			if wizard.IsTerminal() {
+				run, err = askTerminal(proj, a)
+			} else {
+				run, err = wizard.Args(proj, a)
+			}
+			if err == wizard.ErrCanceled {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				return
+			}
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			sub, rest, err := rootCmd.Find(run)
+			if err == nil {
+				err = sub.ParseFlags(rest)
+			}
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			sub.Run(sub, sub.Flags().Args())
+		},
+	}
+	cmd.Flags().StringVar(&a.Kind, "kind", "", "添加的类型 [route,api,svc,rpc,task,errcode], 也可以作为第一个参数")
+	cmd.Flags().StringVar(&a.Path, "path", "", "proto文件地址, errcode 时为目录")
+	cmd.Flags().StringVar(&a.Svc, "svc", "", "接口/rpc/任务所在的路由组或service")
+	cmd.Flags().StringVar(&a.Name, "name", "", "新增的名称")
+	cmd.Flags().StringVar(&a.Method, "method", "", "接口的请求方式 [GET,POST,PUT,DELETE,ANY]")
+	cmd.Flags().StringVar(&a.Desc, "desc", "", "接口描述")
+	cmd.Flags().StringVar(&a.Fields, "fields", "", "接口Req的字段 name:type 以逗号分隔")
+	cmd.Flags().StringVar(&a.RespFields, "resp-fields", "", "接口Resp的字段 格式同 --fields")
+	cmd.Flags().StringVar(&a.GenTo, "gento", "", "路由组/服务/任务代码的生成位置")
+	cmd.Flags().StringVar(&a.API, "api", "", "路由组前缀")
+	return cmd
+}
+
+// askTerminal 在终端中运行向导 结束后恢复终端再执行命令
+func askTerminal(proj *wizard.Project, a *wizard.Answers) ([]string, error) {
+	t, err := wizard.NewTerminal()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = t.Close() }()
+	return wizard.Run(t, proj, a)
+}
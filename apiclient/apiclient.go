@@ -0,0 +1,121 @@
+package apiclient
+
+import (
+	"strings"
+
+	"github.com/actorbuf/iotaer/apidoc"
+	"github.com/actorbuf/iotaer/toolkit"
+)
+
+// 支持生成的客户端语言
+const (
+	LangGo = "go"
+	LangTS = "ts"
+)
+
+// group 一个路由组生成客户端需要的消息、枚举与错误码 按引用的顺序排列
+type group struct {
+	*apidoc.Group
+	Messages []*apidoc.Message
+	Enums    []*apidoc.Enum
+	Errors   []*apidoc.ErrorCode
+	names    map[string]string // 全名 => 类型名
+	used     map[string]bool
+}
+
+// collect 收集路由组接口引用到的消息与枚举 以及路由组所在包与接口标注的错误码
+func collect(p *apidoc.Project, g *apidoc.Group) *group {
+	c := &group{Group: g, names: map[string]string{}, used: map[string]bool{}}
+	seen := map[*apidoc.Message]bool{}
+	var walk func(m *apidoc.Message)
+	walk = func(m *apidoc.Message) {
+		if seen[m] {
+			return
+		}
+		seen[m] = true
+		c.name(m.Name, m.Short)
+		c.Messages = append(c.Messages, m)
+		for _, f := range m.Fields {
+			if f.Enum != nil && c.names[f.Enum.Name] == "" {
+				c.name(f.Enum.Name, f.Enum.Short)
+				c.Enums = append(c.Enums, f.Enum)
+			}
+			if f.Message != nil {
+				walk(f.Message)
+			}
+		}
+	}
+	for _, api := range g.APIs {
+		walk(api.Req)
+		walk(api.Resp)
+	}
+
+	codes := map[int]bool{}
+	add := func(e *apidoc.ErrorCode) {
+		if e.Package == "" || e.Code == 0 || codes[e.Code] {
+			return
+		}
+		codes[e.Code] = true
+		c.Errors = append(c.Errors, e)
+	}
+	for _, ef := range p.Errors {
+		if ef.Package == g.Package {
+			for _, e := range ef.Codes {
+				add(e)
+			}
+		}
+	}
+	for _, api := range g.APIs {
+		for _, e := range api.Errors {
+			add(e)
+		}
+	}
+	return c
+}
+
+// name 消息与枚举的类型名 嵌套的名称去掉点 重名时加上包名
+func (c *group) name(full, short string) string {
+	if name, ok := c.names[full]; ok {
+		return name
+	}
+	name := strings.ReplaceAll(short, ".", "")
+	if c.used[name] {
+		pkg := strings.TrimSuffix(full, "."+short)
+		name = camel(strings.ReplaceAll(pkg, ".", "_")) + name
+	}
+	c.used[name] = true
+	c.names[full] = name
+	return name
+}
+
+// Type 消息或枚举的类型名
+func (c *group) Type(full string) string {
+	return c.names[full]
+}
+
+// camel 下划线转大驼峰
+func camel(s string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(s, "_") {
+		b.WriteString(toolkit.FirstUpper(part))
+	}
+	return b.String()
+}
+
+// errVar 错误码对应的错误变量名 与 errcode gen 一致去掉 ErrCode 前缀
+func errVar(name string) string {
+	return "Err" + strings.TrimPrefix(name, "ErrCode")
+}
+
+// comment 注释中的一行说明
+func comment(s string) string {
+	return strings.ReplaceAll(strings.TrimSpace(s), "\n", " ")
+}
+
+// method 客户端使用的请求方式 ANY 时使用 POST
+func method(api *apidoc.API) string {
+	if api.Method == "ANY" || api.Method == "" {
+		return "POST"
+	}
+	return api.Method
+}
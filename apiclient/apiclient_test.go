@@ -0,0 +1,173 @@
+package apiclient
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/actorbuf/iotaer/internal/testproject"
+)
+
+func TestGo(t *testing.T) {
+	pages, err := Go(testproject.Load(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 || pages[0].Path != "userapi/client.go" {
+		t.Fatalf("unexpected pages %+v", pages)
+	}
+	src := string(pages[0].Content)
+	for _, want := range []string{
+		"package userapi",
+		"`json:\"uid,omitempty\"`",
+		"map[string]*UserTag",
+		"[]byte",
+		"User_LevelVip UserLevel = 1 // 会员",
+		"ErrUserNotFound = &Error{Code: 20001, Msg: \"用户不存在\"}",
+		"ErrForbidden = &Error{Code: 20002",
+		"func (c *Client) GetUser(ctx context.Context, req *GetUserReq) (*GetUserResp, error)",
+		`c.do(ctx, "PUT", "/api/user/users/update", req, resp)`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Fatalf("expected %q in:\n%s", want, src)
+		}
+	}
+
+	// 生成的代码只依赖标准库 可以通过类型检查
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, pages[0].Path, src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("userapi", fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+}
+
+// retryMain 服务端总是返回 503 输出每次调用的请求次数
+const retryMain = `package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"example.com/demo/userapi"
+)
+
+func main() {
+	var calls int32
+	var query atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Method == http.MethodGet {
+			query.Store(r.URL.RawQuery)
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	count := func(call func() error) int32 {
+		atomic.StoreInt32(&calls, 0)
+		_ = call()
+		return atomic.LoadInt32(&calls)
+	}
+	ctx := context.Background()
+	c := userapi.NewClient(srv.URL, userapi.WithRetry(2, time.Millisecond))
+	idempotent := userapi.NewClient(srv.URL, userapi.WithRetry(2, time.Millisecond), userapi.WithRetryMethods("put"))
+	fmt.Print(
+		count(func() error { _, err := c.GetUser(ctx, &userapi.GetUserReq{Uid: 7}); return err }),
+		count(func() error { _, err := c.UpdateUser(ctx, &userapi.UpdateUserReq{}); return err }),
+		count(func() error { _, err := idempotent.UpdateUser(ctx, &userapi.UpdateUserReq{}); return err }),
+		" "+query.Load().(string),
+	)
+}
+`
+
+func TestGoRetry(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not found")
+	}
+	pages, err := Go(testproject.Load(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod":            "module example.com/demo\n\ngo 1.16\n",
+		"main.go":           retryMain,
+		"userapi/client.go": string(pages[0].Content),
+	} {
+		file := filepath.Join(mod, filepath.FromSlash(name))
+		_ = os.MkdirAll(filepath.Dir(file), 0755)
+		if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = mod
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GO111MODULE=on")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	// 默认只重试 GET, 其他请求需要通过 WithRetryMethods 开启
+	// GET 参数按 go 字段名放在 query 中 与 gin 的表单绑定一致
+	if string(out) != "3 1 3 Uid=7" {
+		t.Fatalf("unexpected calls %s", out)
+	}
+}
+
+func TestTypeScript(t *testing.T) {
+	pages, err := TypeScript(testproject.Load(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 1 || pages[0].Path != "user_api.ts" {
+		t.Fatalf("unexpected pages %+v", pages)
+	}
+	src := string(pages[0].Content)
+	for _, want := range []string{
+		"export interface User {",
+		"  /** 用户ID */\n  uid: number;",
+		"  uid?: number;",
+		"  friends?: User[];",
+		"  tags?: Record<string, UserTag>;",
+		"  avatar?: string;",
+		"export enum UserLevel {",
+		"  LevelVip = 1,",
+		"  ErrCodeUserNotFound: 20001,",
+		"export class UserApiClient {",
+		"  getUser(req: GetUserReq, init?: RequestInit): Promise<GetUserResp> {",
+		`this.request<UpdateUserResp>("PUT", "/api/user/users/update", req, init);`,
+		`this.request<GetUserResp>("GET", "/api/user/get_user", req, init, { uid: "Uid", fields: "Fields" });`,
+		`const retries = (this.options.retryMethods ?? ["GET"]).includes(method) ? this.options.retries ?? 2 : 0;`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Fatalf("expected %q in:\n%s", want, src)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	c := &group{names: map[string]string{}, used: map[string]bool{}}
+	var names []string
+	for _, n := range [][2]string{{"user.User", "User"}, {"user.User.Tag", "User.Tag"}, {"common.User", "User"}, {"user.User", "User"}} {
+		names = append(names, c.name(n[0], n[1]))
+	}
+	if !reflect.DeepEqual(names, []string{"User", "UserTag", "CommonUser", "User"}) {
+		t.Fatalf("unexpected names %v", names)
+	}
+}
@@ -0,0 +1,450 @@
+package apiclient
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/actorbuf/iotaer/apidoc"
+)
+
+var goScalars = map[string]string{
+	"double": "float64", "float": "float32",
+	"int32": "int32", "sint32": "int32", "sfixed32": "int32",
+	"int64": "int64", "sint64": "int64", "sfixed64": "int64",
+	"uint32": "uint32", "fixed32": "uint32", "uint64": "uint64", "fixed64": "uint64",
+	"bool": "bool", "string": "string", "bytes": "[]byte",
+}
+
+type goField struct {
+	Name string
+	Type string
+	Tag  string
+	Desc string
+}
+
+type goMessage struct {
+	Name   string
+	Desc   string
+	Fields []goField
+}
+
+type goValue struct {
+	Name   string
+	Number int
+	Desc   string
+}
+
+type goEnum struct {
+	Name   string
+	Desc   string
+	Values []goValue
+}
+
+type goError struct {
+	Var  string
+	Code int
+	Msg  string
+}
+
+type goAPI struct {
+	Name   string
+	Desc   string
+	Method string
+	Path   string
+	Req    string
+	Resp   string
+}
+
+// GoPackage 路由组客户端的包名
+func GoPackage(g *apidoc.Group) string {
+	return strings.ToLower(g.Name)
+}
+
+// goType 字段的 go 类型 消息为指针
+func (c *group) goType(f *apidoc.Field) string {
+	var t string
+	switch {
+	case f.Message != nil:
+		t = "*" + c.Type(f.Message.Name)
+	case f.Enum != nil:
+		t = c.Type(f.Enum.Name)
+	default:
+		t = goScalars[f.Type]
+	}
+	switch {
+	case f.Kind == apidoc.KindMap:
+		return "map[" + goScalars[f.KeyType] + "]" + t
+	case f.Repeated:
+		return "[]" + t
+	}
+	return t
+}
+
+// goValueName 枚举值的常量名 与 protoc-gen-go 一致 嵌套的枚举带上外层消息名
+func goValueName(e *apidoc.Enum, v *apidoc.EnumValue) string {
+	if i := strings.LastIndex(e.Short, "."); i >= 0 {
+		return strings.ReplaceAll(e.Short[:i], ".", "_") + "_" + v.Name
+	}
+	return v.Name
+}
+
+// Go 为每个路由组生成一个 go 客户端包 包含消息结构、枚举、错误码对应的错误与接口方法
+func Go(p *apidoc.Project) ([]apidoc.Page, error) {
+	var pages []apidoc.Page
+	for _, g := range p.Groups {
+		c := collect(p, g)
+		data := struct {
+			Package  string
+			Group    *apidoc.Group
+			Messages []goMessage
+			Enums    []goEnum
+			Errors   []goError
+			APIs     []goAPI
+		}{Package: GoPackage(g), Group: g}
+		for _, m := range c.Messages {
+			gm := goMessage{Name: c.Type(m.Name), Desc: comment(m.Desc)}
+			for _, f := range m.Fields {
+				gm.Fields = append(gm.Fields, goField{
+					Name: apidoc.GoName(f.Proto),
+					Type: c.goType(f),
+					Tag:  fmt.Sprintf("`json:\"%s,omitempty\"`", f.Name),
+					Desc: comment(f.Desc),
+				})
+			}
+			data.Messages = append(data.Messages, gm)
+		}
+		for _, e := range c.Enums {
+			ge := goEnum{Name: c.Type(e.Name), Desc: comment(e.Desc)}
+			for _, v := range e.Values {
+				ge.Values = append(ge.Values, goValue{Name: goValueName(e, v), Number: v.Number, Desc: comment(v.Desc)})
+			}
+			data.Enums = append(data.Enums, ge)
+		}
+		vars := map[string]bool{}
+		for _, e := range c.Errors {
+			v := errVar(e.Name)
+			if vars[v] {
+				v = fmt.Sprintf("%s%d", v, e.Code)
+			}
+			vars[v] = true
+			data.Errors = append(data.Errors, goError{Var: v, Code: e.Code, Msg: comment(e.Msg)})
+		}
+		for _, api := range g.APIs {
+			data.APIs = append(data.APIs, goAPI{
+				Name:   api.Name,
+				Desc:   comment(api.Desc),
+				Method: method(api),
+				Path:   api.Path,
+				Req:    c.Type(api.Req.Name),
+				Resp:   c.Type(api.Resp.Name),
+			})
+		}
+		var buf bytes.Buffer
+		if err := goT.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", g.Name, err)
+		}
+		pages = append(pages, apidoc.Page{Path: path.Join(data.Package, "client.go"), Content: src})
+	}
+	return pages, nil
+}
+
+var goT = template.Must(template.New("go").Parse(goTpl))
+
+const goTpl = `// Code generated by iotaer gen --client. DO NOT EDIT.
+
+// Package {{.Package}} 路由组 {{.Group.Name}} 的 HTTP 客户端{{if .Group.Desc}} {{.Group.Desc}}{{end}}
+// 定义: {{.Group.Proto}}
+package {{.Package}}
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+{{range .Messages}}
+// {{.Name}}{{if .Desc}} {{.Desc}}{{end}}
+type {{.Name}} struct { {{range .Fields}}
+	{{.Name}} {{.Type}} {{.Tag}}{{if .Desc}} // {{.Desc}}{{end}}{{end}}
+}
+{{end}}{{range .Enums}}
+// {{.Name}}{{if .Desc}} {{.Desc}}{{end}}
+type {{.Name}} int32
+
+const ({{$enum := .Name}}{{range .Values}}
+	{{.Name}} {{$enum}} = {{.Number}}{{if .Desc}} // {{.Desc}}{{end}}{{end}}
+)
+{{end}}
+// Error 服务端返回的错误码不为 0 时的错误 可以通过 errors.Is 与下面的错误比较
+type Error struct {
+	Code int32
+	Msg  string
+	Hint string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Msg)
+}
+
+// Is 错误码相同时认为是同一个错误
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// ErrCode err 中的错误码 不是服务端返回的错误时为 0
+func ErrCode(err error) int32 {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return 0
+}
+
+// 服务端 ErrCode 枚举中的错误码
+var ({{range .Errors}}
+	// {{.Var}} {{.Msg}}
+	{{.Var}} = &Error{Code: {{.Code}}, Msg: {{printf "%q" .Msg}}}{{end}}
+)
+
+// StatusError HTTP 状态码不为 200 且没有返回框架结构时的错误
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("http status %d: %s", e.StatusCode, e.Body)
+}
+
+// Client 路由组 {{.Group.Name}} 的客户端 可以在多个 goroutine 中使用
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	header     http.Header
+	retries    int
+	backoff    time.Duration
+	methods    map[string]bool // 允许重试的请求方式
+}
+
+// Option 客户端的配置
+type Option func(c *Client)
+
+// WithHTTPClient 使用指定的 http.Client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithHeader 每个请求都带上的请求头
+func WithHeader(key, value string) Option {
+	return func(c *Client) { c.header.Add(key, value) }
+}
+
+// WithRetry 网络错误与 429、502、503、504 时的重试次数 每次重试的等待时间翻倍
+func WithRetry(retries int, backoff time.Duration) Option {
+	return func(c *Client) { c.retries, c.backoff = retries, backoff }
+}
+
+// WithRetryMethods 允许重试的请求方式 默认只有 GET
+// 其他请求在超时等错误时服务端可能已经处理 重试会重复执行 确认接口幂等后再开启
+func WithRetryMethods(methods ...string) Option {
+	return func(c *Client) {
+		c.methods = map[string]bool{}
+		for _, m := range methods {
+			c.methods[strings.ToUpper(m)] = true
+		}
+	}
+}
+
+// NewClient 创建客户端 baseURL 如 http://user.svc:8080 接口路径直接拼接在后面
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		header:     http.Header{},
+		retries:    2,
+		backoff:    100 * time.Millisecond,
+		methods:    map[string]bool{http.MethodGet: true},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+{{range .APIs}}
+// {{.Name}}{{if .Desc}} {{.Desc}}{{end}}
+// {{.Method}} {{.Path}}
+func (c *Client) {{.Name}}(ctx context.Context, req *{{.Req}}) (*{{.Resp}}, error) {
+	resp := &{{.Resp}}{}
+	if err := c.do(ctx, {{printf "%q" .Method}}, {{printf "%q" .Path}}, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+{{end}}
+// result 框架返回的结构
+type result struct {
+	ErrCode int32           ` + "`json:\"err_code\"`" + `
+	ErrMsg  string          ` + "`json:\"err_msg\"`" + `
+	Hint    string          ` + "`json:\"hint\"`" + `
+	Data    json.RawMessage ` + "`json:\"data\"`" + `
+}
+
+func (c *Client) do(ctx context.Context, method, path string, req, resp interface{}) error {
+	target := c.baseURL + path
+	var body []byte
+	// 服务端使用 gin 的 ShouldBind: GET 请求按表单绑定 参数在 query 中 其他请求使用 json 请求体
+	if method == http.MethodGet {
+		if query := encodeQuery(req); len(query) > 0 {
+			target += "?" + query.Encode()
+		}
+	} else {
+		raw, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = raw
+	}
+
+	backoff := c.backoff
+	for attempt := 0; ; attempt++ {
+		out, err := c.send(ctx, method, target, body)
+		if err == nil {
+			return decode(out, resp)
+		}
+		if attempt >= c.retries || !c.methods[method] || !retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// encodeQuery GET 请求的 query 参数
+// 消息没有 form tag, gin 按 go 字段名匹配 query 参数 所以参数名为字段名而不是 json 名称
+// 零值与 json 的 omitempty 一样不发送, 消息、map 与 bytes 无法通过 query 传递
+func encodeQuery(req interface{}) url.Values {
+	query := url.Values{}
+	v := reflect.Indirect(reflect.ValueOf(req))
+	if v.Kind() != reflect.Struct {
+		return query
+	}
+	for i := 0; i < v.NumField(); i++ {
+		name, field := v.Type().Field(i).Name, v.Field(i)
+		if field.IsZero() {
+			continue
+		}
+		if field.Kind() != reflect.Slice {
+			if s, ok := queryValue(field); ok {
+				query.Set(name, s)
+			}
+			continue
+		}
+		for j := 0; j < field.Len(); j++ {
+			if s, ok := queryValue(field.Index(j)); ok && field.Type().Elem().Kind() != reflect.Uint8 {
+				query.Add(name, s)
+			}
+		}
+	}
+	return query
+}
+
+func queryValue(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), true
+	case reflect.String:
+		return v.String(), true
+	}
+	return "", false
+}
+
+// send 发送一次请求 返回响应体
+func (c *Client) send(ctx context.Context, method, target string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	r, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.header {
+		r.Header[k] = v
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	res, err := c.httpClient.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	out, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		var ret result
+		if json.Unmarshal(out, &ret) == nil && ret.ErrCode != 0 {
+			return nil, &Error{Code: ret.ErrCode, Msg: ret.ErrMsg, Hint: ret.Hint}
+		}
+		return nil, &StatusError{StatusCode: res.StatusCode, Body: string(out)}
+	}
+	return out, nil
+}
+
+func retryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		switch se.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var e *Error
+	return !errors.As(err, &e) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+func decode(out []byte, resp interface{}) error {
+	var ret result
+	if err := json.Unmarshal(out, &ret); err != nil {
+		return err
+	}
+	if ret.ErrCode != 0 {
+		return &Error{Code: ret.ErrCode, Msg: ret.ErrMsg, Hint: ret.Hint}
+	}
+	if len(ret.Data) == 0 || string(ret.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(ret.Data, resp)
+}
+`
@@ -0,0 +1,284 @@
+package apiclient
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/actorbuf/iotaer/apidoc"
+	"github.com/actorbuf/iotaer/toolkit"
+)
+
+var tsNumbers = map[string]bool{
+	"double": true, "float": true, "int32": true, "int64": true, "uint32": true, "uint64": true,
+	"sint32": true, "sint64": true, "fixed32": true, "fixed64": true, "sfixed32": true, "sfixed64": true,
+}
+
+type tsField struct {
+	Name     string
+	Type     string
+	Optional bool
+	Desc     string
+}
+
+type tsMessage struct {
+	Name   string
+	Desc   string
+	Fields []tsField
+}
+
+type tsAPI struct {
+	Name   string // 方法名 小驼峰
+	Desc   string
+	Method string
+	Path   string
+	Req    string
+	Resp   string
+	Query  string // GET 请求 json 名称到 query 参数名的映射
+}
+
+// TSFile 路由组 ts 客户端的文件名
+func TSFile(g *apidoc.Group) string {
+	return toolkit.Calm2Case(g.Name) + ".ts"
+}
+
+// tsType 字段的 ts 类型 json 中的 int64 为数字 bytes 为 base64 字符串
+func (c *group) tsType(f *apidoc.Field) string {
+	var t string
+	switch {
+	case f.Message != nil:
+		t = c.Type(f.Message.Name)
+	case f.Enum != nil:
+		t = c.Type(f.Enum.Name)
+	case tsNumbers[f.Type]:
+		t = "number"
+	case f.Type == "bool":
+		t = "boolean"
+	default:
+		t = "string"
+	}
+	switch {
+	case f.Kind == apidoc.KindMap:
+		return "Record<string, " + t + ">"
+	case f.Repeated:
+		return t + "[]"
+	}
+	return t
+}
+
+// TypeScript 为每个路由组生成一个使用 fetch 的 ts 客户端 包含所有消息的 interface、枚举与错误码
+func TypeScript(p *apidoc.Project) ([]apidoc.Page, error) {
+	var pages []apidoc.Page
+	for _, g := range p.Groups {
+		c := collect(p, g)
+		data := struct {
+			Group    *apidoc.Group
+			Messages []tsMessage
+			Enums    []goEnum
+			Errors   []goError
+			APIs     []tsAPI
+		}{Group: g}
+		for _, m := range c.Messages {
+			tm := tsMessage{Name: c.Type(m.Name), Desc: comment(m.Desc)}
+			for _, f := range m.Fields {
+				tm.Fields = append(tm.Fields, tsField{Name: tsKey(f.Name), Type: c.tsType(f), Optional: !f.Required, Desc: comment(f.Desc)})
+			}
+			data.Messages = append(data.Messages, tm)
+		}
+		for _, e := range c.Enums {
+			te := goEnum{Name: c.Type(e.Name), Desc: comment(e.Desc)}
+			for _, v := range e.Values {
+				te.Values = append(te.Values, goValue{Name: v.Name, Number: v.Number, Desc: comment(v.Desc)})
+			}
+			data.Enums = append(data.Enums, te)
+		}
+		names := map[string]bool{}
+		for _, e := range c.Errors {
+			name := e.Name
+			if names[name] {
+				name = fmt.Sprintf("%s%d", name, e.Code)
+			}
+			names[name] = true
+			data.Errors = append(data.Errors, goError{Var: name, Code: e.Code, Msg: comment(e.Msg)})
+		}
+		for _, api := range g.APIs {
+			data.APIs = append(data.APIs, tsAPI{
+				Name:   toolkit.FirstLower(api.Name),
+				Desc:   comment(api.Desc),
+				Method: method(api),
+				Path:   api.Path,
+				Req:    c.Type(api.Req.Name),
+				Resp:   c.Type(api.Resp.Name),
+				Query:  tsQuery(api),
+			})
+		}
+		var buf bytes.Buffer
+		if err := tsT.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		pages = append(pages, apidoc.Page{Path: TSFile(g), Content: buf.Bytes()})
+	}
+	return pages, nil
+}
+
+// tsQuery GET 请求的参数名 服务端 gin 按表单绑定 没有 form tag 时匹配 go 字段名而不是 json 名称
+func tsQuery(api *apidoc.API) string {
+	if method(api) != "GET" || len(api.Req.Fields) == 0 {
+		return ""
+	}
+	var pairs []string
+	for _, f := range api.Req.Fields {
+		pairs = append(pairs, fmt.Sprintf("%s: %q", tsKey(f.Name), f.Query))
+	}
+	return "{ " + strings.Join(pairs, ", ") + " }"
+}
+
+// tsKey 不是合法标识符的属性名加上引号
+func tsKey(name string) string {
+	for i, r := range name {
+		if !(r == '_' || r == '$' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return fmt.Sprintf("%q", name)
+		}
+	}
+	return name
+}
+
+var tsT = template.Must(template.New("ts").Funcs(template.FuncMap{"quote": func(s string) string {
+	return fmt.Sprintf("%q", s)
+}}).Parse(tsTpl))
+
+const tsTpl = `// Code generated by iotaer gen --client. DO NOT EDIT.
+// 路由组 {{.Group.Name}} 的 HTTP 客户端{{if .Group.Desc}} {{.Group.Desc}}{{end}}
+// 定义: {{.Group.Proto}}
+{{range .Messages}}
+/** {{.Name}}{{if .Desc}} {{.Desc}}{{end}} */
+export interface {{.Name}} { {{- range .Fields}}{{if .Desc}}
+  /** {{.Desc}} */{{end}}
+  {{.Name}}{{if .Optional}}?{{end}}: {{.Type}};{{end}}
+}
+{{end}}{{range .Enums}}
+/** {{.Name}}{{if .Desc}} {{.Desc}}{{end}} */
+export enum {{.Name}} { {{- range .Values}}{{if .Desc}}
+  /** {{.Desc}} */{{end}}
+  {{.Name}} = {{.Number}},{{end}}
+}
+{{end}}
+/** 服务端 ErrCode 枚举中的错误码 */
+export const ErrCode = { {{- range .Errors}}
+  /** {{.Msg}} */
+  {{.Var}}: {{.Code}},{{end}}
+} as const;
+
+/** 服务端返回的错误码不为 0 时抛出的错误 */
+export class ApiError extends Error {
+  constructor(public readonly code: number, message: string, public readonly hint?: string) {
+    super(message);
+    this.name = "ApiError";
+  }
+}
+
+/** HTTP 状态码不为 200 且没有返回框架结构时抛出的错误 */
+export class StatusError extends Error {
+  constructor(public readonly status: number, public readonly body: string) {
+    super(` + "`http status ${status}: ${body}`" + `);
+    this.name = "StatusError";
+  }
+}
+
+export interface ClientOptions {
+  /** 服务地址 如 http://user.svc:8080 */
+  baseURL: string;
+  /** 每个请求都带上的请求头 */
+  headers?: Record<string, string>;
+  /** 网络错误与 429、502、503、504 时的重试次数 默认 2 */
+  retries?: number;
+  /** 允许重试的请求方式 默认只有 GET, 其他请求重试可能重复执行 需要时显式开启 */
+  retryMethods?: string[];
+  /** 第一次重试的等待时间 毫秒 之后每次翻倍 默认 100 */
+  retryDelay?: number;
+  /** 默认使用全局的 fetch */
+  fetch?: typeof fetch;
+}
+
+interface Result<T> {
+  err_code: number;
+  err_msg: string;
+  hint?: string;
+  data?: T;
+}
+
+const retryStatus = [429, 502, 503, 504];
+
+/** 路由组 {{.Group.Name}} 的客户端 */
+export class {{.Group.Name}}Client {
+  constructor(private readonly options: ClientOptions) {}
+{{range .APIs}}
+  /** {{if .Desc}}{{.Desc}} {{end}}{{.Method}} {{.Path}} */
+  {{.Name}}(req: {{.Req}}, init?: RequestInit): Promise<{{.Resp}}> {
+    return this.request<{{.Resp}}>({{quote .Method}}, {{quote .Path}}, req, init{{if .Query}}, {{.Query}}{{end}});
+  }
+{{end}}
+  private async request<T>(method: string, path: string, req: object, init?: RequestInit, queryNames: Record<string, string> = {}): Promise<T> {
+    const params: Record<string, unknown> = { ...(req as Record<string, unknown>) };
+    let url = this.options.baseURL.replace(/\/$/, "") + path;
+    const headers: Record<string, string> = { ...this.options.headers };
+    let body: string | undefined;
+    // 服务端 GET 请求按表单绑定 query 参数名为 go 字段名
+    if (method === "GET") {
+      const query = new URLSearchParams();
+      for (const [key, value] of Object.entries(params)) {
+        if (value === undefined || value === null || typeof value === "object" && !Array.isArray(value)) {
+          continue;
+        }
+        for (const item of Array.isArray(value) ? value : [value]) {
+          query.append(queryNames[key] ?? key, String(item));
+        }
+      }
+      const qs = query.toString();
+      if (qs) {
+        url += "?" + qs;
+      }
+    } else {
+      headers["Content-Type"] = "application/json";
+      body = JSON.stringify(req);
+    }
+
+    const doFetch = this.options.fetch ?? fetch;
+    const retries = (this.options.retryMethods ?? ["GET"]).includes(method) ? this.options.retries ?? 2 : 0;
+    let delay = this.options.retryDelay ?? 100;
+    for (let attempt = 0; ; attempt++) {
+      let res: Response;
+      try {
+        res = await doFetch(url, { ...init, method, headers: { ...headers, ...(init?.headers as Record<string, string>) }, body });
+      } catch (err) {
+        if (attempt >= retries || init?.signal?.aborted) {
+          throw err;
+        }
+        await new Promise((resolve) => setTimeout(resolve, delay));
+        delay *= 2;
+        continue;
+      }
+      const text = await res.text();
+      let result: Result<T> | undefined;
+      try {
+        result = JSON.parse(text) as Result<T>;
+      } catch {
+        result = undefined;
+      }
+      if (result && result.err_code) {
+        throw new ApiError(result.err_code, result.err_msg, result.hint);
+      }
+      if (res.status !== 200) {
+        if (attempt < retries && retryStatus.includes(res.status)) {
+          await new Promise((resolve) => setTimeout(resolve, delay));
+          delay *= 2;
+          continue;
+        }
+        throw new StatusError(res.status, text);
+      }
+      return (result?.data ?? {}) as T;
+    }
+  }
+}
+`
@@ -0,0 +1,483 @@
+package apidoc
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/actorbuf/iotaer/errcode"
+	"github.com/actorbuf/iotaer/protodef"
+	"github.com/actorbuf/iotaer/toolkit"
+)
+
+// 字段类型
+const (
+	KindScalar  = "scalar"
+	KindMessage = "message"
+	KindEnum    = "enum"
+	KindMap     = "map"
+)
+
+// scalars proto 标量类型
+var scalars = map[string]bool{
+	"double": true, "float": true, "int32": true, "int64": true, "uint32": true, "uint64": true,
+	"sint32": true, "sint64": true, "fixed32": true, "fixed64": true, "sfixed32": true, "sfixed64": true,
+	"bool": true, "string": true, "bytes": true,
+}
+
+// Project 项目中的路由组接口 消息与枚举已按 proto 的作用域规则解析
+type Project struct {
+	Groups []*Group
+	Errors []*ErrorFile
+
+	files    []*protodef.File
+	messages map[string]*target // 全名 => 定义
+	enums    map[string]*target
+	cache    map[string]*Message
+	codes    map[string]*ErrorCode // 名称与 包名.名称 => 错误码
+}
+
+type target struct {
+	file *protodef.File
+	msg  *protodef.Message
+	enum *protodef.Enum
+}
+
+// Group 路由组
+type Group struct {
+	Name    string
+	Desc    string
+	Prefix  string
+	Proto   string // 定义所在的 proto 文件
+	Package string // proto 包名
+	APIs    []*API
+}
+
+// API 路由组中的一个接口
+type API struct {
+	Group  *Group
+	Name   string
+	Desc   string
+	Author string
+	Method string
+	Path   string // 完整路径
+	Req    *Message
+	Resp   *Message
+	Errors []*ErrorCode // @error 中标注的错误码 其他项目中的错误码只有名称
+	Pos    protodef.Position
+}
+
+// Service grpc service 即路由组与定时任务之外的 service
+type Service struct {
+	Name  string // 全名 包名.服务名
+	Short string
+	Desc  string
+	Proto string
+	RPCs  []*RPC
+}
+
+// RPC service 中的方法
+type RPC struct {
+	Service    *Service
+	Name       string
+	Desc       string
+	Req        *Message
+	Resp       *Message
+	StreamReq  bool
+	StreamResp bool
+	Pos        protodef.Position
+}
+
+// FullMethod grpc 中的方法名 /包名.服务名/方法名
+func (r *RPC) FullMethod() string {
+	return "/" + r.Service.Name + "/" + r.Name
+}
+
+// Message 消息 Fields 中的消息类型字段指向同一个 Message 递归的消息会形成环
+type Message struct {
+	Name   string // 全名 包名.消息名
+	Short  string // 不带包名的名称
+	Desc   string
+	Fields []*Field
+}
+
+// Field 字段 Name 为 json 名称
+type Field struct {
+	Name     string
+	Proto    string // proto 中的字段名
+	Query    string // GET 请求的 query 参数名 gin 按表单绑定 没有 form tag 时匹配 go 字段名
+	Number   int
+	Type     string // proto 中的类型
+	Kind     string
+	KeyType  string // map 的键类型
+	Repeated bool
+	Required bool // @v 中包含 required
+	Desc     string
+	Example  string   // @example 中的示例值
+	Message  *Message // 消息类型 map 的值为消息时也使用
+	Enum     *Enum
+}
+
+// Enum 枚举
+type Enum struct {
+	Name   string
+	Short  string
+	Desc   string
+	Values []*EnumValue
+}
+
+// EnumValue 枚举值
+type EnumValue struct {
+	Name   string
+	Number int
+	Desc   string
+}
+
+// ErrorFile 一个 proto 文件中的 ErrCode 枚举
+type ErrorFile struct {
+	Package string
+	Proto   string
+	Codes   []*ErrorCode
+}
+
+// ErrorCode 错误码 Package 为空时表示没有找到定义
+type ErrorCode struct {
+	Name    string
+	Code    int
+	Msg     string
+	HTTP    int
+	Package string
+}
+
+// Anchor 错误码在错误码页面中的锚点
+func (e *ErrorCode) Anchor() string {
+	return strings.ToLower(e.Package + "-" + e.Name)
+}
+
+// Load 解析 files 中的路由组 消息与错误码同时从 includes 中查找
+func Load(files, includes []*protodef.File) (*Project, error) {
+	p := &Project{messages: map[string]*target{}, enums: map[string]*target{}, cache: map[string]*Message{}, codes: map[string]*ErrorCode{}}
+	seen := map[string]bool{}
+	var all []*protodef.File
+	for _, f := range append(append([]*protodef.File{}, files...), includes...) {
+		abs, _ := filepath.Abs(f.Path)
+		if seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		all = append(all, f)
+		for _, m := range f.Messages {
+			p.messages[qualify(f.Package, m.Name)] = &target{file: f, msg: m}
+		}
+		for _, e := range f.Enums {
+			p.enums[qualify(f.Package, e.Name)] = &target{file: f, enum: e}
+		}
+	}
+	for _, f := range all {
+		codes := errcode.Codes(f)
+		if codes == nil {
+			continue
+		}
+		ef := &ErrorFile{Package: f.Package, Proto: f.Path}
+		for _, c := range codes {
+			ec := &ErrorCode{Name: c.Name, Code: c.Code, Msg: c.Msg, HTTP: c.HTTP, Package: f.Package}
+			ef.Codes = append(ef.Codes, ec)
+			if _, ok := p.codes[c.Name]; !ok {
+				p.codes[c.Name] = ec
+			}
+			p.codes[qualify(f.Package, c.Name)] = ec
+		}
+		p.Errors = append(p.Errors, ef)
+	}
+
+	for _, f := range files {
+		for _, s := range f.Services {
+			if s.Kind != protodef.KindRoute {
+				continue
+			}
+			g := &Group{Name: s.Name, Desc: s.Desc, Prefix: s.Prefix, Proto: f.Path, Package: f.Package}
+			if g.Desc == "" {
+				g.Desc = protodef.Describe(s.Comment)
+			}
+			for _, m := range s.Methods {
+				api := &API{Group: g, Name: m.Name, Desc: m.Desc, Author: m.Author, Method: m.HTTPMethod, Path: s.FullPath(m), Pos: m.Pos}
+				var err error
+				if api.Req, err = p.message(f, "", m.Req); err != nil {
+					return nil, fmt.Errorf("%s: %v", m.Pos, err)
+				}
+				if api.Resp, err = p.message(f, "", m.Resp); err != nil {
+					return nil, fmt.Errorf("%s: %v", m.Pos, err)
+				}
+				for _, name := range ErrorNames(m.Comment) {
+					api.Errors = append(api.Errors, p.errorCode(f, name))
+				}
+				g.APIs = append(g.APIs, api)
+			}
+			p.Groups = append(p.Groups, g)
+		}
+	}
+	sort.SliceStable(p.Groups, func(i, j int) bool { return p.Groups[i].Name < p.Groups[j].Name })
+	p.files = files
+	return p, nil
+}
+
+// Services 解析 files 中的 grpc service 与路由组不同 只在需要时解析 引用的消息找不到时返回错误
+func (p *Project) Services() ([]*Service, error) {
+	var services []*Service
+	for _, f := range p.files {
+		for _, s := range f.Services {
+			if s.Kind != protodef.KindRPC && s.Kind != protodef.KindPlain {
+				continue
+			}
+			svc := &Service{Name: qualify(f.Package, s.Name), Short: s.Name, Desc: s.Desc, Proto: f.Path}
+			if svc.Desc == "" {
+				svc.Desc = protodef.Describe(s.Comment)
+			}
+			for _, m := range s.Methods {
+				rpc := &RPC{Service: svc, Name: m.Name, Desc: protodef.Describe(m.Comment), StreamReq: m.StreamReq, StreamResp: m.StreamResp, Pos: m.Pos}
+				var err error
+				if rpc.Req, err = p.message(f, "", m.Req); err != nil {
+					return nil, fmt.Errorf("%s: %v", m.Pos, err)
+				}
+				if rpc.Resp, err = p.message(f, "", m.Resp); err != nil {
+					return nil, fmt.Errorf("%s: %v", m.Pos, err)
+				}
+				svc.RPCs = append(svc.RPCs, rpc)
+			}
+			services = append(services, svc)
+		}
+	}
+	sort.SliceStable(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
+
+func qualify(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+// lookup 按 proto 的作用域规则查找类型: 由内向外的嵌套作用域、当前包, 最后为全名
+func lookup(index map[string]*target, f *protodef.File, scope, typ string) (string, *target) {
+	if strings.HasPrefix(typ, ".") {
+		name := strings.TrimPrefix(typ, ".")
+		return name, index[name]
+	}
+	for scope != "" {
+		name := qualify(f.Package, scope+"."+typ)
+		if t, ok := index[name]; ok {
+			return name, t
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+	name := qualify(f.Package, typ)
+	if t, ok := index[name]; ok {
+		return name, t
+	}
+	return typ, index[typ]
+}
+
+// message 解析消息 同名消息只解析一次
+func (p *Project) message(f *protodef.File, scope, typ string) (*Message, error) {
+	name, t := lookup(p.messages, f, scope, typ)
+	if t == nil && strings.HasPrefix(strings.TrimPrefix(typ, "."), "google.protobuf.") {
+		// 没有引入定义的 well-known 类型 按空消息处理
+		name = strings.TrimPrefix(typ, ".")
+		if _, ok := p.cache[name]; !ok {
+			p.cache[name] = &Message{Name: name, Short: strings.TrimPrefix(name, "google.protobuf.")}
+		}
+		return p.cache[name], nil
+	}
+	if t == nil {
+		return nil, fmt.Errorf("找不到消息 %s, 请通过 --include 指定依赖的 proto", typ)
+	}
+	if m, ok := p.cache[name]; ok {
+		return m, nil
+	}
+	m := &Message{Name: name, Short: t.msg.Name, Desc: protodef.Describe(t.msg.Comment)}
+	p.cache[name] = m
+	style := JSONStyle(t.file, t.msg)
+	for _, fd := range t.msg.Fields {
+		field := &Field{
+			Name:     JSONName(fd, style),
+			Proto:    fd.Name,
+			Query:    GoName(fd.Name),
+			Number:   fd.Number,
+			Type:     fd.Type,
+			KeyType:  fd.KeyType,
+			Repeated: fd.Repeated,
+			Required: strings.Contains(protodef.Tag(fd.Comment, "v"), "required"),
+			Desc:     protodef.Describe(fd.Comment),
+			Example:  protodef.Tag(fd.Comment, "example"),
+			Kind:     KindScalar,
+		}
+		if fd.KeyType != "" {
+			field.Kind = KindMap
+		}
+		if !scalars[fd.Type] {
+			if _, et := lookup(p.enums, t.file, t.msg.Name, fd.Type); et != nil {
+				field.Enum = newEnum(t.file, et.enum)
+				if field.Kind == KindScalar {
+					field.Kind = KindEnum
+				}
+			} else {
+				sub, err := p.message(t.file, t.msg.Name, fd.Type)
+				if err != nil {
+					return nil, err
+				}
+				field.Message = sub
+				if field.Kind == KindScalar {
+					field.Kind = KindMessage
+				}
+			}
+		}
+		m.Fields = append(m.Fields, field)
+	}
+	return m, nil
+}
+
+func newEnum(f *protodef.File, e *protodef.Enum) *Enum {
+	enum := &Enum{Name: qualify(f.Package, e.Name), Short: e.Name, Desc: protodef.Describe(e.Comment)}
+	for _, v := range e.Values {
+		enum.Values = append(enum.Values, &EnumValue{Name: v.Name, Number: v.Number, Desc: protodef.Describe(v.Comment)})
+	}
+	return enum
+}
+
+func (p *Project) errorCode(f *protodef.File, name string) *ErrorCode {
+	if c, ok := p.codes[qualify(f.Package, name)]; ok {
+		return c
+	}
+	if c, ok := p.codes[name]; ok {
+		return c
+	}
+	return &ErrorCode{Name: name}
+}
+
+// gen 注入 json tag 时支持的 @json_style
+const (
+	StyleRaw        = "raw"
+	StyleUnderscore = "underscore"
+	StyleLowerCamel = "lower_camel"
+	StyleUpperCamel = "upper_camel"
+	StyleKebabCase  = "kebab_case"
+)
+
+// JSONStyle 消息注释中的 @json_style 嵌套消息继承外层消息的风格 默认为 raw
+func JSONStyle(f *protodef.File, m *protodef.Message) string {
+	name := m.Name
+	for {
+		if msg := f.Message(name); msg != nil {
+			if style := protodef.Tag(msg.Comment, "json_style"); style != "" {
+				return style
+			}
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			return StyleRaw
+		}
+		name = name[:i]
+	}
+}
+
+// JSONName 字段的 json 名称 与 gen 注入的 tag 一致: 优先 @json, 否则按消息的 json 风格转换字段名
+func JSONName(f *protodef.Field, style string) string {
+	name := protodef.Tag(f.Comment, "json")
+	if i := strings.IndexAny(name, ", "); i >= 0 {
+		name = name[:i]
+	}
+	if name != "" {
+		return name
+	}
+	switch style {
+	case StyleUnderscore:
+		return toolkit.Calm2Case(f.Name)
+	case StyleLowerCamel:
+		return toolkit.FirstLower(camel(f.Name))
+	case StyleUpperCamel:
+		return camel(f.Name)
+	case StyleKebabCase:
+		return kebab(f.Name)
+	}
+	return f.Name
+}
+
+// GoName protoc-gen-go 生成的字段名 与 protogen.GoCamelCase 一致
+func GoName(name string) string {
+	lower := func(c byte) bool { return 'a' <= c && c <= 'z' }
+	var b []byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(name) && lower(name[i+1]):
+		case '0' <= c && c <= '9':
+			b = append(b, c)
+		default:
+			if lower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+			for ; i+1 < len(name) && lower(name[i+1]); i++ {
+				b = append(b, name[i+1])
+			}
+		}
+	}
+	return string(b)
+}
+
+// camel 下划线转大驼峰
+func camel(name string) string {
+	return strings.ReplaceAll(strings.Title(strings.ReplaceAll(name, "_", " ")), " ", "")
+}
+
+// kebab 驼峰转短横线 与 gen 一致 Id 单独作为字段名时为 _id
+func kebab(name string) string {
+	if name == "Id" || name == "ID" {
+		return "_id"
+	}
+	name = strings.ReplaceAll(name, "ID", "Id")
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i != 0 {
+				b.WriteRune('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// ErrorNames 接口注释中 @error 之后到下一个标记之间每行一个错误码名称
+func ErrorNames(doc []string) []string {
+	var names []string
+	in := false
+	for _, line := range doc {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "@error") {
+			in = true
+			if rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "@error"), ":")); rest != "" {
+				names = append(names, strings.Fields(rest)...)
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "@") {
+			in = false
+			continue
+		}
+		if in && line != "" {
+			names = append(names, line)
+		}
+	}
+	return names
+}
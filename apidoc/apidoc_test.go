@@ -0,0 +1,267 @@
+package apidoc_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/actorbuf/iotaer/apidoc"
+	"github.com/actorbuf/iotaer/internal/testproject"
+)
+
+func TestLoad(t *testing.T) {
+	files, includes := testproject.Files(t)
+	if _, err := apidoc.Load(files, nil); err == nil || !strings.Contains(err.Error(), "找不到消息 common.Page") {
+		t.Fatalf("expected missing message error, got %v", err)
+	}
+	p, err := apidoc.Load(files, includes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Groups) != 1 || len(p.Groups[0].APIs) != 3 {
+		t.Fatalf("unexpected groups %+v", p.Groups)
+	}
+	get := p.Groups[0].APIs[0]
+	if get.Method != "GET" || get.Path != "/api/user/get_user" || get.Author != "alice" || !get.Req.Fields[0].Required {
+		t.Fatalf("unexpected api %+v", get)
+	}
+	user := get.Resp.Fields[0].Message
+	var names []string
+	for _, f := range user.Fields {
+		names = append(names, f.Name+":"+f.Kind)
+	}
+	if !reflect.DeepEqual(names, []string{"uid:scalar", "nick_name:scalar", "level:enum", "friends:message", "tags:map", "avatar:scalar", "labels:scalar", "scores:map", "delta:scalar", "rate:scalar"}) {
+		t.Fatalf("unexpected fields %v", names)
+	}
+	if user.Fields[3].Message != user || user.Fields[4].Message.Name != "user.User.Tag" || user.Fields[2].Enum.Name != "user.User.Level" {
+		t.Fatalf("unexpected field types %+v", user.Fields)
+	}
+	if len(get.Errors) != 2 || get.Errors[0].Code != 20001 || get.Errors[0].Anchor() != "user-errcodeusernotfound" || get.Errors[1].Package != "" {
+		t.Fatalf("unexpected errors %+v", get.Errors)
+	}
+	list := p.Groups[0].APIs[1]
+	if list.Req.Fields[0].Message.Name != "common.Page" {
+		t.Fatalf("unexpected include %+v", list.Req.Fields[0])
+	}
+	// 嵌套消息继承外层的 @json_style @json 优先
+	stat := list.Resp.Fields[2].Message
+	if list.Resp.Fields[1].Name != "totalCount" || stat.Fields[0].Name != "views" || stat.Fields[1].Name != "likeCount" {
+		t.Fatalf("unexpected json names %+v %+v", list.Resp.Fields, stat.Fields)
+	}
+
+	// 递归的消息输出为空对象
+	want := `{
+  "uid": 0,
+  "nick_name": "",
+  "level": 0,
+  "friends": [
+    {}
+  ],
+  "tags": {
+    "key": {
+      "name": ""
+    }
+  },
+  "avatar": "",
+  "labels": [
+    ""
+  ],
+  "scores": {
+    "key": 0
+  },
+  "delta": 0,
+  "rate": 0
+}`
+	if got := apidoc.ExampleJSON(apidoc.Example(user)); got != want {
+		t.Fatalf("unexpected example\n%s", got)
+	}
+	if got := apidoc.Query(get.Req); got != "Uid=0&Fields=" {
+		t.Fatalf("unexpected query %s", got)
+	}
+
+	// 合成的示例值 字符串为字段说明 枚举为第一个非零值
+	want = `{
+  "uid": 1,
+  "nick_name": "昵称",
+  "level": 1,
+  "friends": [
+    {}
+  ],
+  "tags": {
+    "key": {
+      "name": "name"
+    }
+  },
+  "avatar": "",
+  "labels": [
+    "labels"
+  ],
+  "scores": {
+    "key": 1
+  },
+  "delta": 1,
+  "rate": 1
+}`
+	if got := apidoc.ExampleJSON(apidoc.Sample(user)); got != want {
+		t.Fatalf("unexpected sample\n%s", got)
+	}
+	if got := apidoc.SampleParams(get.Req); !reflect.DeepEqual(got, []apidoc.Param{{Name: "Uid", Value: "10086", Desc: "用户ID"}, {Name: "Fields", Value: "fields"}}) {
+		t.Fatalf("unexpected params %+v", got)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for name, want := range map[string]string{
+		"uid": "Uid", "user_id": "UserId", "nickName": "NickName", "ID": "ID", "a_1": "A_1", "_x": "XX", "user2_name": "User2Name",
+	} {
+		if got := apidoc.GoName(name); got != want {
+			t.Fatalf("GoName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	pages, err := apidoc.Markdown(testproject.Load(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := map[string]string{}
+	var paths []string
+	for _, p := range pages {
+		paths = append(paths, p.Path)
+		content[p.Path] = string(p.Content)
+	}
+	if !reflect.DeepEqual(paths, []string{"README.md", "errcode.md", "user_api/get_user.md", "user_api/list_user.md", "user_api/update_user.md"}) {
+		t.Fatalf("unexpected pages %v", paths)
+	}
+	for page, wants := range map[string][]string{
+		"README.md": {
+			"- [错误码](errcode.md)",
+			"## UserApi\n\n用户接口\n",
+			"| [GetUser](user_api/get_user.md) | GET | `/api/user/get_user` | 获取用户 |",
+		},
+		"errcode.md": {
+			`| <a id="user-errcodeusernotfound"></a>ErrCodeUserNotFound | 20001 | 404 | 用户不存在 |`,
+		},
+		"user_api/get_user.md": {
+			"[接口文档](../README.md) / [UserApi](../README.md#userapi)",
+			"| Uid | 是 | int64 | 用户ID |",
+			"GET /api/user/get_user?Uid=0",
+			"| data.user.level | [User.Level](#userlevel)(integer枚举) | - |",
+			"| data.user.friends | []User(object对象) | - |",
+			"| LevelVip | 1 | 会员 |",
+			"| [ErrCodeUserNotFound](../errcode.md#user-errcodeusernotfound) | 20001 | 用户不存在 |",
+			"| ErrCodeOrderNotFound | - | 其他项目中的错误码 |",
+			"下一个: [ListUser](../user_api/list_user.md)",
+		},
+		"user_api/list_user.md": {
+			"| page.page | 否 | int32 | 页码 |",
+			"\"page\": {\n    \"page\": 0,",
+			"上一个: [GetUser](../user_api/get_user.md)",
+		},
+		"user_api/update_user.md": {
+			"- `/api/user/users/update`",
+			"| vip | 否 | bool | - |",
+			"上一个: [ListUser](../user_api/list_user.md)",
+		},
+	} {
+		for _, want := range wants {
+			if !strings.Contains(content[page], want) {
+				t.Fatalf("%s: expected %q in\n%s", page, want, content[page])
+			}
+		}
+	}
+	if strings.Contains(content["user_api/update_user.md"], "下一个") {
+		t.Fatal("unexpected next link on last page")
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	doc := apidoc.OpenAPI(testproject.Load(t), apidoc.Info{Title: "user", Version: "1.0.0", Servers: []string{"http://localhost:8080"}})
+	out, err := doc.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		OpenAPI string
+		Paths   map[string]map[string]struct {
+			Parameters []struct {
+				Name     string
+				In       string
+				Required bool
+			}
+			RequestBody map[string]interface{}
+			Errors      []struct {
+				Name string
+				Code int
+			} `json:"x-error-codes"`
+		}
+		Components struct {
+			Schemas map[string]map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(out, &v); err != nil {
+		t.Fatal(err)
+	}
+	get := v.Paths["/api/user/get_user"]["get"]
+	if v.OpenAPI != apidoc.OpenAPIVersion || len(get.Parameters) != 2 || get.Parameters[0].Name != "Uid" || !get.Parameters[0].Required || get.Parameters[1].Name != "Fields" || get.RequestBody != nil {
+		t.Fatalf("unexpected get operation %+v", get)
+	}
+	if len(get.Errors) != 2 || get.Errors[0].Code != 20001 || get.Errors[1].Code != 0 {
+		t.Fatalf("unexpected errors %+v", get.Errors)
+	}
+	if v.Paths["/api/user/list_user"]["post"].RequestBody == nil {
+		t.Fatal("expected request body for post")
+	}
+	var names []string
+	for name := range v.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"Result", "common.Page", "user.ErrCode", "user.GetUserResp", "user.ListUserReq", "user.ListUserResp", "user.ListUserResp.Stat", "user.UpdateUserReq", "user.UpdateUserResp", "user.User", "user.User.Level", "user.User.Tag"}) {
+		t.Fatalf("unexpected schemas %v", names)
+	}
+
+	out, err = doc.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"openapi: 3.1.0\n",
+		"      responses:\n        \"200\":\n",
+		"        friends:\n          type: array\n          items:\n            $ref: '#/components/schemas/user.User'\n",
+		"        tags:\n          type: object\n          additionalProperties:\n            $ref: '#/components/schemas/user.User.Tag'\n",
+		"        level:\n          $ref: '#/components/schemas/user.User.Level'\n",
+		"        - 20001\n",
+		"      x-enum-http:\n",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Fatalf("expected %q in\n%s", want, out)
+		}
+	}
+
+	// ANY 展开为多个操作 OpenAPI 中没有 any
+	p := testproject.Load(t)
+	p.Groups[0].APIs[1].Method = "ANY"
+	out, err = apidoc.OpenAPI(p, apidoc.Info{Title: "user"}).JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var anyDoc struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Parameters  []interface{}
+			RequestBody map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(out, &anyDoc); err != nil {
+		t.Fatal(err)
+	}
+	ops := anyDoc.Paths["/api/user/list_user"]
+	if len(ops) != 5 || ops["any"].OperationID != "" || ops["get"].RequestBody != nil || ops["delete"].RequestBody == nil ||
+		ops["post"].OperationID != "UserApi_ListUser_post" {
+		t.Fatalf("unexpected operations %+v", ops)
+	}
+}
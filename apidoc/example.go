@@ -0,0 +1,202 @@
+package apidoc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// object 按字段顺序输出的 json 对象
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (o *object) set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		buf.Write(key)
+		buf.WriteByte(':')
+		v, err := json.Marshal(o.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Example 消息的示例值 字段为类型的零值 数组与 map 包含一个元素 递归的消息输出为空对象
+func Example(m *Message) interface{} {
+	return example(m, map[*Message]bool{}, zero)
+}
+
+// Sample 按字段类型与注释合成的示例值 用于可以直接发送的请求与 mock 的响应
+// @example 优先, 字符串为字段说明或字段名, 数字为 1, 布尔为 true, 枚举为第一个非零值
+func Sample(m *Message) interface{} {
+	return example(m, map[*Message]bool{}, sample)
+}
+
+// ExampleJSON 格式化后的示例 json
+func ExampleJSON(v interface{}) string {
+	out, _ := json.MarshalIndent(v, "", "  ")
+	return string(out)
+}
+
+// Response 框架返回的结构 data 为响应消息
+func Response(m *Message) interface{} {
+	return Result(Example(m))
+}
+
+// Result 框架返回的结构 data 为 v
+func Result(v interface{}) interface{} {
+	o := &object{values: map[string]interface{}{}}
+	o.set("err_code", 0)
+	o.set("err_msg", "ok")
+	o.set("data", v)
+	return o
+}
+
+func example(m *Message, visiting map[*Message]bool, leaf func(f *Field) interface{}) interface{} {
+	o := &object{values: map[string]interface{}{}}
+	if visiting[m] {
+		return o
+	}
+	visiting[m] = true
+	defer delete(visiting, m)
+	for _, f := range m.Fields {
+		var v interface{}
+		if f.Message != nil {
+			v = example(f.Message, visiting, leaf)
+		} else {
+			v = leaf(f)
+		}
+		switch {
+		case f.Kind == KindMap:
+			key := "key"
+			if f.KeyType != "string" {
+				key = "0"
+			}
+			mo := &object{values: map[string]interface{}{}}
+			mo.set(key, v)
+			o.set(f.Name, mo)
+		case f.Repeated:
+			o.set(f.Name, []interface{}{v})
+		default:
+			o.set(f.Name, v)
+		}
+	}
+	return o
+}
+
+// zero 非消息字段单个元素的零值 枚举为第一个值
+func zero(f *Field) interface{} {
+	if f.Enum != nil {
+		if len(f.Enum.Values) > 0 {
+			return f.Enum.Values[0].Number
+		}
+		return 0
+	}
+	switch f.Type {
+	case "string", "bytes":
+		return ""
+	case "bool":
+		return false
+	}
+	return 0
+}
+
+// sample 非消息字段单个元素的示例值
+func sample(f *Field) interface{} {
+	if f.Enum != nil {
+		for _, v := range f.Enum.Values {
+			if f.Example == v.Name || f.Example == strconv.Itoa(v.Number) {
+				return v.Number
+			}
+		}
+		for _, v := range f.Enum.Values {
+			if v.Number != 0 {
+				return v.Number
+			}
+		}
+		return zero(f)
+	}
+	switch f.Type {
+	case "string":
+		switch {
+		case f.Example != "":
+			return f.Example
+		case f.Desc != "":
+			return f.Desc
+		}
+		return f.Name
+	case "bytes":
+		// json 中的 bytes 为 base64
+		if f.Example != "" {
+			return base64.StdEncoding.EncodeToString([]byte(f.Example))
+		}
+		return ""
+	case "bool":
+		if b, err := strconv.ParseBool(f.Example); err == nil {
+			return b
+		}
+		return true
+	}
+	if _, err := strconv.ParseFloat(f.Example, 64); err == nil {
+		return json.Number(f.Example)
+	}
+	return 1
+}
+
+// Query GET 请求的示例参数 只包含顶层的非消息字段 参数名为 gin 表单绑定匹配的 go 字段名
+func Query(m *Message) string {
+	var parts []string
+	for _, f := range m.Fields {
+		if f.Message != nil || f.Kind == KindMap {
+			continue
+		}
+		v := zero(f)
+		if s, ok := v.(string); ok {
+			v = url.QueryEscape(s)
+		}
+		parts = append(parts, fmt.Sprintf("%s=%v", url.QueryEscape(f.Query), v))
+	}
+	return strings.Join(parts, "&")
+}
+
+// Param 请求中的一个 query 参数
+type Param struct {
+	Name  string
+	Value string
+	Desc  string
+}
+
+// SampleParams GET 请求的示例参数 与 Query 相同只包含顶层的非消息字段 值与 Sample 一致
+// 参数名为 gin 表单绑定匹配的 go 字段名
+func SampleParams(m *Message) []Param {
+	var params []Param
+	for _, f := range m.Fields {
+		if f.Message != nil || f.Kind == KindMap {
+			continue
+		}
+		params = append(params, Param{Name: f.Query, Value: fmt.Sprint(sample(f)), Desc: f.Desc})
+	}
+	return params
+}
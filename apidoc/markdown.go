@@ -0,0 +1,291 @@
+package apidoc
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/actorbuf/iotaer/toolkit"
+)
+
+// Page 一个文档页面 Path 为相对文档目录的路径
+type Page struct {
+	Path    string
+	Content []byte
+}
+
+// 文档目录中固定的页面
+const (
+	IndexPage   = "README.md"
+	ErrCodePage = "errcode.md"
+)
+
+// PagePath 接口页面的路径 按路由组分目录
+func PagePath(api *API) string {
+	return path.Join(toolkit.Calm2Case(api.Group.Name), toolkit.Calm2Case(api.Name)+".md")
+}
+
+// Anchor 与 GitHub 一致的标题锚点
+func Anchor(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(title)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// row 表格中的一行 嵌套消息的字段名带上父字段前缀
+type row struct {
+	Name     string
+	Required bool
+	Type     string
+	Desc     string
+}
+
+// fieldType 文档中的类型 消息与枚举链接到页面中的说明
+func fieldType(f *Field) string {
+	var t string
+	switch {
+	case f.Kind == KindMap:
+		t = fmt.Sprintf("map<%s, %s>", f.KeyType, f.Type)
+	case f.Enum != nil:
+		t = fmt.Sprintf("[%s](#%s)(integer枚举)", f.Enum.Short, Anchor(f.Enum.Short))
+	case f.Message != nil:
+		t = f.Message.Short + "(object对象)"
+	default:
+		t = f.Type
+	}
+	if f.Repeated {
+		t = "[]" + t
+	}
+	return t
+}
+
+func cell(s string) string {
+	if s == "" {
+		return "-"
+	}
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+// rows 展开消息的字段 递归的消息只展开一层
+func rows(m *Message, prefix string, visiting map[*Message]bool, enums *[]*Enum) []row {
+	if visiting[m] {
+		return nil
+	}
+	visiting[m] = true
+	defer delete(visiting, m)
+	var list []row
+	for _, f := range m.Fields {
+		list = append(list, row{Name: prefix + f.Name, Required: f.Required, Type: fieldType(f), Desc: cell(f.Desc)})
+		addEnum(enums, f.Enum)
+		if f.Message != nil {
+			list = append(list, rows(f.Message, prefix+f.Name+".", visiting, enums)...)
+		}
+	}
+	return list
+}
+
+// queryRows GET 请求的参数 与 Query 一致只包含顶层的非消息字段 参数名为 gin 表单绑定匹配的 go 字段名
+func queryRows(m *Message, enums *[]*Enum) []row {
+	var list []row
+	for _, f := range m.Fields {
+		if f.Message != nil || f.Kind == KindMap {
+			continue
+		}
+		list = append(list, row{Name: f.Query, Required: f.Required, Type: fieldType(f), Desc: cell(f.Desc)})
+		addEnum(enums, f.Enum)
+	}
+	return list
+}
+
+func addEnum(enums *[]*Enum, enum *Enum) {
+	if enum == nil {
+		return
+	}
+	for _, e := range *enums {
+		if e.Name == enum.Name {
+			return
+		}
+	}
+	*enums = append(*enums, enum)
+}
+
+var funcs = template.FuncMap{
+	"cell":   cell,
+	"anchor": Anchor,
+	"page":   PagePath,
+}
+
+const indexTpl = `# 接口文档
+{{if .Errors}}
+- [错误码](` + ErrCodePage + `)
+{{end}}{{range .Groups}}
+## {{.Name}}
+{{if .Desc}}
+{{.Desc}}
+{{end}}
+- 前缀: ` + "`{{if .Prefix}}{{.Prefix}}{{else}}/{{end}}`" + `
+- 定义: ` + "`{{.Proto}}`" + `
+
+|接口|请求方式|路径|说明|
+| :---- | :---- | :---- | ---- |{{range .APIs}}
+| [{{.Name}}]({{page .}}) | {{.Method}} | ` + "`{{.Path}}`" + ` | {{cell .Desc}} |{{end}}
+{{end}}`
+
+const apiTpl = `# {{.API.Name}}
+
+[接口文档](../` + IndexPage + `) / [{{.API.Group.Name}}](../` + IndexPage + `#{{anchor .API.Group.Name}})
+
+**简要描述:**
+
+- {{cell .API.Desc}}
+
+**请求URL:**
+
+- ` + "`{{.API.Path}}`" + `
+
+**请求方式:**
+
+- {{.API.Method}}
+
+**对接人:**
+
+- {{cell .API.Author}}
+
+**参数:**
+{{if .ReqRows}}
+|参数名|必选|类型|说明|
+| :---- | :--- | :----- | ----- |{{range .ReqRows}}
+| {{.Name}} | {{if .Required}}是{{else}}否{{end}} | {{.Type}} | {{.Desc}} |{{end}}
+{{else}}
+> 该接口没有请求参数
+{{end}}
+**请求示例**
+{{if .Get}}
+` + "```" + `
+{{.API.Method}} {{.API.Path}}{{if .Query}}?{{.Query}}{{end}}
+` + "```" + `
+{{else}}
+` + "```json" + `
+{{.ReqBody}}
+` + "```" + `
+{{end}}
+**返回示例**
+
+` + "```json" + `
+{{.RespBody}}
+` + "```" + `
+
+**返回参数说明**
+{{if .RespRows}}
+|参数名|类型|说明|
+| :---- | :---- | ----- |{{range .RespRows}}
+| data.{{.Name}} | {{.Type}} | {{.Desc}} |{{end}}
+{{else}}
+> 该接口不需要关注输出而应该关注错误码
+{{end}}{{if .Enums}}
+**枚举说明**
+{{range .Enums}}
+### {{.Short}}
+{{if .Desc}}
+{{.Desc}}
+{{end}}
+|枚举参数|枚举数值|枚举说明|
+| :---- | :----- | ----- |{{range .Values}}
+| {{.Name}} | {{.Number}} | {{cell .Desc}} |{{end}}
+{{end}}{{end}}{{if .API.Errors}}
+**接口返回错误码**
+
+|错误标注|错误码|说明|
+| :---- | :---- | ---- |{{range .API.Errors}}{{if .Package}}
+| [{{.Name}}](../` + ErrCodePage + `#{{.Anchor}}) | {{.Code}} | {{cell .Msg}} |{{else}}
+| {{.Name}} | - | 其他项目中的错误码 |{{end}}{{end}}
+{{end}}
+---
+{{if .Prev}}
+上一个: [{{.Prev.Name}}](../{{page .Prev}})
+{{end}}{{if .Next}}
+下一个: [{{.Next.Name}}](../{{page .Next}})
+{{end}}`
+
+const errCodeTpl = `# 错误码
+
+[接口文档](` + IndexPage + `)
+{{range .Errors}}
+## {{.Package}}
+
+- 定义: ` + "`{{.Proto}}`" + `
+
+|名称|错误码|HTTP|说明|
+| :---- | :---- | :---- | ---- |{{range .Codes}}
+| <a id="{{.Anchor}}"></a>{{.Name}} | {{.Code}} | {{if .HTTP}}{{.HTTP}}{{else}}-{{end}} | {{cell .Msg}} |{{end}}
+{{end}}`
+
+var (
+	indexT   = template.Must(template.New("index").Funcs(funcs).Parse(indexTpl))
+	apiT     = template.Must(template.New("api").Funcs(funcs).Parse(apiTpl))
+	errCodeT = template.Must(template.New("errcode").Funcs(funcs).Parse(errCodeTpl))
+)
+
+// Markdown 生成整个项目的文档: 按路由组的目录页、每个接口一页以及错误码页面
+func Markdown(p *Project) ([]Page, error) {
+	var pages []Page
+	render := func(name string, t *template.Template, data interface{}) error {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return err
+		}
+		pages = append(pages, Page{Path: name, Content: buf.Bytes()})
+		return nil
+	}
+	if err := render(IndexPage, indexT, p); err != nil {
+		return nil, err
+	}
+	if len(p.Errors) > 0 {
+		if err := render(ErrCodePage, errCodeT, p); err != nil {
+			return nil, err
+		}
+	}
+	for _, g := range p.Groups {
+		for i, api := range g.APIs {
+			data := struct {
+				API               *API
+				ReqRows, RespRows []row
+				Enums             []*Enum
+				Get               bool // GET 请求的参数在 query 中
+				Query             string
+				ReqBody, RespBody string
+				Prev, Next        *API
+			}{API: api}
+			if api.Method == "GET" {
+				data.Get, data.Query = true, Query(api.Req)
+				data.ReqRows = queryRows(api.Req, &data.Enums)
+			} else {
+				data.ReqRows = rows(api.Req, "", map[*Message]bool{}, &data.Enums)
+			}
+			data.RespRows = rows(api.Resp, "", map[*Message]bool{}, &data.Enums)
+			data.ReqBody = ExampleJSON(Example(api.Req))
+			data.RespBody = ExampleJSON(Response(api.Resp))
+			if i > 0 {
+				data.Prev = g.APIs[i-1]
+			}
+			if i+1 < len(g.APIs) {
+				data.Next = g.APIs[i+1]
+			}
+			if err := render(PagePath(api), apiT, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return pages, nil
+}
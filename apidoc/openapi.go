@@ -0,0 +1,348 @@
+package apidoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIVersion 生成文档的 OpenAPI 版本
+const OpenAPIVersion = "3.1.0"
+
+// ResultSchema 框架返回结构在 components.schemas 中的名称
+const ResultSchema = "Result"
+
+// Info 文档的基本信息
+type Info struct {
+	Title   string
+	Version string
+	Servers []string
+}
+
+// Document OpenAPI 文档 保持生成时的字段顺序
+type Document struct {
+	root *object
+}
+
+// JSON 输出 json 格式的文档
+func (d *Document) JSON() ([]byte, error) {
+	out, err := json.MarshalIndent(d.root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// YAML 输出 yaml 格式的文档
+func (d *Document) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalYAML 按字段顺序输出 yaml 的键 数字形式的键(响应状态码)保持为字符串
+func (o *object) MarshalYAML() (interface{}, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, k := range o.keys {
+		var v yaml.Node
+		if err := v.Encode(o.values[k]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, &v)
+	}
+	return node, nil
+}
+
+// obj 按参数顺序构造对象 参数为 键, 值 交替
+func obj(kv ...interface{}) *object {
+	o := &object{values: map[string]interface{}{}}
+	for i := 0; i+1 < len(kv); i += 2 {
+		o.set(kv[i].(string), kv[i+1])
+	}
+	return o
+}
+
+func ref(name string) *object {
+	return obj("$ref", "#/components/schemas/"+name)
+}
+
+// openapi 生成过程中收集的 schema
+type openapi struct {
+	schemas map[string]*object
+}
+
+// OpenAPI 生成路由组接口的 OpenAPI 3.1 文档
+// 消息、枚举与错误码放在 components.schemas 中 键为带包名的全名
+// 响应按框架的返回结构包装 接口标注的错误码输出在 x-error-codes 中
+func OpenAPI(p *Project, info Info) *Document {
+	g := &openapi{schemas: map[string]*object{}}
+	root := obj("openapi", OpenAPIVersion, "info", obj("title", info.Title, "version", info.Version))
+	if len(info.Servers) > 0 {
+		var servers []interface{}
+		for _, s := range info.Servers {
+			servers = append(servers, obj("url", s))
+		}
+		root.set("servers", servers)
+	}
+
+	var tags []interface{}
+	paths := obj()
+	for _, group := range p.Groups {
+		tag := obj("name", group.Name)
+		if group.Desc != "" {
+			tag.set("description", group.Desc)
+		}
+		tags = append(tags, tag)
+		for _, api := range group.APIs {
+			item, ok := paths.values[api.Path].(*object)
+			if !ok {
+				item = obj()
+				paths.set(api.Path, item)
+			}
+			for _, method := range operationMethods(api) {
+				item.set(strings.ToLower(method), g.operation(api, method))
+			}
+		}
+	}
+	if len(tags) > 0 {
+		root.set("tags", tags)
+	}
+	root.set("paths", paths)
+
+	g.schemas[ResultSchema] = obj(
+		"type", "object",
+		"description", "框架返回的结构 err_code 为 0 时表示成功",
+		"properties", obj(
+			"err_code", obj("type", "integer", "format", "int32", "description", "错误码"),
+			"err_msg", obj("type", "string", "description", "错误信息"),
+			"hint", obj("type", "string", "description", "错误提示"),
+			"data", obj("description", "接口的响应消息"),
+		),
+		"required", []interface{}{"err_code", "err_msg"},
+	)
+	for _, ef := range p.Errors {
+		g.schemas[qualify(ef.Package, "ErrCode")] = errorSchema(ef)
+	}
+	var names []string
+	for name := range g.schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	schemas := obj()
+	for _, name := range names {
+		schemas.set(name, g.schemas[name])
+	}
+	root.set("components", obj("schemas", schemas))
+	return &Document{root: root}
+}
+
+// anyMethods @method: ANY 的接口在文档中展开的请求方式 OpenAPI 中没有 any 操作
+var anyMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// operationMethods 接口在文档中的请求方式
+func operationMethods(api *API) []string {
+	if api.Method == "ANY" {
+		return anyMethods
+	}
+	return []string{api.Method}
+}
+
+// operation 一个接口 GET 请求的参数放在 query 中 其他请求使用 json 请求体
+// gin 按表单绑定 GET 请求 query 参数名为 go 字段名而不是 json 名称
+func (g *openapi) operation(api *API, method string) *object {
+	id := api.Group.Name + "_" + api.Name
+	if method != api.Method {
+		id += "_" + strings.ToLower(method)
+	}
+	op := obj("tags", []interface{}{api.Group.Name}, "operationId", id)
+	if api.Desc != "" {
+		op.set("summary", api.Desc)
+	}
+	if api.Author != "" {
+		op.set("description", "对接人: "+api.Author)
+	}
+	if method == "GET" {
+		var params []interface{}
+		for _, f := range api.Req.Fields {
+			if f.Message != nil || f.Kind == KindMap {
+				continue
+			}
+			param := obj("name", f.Query, "in", "query")
+			if f.Desc != "" {
+				param.set("description", f.Desc)
+			}
+			if f.Required {
+				param.set("required", true)
+			}
+			param.set("schema", g.field(&Field{Type: f.Type, Kind: f.Kind, Repeated: f.Repeated, Enum: f.Enum}))
+			params = append(params, param)
+		}
+		if len(params) > 0 {
+			op.set("parameters", params)
+		}
+	} else {
+		op.set("requestBody", obj(
+			"required", true,
+			"content", obj("application/json", obj("schema", g.message(api.Req))),
+		))
+	}
+
+	schema := obj("allOf", []interface{}{
+		ref(ResultSchema),
+		obj("type", "object", "properties", obj("data", g.message(api.Resp))),
+	})
+	op.set("responses", obj("200", obj(
+		"description", "ok",
+		"content", obj("application/json", obj("schema", schema)),
+	)))
+
+	if len(api.Errors) > 0 {
+		var codes []interface{}
+		for _, e := range api.Errors {
+			code := obj("name", e.Name)
+			if e.Package != "" {
+				code.set("code", e.Code)
+				code.set("message", e.Msg)
+				if e.HTTP != 0 {
+					code.set("http", e.HTTP)
+				}
+				code.set("schema", "#/components/schemas/"+qualify(e.Package, "ErrCode"))
+			}
+			codes = append(codes, code)
+		}
+		op.set("x-error-codes", codes)
+	}
+	return op
+}
+
+// message 消息的引用 第一次引用时生成 schema
+func (g *openapi) message(m *Message) *object {
+	if _, ok := g.schemas[m.Name]; !ok {
+		s := obj("type", "object")
+		g.schemas[m.Name] = s
+		if m.Desc != "" {
+			s.set("description", m.Desc)
+		}
+		props := obj()
+		var required []interface{}
+		for _, f := range m.Fields {
+			props.set(f.Name, g.field(f))
+			if f.Required {
+				required = append(required, f.Name)
+			}
+		}
+		if len(m.Fields) > 0 {
+			s.set("properties", props)
+		}
+		if len(required) > 0 {
+			s.set("required", required)
+		}
+	}
+	return ref(m.Name)
+}
+
+// enum 枚举的引用 值为整数 名称与说明输出在 x-enum-varnames 与 x-enum-descriptions 中
+func (g *openapi) enum(e *Enum) *object {
+	if _, ok := g.schemas[e.Name]; !ok {
+		s := obj("type", "integer", "format", "int32")
+		if e.Desc != "" {
+			s.set("description", e.Desc)
+		}
+		var values, names, descs []interface{}
+		described := false
+		for _, v := range e.Values {
+			values = append(values, v.Number)
+			names = append(names, v.Name)
+			descs = append(descs, v.Desc)
+			described = described || v.Desc != ""
+		}
+		s.set("enum", values)
+		s.set("x-enum-varnames", names)
+		if described {
+			s.set("x-enum-descriptions", descs)
+		}
+		g.schemas[e.Name] = s
+	}
+	return ref(e.Name)
+}
+
+// field 字段的 schema repeated 为数组 map 为 additionalProperties
+func (g *openapi) field(f *Field) *object {
+	var item *object
+	switch {
+	case f.Message != nil:
+		item = g.message(f.Message)
+	case f.Enum != nil:
+		item = g.enum(f.Enum)
+	default:
+		item = scalar(f.Type)
+	}
+	s := item
+	switch {
+	case f.Kind == KindMap:
+		s = obj("type", "object", "additionalProperties", item)
+		if f.KeyType != "string" && f.KeyType != "" {
+			s.set("propertyNames", obj("pattern", "^-?[0-9]+$"))
+		}
+	case f.Repeated:
+		s = obj("type", "array", "items", item)
+	}
+	if f.Desc != "" {
+		s.set("description", f.Desc)
+	}
+	return s
+}
+
+// scalar proto 标量类型对应的 json schema 整数与 encoding/json 一致输出为数字
+func scalar(typ string) *object {
+	switch typ {
+	case "int32", "sint32", "sfixed32":
+		return obj("type", "integer", "format", "int32")
+	case "uint32", "fixed32":
+		return obj("type", "integer", "format", "int64", "minimum", 0, "maximum", 4294967295)
+	case "int64", "sint64", "sfixed64":
+		return obj("type", "integer", "format", "int64")
+	case "uint64", "fixed64":
+		return obj("type", "integer", "format", "int64", "minimum", 0)
+	case "float":
+		return obj("type", "number", "format", "float")
+	case "double":
+		return obj("type", "number", "format", "double")
+	case "bool":
+		return obj("type", "boolean")
+	case "bytes":
+		return obj("type", "string", "contentEncoding", "base64")
+	}
+	return obj("type", "string")
+}
+
+// errorSchema 一个 proto 文件中的错误码 HTTP 状态码输出在 x-enum-http 中
+func errorSchema(ef *ErrorFile) *object {
+	s := obj("type", "integer", "format", "int32", "title", qualify(ef.Package, "ErrCode"), "description", "定义: "+ef.Proto)
+	var values, names, descs, https []interface{}
+	hasHTTP := false
+	for _, c := range ef.Codes {
+		values = append(values, c.Code)
+		names = append(names, c.Name)
+		descs = append(descs, c.Msg)
+		https = append(https, c.HTTP)
+		hasHTTP = hasHTTP || c.HTTP != 0
+	}
+	s.set("enum", values)
+	s.set("x-enum-varnames", names)
+	s.set("x-enum-descriptions", descs)
+	if hasHTTP {
+		s.set("x-enum-http", https)
+	}
+	return s
+}
@@ -0,0 +1,111 @@
+package apiexport
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/actorbuf/iotaer/apidoc"
+	"github.com/actorbuf/iotaer/k8s"
+)
+
+// 支持导出的格式
+const (
+	FormatPostman = "postman"
+	FormatHTTP    = "http"
+)
+
+// BaseURL 集合中表示服务地址的变量名
+const BaseURL = "baseUrl"
+
+// DefaultPort config_<env>.yaml 中没有监听端口时使用的端口
+const DefaultPort = 8080
+
+// Env 一个环境的变量
+type Env struct {
+	Name    string
+	BaseURL string
+}
+
+var configReg = regexp.MustCompile(`^config_(.+)\.yaml$`)
+
+// Envs 按 dir 下的 config_<env>.yaml 生成环境 服务地址为 http://host:api端口 没有配置文件时只有 local 环境
+func Envs(dir, host string) ([]Env, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "config_*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var envs []Env
+	for _, file := range files {
+		res := configReg.FindStringSubmatch(filepath.Base(file))
+		if res == nil {
+			continue
+		}
+		ports, err := k8s.DetectPorts(file)
+		if err != nil {
+			return nil, err
+		}
+		port := ports["api"]
+		if port == 0 {
+			port = DefaultPort
+		}
+		envs = append(envs, Env{Name: res[1], BaseURL: fmt.Sprintf("http://%s:%d", host, port)})
+	}
+	if len(envs) == 0 {
+		envs = append(envs, Env{Name: "local", BaseURL: fmt.Sprintf("http://%s:%d", host, DefaultPort)})
+	}
+	return envs, nil
+}
+
+// request 一个接口可以直接发送的示例请求
+type request struct {
+	API    *apidoc.API
+	Method string
+	Query  []apidoc.Param
+	Body   string // 非 GET 请求的 json 请求体
+}
+
+// newRequest 与服务端的 ShouldBind 一致 GET 请求按表单绑定 参数在 query 中 参数名为 go 字段名
+// 其他请求使用 json 请求体
+func newRequest(api *apidoc.API) *request {
+	r := &request{API: api, Method: api.Method}
+	if r.Method == "ANY" || r.Method == "" {
+		r.Method = "POST"
+	}
+	if r.Method == "GET" {
+		r.Query = apidoc.SampleParams(api.Req)
+		return r
+	}
+	r.Body = apidoc.ExampleJSON(apidoc.Sample(api.Req))
+	return r
+}
+
+// describe 接口的说明 包含请求方式、负责人与可能返回的错误码
+func describe(api *apidoc.API) string {
+	var lines []string
+	if api.Desc != "" {
+		lines = append(lines, api.Desc)
+	}
+	if api.Author != "" {
+		lines = append(lines, "负责人: "+api.Author)
+	}
+	for _, e := range api.Errors {
+		if e.Package == "" {
+			lines = append(lines, fmt.Sprintf("错误码: %s", e.Name))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("错误码: %s(%d) %s", e.Name, e.Code, e.Msg))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// title 集合中接口的名称
+func title(api *apidoc.API) string {
+	if api.Desc == "" {
+		return api.Name
+	}
+	return api.Name + " " + api.Desc
+}
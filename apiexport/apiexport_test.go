@@ -0,0 +1,93 @@
+package apiexport
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/actorbuf/iotaer/internal/testproject"
+)
+
+var envs = []Env{{Name: "dev", BaseURL: "http://localhost:8081"}, {Name: "local", BaseURL: "http://localhost:8080"}}
+
+func TestEnvs(t *testing.T) {
+	dir := t.TempDir()
+	if got, err := Envs(dir, "localhost"); err != nil || !reflect.DeepEqual(got, []Env{{Name: "local", BaseURL: "http://localhost:8080"}}) {
+		t.Fatalf("unexpected default envs %+v %v", got, err)
+	}
+	testproject.WriteFiles(t, dir, map[string]string{
+		"config_local.yaml": "api:\n  port: 8080\n",
+		"config_dev.yaml":   "http:\n  addr: \":8081\"\n",
+		"config_prod.yaml":  "log:\n  level: info\n",
+	})
+	got, err := Envs(dir, "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Env{{Name: "dev", BaseURL: "http://127.0.0.1:8081"}, {Name: "local", BaseURL: "http://127.0.0.1:8080"}, {Name: "prod", BaseURL: "http://127.0.0.1:8080"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected envs %+v", got)
+	}
+}
+
+func TestPostman(t *testing.T) {
+	pages, err := Postman(testproject.Load(t), envs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, p := range pages {
+		paths = append(paths, p.Path)
+	}
+	if !reflect.DeepEqual(paths, []string{"user_api.postman_collection.json", "dev.postman_environment.json", "local.postman_environment.json"}) {
+		t.Fatalf("unexpected pages %v", paths)
+	}
+	var c postmanCollection
+	if err := json.Unmarshal(pages[0].Content, &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Info.Name != "UserApi" || c.Info.Schema != PostmanSchema || len(c.Item) != 3 || c.Variable[0].Value != "http://localhost:8081" {
+		t.Fatalf("unexpected collection %+v", c)
+	}
+	get := c.Item[0].Request
+	if c.Item[0].Name != "GetUser 获取用户" || get.Method != "GET" || get.Body != nil ||
+		get.URL.Raw != "{{baseUrl}}/api/user/get_user?Uid=10086&Fields=fields" ||
+		!reflect.DeepEqual(get.URL.Path, []string{"api", "user", "get_user"}) ||
+		!strings.Contains(get.Description, "错误码: ErrCodeUserNotFound(20001) 用户不存在") {
+		t.Fatalf("unexpected request %+v", get)
+	}
+	update := c.Item[2].Request
+	if update.Method != "PUT" || update.URL.Raw != "{{baseUrl}}/api/user/users/update" ||
+		!reflect.DeepEqual(update.URL.Path, []string{"api", "user", "users", "update"}) ||
+		update.Body.Raw != "{\n  \"id\": 1,\n  \"nick_name\": \"昵称\",\n  \"vip\": true\n}" {
+		t.Fatalf("unexpected request %+v", update)
+	}
+	if !strings.Contains(string(pages[1].Content), `"value": "http://localhost:8081"`) {
+		t.Fatalf("unexpected env\n%s", pages[1].Content)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	pages, err := HTTP(testproject.Load(t), envs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) != 2 || pages[0].Path != "user_api.http" || pages[1].Path != HTTPEnvFile {
+		t.Fatalf("unexpected pages %+v", pages)
+	}
+	src := string(pages[0].Content)
+	for _, want := range []string{
+		"# UserApi 用户接口\n",
+		"### GetUser 获取用户\n# 负责人: alice\n# 错误码: ErrCodeUserNotFound(20001) 用户不存在\n# 错误码: ErrCodeOrderNotFound\nGET {{baseUrl}}/api/user/get_user?Uid=10086&Fields=fields\n",
+		"### UpdateUser 修改用户\nPUT {{baseUrl}}/api/user/users/update\nContent-Type: application/json\n\n{\n  \"id\": 1,",
+	} {
+		if !strings.Contains(src, want) {
+			t.Fatalf("expected %q in:\n%s", want, src)
+		}
+	}
+	var env map[string]map[string]string
+	if err := json.Unmarshal(pages[1].Content, &env); err != nil || env["dev"][BaseURL] != "http://localhost:8081" {
+		t.Fatalf("unexpected env %s %v", pages[1].Content, err)
+	}
+}
@@ -0,0 +1,68 @@
+package apiexport
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/actorbuf/iotaer/apidoc"
+	"github.com/actorbuf/iotaer/toolkit"
+)
+
+// HTTPEnvFile JetBrains HTTP Client 的环境文件 VS Code REST Client 可以在设置中使用相同的变量
+const HTTPEnvFile = "http-client.env.json"
+
+// HTTPFile 路由组 .http 文件的文件名
+func HTTPFile(g *apidoc.Group) string {
+	return toolkit.Calm2Case(g.Name) + ".http"
+}
+
+// HTTP 每个路由组一个 .http 文件 环境写入 http-client.env.json
+func HTTP(p *apidoc.Project, envs []Env) ([]apidoc.Page, error) {
+	var pages []apidoc.Page
+	for _, g := range p.Groups {
+		var buf bytes.Buffer
+		buf.WriteString("# " + g.Name)
+		if g.Desc != "" {
+			buf.WriteString(" " + g.Desc)
+		}
+		buf.WriteString("\n# 由 iotaer export 生成, 服务地址为所选环境的 {{" + BaseURL + "}}\n")
+		for _, api := range g.APIs {
+			writeHTTP(&buf, api)
+		}
+		pages = append(pages, apidoc.Page{Path: HTTPFile(g), Content: buf.Bytes()})
+	}
+	env := map[string]map[string]string{}
+	for _, e := range envs {
+		env[e.Name] = map[string]string{BaseURL: e.BaseURL}
+	}
+	out, err := marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	pages = append(pages, apidoc.Page{Path: HTTPEnvFile, Content: out})
+	return pages, nil
+}
+
+func writeHTTP(buf *bytes.Buffer, api *apidoc.API) {
+	r := newRequest(api)
+	_, _ = fmt.Fprintf(buf, "\n### %s\n", title(api))
+	for _, line := range strings.Split(describe(api), "\n") {
+		if line != "" && line != api.Desc {
+			_, _ = fmt.Fprintf(buf, "# %s\n", line)
+		}
+	}
+	target := "{{" + BaseURL + "}}" + api.Path
+	if len(r.Query) > 0 {
+		q := make([]string, 0, len(r.Query))
+		for _, p := range r.Query {
+			q = append(q, url.QueryEscape(p.Name)+"="+url.QueryEscape(p.Value))
+		}
+		target += "?" + strings.Join(q, "&")
+	}
+	_, _ = fmt.Fprintf(buf, "%s %s\n", r.Method, target)
+	if r.Body != "" {
+		_, _ = fmt.Fprintf(buf, "Content-Type: application/json\n\n%s\n", r.Body)
+	}
+}
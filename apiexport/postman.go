@@ -0,0 +1,133 @@
+package apiexport
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/actorbuf/iotaer/apidoc"
+	"github.com/actorbuf/iotaer/toolkit"
+)
+
+// PostmanSchema Postman 集合的格式 Bruno、Apifox 等工具也可以导入
+const PostmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+type postmanCollection struct {
+	Info     postmanInfo   `json:"info"`
+	Item     []postmanItem `json:"item"`
+	Variable []postmanKV   `json:"variable,omitempty"`
+}
+
+type postmanInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+type postmanItem struct {
+	Name     string         `json:"name"`
+	Request  postmanRequest `json:"request"`
+	Response []interface{}  `json:"response"`
+}
+
+type postmanRequest struct {
+	Method      string       `json:"method"`
+	Header      []postmanKV  `json:"header"`
+	URL         postmanURL   `json:"url"`
+	Body        *postmanBody `json:"body,omitempty"`
+	Description string       `json:"description,omitempty"`
+}
+
+type postmanURL struct {
+	Raw   string      `json:"raw"`
+	Host  []string    `json:"host"`
+	Path  []string    `json:"path"`
+	Query []postmanKV `json:"query,omitempty"`
+}
+
+type postmanBody struct {
+	Mode    string          `json:"mode"`
+	Raw     string          `json:"raw"`
+	Options json.RawMessage `json:"options"`
+}
+
+type postmanKV struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type,omitempty"`
+	Enabled     *bool  `json:"enabled,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type postmanEnv struct {
+	Name   string      `json:"name"`
+	Values []postmanKV `json:"values"`
+}
+
+// PostmanFile 路由组集合的文件名
+func PostmanFile(g *apidoc.Group) string {
+	return toolkit.Calm2Case(g.Name) + ".postman_collection.json"
+}
+
+// Postman 每个路由组一个 Postman 集合 每个环境一个环境文件 集合中的 baseUrl 默认为第一个环境的地址
+func Postman(p *apidoc.Project, envs []Env) ([]apidoc.Page, error) {
+	var pages []apidoc.Page
+	for _, g := range p.Groups {
+		c := postmanCollection{Info: postmanInfo{Name: g.Name, Description: g.Desc, Schema: PostmanSchema}}
+		if len(envs) > 0 {
+			c.Variable = []postmanKV{{Key: BaseURL, Value: envs[0].BaseURL}}
+		}
+		for _, api := range g.APIs {
+			c.Item = append(c.Item, postmanAPI(api))
+		}
+		out, err := marshal(c)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, apidoc.Page{Path: PostmanFile(g), Content: out})
+	}
+	enabled := true
+	for _, env := range envs {
+		out, err := marshal(postmanEnv{Name: env.Name, Values: []postmanKV{{Key: BaseURL, Value: env.BaseURL, Type: "default", Enabled: &enabled}}})
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, apidoc.Page{Path: env.Name + ".postman_environment.json", Content: out})
+	}
+	return pages, nil
+}
+
+func postmanAPI(api *apidoc.API) postmanItem {
+	r := newRequest(api)
+	u := postmanURL{Raw: "{{" + BaseURL + "}}" + api.Path, Host: []string{"{{" + BaseURL + "}}"}}
+	for _, seg := range strings.Split(strings.Trim(api.Path, "/"), "/") {
+		if seg != "" {
+			u.Path = append(u.Path, seg)
+		}
+	}
+	var query []string
+	for _, q := range r.Query {
+		u.Query = append(u.Query, postmanKV{Key: q.Name, Value: q.Value, Description: q.Desc})
+		query = append(query, q.Name+"="+q.Value)
+	}
+	if len(query) > 0 {
+		u.Raw += "?" + strings.Join(query, "&")
+	}
+	req := postmanRequest{Method: r.Method, Header: []postmanKV{}, URL: u, Description: describe(api)}
+	if r.Body != "" {
+		req.Header = append(req.Header, postmanKV{Key: "Content-Type", Value: "application/json"})
+		req.Body = &postmanBody{Mode: "raw", Raw: r.Body, Options: json.RawMessage(`{"raw":{"language":"json"}}`)}
+	}
+	return postmanItem{Name: title(api), Request: req, Response: []interface{}{}}
+}
+
+// marshal 格式化的 json 不转义 & 与 <> 便于阅读
+func marshal(v interface{}) ([]byte, error) {
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return []byte(buf.String()), nil
+}
@@ -0,0 +1,430 @@
+package apigen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/actorbuf/iotaer/protodef"
+	"github.com/actorbuf/iotaer/toolkit"
+)
+
+// DirLogic 项目骨架中 logic 层的目录
+const DirLogic = "internal/logic"
+
+// Option 生成接口代码的参数
+type Option struct {
+	Proto      string  // proto 文件
+	Service    string  // 路由组名称
+	Name       string  // 接口名称
+	Desc       string  // 接口描述
+	Path       string  // 接口相对路由组前缀的路径 为空时使用 addapi 默认的 /方法名
+	Fields     []Field // Req 的字段
+	RespFields []Field // Resp 的字段
+	Logic      string  // logic 层目录 相对项目根目录 默认 internal/logic
+}
+
+// api 渲染模板需要的信息
+type api struct {
+	*Option
+	Method      string // 请求方式 ANY 时测试使用 POST
+	Path        string // 完整路由
+	Req         string // 请求类型 以 rpc 定义为准
+	Resp        string // 响应类型
+	PBImport    string // pb 包导入路径
+	LogicImport string // logic 包导入路径
+	PB          string // 当前文件中引用 pb 包的名称
+	LogicPkg    string // 当前文件中引用 logic 包的名称
+	Controller  string // controller 包名
+	pbName      string // pb 包名
+}
+
+// Import 生成 import 语句 名称与路径最后一段不同时带上别名
+func (a *api) Import(name, p string) string {
+	if name == path.Base(p) {
+		return strconv.Quote(p)
+	}
+	return name + " " + strconv.Quote(p)
+}
+
+// EngineFunc 测试中获取路由组 engine 的函数
+func (a *api) EngineFunc() string {
+	return engineFunc(a.Service)
+}
+
+// EngineVar 测试中保存路由组 engine 的变量
+func (a *api) EngineVar() string {
+	return engineVar(a.Service)
+}
+
+func engineFunc(service string) string {
+	return "test" + service + "Engine"
+}
+
+func engineVar(service string) string {
+	return strings.ToLower(service[:1]) + service[1:] + "Engine"
+}
+
+// Generate 按 proto 中的接口定义生成 controller 方法、logic 函数与 httptest 测试
+// 已存在的方法与文件不会覆盖
+func Generate(o *Option) error {
+	f, err := protodef.ParseFile(o.Proto)
+	if err != nil {
+		return err
+	}
+	var svc *protodef.Service
+	var method *protodef.Method
+	for _, s := range f.Services {
+		if s.Name != o.Service {
+			continue
+		}
+		svc = s
+		for _, m := range s.Methods {
+			if m.Name == o.Name {
+				method = m
+			}
+		}
+	}
+	if svc == nil || method == nil {
+		return fmt.Errorf("路由组 %s 中没有接口 %s", o.Service, o.Name)
+	}
+	if svc.GenTo == "" {
+		return fmt.Errorf("路由组 %s 没有配置 @gen_to", o.Service)
+	}
+	if o.Desc == "" {
+		o.Desc = method.Desc
+	}
+	if o.Logic == "" {
+		o.Logic = DirLogic
+	}
+
+	root, module, err := findModule(filepath.Dir(o.Proto))
+	if err != nil {
+		return err
+	}
+	for _, typ := range []string{method.Req, method.Resp} {
+		if f.Message(typ) == nil {
+			return fmt.Errorf("接口 %s 的 %s 不是 %s 中定义的消息, 无法生成代码", o.Name, typ, o.Proto)
+		}
+	}
+	a := &api{Option: o, Method: method.HTTPMethod, Path: svc.FullPath(method),
+		Req: f.Message(method.Req).Name, Resp: f.Message(method.Resp).Name}
+	if a.Method == "ANY" {
+		a.Method = "POST"
+	}
+	a.PBImport, a.pbName = goPackage(f, root, module)
+	a.LogicImport = path.Join(module, filepath.ToSlash(o.Logic))
+
+	// controller 在 gen_to 文件中 与 gen 保持一致 gen_to 相对项目根目录
+	controller := filepath.Join(root, svc.GenTo)
+	if err := writeLogic(filepath.Join(root, o.Logic, toolkit.Calm2Case(o.Service)+".go"), a); err != nil {
+		return err
+	}
+	if err := writeController(controller, a); err != nil {
+		return err
+	}
+	if err := writeTestEngine(engineFile(filepath.Dir(controller), o.Service), a); err != nil {
+		return err
+	}
+	return writeTest(testFile(filepath.Dir(controller), o.Service, o.Name), a)
+}
+
+// engineFile 路由组测试共用 engine 的文件
+func engineFile(dir, service string) string {
+	return filepath.Join(dir, toolkit.Calm2Case(service)+"_engine_test.go")
+}
+
+// testFile 接口的 httptest 测试文件 按路由组与接口命名 避免不同路由组的同名接口共用一个文件
+func testFile(dir, service, name string) string {
+	return filepath.Join(dir, toolkit.Calm2Case(service)+"_"+toolkit.Calm2Case(name)+"_test.go")
+}
+
+// findModule 向上查找 go.mod 返回项目根目录与 module 名称
+func findModule(dir string) (string, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", err
+	}
+	for {
+		body, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			scanner := bufio.NewScanner(bytes.NewReader(body))
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if strings.HasPrefix(line, "module ") {
+					return dir, strings.Trim(strings.TrimSpace(line[len("module "):]), `"`), nil
+				}
+			}
+			return "", "", fmt.Errorf("%s 中没有 module", filepath.Join(dir, "go.mod"))
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", fmt.Errorf("没有找到 go.mod")
+		}
+		dir = parent
+	}
+}
+
+// goPackage pb 包的导入路径与包名 go_package 可以是完整路径 也可以是相对项目根目录的路径
+func goPackage(f *protodef.File, root, module string) (string, string) {
+	pkg, name := f.GoPackage, ""
+	if i := strings.Index(pkg, ";"); i >= 0 {
+		pkg, name = pkg[:i], pkg[i+1:]
+	}
+	switch {
+	case pkg == "":
+		rel, _ := filepath.Rel(root, filepath.Dir(absPath(f.Path)))
+		pkg = path.Join(module, filepath.ToSlash(rel))
+	case pkg != module && !strings.HasPrefix(pkg, module+"/"):
+		pkg = path.Join(module, strings.TrimPrefix(pkg, "./"))
+	}
+	if name == "" {
+		name = path.Base(pkg)
+	}
+	return pkg, name
+}
+
+func absPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return p
+}
+
+func writeLogic(file string, a *api) error {
+	a.PB, a.LogicPkg = a.pbName, path.Base(a.LogicImport)
+	src, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return create(file, templateLogicFile, a)
+	}
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	af, err := parser.ParseFile(fset, file, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	if hasFunc(af, "", a.Name) {
+		fmt.Printf("skip     %s 已存在 %s\n", file, a.Name)
+		return nil
+	}
+	imports := []*goImport{{path: coreImport}, {path: a.PBImport, name: &a.PB}}
+	return update(file, src, fset, af, imports, templateLogicFunc, a)
+}
+
+func writeController(file string, a *api) error {
+	a.PB, a.LogicPkg = a.pbName, path.Base(a.LogicImport)
+	src, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		a.Controller = controllerPkg(file)
+		return create(file, templateControllerFile, a)
+	}
+	if err != nil {
+		return err
+	}
+	fset := token.NewFileSet()
+	af, err := parser.ParseFile(fset, file, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	a.Controller = af.Name.Name
+	if hasFunc(af, a.Service, a.Name) {
+		fmt.Printf("skip     %s 已存在 %s.%s\n", file, a.Service, a.Name)
+		return nil
+	}
+	tpl := templateControllerFunc
+	if !hasType(af, a.Service) {
+		tpl = templateControllerType + tpl
+	}
+	imports := []*goImport{{path: coreImport}, {path: a.PBImport, name: &a.PB}, {path: a.LogicImport, name: &a.LogicPkg}}
+	return update(file, src, fset, af, imports, tpl, a)
+}
+
+func writeTestEngine(file string, a *api) error {
+	if _, err := os.Stat(file); err == nil {
+		return nil
+	}
+	a.PB = a.pbName
+	return create(file, templateTestEngine, a)
+}
+
+func writeTest(file string, a *api) error {
+	if _, err := os.Stat(file); err == nil {
+		fmt.Printf("skip     %s 已存在\n", file)
+		return nil
+	}
+	a.PB = a.pbName
+	return create(file, templateTest, a)
+}
+
+// controllerPkg 与 gen 创建 gen_to 文件时的包名一致
+func controllerPkg(file string) string {
+	name := filepath.Base(filepath.Dir(file))
+	if name == "controller" {
+		return name
+	}
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name) + "_controller"
+}
+
+func hasFunc(f *ast.File, recv, name string) bool {
+	for _, decl := range f.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Name.Name != name {
+			continue
+		}
+		if recv == "" && fd.Recv == nil {
+			return true
+		}
+		if recv != "" && fd.Recv != nil && len(fd.Recv.List) == 1 {
+			t := fd.Recv.List[0].Type
+			if star, ok := t.(*ast.StarExpr); ok {
+				t = star.X
+			}
+			if id, ok := t.(*ast.Ident); ok && id.Name == recv {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasType(f *ast.File, name string) bool {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			if ts, ok := spec.(*ast.TypeSpec); ok && ts.Name.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func render(tpl string, a *api) ([]byte, error) {
+	t, err := template.New("api").Parse(tpl)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, a); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func create(file, tpl string, a *api) error {
+	body, err := render(tpl, a)
+	if err != nil {
+		return err
+	}
+	if body, err = format.Source(body); err != nil {
+		return fmt.Errorf("格式化 %s 失败: %w", file, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, body, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("create   %s \n", file)
+	return nil
+}
+
+// goImport 需要的导入 name 为代码中引用的名称 文件中已导入时改为文件中使用的名称
+type goImport struct {
+	path string
+	name *string
+}
+
+// update 补充缺少的 import 后追加代码
+func update(file string, src []byte, fset *token.FileSet, f *ast.File, imports []*goImport, tpl string, a *api) error {
+	var missing []string
+	for _, imp := range imports {
+		if local, ok := importName(f, imp.path); ok {
+			if imp.name != nil {
+				*imp.name = local
+			}
+			continue
+		}
+		name := path.Base(imp.path)
+		if imp.name != nil {
+			name = *imp.name
+		}
+		missing = append(missing, a.Import(name, imp.path))
+	}
+	body, err := render(tpl, a)
+	if err != nil {
+		return err
+	}
+	src = append(addImports(src, fset, f, missing), body...)
+	if src, err = format.Source(src); err != nil {
+		return fmt.Errorf("格式化 %s 失败: %w", file, err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, src, info.Mode().Perm()); err != nil {
+		return err
+	}
+	fmt.Printf("update   %s \n", file)
+	return nil
+}
+
+// importName 文件中导入 p 时使用的名称
+func importName(f *ast.File, p string) (string, bool) {
+	for _, spec := range f.Imports {
+		if v, _ := strconv.Unquote(spec.Path.Value); v == p {
+			if spec.Name != nil {
+				return spec.Name.Name, true
+			}
+			return path.Base(p), true
+		}
+	}
+	return "", false
+}
+
+// addImports 在第一个 import 声明中追加导入 没有 import 时加在 package 之后
+func addImports(src []byte, fset *token.FileSet, f *ast.File, specs []string) []byte {
+	if len(specs) == 0 {
+		return src
+	}
+	lines := strings.Join(specs, "\n\t")
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+		if gd.Lparen.IsValid() {
+			at := offset(gd.Rparen)
+			return join(src[:at], "\t"+lines+"\n", src[at:])
+		}
+		// 单行 import 改为 import 块
+		at, end := offset(gd.Pos()), offset(gd.End())
+		spec := strings.TrimSpace(string(src[at+len("import") : end]))
+		return join(src[:at], "import (\n\t"+spec+"\n\t"+lines+"\n)", src[end:])
+	}
+	at := offset(f.Name.End())
+	return join(src[:at], "\n\nimport (\n\t"+lines+"\n)", src[at:])
+}
+
+func join(a []byte, s string, b []byte) []byte {
+	out := make([]byte, 0, len(a)+len(s)+len(b))
+	return append(append(append(out, a...), s...), b...)
+}
@@ -0,0 +1,212 @@
+package apigen
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 模拟 addapi 写入 rpc 与空 Req/Resp 之后的 proto
+const userProto = `syntax = "proto3";
+package user;
+option go_package = "model/user";
+
+// @route_group: true
+// @route_api: /api/user
+// @gen_to: ./internal/controller/user_api.go
+service UserApi {
+    // @desc:
+    // @method: GET
+    // @api: /get_user
+    rpc GetUser (GetUserReq) returns (GetUserResp);
+    // @desc: 创建用户
+    rpc CreateUser (CreateUserReq) returns (CreateUserResp);
+}
+
+message GetUserReq {
+    int64 id = 1;
+    reserved 2 to 4;
+}
+message GetUserResp {}
+message CreateUserReq {}
+message CreateUserResp {}
+`
+
+func TestParseFields(t *testing.T) {
+	fields, err := ParseFields("user_id:int64, tags:[]string,profile:user.Profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Field{{Name: "user_id", Type: "int64"}, {Name: "tags", Type: "string", Repeated: true}, {Name: "profile", Type: "user.Profile"}}
+	if !reflect.DeepEqual(fields, want) {
+		t.Fatalf("unexpected fields %+v", fields)
+	}
+	for _, spec := range []string{"userId:int64", "id", "id:int64,id:string", "id:[]"} {
+		if _, err := ParseFields(spec); err == nil {
+			t.Fatalf("%s: expected error", spec)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	root := t.TempDir()
+	pb := filepath.Join(root, "model", "user", "user.proto")
+	write(t, filepath.Join(root, "go.mod"), "module example.com/app\n\ngo 1.16\n")
+	write(t, pb, userProto)
+
+	fields, _ := ParseFields("name:string,tags:[]string")
+	o := &Option{Proto: pb, Service: "UserApi", Name: "GetUser", Desc: "获取用户", Fields: fields}
+	if err := UpdateProto(pb, o); err != nil {
+		t.Fatal(err)
+	}
+	src := read(t, pb)
+	for _, s := range []string{"@desc: 获取用户", " string name = 5;", "repeated string tags = 6;"} {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected %q in proto:\n%s", s, src)
+		}
+	}
+	if err := UpdateProto(pb, o); err == nil {
+		t.Fatal("expected duplicate field error")
+	}
+	if err := Generate(o); err != nil {
+		t.Fatal(err)
+	}
+
+	controller := filepath.Join(root, "internal", "controller", "user_api.go")
+	logic := filepath.Join(root, "internal", "logic", "user_api.go")
+	test := filepath.Join(root, "internal", "controller", "user_api_get_user_test.go")
+	expect(t, controller, "package controller", `"example.com/app/model/user"`, `"example.com/app/internal/logic"`,
+		"var _ user.UserApiImpl = (*UserApi)(nil)", "return logic.GetUser(ctx, req)", "// GetUser 获取用户")
+	expect(t, logic, "package logic", "func GetUser(ctx *core.Context, req *user.GetUserReq) (*user.GetUserResp, error)")
+	expect(t, test, "func TestUserApi_GetUser", "engine := testUserApiEngine()",
+		`httptest.NewRequest("GET", "/api/user/get_user?"+tt.query.Encode(), nil)`)
+	expect(t, filepath.Join(root, "internal", "controller", "user_api_engine_test.go"), "userApiEngineOnce.Do(func() {",
+		"core.NewRegister().BindRouteMap(user.UserApiGroupRouterMap).RegisterStruct(userApiEngine, new(UserApi))")
+
+	// 已有文件时追加 并沿用文件中的导入名称
+	write(t, logic, "package logic\n\nimport pb \"example.com/app/model/user\"\n\nvar _ = pb.GetUserReq{}\n")
+	o = &Option{Proto: pb, Service: "UserApi", Name: "CreateUser"}
+	if err := Generate(o); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, controller, "func (receiver *UserApi) CreateUser(ctx *core.Context, req *user.CreateUserReq)")
+	expect(t, logic, "import (\n\tpb \"example.com/app/model/user\"\n\t\"github.com/actorbuf/iota/core\"\n)",
+		"// CreateUser 创建用户\nfunc CreateUser(ctx *core.Context, req *pb.CreateUserReq) (*pb.CreateUserResp, error)")
+	expect(t, filepath.Join(root, "internal", "controller", "user_api_create_user_test.go"), `httptest.NewRequest("POST", "/api/user/create_user", bytes.NewReader(body))`)
+
+	// 重复执行不会覆盖
+	before := read(t, controller)
+	if err := Generate(o); err != nil {
+		t.Fatal(err)
+	}
+	if read(t, controller) != before {
+		t.Fatal("controller should not change")
+	}
+	if err := Generate(&Option{Proto: pb, Service: "UserApi", Name: "Missing"}); err == nil {
+		t.Fatal("expected missing api error")
+	}
+
+	// 不同路由组的同名接口使用各自的测试文件
+	admin := filepath.Join(root, "model", "admin", "admin.proto")
+	write(t, admin, strings.NewReplacer("package user", "package admin", "model/user", "model/admin",
+		"/api/user", "/api/admin", "user_api.go", "admin_api.go", "UserApi", "AdminApi").Replace(userProto))
+	if err := Generate(&Option{Proto: admin, Service: "AdminApi", Name: "GetUser"}); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, filepath.Join(root, "internal", "controller", "admin_api_get_user_test.go"), "func TestAdminApi_GetUser", `"/api/admin/get_user?"`)
+	expect(t, test, "func TestUserApi_GetUser")
+
+	// Req/Resp 以 rpc 定义为准 不要求以接口名加 Req/Resp 命名
+	write(t, pb, strings.Replace(read(t, pb), "service UserApi {", "service UserApi {\n    // @desc: 登录\n    rpc Login (user.LoginForm) returns (Token);", 1)+
+		"message LoginForm {}\nmessage Token {}\n")
+	if err := Generate(&Option{Proto: pb, Service: "UserApi", Name: "Login"}); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, controller, "func (receiver *UserApi) Login(ctx *core.Context, req *user.LoginForm) (resp *user.Token, err error)")
+	expect(t, filepath.Join(root, "internal", "controller", "user_api_login_test.go"), "req: &user.LoginForm{}")
+	write(t, pb, strings.Replace(read(t, pb), "rpc Login (user.LoginForm)", "rpc Login (common.LoginForm)", 1))
+	if err := Generate(&Option{Proto: pb, Service: "UserApi", Name: "Login"}); err == nil {
+		t.Fatal("expected error for message from another package")
+	}
+}
+
+func TestAddMessages(t *testing.T) {
+	pb := filepath.Join(t.TempDir(), "user.proto")
+	write(t, pb, userProto)
+	msgs := []Message{{Name: "Profile", Comment: []string{"资料"}, Fields: []Field{
+		{Name: "nick_name", Type: "string", Comment: []string{"昵称", "@json: nickName"}},
+		{Name: "tags", Type: "string", KeyType: "string"},
+	}}}
+	if err := AddMessages(pb, msgs); err != nil {
+		t.Fatal(err)
+	}
+	o := &Option{Proto: pb, Service: "UserApi", Name: "CreateUser", Path: "/users/:id",
+		RespFields: []Field{{Name: "profile", Type: "Profile"}}}
+	if err := UpdateProto(pb, o); err != nil {
+		t.Fatal(err)
+	}
+	src := read(t, pb)
+	for _, want := range []string{"// 资料\nmessage Profile {", "// @json: nickName\n", "map<string,string> tags", "// @api: /users/:id", "Profile profile = 1;"} {
+		if !strings.Contains(src, want) {
+			t.Fatalf("expected %q in proto:\n%s", want, src)
+		}
+	}
+	if err := AddMessages(pb, msgs); err == nil {
+		t.Fatal("expected duplicate message error")
+	}
+}
+
+func TestAddImports(t *testing.T) {
+	for _, src := range []string{
+		"package a\n",
+		"package a\n\nimport \"fmt\"\n\nvar _ = fmt.Sprint\n",
+		"package a\n\nimport (\n\t\"fmt\"\n)\n\nvar _ = fmt.Sprint\n",
+	} {
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, "", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := addImports([]byte(src), fset, f, []string{`"os"`, `pb "example.com/pb"`})
+		f, err = parser.ParseFile(token.NewFileSet(), "", out, 0)
+		if err != nil {
+			t.Fatalf("%s\n%v", out, err)
+		}
+		if _, ok := importName(f, "example.com/pb"); !ok || len(f.Imports) < 2 {
+			t.Fatalf("missing import:\n%s", out)
+		}
+	}
+}
+
+func write(t *testing.T, file, body string) {
+	_ = os.MkdirAll(filepath.Dir(file), 0755)
+	if err := ioutil.WriteFile(file, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func read(t *testing.T, file string) string {
+	body, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// expect 文件是合法的 go 代码并包含指定内容
+func expect(t *testing.T, file string, contains ...string) {
+	src := read(t, file)
+	if _, err := parser.ParseFile(token.NewFileSet(), file, src, 0); err != nil {
+		t.Fatalf("%s: %v\n%s", file, err, src)
+	}
+	for _, s := range contains {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected %q in %s:\n%s", s, file, src)
+		}
+	}
+}
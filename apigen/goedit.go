@@ -0,0 +1,279 @@
+package apigen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goFile 通过 ast 定位 按字节替换修改 go 源码 保留原有的注释与格式
+type goFile struct {
+	name  string
+	src   []byte
+	fset  *token.FileSet
+	f     *ast.File
+	edits []edit
+}
+
+type edit struct {
+	start, end int
+	text       string
+}
+
+// editGo 修改 go 文件 没有修改时不写入
+// 修改后不再使用的 import 会被删除 只剩 import 的文件会被删除
+func editGo(file string, fn func(g *goFile)) error {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	g := &goFile{name: file, src: src, fset: fset, f: f}
+	fn(g)
+	if len(g.edits) == 0 {
+		return nil
+	}
+	out, empty, err := g.apply()
+	if err != nil {
+		return err
+	}
+	if empty {
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+		fmt.Printf("delete   %s \n", file)
+		return nil
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(file, out, info.Mode().Perm()); err != nil {
+		return err
+	}
+	fmt.Printf("update   %s \n", file)
+	return nil
+}
+
+func (g *goFile) offset(pos token.Pos) int {
+	return g.fset.Position(pos).Offset
+}
+
+func (g *goFile) text(n ast.Node) string {
+	return string(g.src[g.offset(n.Pos()):g.offset(n.End())])
+}
+
+func (g *goFile) replace(n ast.Node, text string) {
+	g.edits = append(g.edits, edit{g.offset(n.Pos()), g.offset(n.End()), text})
+}
+
+// removeLines 删除 [start, end) 所在的整行
+func (g *goFile) removeLines(start, end token.Pos) {
+	g.edits = append(g.edits, lines(g.src, g.offset(start), g.offset(end)))
+}
+
+// lines 把区间扩展到整行 前后还有其他代码时只删除区间本身
+func lines(src []byte, s, e int) edit {
+	i := s
+	for i > 0 && (src[i-1] == ' ' || src[i-1] == '\t') {
+		i--
+	}
+	if i == 0 || src[i-1] == '\n' {
+		s = i
+	}
+	j := e
+	for j < len(src) && (src[j] == ' ' || src[j] == '\t') {
+		j++
+	}
+	if j < len(src) && src[j] == '\n' {
+		e = j + 1
+	}
+	return edit{start: s, end: e}
+}
+
+func (g *goFile) removeDecl(decl ast.Decl) {
+	start := decl.Pos()
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	}
+	g.removeLines(start, decl.End())
+}
+
+// removeSpecs 删除声明中满足条件的 spec 全部删除时删除整个声明
+func (g *goFile) removeSpecs(d *ast.GenDecl, match func(spec ast.Spec) bool) {
+	var specs []ast.Spec
+	for _, spec := range d.Specs {
+		if match(spec) {
+			specs = append(specs, spec)
+		}
+	}
+	if len(specs) == 0 {
+		return
+	}
+	if len(specs) == len(d.Specs) || !d.Lparen.IsValid() {
+		g.removeDecl(d)
+		return
+	}
+	for _, spec := range specs {
+		start := spec.Pos()
+		switch s := spec.(type) {
+		case *ast.TypeSpec:
+			if s.Doc != nil {
+				start = s.Doc.Pos()
+			}
+		case *ast.ValueSpec:
+			if s.Doc != nil {
+				start = s.Doc.Pos()
+			}
+		}
+		g.removeLines(start, spec.End())
+	}
+}
+
+// renameFunc 修改函数名 以及以函数名开头的注释
+func (g *goFile) renameFunc(fd *ast.FuncDecl, name string) {
+	g.replace(fd.Name, name)
+	if fd.Doc == nil {
+		return
+	}
+	c := fd.Doc.List[0]
+	if rest := strings.TrimPrefix(c.Text, "// "+fd.Name.Name); rest != c.Text && (rest == "" || rest[0] == ' ') {
+		start := g.offset(c.Slash) + len("// ")
+		g.edits = append(g.edits, edit{start, start + len(fd.Name.Name), name})
+	}
+}
+
+// unregister 删除路由组的注册
+// 如 core.NewRegister().BindRouteMap(pb.XxxGroupRouterMap).RegisterStruct(engine, new(controller.Xxx))
+// 参数全部删除时 BindRouteMap 从调用链中去掉 RegisterStruct 所在的语句整行删除
+func (g *goFile) unregister(name string) {
+	ast.Inspect(g.f, func(n ast.Node) bool {
+		stmt, ok := n.(*ast.ExprStmt)
+		if !ok {
+			return true
+		}
+		call, _ := stmt.X.(*ast.CallExpr)
+		for top := true; call != nil; top = false {
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				break
+			}
+			skip := -1
+			switch sel.Sel.Name {
+			case "BindRouteMap":
+				skip = 0
+			case "RegisterStruct":
+				skip = 1 // 第一个参数是 gin.IRouter
+			}
+			if skip >= 0 {
+				var kept []string
+				for i, arg := range call.Args {
+					if i < skip || !registers(arg, name) {
+						kept = append(kept, g.text(arg))
+					}
+				}
+				switch {
+				case len(kept) == len(call.Args):
+				case len(kept) == skip && top:
+					g.removeLines(stmt.Pos(), stmt.End())
+					return false
+				case len(kept) == 0:
+					g.edits = append(g.edits, edit{g.offset(sel.X.End()), g.offset(call.End()), ""})
+				default:
+					args := strings.Join(kept, ", ")
+					if strings.Contains(string(g.src[g.offset(call.Lparen):g.offset(call.Rparen)]), "\n") {
+						args = strings.Join(kept, ",\n") + ",\n"
+					}
+					g.edits = append(g.edits, edit{g.offset(call.Lparen) + 1, g.offset(call.Rparen), args})
+				}
+			}
+			call, _ = sel.X.(*ast.CallExpr)
+		}
+		return false
+	})
+}
+
+// apply 应用修改并格式化 返回文件是否只剩下 import
+func (g *goFile) apply() ([]byte, bool, error) {
+	sort.Slice(g.edits, func(i, j int) bool { return g.edits[i].start > g.edits[j].start })
+	src := g.src
+	for _, e := range g.edits {
+		src = join(src[:e.start], e.text, src[e.end:])
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, g.name, src, parser.ParseComments)
+	if err != nil {
+		return nil, false, fmt.Errorf("修改 %s 失败: %w", g.name, err)
+	}
+	empty := true
+	for _, decl := range f.Decls {
+		if gd, ok := decl.(*ast.GenDecl); !ok || gd.Tok != token.IMPORT {
+			empty = false
+		}
+	}
+	src = pruneImports(src, fset, f, usedNames(g.f))
+	if src, err = format.Source(src); err != nil {
+		return nil, false, fmt.Errorf("格式化 %s 失败: %w", g.name, err)
+	}
+	return src, empty, nil
+}
+
+// usedNames 文件中以 name.X 形式引用的名称
+func usedNames(f *ast.File) map[string]bool {
+	names := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				names[id.Name] = true
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// pruneImports 删除修改前使用、修改后不再使用的 import
+func pruneImports(src []byte, fset *token.FileSet, f *ast.File, before map[string]bool) []byte {
+	after := usedNames(f)
+	g := &goFile{src: src, fset: fset, f: f}
+	for _, decl := range f.Decls {
+		if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+			g.removeSpecs(gd, func(spec ast.Spec) bool {
+				is := spec.(*ast.ImportSpec)
+				p, _ := strconv.Unquote(is.Path.Value)
+				name := path.Base(p)
+				if is.Name != nil {
+					name = is.Name.Name
+				}
+				return before[name] && !after[name]
+			})
+		}
+	}
+	sort.Slice(g.edits, func(i, j int) bool { return g.edits[i].start > g.edits[j].start })
+	for _, e := range g.edits {
+		src = join(src[:e.start], e.text, src[e.end:])
+	}
+	return src
+}
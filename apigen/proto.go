@@ -0,0 +1,424 @@
+package apigen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/actorbuf/iotaer/protodef"
+	"github.com/actorbuf/iotaer/protofmt"
+	"github.com/actorbuf/iotaer/toolkit"
+	"github.com/emicklei/proto"
+)
+
+// Field 通过 --fields 声明的字段
+type Field struct {
+	Name     string
+	Type     string
+	Repeated bool
+	KeyType  string   // map 的键类型 不为空时为 map<KeyType, Type>
+	Comment  []string // 字段前的注释 如说明与 @json
+}
+
+// Message 通过 AddMessages 新增的消息
+type Message struct {
+	Name    string
+	Comment []string
+	Fields  []Field
+}
+
+var (
+	fieldNameReg = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+	fieldTypeReg = regexp.MustCompile(`^[A-Za-z][\w]*(\.[A-Za-z][\w]*)*$`)
+)
+
+// ParseFields 解析 name:type 形式的字段列表 以逗号分隔 类型前加 [] 表示 repeated
+// 如 user_id:int64,tags:[]string
+func ParseFields(spec string) ([]Field, error) {
+	var fields []Field
+	seen := map[string]bool{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("字段 %s 格式不正确, 应为 name:type", item)
+		}
+		f := Field{Name: strings.TrimSpace(kv[0]), Type: strings.TrimSpace(kv[1])}
+		if strings.HasPrefix(f.Type, "[]") {
+			f.Repeated, f.Type = true, strings.TrimPrefix(f.Type, "[]")
+		}
+		if !fieldNameReg.MatchString(f.Name) {
+			return nil, fmt.Errorf("字段名 %s 应使用下划线命名", f.Name)
+		}
+		if !fieldTypeReg.MatchString(f.Type) {
+			return nil, fmt.Errorf("字段 %s 的类型 %s 不正确", f.Name, f.Type)
+		}
+		if seen[f.Name] {
+			return nil, fmt.Errorf("字段 %s 重复", f.Name)
+		}
+		seen[f.Name] = true
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// UpdateProto 给接口的 Req/Resp 追加字段并填写 @desc
+// addapi 已经创建了 rpc 与空的 Req/Resp 这里只做补充
+func UpdateProto(pbFile string, o *Option) error {
+	def, err := protofmt.Load(pbFile)
+	if err != nil {
+		return err
+	}
+	var rpc *proto.RPC
+	messages := map[string]*proto.Message{}
+	proto.Walk(def,
+		proto.WithService(func(s *proto.Service) {
+			if s.Name != o.Service {
+				return
+			}
+			for _, e := range s.Elements {
+				if r, ok := e.(*proto.RPC); ok && r.Name == o.Name {
+					rpc = r
+				}
+			}
+		}),
+		proto.WithMessage(func(m *proto.Message) {
+			if _, ok := m.Parent.(*proto.Proto); ok {
+				messages[m.Name] = m
+			}
+		}),
+	)
+	if rpc == nil {
+		return fmt.Errorf("路由组 %s 中没有接口 %s", o.Service, o.Name)
+	}
+	if rpc.Comment == nil && o.Path != "" {
+		rpc.Comment = &proto.Comment{}
+	}
+	if rpc.Comment != nil {
+		hasPath := false
+		for i, line := range rpc.Comment.Lines {
+			if o.Desc != "" && strings.Contains(line, "@desc:") {
+				rpc.Comment.Lines[i] = " @desc: " + o.Desc
+			}
+			if o.Path != "" && strings.Contains(line, "@api:") {
+				rpc.Comment.Lines[i] = " @api: " + o.Path
+				hasPath = true
+			}
+		}
+		if o.Path != "" && !hasPath {
+			rpc.Comment.Lines = append(rpc.Comment.Lines, " @api: "+o.Path)
+		}
+	}
+	for _, v := range []struct {
+		name   string
+		fields []Field
+	}{{rpc.RequestType, o.Fields}, {rpc.ReturnsType, o.RespFields}} {
+		if len(v.fields) == 0 {
+			continue
+		}
+		m, ok := messages[v.name]
+		if !ok {
+			return fmt.Errorf("message %s 不存在", v.name)
+		}
+		if err := addFields(m, v.fields); err != nil {
+			return err
+		}
+	}
+	return protofmt.Save(pbFile, def)
+}
+
+// AddMessages 在 proto 中新增消息 已存在同名消息时返回错误
+func AddMessages(pbFile string, msgs []Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	def, err := protofmt.Load(pbFile)
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		if findMessage(def, msg.Name) != nil {
+			return fmt.Errorf("message %s 已存在", msg.Name)
+		}
+		m := &proto.Message{Name: msg.Name, Comment: comment(msg.Comment), Parent: def}
+		if err := addFields(m, msg.Fields); err != nil {
+			return err
+		}
+		def.Elements = append(def.Elements, m)
+	}
+	return protofmt.Save(pbFile, def)
+}
+
+func comment(lines []string) *proto.Comment {
+	if len(lines) == 0 {
+		return nil
+	}
+	c := &proto.Comment{}
+	for _, line := range lines {
+		c.Lines = append(c.Lines, " "+line)
+	}
+	return c
+}
+
+// addFields 字段号从已有字段与保留号的最大值之后开始
+func addFields(m *proto.Message, fields []Field) error {
+	next := 1
+	names := map[string]bool{}
+	for _, e := range m.Elements {
+		switch v := e.(type) {
+		case *proto.NormalField:
+			names[v.Name] = true
+			if v.Sequence >= next {
+				next = v.Sequence + 1
+			}
+		case *proto.MapField:
+			names[v.Name] = true
+			if v.Sequence >= next {
+				next = v.Sequence + 1
+			}
+		case *proto.Reserved:
+			for _, r := range v.Ranges {
+				if !r.Max && r.To >= next {
+					next = r.To + 1
+				}
+			}
+		}
+	}
+	for _, f := range fields {
+		if names[f.Name] {
+			return fmt.Errorf("%s 中已存在字段 %s", m.Name, f.Name)
+		}
+		field := &proto.Field{
+			Name:     f.Name,
+			Type:     f.Type,
+			Sequence: next,
+			Comment:  comment(f.Comment),
+			Parent:   m,
+		}
+		if f.KeyType != "" {
+			m.Elements = append(m.Elements, &proto.MapField{Field: field, KeyType: f.KeyType})
+		} else {
+			m.Elements = append(m.Elements, &proto.NormalField{Field: field, Repeated: f.Repeated})
+		}
+		names[f.Name] = true
+		next++
+	}
+	return nil
+}
+
+// findService 顶层的 service
+func findService(def *proto.Proto, name string) *proto.Service {
+	for _, e := range def.Elements {
+		if s, ok := e.(*proto.Service); ok && s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func findMessage(def *proto.Proto, name string) *proto.Message {
+	for _, e := range def.Elements {
+		if m, ok := e.(*proto.Message); ok && m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func packageName(def *proto.Proto) string {
+	for _, e := range def.Elements {
+		if p, ok := e.(*proto.Package); ok {
+			return p.Name
+		}
+	}
+	return ""
+}
+
+// localType 引用的类型在当前文件顶层时返回 message 名称
+func localType(def *proto.Proto, typ string) string {
+	typ = strings.TrimPrefix(typ, ".")
+	if pkg := packageName(def); pkg != "" {
+		typ = strings.TrimPrefix(typ, pkg+".")
+	}
+	if strings.Contains(typ, ".") || findMessage(def, typ) == nil {
+		return ""
+	}
+	return typ
+}
+
+// eachType 遍历 rpc 与字段引用的类型 skip 中的顶层 message 不遍历
+func eachType(def *proto.Proto, skip string, fn func(typ *string)) {
+	var visit func(elements []proto.Visitee)
+	visit = func(elements []proto.Visitee) {
+		for _, e := range elements {
+			switch v := e.(type) {
+			case *proto.Message:
+				if v.Name != skip || v.Parent != def {
+					visit(v.Elements)
+				}
+			case *proto.Service:
+				visit(v.Elements)
+			case *proto.Oneof:
+				visit(v.Elements)
+			case *proto.RPC:
+				fn(&v.RequestType)
+				fn(&v.ReturnsType)
+			case *proto.NormalField:
+				fn(&v.Type)
+			case *proto.MapField:
+				fn(&v.Type)
+			case *proto.OneOfField:
+				fn(&v.Type)
+			}
+		}
+	}
+	visit(def.Elements)
+}
+
+// sameType 类型引用 typ 是否指向顶层的 message name
+func sameType(def *proto.Proto, typ, name string) bool {
+	typ = strings.TrimPrefix(typ, ".")
+	return typ == name || typ == packageName(def)+"."+name
+}
+
+// refers 文件 from 中的类型引用 typ 是否指向包 pkg 中的顶层 message name
+func refers(from *proto.Proto, typ, pkg, name string) bool {
+	typ = strings.TrimPrefix(typ, ".")
+	return typ == pkg+"."+name || typ == name && packageName(from) == pkg
+}
+
+// removeRPC 从 service 中删除 rpc 以及不再被引用的 Req/Resp
+func removeRPC(def *proto.Proto, others []*proto.Proto, s *proto.Service, name string) ([]string, error) {
+	var rpc *proto.RPC
+	elements := make([]proto.Visitee, 0, len(s.Elements))
+	for _, e := range s.Elements {
+		if r, ok := e.(*proto.RPC); ok && r.Name == name {
+			rpc = r
+			continue
+		}
+		elements = append(elements, e)
+	}
+	if rpc == nil {
+		return nil, fmt.Errorf("%s 中没有 %s", s.Name, name)
+	}
+	s.Elements = elements
+	return pruneMessages(def, others, rpc.RequestType, rpc.ReturnsType), nil
+}
+
+// removeService 删除 service 以及其中 rpc 不再被引用的 Req/Resp
+func removeService(def *proto.Proto, others []*proto.Proto, s *proto.Service) []string {
+	var types []string
+	elements := make([]proto.Visitee, 0, len(def.Elements))
+	for _, e := range def.Elements {
+		if e != proto.Visitee(s) {
+			elements = append(elements, e)
+		}
+	}
+	def.Elements = elements
+	for _, e := range s.Elements {
+		if r, ok := e.(*proto.RPC); ok {
+			types = append(types, r.RequestType, r.ReturnsType)
+		}
+	}
+	return pruneMessages(def, others, types...)
+}
+
+// pruneMessages 删除当前文件中不再被引用的顶层 message
+// others 为项目中的其他 proto, 同一个包的文件可以直接引用 其他包通过全名引用
+func pruneMessages(def *proto.Proto, others []*proto.Proto, types ...string) []string {
+	var removed []string
+	pkg := packageName(def)
+	for _, typ := range types {
+		name := localType(def, typ)
+		if name == "" {
+			continue
+		}
+		used := false
+		eachType(def, name, func(t *string) {
+			used = used || sameType(def, *t, name)
+		})
+		for _, other := range others {
+			eachType(other, "", func(t *string) {
+				used = used || refers(other, *t, pkg, name)
+			})
+		}
+		if used {
+			continue
+		}
+		elements := make([]proto.Visitee, 0, len(def.Elements))
+		for _, e := range def.Elements {
+			if m, ok := e.(*proto.Message); !ok || m.Name != name {
+				elements = append(elements, e)
+			}
+		}
+		def.Elements = elements
+		removed = append(removed, name)
+	}
+	return removed
+}
+
+// renameRPC 重命名 rpc 按 NameReq/NameResp 命名的 message 一并修改
+// pin 为 true 时没有 @api 的接口写入修改前的默认路径 保持 URL 不变
+// renamePath 为 true 时与默认路径相同的 @api 改为新名称的默认路径
+// 返回 message 的旧名称到新名称
+func renameRPC(def *proto.Proto, s *proto.Service, name, to string, pin, renamePath bool) (map[string]string, error) {
+	var rpc *proto.RPC
+	for _, e := range s.Elements {
+		r, ok := e.(*proto.RPC)
+		if !ok {
+			continue
+		}
+		if r.Name == to {
+			return nil, fmt.Errorf("%s 中已存在 %s", s.Name, to)
+		}
+		if r.Name == name {
+			rpc = r
+		}
+	}
+	if rpc == nil {
+		return nil, fmt.Errorf("%s 中没有 %s", s.Name, name)
+	}
+	renames := map[string]string{}
+	for _, v := range []struct{ typ, suffix string }{{rpc.RequestType, "Req"}, {rpc.ReturnsType, "Resp"}} {
+		if localType(def, v.typ) != name+v.suffix {
+			continue
+		}
+		if findMessage(def, to+v.suffix) != nil {
+			return nil, fmt.Errorf("message %s 已存在", to+v.suffix)
+		}
+		renames[name+v.suffix] = to + v.suffix
+	}
+	eachType(def, "", func(t *string) {
+		for old, cur := range renames {
+			if sameType(def, *t, old) {
+				*t = strings.TrimSuffix(*t, old) + cur
+			}
+		}
+	})
+	for old, cur := range renames {
+		findMessage(def, old).Name = cur
+	}
+	rpc.Name = to
+	var lines []string
+	if rpc.Comment != nil {
+		lines = rpc.Comment.Lines
+	}
+	if protodef.Tag(lines, "api") == "" {
+		if pin && !renamePath {
+			if rpc.Comment == nil {
+				rpc.Comment = &proto.Comment{}
+			}
+			rpc.Comment.Lines = append(rpc.Comment.Lines, " @api: /"+toolkit.Calm2Case(name))
+		}
+		return renames, nil
+	}
+	for i, line := range lines {
+		if api := protodef.Tag([]string{line}, "api"); renamePath && api == "/"+toolkit.Calm2Case(name) {
+			lines[i] = " @api: /" + toolkit.Calm2Case(to)
+		}
+	}
+	return renames, nil
+}
@@ -0,0 +1,473 @@
+package apigen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/actorbuf/iotaer/protodef"
+	"github.com/actorbuf/iotaer/protofmt"
+	"github.com/emicklei/proto"
+)
+
+// DirRouter 项目骨架中注册路由的目录
+const DirRouter = "internal/router"
+
+// Target 删除或重命名接口的参数
+type Target struct {
+	Proto   string // proto 文件
+	Service string // 路由组或 service 名称
+	Name    string // 接口名称
+	Logic   string // logic 层目录 相对项目根目录 默认 internal/logic
+	Router  string // 注册路由的目录 相对项目根目录 默认 internal/router
+}
+
+// project 修改代码需要的项目信息
+type project struct {
+	*Target
+	def         *proto.Proto
+	svc         *protodef.Service
+	root        string
+	pbImport    string
+	logicImport string
+}
+
+func open(t *Target) (*project, error) {
+	f, err := protodef.ParseFile(t.Proto)
+	if err != nil {
+		return nil, err
+	}
+	p := &project{Target: t}
+	for _, s := range f.Services {
+		if s.Name == t.Service {
+			p.svc = s
+		}
+	}
+	if p.svc == nil {
+		return nil, fmt.Errorf("service %s 不存在", t.Service)
+	}
+	if p.def, err = protofmt.Load(t.Proto); err != nil {
+		return nil, err
+	}
+	root, module, err := findModule(filepath.Dir(t.Proto))
+	if err != nil {
+		return nil, err
+	}
+	if t.Logic == "" {
+		t.Logic = DirLogic
+	}
+	if t.Router == "" {
+		t.Router = DirRouter
+	}
+	p.root = root
+	p.pbImport, _ = goPackage(f, root, module)
+	p.logicImport = path.Join(module, filepath.ToSlash(t.Logic))
+	return p, nil
+}
+
+func (p *project) method(name string) (*protodef.Method, error) {
+	for _, m := range p.svc.Methods {
+		if m.Name == name {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("%s 中没有 %s", p.Service, name)
+}
+
+func (p *project) route() bool {
+	return p.svc.Kind == protodef.KindRoute
+}
+
+func (p *project) controller() string {
+	if p.svc.GenTo == "" {
+		return ""
+	}
+	return filepath.Join(p.root, p.svc.GenTo)
+}
+
+func (p *project) saveProto(removed []string) error {
+	if err := protofmt.Save(p.Proto, p.def); err != nil {
+		return err
+	}
+	fmt.Printf("update   %s \n", p.Proto)
+	for _, name := range removed {
+		fmt.Printf("         删除 message %s\n", name)
+	}
+	return nil
+}
+
+// otherProtos 项目中除当前文件外的 proto 删除 message 前检查其中的引用
+func (p *project) otherProtos() ([]*proto.Proto, error) {
+	var defs []*proto.Proto
+	self := absPath(p.Proto)
+	err := filepath.WalkDir(p.root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if file != p.root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(file) != ".proto" || absPath(file) == self {
+			return nil
+		}
+		def, err := protofmt.Load(file)
+		if err != nil {
+			return fmt.Errorf("解析 %s 失败: %v", file, err)
+		}
+		defs = append(defs, def)
+		return nil
+	})
+	return defs, err
+}
+
+// RemoveAPI 删除路由组中的接口 以及 controller 方法、logic 函数与测试
+func RemoveAPI(t *Target) error {
+	p, err := open(t)
+	if err != nil {
+		return err
+	}
+	if !p.route() {
+		return fmt.Errorf("%s 不是路由组, 删除 rpc 请使用 rmrpc", t.Service)
+	}
+	return p.remove()
+}
+
+// RemoveRPC 删除 service 中的 rpc 以及 gen_to 文件中的实现方法
+func RemoveRPC(t *Target) error {
+	p, err := open(t)
+	if err != nil {
+		return err
+	}
+	if p.route() {
+		return fmt.Errorf("%s 是路由组, 删除接口请使用 rmapi", t.Service)
+	}
+	return p.remove()
+}
+
+func (p *project) remove() error {
+	m, err := p.method(p.Name)
+	if err != nil {
+		return err
+	}
+	others, err := p.otherProtos()
+	if err != nil {
+		return err
+	}
+	removed, err := removeRPC(p.def, others, findService(p.def, p.Service), p.Name)
+	if err != nil {
+		return err
+	}
+	if err := p.saveProto(removed); err != nil {
+		return err
+	}
+	return p.removeCode([]*protodef.Method{m}, false)
+}
+
+// RemoveRoute 删除路由组 以及 controller、logic 函数、测试与路由注册
+func RemoveRoute(t *Target) error {
+	p, err := open(t)
+	if err != nil {
+		return err
+	}
+	if !p.route() {
+		return fmt.Errorf("%s 不是路由组", t.Service)
+	}
+	others, err := p.otherProtos()
+	if err != nil {
+		return err
+	}
+	if err := p.saveProto(removeService(p.def, others, findService(p.def, p.Service))); err != nil {
+		return err
+	}
+	if err := p.removeCode(p.svc.Methods, true); err != nil {
+		return err
+	}
+	return eachGoFile(filepath.Join(p.root, p.Router), false, func(file string) error {
+		return editGo(file, func(g *goFile) { g.unregister(p.Service) })
+	})
+}
+
+// removeCode 删除方法对应的代码 all 为 true 时连同 controller 结构体一起删除
+func (p *project) removeCode(methods []*protodef.Method, all bool) error {
+	names := map[string]bool{}
+	tests := map[string]bool{}
+	reqs := map[string]string{}
+	for _, m := range methods {
+		names[m.Name] = true
+		tests["Test"+p.Service+"_"+m.Name] = true
+		reqs[m.Name] = baseType(m.Req)
+	}
+	if all {
+		tests[engineFunc(p.Service)] = true
+	}
+	controller := p.controller()
+	if controller != "" {
+		err := editGo(controller, func(g *goFile) {
+			for _, decl := range g.f.Decls {
+				switch d := decl.(type) {
+				case *ast.FuncDecl:
+					if recv := recvName(d); recv == p.Service && (all || names[d.Name.Name]) {
+						g.removeDecl(d)
+					}
+				case *ast.GenDecl:
+					if all {
+						g.removeSpecs(d, func(spec ast.Spec) bool { return definesController(spec, p.Service) })
+					}
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !p.route() {
+		return nil
+	}
+	err := eachGoFile(filepath.Join(p.root, p.Logic), false, func(file string) error {
+		return editGo(file, func(g *goFile) {
+			for _, decl := range g.f.Decls {
+				if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil && names[fd.Name.Name] && usesType(fd, reqs[fd.Name.Name]) {
+					g.removeDecl(fd)
+				}
+			}
+		})
+	})
+	if err != nil || controller == "" {
+		return err
+	}
+	return eachGoFile(filepath.Dir(controller), false, func(file string) error {
+		return editGo(file, func(g *goFile) {
+			for _, decl := range g.f.Decls {
+				switch d := decl.(type) {
+				case *ast.FuncDecl:
+					if d.Recv == nil && tests[d.Name.Name] {
+						g.removeDecl(d)
+					}
+				case *ast.GenDecl:
+					if all && strings.HasSuffix(g.name, "_test.go") {
+						g.removeSpecs(d, func(spec ast.Spec) bool { return definesEngine(spec, p.Service) })
+					}
+				}
+			}
+		})
+	})
+}
+
+// RenameAPI 重命名接口或 rpc
+// 同时修改 Req/Resp、controller 方法、logic 函数、测试以及项目中对它们的引用
+// 接口的 URL 默认不变: 没有 @api 时写入原来的默认路径, renamePath 为 true 时改为新名称的默认路径
+func RenameAPI(t *Target, to string, renamePath bool) error {
+	p, err := open(t)
+	if err != nil {
+		return err
+	}
+	m, err := p.method(t.Name)
+	if err != nil {
+		return err
+	}
+	renames, err := renameRPC(p.def, findService(p.def, p.Service), t.Name, to, p.route(), renamePath)
+	if err != nil {
+		return err
+	}
+	if err := p.saveProto(nil); err != nil {
+		return err
+	}
+	r := &renamer{project: p, to: to, req: baseType(m.Req), types: renames}
+	if p.route() {
+		f, err := protodef.ParseFile(t.Proto)
+		if err != nil {
+			return err
+		}
+		for _, s := range f.Services {
+			if s.Name == p.Service {
+				for _, cur := range s.Methods {
+					if cur.Name == to {
+						r.oldPath, r.newPath = p.svc.FullPath(m), s.FullPath(cur)
+					}
+				}
+			}
+		}
+	}
+	err = eachGoFile(p.root, true, func(file string) error {
+		return editGo(file, r.rewrite)
+	})
+	if err != nil || p.controller() == "" || !p.route() {
+		return err
+	}
+	// 测试文件按路由组与接口命名
+	dir := filepath.Dir(p.controller())
+	from, dst := testFile(dir, p.Service, t.Name), testFile(dir, p.Service, to)
+	if _, err := os.Stat(from); err != nil {
+		return nil
+	}
+	if _, err := os.Stat(dst); err == nil {
+		fmt.Printf("skip     %s 已存在\n", dst)
+		return nil
+	}
+	if err := os.Rename(from, dst); err != nil {
+		return err
+	}
+	fmt.Printf("rename   %s -> %s \n", from, dst)
+	return nil
+}
+
+// renamer 重命名时对每个 go 文件的修改
+type renamer struct {
+	*project
+	to      string
+	req     string            // 修改前的 Req 类型
+	types   map[string]string // pb 类型的旧名称到新名称
+	oldPath string
+	newPath string
+}
+
+func (r *renamer) rewrite(g *goFile) {
+	dir := filepath.Dir(absPath(g.name))
+	test := strings.HasSuffix(g.name, "_test.go")
+	pb, hasPB := importName(g.f, r.pbImport)
+	logic, hasLogic := importName(g.f, r.logicImport)
+	ast.Inspect(g.f, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case *ast.SelectorExpr:
+			if x, ok := v.X.(*ast.Ident); ok {
+				if to, ok := r.types[v.Sel.Name]; ok && hasPB && x.Name == pb {
+					g.replace(v.Sel, to)
+				}
+				if hasLogic && x.Name == logic && v.Sel.Name == r.Name && r.route() {
+					g.replace(v.Sel, r.to)
+				}
+			}
+		case *ast.FuncDecl:
+			switch {
+			case recvName(v) == r.Service && v.Name.Name == r.Name && absPath(g.name) == r.controller(),
+				v.Recv == nil && v.Name.Name == r.Name && r.route() && dir == filepath.Join(r.root, r.Logic) && usesType(v, r.req):
+				g.renameFunc(v, r.to)
+			case v.Recv == nil && test && v.Name.Name == "Test"+r.Service+"_"+r.Name:
+				g.renameFunc(v, "Test"+r.Service+"_"+r.to)
+			}
+		case *ast.BasicLit:
+			if v.Kind != token.STRING || !test || r.oldPath == r.newPath {
+				break
+			}
+			if s, err := strconv.Unquote(v.Value); err == nil && strings.HasPrefix(s, r.oldPath) {
+				if rest := s[len(r.oldPath):]; rest == "" || rest[0] == '?' {
+					g.replace(v, strconv.Quote(r.newPath+rest))
+				}
+			}
+		}
+		return true
+	})
+}
+
+// eachGoFile 遍历目录下的 go 文件 recursive 为 true 时包含子目录
+// 跳过隐藏目录、vendor 与 pb 代码 目录不存在时直接返回
+func eachGoFile(dir string, recursive bool, fn func(file string) error) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && (!recursive || strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") || strings.HasSuffix(p, ".pb.go") {
+			return nil
+		}
+		return fn(p)
+	})
+}
+
+// baseType 去掉 proto 类型的包名
+func baseType(typ string) string {
+	return typ[strings.LastIndex(typ, ".")+1:]
+}
+
+// typeName 类型表达式的名称 忽略包名与指针
+func typeName(e ast.Expr) string {
+	switch v := e.(type) {
+	case *ast.Ident:
+		return v.Name
+	case *ast.SelectorExpr:
+		return v.Sel.Name
+	case *ast.StarExpr:
+		return typeName(v.X)
+	}
+	return ""
+}
+
+func recvName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) != 1 {
+		return ""
+	}
+	return typeName(fd.Recv.List[0].Type)
+}
+
+// usesType 函数签名中是否使用了类型 typ
+func usesType(fd *ast.FuncDecl, typ string) bool {
+	found := false
+	ast.Inspect(fd.Type, func(n ast.Node) bool {
+		if e, ok := n.(ast.Expr); ok && !found {
+			if _, ok := e.(*ast.StarExpr); !ok {
+				found = typeName(e) == typ
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// definesController 路由组的结构体定义或 var _ pb.XxxImpl = (*Xxx)(nil)
+func definesController(spec ast.Spec, name string) bool {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Name.Name == name
+	case *ast.ValueSpec:
+		return len(s.Names) == 1 && s.Names[0].Name == "_" && typeName(s.Type) == name+"Impl"
+	}
+	return false
+}
+
+// definesEngine 是否为 addapi 生成的测试中保存路由组 engine 的变量
+func definesEngine(spec ast.Spec, name string) bool {
+	vs, ok := spec.(*ast.ValueSpec)
+	if !ok {
+		return false
+	}
+	for _, id := range vs.Names {
+		if id.Name != engineVar(name) && id.Name != engineVar(name)+"Once" {
+			return false
+		}
+	}
+	return true
+}
+
+// registers 是否为注册路由组的参数 pb.XxxGroupRouterMap、new(controller.Xxx) 或 &controller.Xxx{}
+func registers(e ast.Expr, name string) bool {
+	switch v := e.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		return typeName(v) == name+"GroupRouterMap"
+	case *ast.CallExpr:
+		if id, ok := v.Fun.(*ast.Ident); ok && id.Name == "new" && len(v.Args) == 1 {
+			return typeName(v.Args[0]) == name
+		}
+	case *ast.UnaryExpr:
+		return v.Op == token.AND && registers(v.X, name)
+	case *ast.CompositeLit:
+		return typeName(v.Type) == name
+	}
+	return false
+}
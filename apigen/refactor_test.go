@@ -0,0 +1,134 @@
+package apigen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const routerSrc = `package router
+
+import (
+	"example.com/app/internal/controller"
+	"example.com/app/model/user"
+	"github.com/actorbuf/iota/core"
+	"github.com/gin-gonic/gin"
+)
+
+func Register(engine *gin.Engine) {
+	core.NewRegister().BindRouteMap(user.UserApiGroupRouterMap).RegisterStruct(engine, new(controller.UserApi))
+	core.NewRegister().
+		BindRouteMap(user.OrderApiGroupRouterMap).
+		BindRouteMap(user.UserApiGroupRouterMap).
+		RegisterStruct(engine,
+			&controller.OrderApi{},
+			new(controller.UserApi),
+		)
+}
+`
+
+// setupProject 生成 GetUser 与 CreateUser 两个接口的项目
+func setupProject(t *testing.T) (string, string) {
+	root := t.TempDir()
+	pb := filepath.Join(root, "model", "user", "user.proto")
+	write(t, filepath.Join(root, "go.mod"), "module example.com/app\n\ngo 1.16\n")
+	write(t, pb, userProto+"\nmessage Profile {\n    GetUserResp last = 1;\n}\n")
+	write(t, filepath.Join(root, "internal", "router", "router.go"), routerSrc)
+	for _, name := range []string{"GetUser", "CreateUser"} {
+		if err := Generate(&Option{Proto: pb, Service: "UserApi", Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, pb
+}
+
+func TestRemoveAPI(t *testing.T) {
+	root, pb := setupProject(t)
+	if err := RemoveAPI(&Target{Proto: pb, Service: "UserApi", Name: "GetUser"}); err != nil {
+		t.Fatal(err)
+	}
+	src := read(t, pb)
+	// GetUserResp 仍被 Profile 引用
+	if strings.Contains(src, "rpc GetUser") || strings.Contains(src, "message GetUserReq") || !strings.Contains(src, "message GetUserResp") {
+		t.Fatalf("unexpected proto:\n%s", src)
+	}
+	controller := filepath.Join(root, "internal", "controller", "user_api.go")
+	expect(t, controller, "func (receiver *UserApi) CreateUser(")
+	expect(t, filepath.Join(root, "internal", "logic", "user_api.go"), "func CreateUser(")
+	for _, file := range []string{controller, filepath.Join(root, "internal", "logic", "user_api.go")} {
+		if strings.Contains(read(t, file), "GetUser(") {
+			t.Fatalf("GetUser not removed from %s", file)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "internal", "controller", "user_api_get_user_test.go")); !os.IsNotExist(err) {
+		t.Fatal("test file should be removed")
+	}
+	if err := RemoveAPI(&Target{Proto: pb, Service: "UserApi", Name: "GetUser"}); err == nil {
+		t.Fatal("expected missing api error")
+	}
+	if err := RemoveRPC(&Target{Proto: pb, Service: "UserApi", Name: "CreateUser"}); err == nil {
+		t.Fatal("expected route group error")
+	}
+}
+
+func TestRenameAPI(t *testing.T) {
+	root, pb := setupProject(t)
+	if err := RenameAPI(&Target{Proto: pb, Service: "UserApi", Name: "GetUser"}, "FetchUser", true); err != nil {
+		t.Fatal(err)
+	}
+	src := read(t, pb)
+	for _, s := range []string{"rpc FetchUser (FetchUserReq) returns (FetchUserResp)", "@api: /fetch_user", "message FetchUserReq", "FetchUserResp last = 1;"} {
+		if !strings.Contains(src, s) {
+			t.Fatalf("expected %q in proto:\n%s", s, src)
+		}
+	}
+	expect(t, filepath.Join(root, "internal", "controller", "user_api.go"),
+		"// FetchUser\nfunc (receiver *UserApi) FetchUser(ctx *core.Context, req *user.FetchUserReq) (resp *user.FetchUserResp, err error)",
+		"return logic.FetchUser(ctx, req)")
+	expect(t, filepath.Join(root, "internal", "logic", "user_api.go"), "func FetchUser(ctx *core.Context, req *user.FetchUserReq) (*user.FetchUserResp, error)")
+	expect(t, filepath.Join(root, "internal", "controller", "user_api_fetch_user_test.go"),
+		"func TestUserApi_FetchUser", `"/api/user/fetch_user?"`)
+	if err := RenameAPI(&Target{Proto: pb, Service: "UserApi", Name: "FetchUser"}, "CreateUser", false); err == nil {
+		t.Fatal("expected duplicate api error")
+	}
+
+	// 默认保持 URL 不变 没有 @api 时写入原来的默认路径
+	if err := RenameAPI(&Target{Proto: pb, Service: "UserApi", Name: "CreateUser"}, "AddUser", false); err != nil {
+		t.Fatal(err)
+	}
+	if src := read(t, pb); !strings.Contains(src, "// @desc: 创建用户\n    // @api: /create_user\n    rpc AddUser (AddUserReq) returns (AddUserResp);") {
+		t.Fatalf("unexpected proto:\n%s", src)
+	}
+	expect(t, filepath.Join(root, "internal", "controller", "user_api_add_user_test.go"), `"/api/user/create_user"`)
+}
+
+func TestRemoveRoute(t *testing.T) {
+	root, pb := setupProject(t)
+	logic := filepath.Join(root, "internal", "logic", "user_api.go")
+	write(t, logic, read(t, logic)+"\nfunc helper() {}\n")
+	// 同一个包的其他文件直接引用 其他包通过全名引用
+	write(t, filepath.Join(root, "model", "user", "extra.proto"), "syntax = \"proto3\";\npackage user;\n\nmessage Extra {\n    CreateUserResp last = 1;\n}\n")
+	write(t, filepath.Join(root, "model", "order", "order.proto"), "syntax = \"proto3\";\npackage order;\n\nmessage Order {\n    user.GetUserReq req = 1;\n}\n")
+	if err := RemoveRoute(&Target{Proto: pb, Service: "UserApi"}); err != nil {
+		t.Fatal(err)
+	}
+	if src := read(t, pb); strings.Contains(src, "UserApi") || strings.Contains(src, "CreateUserReq") ||
+		!strings.Contains(src, "message CreateUserResp") || !strings.Contains(src, "message GetUserReq") {
+		t.Fatalf("unexpected proto:\n%s", src)
+	}
+	for _, name := range []string{"user_api.go", "user_api_get_user_test.go", "user_api_create_user_test.go", "user_api_engine_test.go"} {
+		if _, err := os.Stat(filepath.Join(root, "internal", "controller", name)); !os.IsNotExist(err) {
+			t.Fatalf("%s should be removed", name)
+		}
+	}
+	expect(t, logic, "func helper() {}")
+	if src := read(t, logic); strings.Contains(src, "GetUser") || strings.Contains(src, `"example.com/app/model/user"`) {
+		t.Fatalf("unexpected logic:\n%s", src)
+	}
+	router := filepath.Join(root, "internal", "router", "router.go")
+	expect(t, router, "core.NewRegister().\n\t\tBindRouteMap(user.OrderApiGroupRouterMap).\n\t\tRegisterStruct(engine,\n\t\t\t&controller.OrderApi{},\n\t\t)")
+	if src := read(t, router); strings.Contains(src, "UserApi") {
+		t.Fatalf("unexpected router:\n%s", src)
+	}
+}
@@ -0,0 +1,144 @@
+package apigen
+
+const coreImport = "github.com/actorbuf/iota/core"
+
+const templateLogicFunc = `
+// {{.Name}} {{.Desc}}
+func {{.Name}}(ctx *core.Context, req *{{.PB}}.{{.Req}}) (*{{.PB}}.{{.Resp}}, error) {
+	resp := new({{.PB}}.{{.Resp}})
+
+	// TODO impl...
+
+	return resp, nil
+}
+`
+
+const templateLogicFile = `package {{.LogicPkg}}
+
+import (
+	"github.com/actorbuf/iota/core"
+	{{.Import .PB .PBImport}}
+)
+` + templateLogicFunc
+
+// templateControllerType 与 gen 生成的路由组结构一致
+const templateControllerType = `
+type {{.Service}} struct{}
+
+// IDE: {{.Service}} implemented {{.PB}}.{{.Service}}Impl interface
+var _ {{.PB}}.{{.Service}}Impl = (*{{.Service}})(nil)
+
+// Bind 绑定路由组名称 默认service名称 请不要擅自修改
+func (receiver *{{.Service}}) Bind() string {
+	return "{{.Service}}"
+}
+`
+
+const templateControllerFunc = `
+// {{.Name}} {{.Desc}}
+func (receiver *{{.Service}}) {{.Name}}(ctx *core.Context, req *{{.PB}}.{{.Req}}) (resp *{{.PB}}.{{.Resp}}, err error) {
+	return {{.LogicPkg}}.{{.Name}}(ctx, req)
+}
+`
+
+const templateControllerFile = `package {{.Controller}}
+
+import (
+	"github.com/actorbuf/iota/core"
+	{{.Import .PB .PBImport}}
+	{{.Import .LogicPkg .LogicImport}}
+)
+` + templateControllerType + templateControllerFunc
+
+// templateTestEngine 路由组的测试共用一个 engine
+// iota 注册时会把前缀拼到 GroupRouterMap 的路径上 同一个测试进程中重复注册会得到重复前缀的路由
+const templateTestEngine = `package {{.Controller}}
+
+import (
+	"sync"
+
+	"github.com/actorbuf/iota/core"
+	"github.com/gin-gonic/gin"
+	{{.Import .PB .PBImport}}
+)
+
+var (
+	{{.EngineVar}}     *gin.Engine
+	{{.EngineVar}}Once sync.Once
+)
+
+// {{.EngineFunc}} 只注册一次 {{.Service}} 路由组 同一个包中的测试共用
+func {{.EngineFunc}}() *gin.Engine {
+	{{.EngineVar}}Once.Do(func() {
+		gin.SetMode(gin.TestMode)
+		{{.EngineVar}} = gin.New()
+		core.NewRegister().BindRouteMap({{.PB}}.{{.Service}}GroupRouterMap).RegisterStruct({{.EngineVar}}, new({{.Service}}))
+	})
+	return {{.EngineVar}}
+}
+`
+
+const templateTest = `package {{.Controller}}
+
+import (
+{{- if ne .Method "GET"}}
+	"bytes"
+{{- end}}
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+{{- if eq .Method "GET"}}
+	"net/url"
+{{- end}}
+	"testing"
+
+	"github.com/actorbuf/iota/core"
+{{- if ne .Method "GET"}}
+	{{.Import .PB .PBImport}}
+{{- end}}
+)
+
+func Test{{.Service}}_{{.Name}}(t *testing.T) {
+	engine := {{.EngineFunc}}()
+
+	tests := []struct {
+		name    string
+{{- if eq .Method "GET"}}
+		query   url.Values
+{{- else}}
+		req     *{{.PB}}.{{.Req}}
+{{- end}}
+		errCode int
+	}{
+		// TODO 补充用例
+{{- if eq .Method "GET"}}
+		{name: "ok", query: url.Values{}, errCode: core.ErrNil},
+{{- else}}
+		{name: "ok", req: &{{.PB}}.{{.Req}}{}, errCode: core.ErrNil},
+{{- end}}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+{{- if eq .Method "GET"}}
+			r := httptest.NewRequest("{{.Method}}", "{{.Path}}?"+tt.query.Encode(), nil)
+{{- else}}
+			body, err := json.Marshal(tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := httptest.NewRequest("{{.Method}}", "{{.Path}}", bytes.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+{{- end}}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, r)
+			var res core.Result
+			if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &res) != nil {
+				t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+			}
+			if res.ErrCode != tt.errCode {
+				t.Fatalf("want err_code %d, got %d: %s", tt.errCode, res.ErrCode, res.ErrMsg)
+			}
+		})
+	}
+}
+`
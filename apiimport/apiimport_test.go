@@ -0,0 +1,289 @@
+package apiimport
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/actorbuf/iotaer/apigen"
+	proto "github.com/actorbuf/proto-parser"
+)
+
+const petstore = `openapi: 3.0.3
+info:
+  title: Partner API
+  version: 1.0.0
+servers:
+  - url: https://partner.example.com/v1
+paths:
+  /partner/pets:
+    get:
+      operationId: listPets
+      summary: 宠物列表
+      parameters:
+        - name: pageSize
+          in: query
+          schema:
+            type: integer
+            format: int32
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: object
+                properties:
+                  items:
+                    type: array
+                    items:
+                      $ref: '#/components/schemas/Pet'
+                  total:
+                    type: integer
+    post:
+      summary: 创建宠物
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Pet'
+  /partner/pets/delete:
+    parameters:
+      - name: petId
+        in: query
+        required: true
+        schema:
+          type: string
+    delete:
+      operationId: delete-pet
+      responses:
+        "204":
+          description: deleted
+components:
+  schemas:
+    Pet:
+      description: 宠物
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+          description: 名称
+        status:
+          type: string
+          enum: [available, sold]
+        tags:
+          type: object
+          additionalProperties:
+            type: string
+        owner:
+          type: object
+          properties:
+            userID:
+              type: integer
+              format: int64
+        children:
+          type: array
+          items:
+            $ref: '#/components/schemas/Pet'
+`
+
+const swagger = `{
+  "swagger": "2.0",
+  "info": {"title": "legacy", "version": "1"},
+  "basePath": "/api",
+  "paths": {
+    "/users/detail": {
+      "get": {
+        "parameters": [{"name": "id", "in": "query", "required": true, "type": "integer"}],
+        "responses": {"200": {"description": "ok", "schema": {"$ref": "#/definitions/User"}}}
+      }
+    },
+    "/users": {
+      "post": {
+        "parameters": [{"name": "body", "in": "body", "schema": {"$ref": "#/definitions/User"}}],
+        "responses": {"200": {"description": "ok", "schema": {"type": "array", "items": {"$ref": "#/definitions/User"}}}}
+      }
+    }
+  },
+  "definitions": {
+    "User": {"type": "object", "properties": {"nick-name": {"type": "string"}}}
+  }
+}`
+
+func names(fields []apigen.Field) []string {
+	var list []string
+	for _, f := range fields {
+		t := f.Type
+		if f.KeyType != "" {
+			t = "map<" + f.KeyType + "," + t + ">"
+		}
+		if f.Repeated {
+			t = "[]" + t
+		}
+		list = append(list, f.Name+":"+t)
+	}
+	return list
+}
+
+func TestConvert(t *testing.T) {
+	spec, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := Convert(spec, Option{Group: "PartnerApi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Prefix != "/v1/partner" || len(plan.APIs) != 3 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	var apis []string
+	for _, api := range plan.APIs {
+		apis = append(apis, api.Method+" "+api.Name+" "+api.Path)
+	}
+	if !reflect.DeepEqual(apis, []string{"GET ListPets /pets", "POST PostPets /pets", "DELETE DeletePet /pets/delete"}) {
+		t.Fatalf("unexpected apis %v", apis)
+	}
+	list, create, del := plan.APIs[0], plan.APIs[1], plan.APIs[2]
+	if got := names(list.Fields); !reflect.DeepEqual(got, []string{"page_size:int32"}) || list.Fields[0].Comment[0] != "@json: pageSize" {
+		t.Fatalf("unexpected list fields %v %v", got, list.Fields)
+	}
+	if got := names(list.RespFields); !reflect.DeepEqual(got, []string{"items:[]Pet", "total:int64"}) {
+		t.Fatalf("unexpected list resp %v", got)
+	}
+	if got := names(create.Fields); !reflect.DeepEqual(got, []string{"name:string", "status:string", "tags:map<string,string>", "owner:PetOwner", "children:[]Pet"}) {
+		t.Fatalf("unexpected create fields %v", got)
+	}
+	if !reflect.DeepEqual(create.Fields[0].Comment, []string{"名称", "@v: required"}) || create.Fields[1].Comment[0] != "可选值: available, sold" {
+		t.Fatalf("unexpected comments %+v", create.Fields)
+	}
+	if got := names(del.Fields); !reflect.DeepEqual(got, []string{"pet_id:string"}) || del.RespFields != nil {
+		t.Fatalf("unexpected delete fields %v", got)
+	}
+
+	var msgs []string
+	for _, m := range plan.Messages {
+		msgs = append(msgs, m.Name+strings.Join(names(m.Fields), ","))
+	}
+	if !reflect.DeepEqual(msgs, []string{
+		"Petname:string,status:string,tags:map<string,string>,owner:PetOwner,children:[]Pet",
+		"PetOwneruser_id:int64",
+	}) {
+		t.Fatalf("unexpected messages %v", msgs)
+	}
+}
+
+func TestConvertSwagger(t *testing.T) {
+	spec, err := Parse([]byte(swagger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := Convert(spec, Option{Group: "LegacyApi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Prefix != "/api" || plan.APIs[0].Name != "GetUsersDetail" || plan.APIs[0].Path != "/users/detail" {
+		t.Fatalf("unexpected plan %+v", plan.APIs[0])
+	}
+	if got := names(plan.APIs[0].RespFields); !reflect.DeepEqual(got, []string{"nick_name:string"}) {
+		t.Fatalf("unexpected resp %v", got)
+	}
+	if got := names(plan.APIs[1].Fields); !reflect.DeepEqual(got, []string{"nick_name:string"}) {
+		t.Fatalf("unexpected body %v", got)
+	}
+	if got := names(plan.APIs[1].RespFields); !reflect.DeepEqual(got, []string{"data:[]User"}) {
+		t.Fatalf("unexpected array resp %v", got)
+	}
+	if _, err := Convert(spec, Option{Group: "legacy"}); err == nil {
+		t.Fatal("expected invalid group error")
+	}
+	if _, err := Parse([]byte("info: {}")); err == nil {
+		t.Fatal("expected version error")
+	}
+}
+
+func TestConvertUnsupported(t *testing.T) {
+	spec, err := Parse([]byte(strings.NewReplacer("/users/detail", "/users/{id}", `"/users"`, `"/order-items"`).Replace(swagger)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Convert(spec, Option{Group: "LegacyApi"})
+	if err == nil || !strings.Contains(err.Error(), "GET /api/users/{id}\n  POST /api/order-items") {
+		t.Fatalf("expected unsupported paths, got %v", err)
+	}
+	// HEAD 与 TRACE 没有对应的 @method
+	head := strings.Replace(swagger, `"post": {`, `"head": {"operationId": "checkUsers", `, 1)
+	if _, err := Parse([]byte(head)); err == nil || !strings.Contains(err.Error(), "HEAD /users (checkUsers)") {
+		t.Fatalf("expected unsupported method, got %v", err)
+	}
+	spec, _ = Parse([]byte(swagger))
+	if _, err := Convert(spec, Option{Group: "LegacyApi", Prefix: "/api/legacy-v1"}); err == nil {
+		t.Fatal("expected invalid prefix error")
+	}
+}
+
+// TestRoutes 按 addroute/addapi 的方式写入 proto 后 gen 注册的路由与文档中的路径一致
+func TestRoutes(t *testing.T) {
+	spec, err := Parse([]byte(petstore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := Convert(spec, Option{Group: "PartnerApi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "syntax = \"proto3\";\npackage partner;\n\n// @route_group: true\n// @route_api: %s\nservice %s {\n", plan.Prefix, plan.Group)
+	for _, api := range plan.APIs {
+		_, _ = fmt.Fprintf(&b, "    // @method: %s\n    rpc %s (%sReq) returns (%sResp);\n", api.Method, api.Name, api.Name, api.Name)
+	}
+	b.WriteString("}\n")
+	for _, api := range plan.APIs {
+		_, _ = fmt.Fprintf(&b, "message %sReq {}\nmessage %sResp {}\n", api.Name, api.Name)
+	}
+	pb := filepath.Join(t.TempDir(), "partner.proto")
+	if err := ioutil.WriteFile(pb, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := apigen.AddMessages(pb, plan.Messages); err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, api := range plan.APIs {
+		o := &apigen.Option{Proto: pb, Service: plan.Group, Name: api.Name, Path: api.Path, Fields: api.Fields, RespFields: api.RespFields}
+		if err := apigen.UpdateProto(pb, o); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, plan.Prefix+api.Path)
+	}
+
+	src, err := ioutil.ReadFile(pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group, url := regexp.MustCompile(proto.RegexpGroupRouterAPI), regexp.MustCompile(proto.RegexpRouterRpcURL)
+	var prefix string
+	var got []string
+	for _, line := range strings.Split(string(src), "\n") {
+		if res := group.FindStringSubmatch(line); res != nil {
+			prefix = res[1]
+		} else if res := url.FindStringSubmatch(line); res != nil {
+			got = append(got, prefix+res[1])
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("registered %v, want %v\n%s", got, want, src)
+	}
+}
@@ -0,0 +1,706 @@
+package apiimport
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/actorbuf/iotaer/apigen"
+)
+
+// Option 导入的参数
+type Option struct {
+	Group  string // 路由组名称
+	Prefix string // 路由组前缀 为空时为所有接口路径的公共前缀
+}
+
+// API 路由组中的一个接口 Fields 与 RespFields 为 Req/Resp 的字段
+type API struct {
+	Name       string
+	Method     string
+	Path       string // 相对路由组前缀的路径
+	Desc       string
+	Fields     []apigen.Field
+	RespFields []apigen.Field
+}
+
+// Plan 导入文档需要在 proto 中新增的路由组、接口与消息
+type Plan struct {
+	Group    string
+	Prefix   string
+	APIs     []*API
+	Messages []apigen.Message
+}
+
+var identReg = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// routeReg 与 proto-parser 解析 @route_api 与 @api 的字符集一致, 其他字符及之后的内容不会被 gen 注册
+var routeReg = regexp.MustCompile(`^[\w|/]*$`)
+
+// converter 转换过程中的状态 被引用到的 schema 才生成消息
+type converter struct {
+	spec     *Spec
+	schemas  Schemas
+	names    map[string]string  // schema 名称 => 消息名称
+	inline   map[*Schema]string // 内联对象 => 消息名称
+	used     map[string]bool    // 已使用的消息名称
+	messages []apigen.Message
+}
+
+// Convert 把文档转换为路由组与消息
+// 请求参数与请求体的属性合并为 Req 的字段, 成功响应的属性为 Resp 的字段
+// 属性名转换为下划线形式的字段名 与原名称不同时通过 @json 保持原名称
+func Convert(spec *Spec, opt Option) (*Plan, error) {
+	if !identReg.MatchString(opt.Group) {
+		return nil, fmt.Errorf("路由组名称 %s 应为大驼峰", opt.Group)
+	}
+	c := &converter{spec: spec, schemas: spec.Components.Schemas, names: map[string]string{}, inline: map[*Schema]string{}, used: map[string]bool{}}
+	if spec.Swagger != "" {
+		c.schemas = spec.Definitions
+	}
+	plan := &Plan{Group: opt.Group, Prefix: strings.TrimSuffix(opt.Prefix, "/")}
+
+	type operation struct {
+		path string
+		item *Path
+		op   *Operation
+	}
+	var ops []operation
+	var paths, unsupported []string
+	for _, item := range spec.Paths {
+		for _, op := range item.Operations {
+			p := cleanPath(c.basePath() + "/" + strings.Trim(item.Path, "/"))
+			ops = append(ops, operation{path: p, item: item, op: op})
+			paths = append(paths, p)
+			if !routeReg.MatchString(p) {
+				unsupported = append(unsupported, op.Method+" "+p)
+			}
+		}
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("文档中没有接口")
+	}
+	// gen 只注册由字母、数字、_ 与 / 组成的静态路由, 路径参数不会被 ShouldBind 填充
+	if len(unsupported) > 0 {
+		return nil, fmt.Errorf("以下接口的路径包含路径参数或 gen 不支持的字符, 只支持由字母、数字、_ 与 / 组成的路径, 请修改文档后重新导入:\n  %s",
+			strings.Join(unsupported, "\n  "))
+	}
+	if !routeReg.MatchString(opt.Prefix) {
+		return nil, fmt.Errorf("路由组前缀 %s 包含 gen 不支持的字符", opt.Prefix)
+	}
+	if opt.Prefix == "" {
+		plan.Prefix = commonPrefix(paths)
+	}
+
+	// 先确定接口名称 Req/Resp 不与 schema 生成的消息重名
+	names := map[string]bool{}
+	for _, o := range ops {
+		rel := strings.TrimPrefix(o.path, plan.Prefix)
+		if !strings.HasPrefix(o.path, plan.Prefix+"/") && o.path != plan.Prefix {
+			return nil, fmt.Errorf("接口 %s %s 不在前缀 %s 下", o.op.Method, o.path, plan.Prefix)
+		}
+		if rel == "" {
+			rel = "/"
+		}
+		base := apiName(o.op, rel)
+		name := base
+		for i := 2; names[name]; i++ {
+			name = base + strconv.Itoa(i)
+		}
+		names[name] = true
+		c.used[name+"Req"], c.used[name+"Resp"] = true, true
+		desc := o.op.Summary
+		if desc == "" {
+			desc = firstLine(o.op.Description)
+		}
+		plan.APIs = append(plan.APIs, &API{Name: name, Method: o.op.Method, Path: rel, Desc: desc})
+	}
+	for i, o := range ops {
+		api := plan.APIs[i]
+		var err error
+		if api.Fields, err = c.request(api.Name+"Req", o.item, o.op); err != nil {
+			return nil, fmt.Errorf("%s %s: %v", o.op.Method, o.path, err)
+		}
+		if api.RespFields, err = c.response(api.Name+"Resp", o.op); err != nil {
+			return nil, fmt.Errorf("%s %s: %v", o.op.Method, o.path, err)
+		}
+		api.RespFields = optional(api.RespFields)
+	}
+	plan.Messages = c.messages
+	return plan, nil
+}
+
+// basePath swagger 2 的 basePath 或 OpenAPI 3 第一个 server 的路径
+func (c *converter) basePath() string {
+	base := c.spec.BasePath
+	if len(c.spec.Servers) > 0 {
+		if u, err := url.Parse(c.spec.Servers[0].URL); err == nil {
+			base = u.Path
+		}
+	}
+	base = strings.Trim(base, "/")
+	if base == "" {
+		return ""
+	}
+	return "/" + base
+}
+
+// cleanPath 去掉末尾的 /
+func cleanPath(p string) string {
+	if p != "/" {
+		p = strings.TrimSuffix(p, "/")
+	}
+	return p
+}
+
+// commonPrefix 所有路径的公共前缀 每个接口至少保留最后一段
+func commonPrefix(paths []string) string {
+	var prefix []string
+	for i, p := range paths {
+		segs := strings.Split(strings.Trim(p, "/"), "/")
+		if len(segs) > 0 {
+			segs = segs[:len(segs)-1]
+		}
+		if i == 0 {
+			prefix = segs
+			continue
+		}
+		n := 0
+		for n < len(prefix) && n < len(segs) && prefix[n] == segs[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	for i, seg := range prefix {
+		if strings.HasPrefix(seg, ":") {
+			prefix = prefix[:i]
+			break
+		}
+	}
+	if len(prefix) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(prefix, "/")
+}
+
+// apiName 优先使用 operationId, 否则为请求方式与路径 如 GET /users/detail => GetUsersDetail
+func apiName(op *Operation, path string) string {
+	if name := camel(op.OperationID); identReg.MatchString(name) {
+		return name
+	}
+	name := camel(strings.ToLower(op.Method))
+	for _, seg := range strings.Split(path, "/") {
+		name += camel(seg)
+	}
+	return name
+}
+
+// camel 任意名称转大驼峰 保留单词内部的大小写
+func camel(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) || r > unicode.MaxASCII {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// snake 属性名转下划线形式的字段名
+func snake(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case r > unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			b.WriteRune('_')
+		case unicode.IsUpper(r):
+			// userID => user_id, HTTPCode => http_code
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	var parts []string
+	for _, p := range strings.Split(b.String(), "_") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	name := strings.Join(parts, "_")
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "f_" + name
+	}
+	return strings.TrimSuffix(name, "_")
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
+
+// ref 引用的最后一段 按 JSON Pointer 还原转义
+func ref(r string) string {
+	name := r[strings.LastIndex(r, "/")+1:]
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~")
+}
+
+// resolve 展开 schema 的引用
+func (c *converter) resolve(s *Schema) (*Schema, string) {
+	name := ""
+	for i := 0; s != nil && s.Ref != "" && i < 32; i++ {
+		name = ref(s.Ref)
+		s = c.schemas.Get(name)
+	}
+	return s, name
+}
+
+// isObject 带属性的对象或组合的对象 生成为消息
+func (c *converter) isObject(s *Schema) bool {
+	s, _ = c.resolve(s)
+	if s == nil {
+		return false
+	}
+	if len(s.Properties) > 0 {
+		return true
+	}
+	for _, list := range [][]*Schema{s.AllOf, s.OneOf, s.AnyOf} {
+		for _, sub := range list {
+			if c.isObject(sub) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// properties 对象的属性 allOf/oneOf/anyOf 中的属性合并在一起 只有 allOf 中的必填属性仍为必填
+func (c *converter) properties(s *Schema, required map[string]bool, seen map[*Schema]bool) Schemas {
+	s, _ = c.resolve(s)
+	if s == nil || seen[s] {
+		return nil
+	}
+	seen[s] = true
+	var props Schemas
+	for _, name := range s.Required {
+		required[name] = true
+	}
+	props = append(props, s.Properties...)
+	for i, list := range [][]*Schema{s.AllOf, s.OneOf, s.AnyOf} {
+		req := required
+		if i > 0 {
+			req = map[string]bool{}
+		}
+		for _, sub := range list {
+			for _, p := range c.properties(sub, req, seen) {
+				if props.Get(p.Name) == nil {
+					props = append(props, p)
+				}
+			}
+		}
+	}
+	return props
+}
+
+// fields 对象属性生成的字段 ctx 为内联对象生成消息时的名称前缀
+func (c *converter) fields(s *Schema, ctx string) ([]apigen.Field, error) {
+	required := map[string]bool{}
+	var list []apigen.Field
+	for _, p := range c.properties(s, required, map[*Schema]bool{}) {
+		f, err := c.field(p.Name, p.Schema, ctx, "", required[p.Name])
+		if err != nil {
+			return nil, err
+		}
+		list = appendField(list, f)
+	}
+	return list, nil
+}
+
+// appendField 下划线形式相同的属性只保留第一个
+func appendField(list []apigen.Field, f apigen.Field) []apigen.Field {
+	for _, v := range list {
+		if v.Name == f.Name {
+			return list
+		}
+	}
+	return append(list, f)
+}
+
+// field 一个属性对应的字段 desc 为空时使用 schema 的说明
+func (c *converter) field(name string, s *Schema, ctx, desc string, required bool) (apigen.Field, error) {
+	f := apigen.Field{Name: snake(name)}
+	t, err := c.typeOf(s, ctx+camel(f.Name))
+	if err != nil {
+		return f, fmt.Errorf("%s: %v", name, err)
+	}
+	f.Type, f.Repeated, f.KeyType = t.typ, t.repeated, t.key
+	if desc == "" && s != nil {
+		desc = s.Description
+		if rs, _ := c.resolve(s); desc == "" && rs != nil {
+			desc = rs.Description
+		}
+	}
+	if desc = firstLine(desc); desc != "" {
+		f.Comment = append(f.Comment, desc)
+	}
+	f.Comment = append(f.Comment, t.notes...)
+	if f.Name != name {
+		f.Comment = append(f.Comment, "@json: "+name)
+	}
+	if required {
+		f.Comment = append(f.Comment, "@v: required")
+	}
+	return f, nil
+}
+
+// fieldType 字段的 proto 类型
+type fieldType struct {
+	typ      string
+	repeated bool
+	key      string
+	notes    []string
+}
+
+// typeOf schema 对应的 proto 类型 内联对象生成名为 ctx 的消息
+func (c *converter) typeOf(s *Schema, ctx string) (fieldType, error) {
+	if s != nil && s.Ref != "" {
+		rs, name := c.resolve(s)
+		if rs == nil {
+			return fieldType{}, fmt.Errorf("找不到引用 %s", s.Ref)
+		}
+		if c.isObject(rs) {
+			msg, err := c.message(name, rs)
+			return fieldType{typ: msg}, err
+		}
+		return c.typeOf(rs, camel(name))
+	}
+	if s == nil {
+		return fieldType{typ: "string"}, nil
+	}
+	if c.isObject(s) {
+		if name, ok := c.inline[s]; ok {
+			return fieldType{typ: name}, nil
+		}
+		name := c.unique(ctx)
+		c.inline[s] = name
+		fields, err := c.fields(s, name)
+		if err != nil {
+			return fieldType{}, err
+		}
+		c.messages = append(c.messages, apigen.Message{Name: name, Comment: comment(s), Fields: fields})
+		return fieldType{typ: name}, nil
+	}
+	for _, list := range [][]*Schema{s.AllOf, s.OneOf, s.AnyOf} {
+		if len(list) > 0 {
+			return c.typeOf(list[0], ctx)
+		}
+	}
+	var t fieldType
+	switch s.Type.Main() {
+	case "array":
+		item, err := c.typeOf(s.Items, ctx+"Item")
+		if err != nil {
+			return t, err
+		}
+		if item.repeated || item.key != "" {
+			// proto 中不能直接嵌套数组与 map 使用包装消息
+			name := c.unique(ctx + "Item")
+			c.messages = append(c.messages, apigen.Message{Name: name, Comment: []string{"数组元素的包装消息"},
+				Fields: []apigen.Field{{Name: "values", Type: item.typ, Repeated: item.repeated, KeyType: item.key}}})
+			item = fieldType{typ: name}
+		}
+		item.repeated = true
+		return item, nil
+	case "object":
+		value := fieldType{typ: "string"}
+		if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+			var err error
+			if value, err = c.typeOf(s.AdditionalProperties.Schema, ctx+"Value"); err != nil {
+				return t, err
+			}
+		} else {
+			value.notes = []string{"任意对象"}
+		}
+		if value.repeated || value.key != "" {
+			name := c.unique(ctx + "Value")
+			c.messages = append(c.messages, apigen.Message{Name: name, Comment: []string{"map 值的包装消息"},
+				Fields: []apigen.Field{{Name: "values", Type: value.typ, Repeated: value.repeated, KeyType: value.key}}})
+			value = fieldType{typ: name}
+		}
+		value.key = "string"
+		return value, nil
+	case "integer":
+		t.typ = "int64"
+		if s.Format == "int32" {
+			t.typ = "int32"
+		}
+	case "number":
+		t.typ = "double"
+		if s.Format == "float" {
+			t.typ = "float"
+		}
+	case "boolean":
+		t.typ = "bool"
+	case "string":
+		t.typ = "string"
+		if s.Format == "byte" || s.Format == "binary" {
+			t.typ = "bytes"
+		}
+	default:
+		t.typ = "string"
+	}
+	if len(s.Enum) > 0 {
+		var values []string
+		for _, v := range s.Enum {
+			if v != nil {
+				values = append(values, fmt.Sprint(v))
+			}
+		}
+		t.notes = append(t.notes, "可选值: "+strings.Join(values, ", "))
+	}
+	return t, nil
+}
+
+// message schema 生成的消息 同一个 schema 只生成一次
+func (c *converter) message(name string, s *Schema) (string, error) {
+	if msg, ok := c.names[name]; ok {
+		return msg, nil
+	}
+	msg := camel(name)
+	if !identReg.MatchString(msg) {
+		msg = "Model" + msg
+	}
+	if c.used[msg] {
+		msg += "Model"
+	}
+	msg = c.unique(msg)
+	c.names[name] = msg
+	// 先占位 递归引用时使用同一个消息
+	index := len(c.messages)
+	c.messages = append(c.messages, apigen.Message{Name: msg, Comment: comment(s)})
+	fields, err := c.fields(s, msg)
+	if err != nil {
+		return "", err
+	}
+	c.messages[index].Fields = fields
+	return msg, nil
+}
+
+func (c *converter) unique(name string) string {
+	result := name
+	for i := 2; c.used[result]; i++ {
+		result = name + strconv.Itoa(i)
+	}
+	c.used[result] = true
+	return result
+}
+
+func comment(s *Schema) []string {
+	if s == nil {
+		return nil
+	}
+	if desc := firstLine(s.Description); desc != "" {
+		return []string{desc}
+	}
+	if s.Title != "" {
+		return []string{s.Title}
+	}
+	return nil
+}
+
+// parameter 展开参数的引用
+func (c *converter) parameter(p *Parameter) *Parameter {
+	for i := 0; p != nil && p.Ref != ""; i++ {
+		name := ref(p.Ref)
+		if c.spec.Swagger != "" {
+			p = c.spec.Parameters[name]
+		} else {
+			p = c.spec.Components.Parameters[name]
+		}
+	}
+	return p
+}
+
+// request 路径与 query 参数以及请求体的属性
+func (c *converter) request(name string, item *Path, op *Operation) ([]apigen.Field, error) {
+	var list []apigen.Field
+	var body *Schema
+	params := append(append([]*Parameter{}, item.Parameters...), op.Parameters...)
+	// 接口中的参数覆盖路径中的同名参数
+	seen := map[string]bool{}
+	for i := len(params) - 1; i >= 0; i-- {
+		p := c.parameter(params[i])
+		if p == nil || seen[p.In+p.Name] {
+			continue
+		}
+		seen[p.In+p.Name] = true
+		switch p.In {
+		case "body":
+			body = p.Schema
+		case "query", "formData":
+			schema := p.Schema
+			if schema == nil {
+				schema = &Schema{Type: p.Type, Format: p.Format, Items: p.Items, Enum: p.Enum}
+			}
+			f, err := c.field(p.Name, schema, name, p.Description, p.Required)
+			if err != nil {
+				return nil, err
+			}
+			list = append([]apigen.Field{f}, list...)
+		}
+	}
+	if rb := c.requestBody(op.RequestBody); rb != nil {
+		body = content(rb.Content)
+	}
+	if body == nil {
+		return dedup(list), nil
+	}
+	if c.isObject(body) {
+		fields, err := c.object(body, name)
+		if err != nil {
+			return nil, err
+		}
+		return dedup(append(list, fields...)), nil
+	}
+	f, err := c.field("body", body, name, "", true)
+	if err != nil {
+		return nil, err
+	}
+	return dedup(append(list, f)), nil
+}
+
+// object 请求体与响应的字段 引用的 schema 先生成消息 使内联对象沿用该消息中的名称
+func (c *converter) object(body *Schema, ctx string) ([]apigen.Field, error) {
+	if body.Ref == "" {
+		return c.fields(body, ctx)
+	}
+	rs, name := c.resolve(body)
+	msg, err := c.message(name, rs)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range c.messages {
+		if m.Name == msg {
+			return m.Fields, nil
+		}
+	}
+	return nil, nil
+}
+
+// optional 去掉响应字段中的 @v 校验标记
+func optional(list []apigen.Field) []apigen.Field {
+	var result []apigen.Field
+	for _, f := range list {
+		var lines []string
+		for _, line := range f.Comment {
+			if !strings.HasPrefix(line, "@v:") {
+				lines = append(lines, line)
+			}
+		}
+		f.Comment = lines
+		result = append(result, f)
+	}
+	return result
+}
+
+func dedup(list []apigen.Field) []apigen.Field {
+	var result []apigen.Field
+	for _, f := range list {
+		result = appendField(result, f)
+	}
+	return result
+}
+
+func (c *converter) requestBody(rb *RequestBody) *RequestBody {
+	for i := 0; rb != nil && rb.Ref != ""; i++ {
+		rb = c.spec.Components.RequestBodies[ref(rb.Ref)]
+	}
+	return rb
+}
+
+// response 成功响应的属性 依次查找 200、其他 2xx 与 default
+func (c *converter) response(name string, op *Operation) ([]apigen.Field, error) {
+	var codes []string
+	for code := range op.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	codes = append(codes, "default")
+	var body *Schema
+	for _, code := range codes {
+		r := op.Responses[code]
+		for i := 0; r != nil && r.Ref != ""; i++ {
+			if c.spec.Swagger != "" {
+				r = c.spec.Responses[ref(r.Ref)]
+			} else {
+				r = c.spec.Components.Responses[ref(r.Ref)]
+			}
+		}
+		if r == nil {
+			continue
+		}
+		if body = r.Schema; body == nil {
+			body = content(r.Content)
+		}
+		break
+	}
+	if body == nil {
+		return nil, nil
+	}
+	if c.isObject(body) {
+		return c.object(body, name)
+	}
+	f, err := c.field("data", body, name, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return []apigen.Field{f}, nil
+}
+
+// content 优先使用 json 类型的内容
+func content(m map[string]*MediaType) *Schema {
+	var types []string
+	for t := range m {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		ji, jj := strings.Contains(types[i], "json"), strings.Contains(types[j], "json")
+		if ji != jj {
+			return ji
+		}
+		return types[i] < types[j]
+	})
+	for _, t := range types {
+		if m[t] != nil && m[t].Schema != nil {
+			return m[t].Schema
+		}
+	}
+	return nil
+}
+
+// GroupName 默认的路由组名称 为 proto 文件名的大驼峰形式加 Api
+func GroupName(pbFile string) string {
+	name := strings.TrimSuffix(filepath.Base(pbFile), filepath.Ext(pbFile))
+	return camel(strings.TrimSuffix(strings.TrimSuffix(name, "_api"), "Api")) + "Api"
+}
@@ -0,0 +1,268 @@
+package apiimport
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec OpenAPI 3 或 Swagger 2 文档中导入时用到的部分 json 文档同样按 yaml 解析
+type Spec struct {
+	Swagger string `yaml:"swagger"`
+	OpenAPI string `yaml:"openapi"`
+	Info    Info   `yaml:"info"`
+	Servers []struct {
+		URL string `yaml:"url"`
+	} `yaml:"servers"`
+	BasePath    string                `yaml:"basePath"`
+	Paths       Paths                 `yaml:"paths"`
+	Components  Components            `yaml:"components"`
+	Definitions Schemas               `yaml:"definitions"` // swagger 2
+	Parameters  map[string]*Parameter `yaml:"parameters"`  // swagger 2
+	Responses   map[string]*Response  `yaml:"responses"`   // swagger 2
+}
+
+// Info 文档信息
+type Info struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Version     string `yaml:"version"`
+}
+
+// Components OpenAPI 3 中可以被引用的定义
+type Components struct {
+	Schemas       Schemas                 `yaml:"schemas"`
+	Parameters    map[string]*Parameter   `yaml:"parameters"`
+	RequestBodies map[string]*RequestBody `yaml:"requestBodies"`
+	Responses     map[string]*Response    `yaml:"responses"`
+}
+
+// Path 一个路径与其中的接口
+type Path struct {
+	Path       string
+	Parameters []*Parameter
+	Operations []*Operation
+}
+
+// Paths 按文档中的顺序保存的路径
+type Paths []*Path
+
+// methods 按此顺序读取路径中的接口 与 proto-parser 支持的 @method 一致
+var methods = []string{"get", "put", "post", "delete", "options", "patch"}
+
+// unsupportedMethods proto-parser 不识别的请求方式 生成的路由没有请求方式 服务启动时 iota 注册路由会 panic
+var unsupportedMethods = []string{"head", "trace"}
+
+// UnmarshalYAML 保持路径在文档中的顺序
+func (p *Paths) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("paths 应为对象")
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		var item map[string]yaml.Node
+		if err := value.Content[i+1].Decode(&item); err != nil {
+			return err
+		}
+		path := &Path{Path: value.Content[i].Value}
+		if node, ok := item["parameters"]; ok {
+			if err := node.Decode(&path.Parameters); err != nil {
+				return err
+			}
+		}
+		for _, method := range unsupportedMethods {
+			if node, ok := item[method]; ok {
+				var op Operation
+				_ = node.Decode(&op)
+				name := strings.ToUpper(method) + " " + path.Path
+				if op.OperationID != "" {
+					name += " (" + op.OperationID + ")"
+				}
+				return fmt.Errorf("接口 %s: gen 不支持 %s 请求方式, 请从文档中删除后重新导入", name, strings.ToUpper(method))
+			}
+		}
+		for _, method := range methods {
+			node, ok := item[method]
+			if !ok {
+				continue
+			}
+			op := &Operation{Method: strings.ToUpper(method)}
+			if err := node.Decode(op); err != nil {
+				return fmt.Errorf("%s %s: %v", op.Method, path.Path, err)
+			}
+			path.Operations = append(path.Operations, op)
+		}
+		*p = append(*p, path)
+	}
+	return nil
+}
+
+// Operation 一个接口
+type Operation struct {
+	Method      string               `yaml:"-"`
+	OperationID string               `yaml:"operationId"`
+	Summary     string               `yaml:"summary"`
+	Description string               `yaml:"description"`
+	Parameters  []*Parameter         `yaml:"parameters"`
+	RequestBody *RequestBody         `yaml:"requestBody"`
+	Responses   map[string]*Response `yaml:"responses"`
+}
+
+// Parameter 接口参数 swagger 2 中非 body 参数的类型直接写在参数上
+type Parameter struct {
+	Ref         string        `yaml:"$ref"`
+	Name        string        `yaml:"name"`
+	In          string        `yaml:"in"`
+	Description string        `yaml:"description"`
+	Required    bool          `yaml:"required"`
+	Schema      *Schema       `yaml:"schema"`
+	Type        Types         `yaml:"type"`
+	Format      string        `yaml:"format"`
+	Items       *Schema       `yaml:"items"`
+	Enum        []interface{} `yaml:"enum"`
+}
+
+// RequestBody OpenAPI 3 的请求体
+type RequestBody struct {
+	Ref         string                `yaml:"$ref"`
+	Description string                `yaml:"description"`
+	Content     map[string]*MediaType `yaml:"content"`
+}
+
+// Response 接口响应 swagger 2 中 schema 直接写在响应上
+type Response struct {
+	Ref         string                `yaml:"$ref"`
+	Description string                `yaml:"description"`
+	Content     map[string]*MediaType `yaml:"content"`
+	Schema      *Schema               `yaml:"schema"`
+}
+
+// MediaType 一种内容类型
+type MediaType struct {
+	Schema *Schema `yaml:"schema"`
+}
+
+// Schema 数据结构
+type Schema struct {
+	Ref                  string        `yaml:"$ref"`
+	Type                 Types         `yaml:"type"`
+	Format               string        `yaml:"format"`
+	Title                string        `yaml:"title"`
+	Description          string        `yaml:"description"`
+	Enum                 []interface{} `yaml:"enum"`
+	Items                *Schema       `yaml:"items"`
+	Properties           Schemas       `yaml:"properties"`
+	Required             []string      `yaml:"required"`
+	AdditionalProperties *Additional   `yaml:"additionalProperties"`
+	AllOf                []*Schema     `yaml:"allOf"`
+	OneOf                []*Schema     `yaml:"oneOf"`
+	AnyOf                []*Schema     `yaml:"anyOf"`
+}
+
+// Types OpenAPI 3.1 中 type 可以为数组 如 [string, "null"]
+type Types []string
+
+// UnmarshalYAML 同时支持单个类型与类型数组
+func (t *Types) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*t = Types{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// Is 是否为指定的类型
+func (t Types) Is(typ string) bool {
+	for _, v := range t {
+		if v == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// Main 忽略 null 之后的类型
+func (t Types) Main() string {
+	for _, v := range t {
+		if v != "null" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Property 对象的一个属性
+type Property struct {
+	Name   string
+	Schema *Schema
+}
+
+// Schemas 按文档中的顺序保存的命名 schema 属性的顺序即为字段号的顺序
+type Schemas []Property
+
+// UnmarshalYAML 保持属性在文档中的顺序
+func (s *Schemas) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("properties 应为对象")
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		schema := &Schema{}
+		if err := value.Content[i+1].Decode(schema); err != nil {
+			return err
+		}
+		*s = append(*s, Property{Name: value.Content[i].Value, Schema: schema})
+	}
+	return nil
+}
+
+// Get 按名称查找
+func (s Schemas) Get(name string) *Schema {
+	for _, p := range s {
+		if p.Name == name {
+			return p.Schema
+		}
+	}
+	return nil
+}
+
+// Additional additionalProperties 可以为布尔值或 schema
+type Additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+// UnmarshalYAML 同时支持布尔值与 schema
+func (a *Additional) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&a.Allowed)
+	}
+	a.Allowed, a.Schema = true, &Schema{}
+	return value.Decode(a.Schema)
+}
+
+// Load 读取 yaml 或 json 格式的文档
+func Load(file string) (*Spec, error) {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(src)
+}
+
+// Parse 解析文档 只支持 OpenAPI 3 与 Swagger 2
+func Parse(src []byte) (*Spec, error) {
+	spec := &Spec{}
+	if err := yaml.Unmarshal(src, spec); err != nil {
+		return nil, fmt.Errorf("解析文档失败: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") && !strings.HasPrefix(spec.Swagger, "2.") {
+		return nil, fmt.Errorf("只支持 OpenAPI 3 与 Swagger 2 文档")
+	}
+	return spec, nil
+}
@@ -0,0 +1,244 @@
+package apimock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/actorbuf/iotaer/apidoc"
+)
+
+// 请求中用于指定本次响应的头 grpc 中为同名的 metadata
+const (
+	HeaderError   = "X-Mock-Error"   // 返回指定的错误码 值为错误码或名称
+	HeaderLatency = "X-Mock-Latency" // 本次请求的延迟 如 500ms
+)
+
+// ErrMock 没有可以返回的错误码时注入的错误
+var ErrMock = &apidoc.ErrorCode{Name: "ErrCodeMock", Code: http.StatusInternalServerError, Msg: "mock 注入的错误", HTTP: http.StatusInternalServerError}
+
+// Option mock 服务的配置
+type Option struct {
+	Fixtures  string        // 固定响应所在的目录 <路由组或service>/<方法名>.json 优先于合成的示例值
+	Latency   time.Duration // 每个请求的延迟
+	Jitter    time.Duration // 在延迟上随机增加 0 到 Jitter
+	ErrorRate float64       // 返回错误的比例 0 到 1
+	Log       io.Writer     // 请求日志 为空时不输出
+}
+
+// Server 按 proto 定义返回示例响应的 http 与 grpc 服务
+type Server struct {
+	opt    Option
+	errors map[string][]*apidoc.ErrorCode // 包名 => 错误码
+	codes  map[string]*apidoc.ErrorCode   // 名称与错误码 => 错误码
+	routes map[string][]*apidoc.API       // 路径 => 接口
+	rpcs   map[string]*apidoc.RPC
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// New 创建 mock 服务 services 为需要 mock 的 grpc service
+func New(p *apidoc.Project, services []*apidoc.Service, opt Option) *Server {
+	s := &Server{
+		opt:    opt,
+		errors: map[string][]*apidoc.ErrorCode{},
+		codes:  map[string]*apidoc.ErrorCode{},
+		routes: map[string][]*apidoc.API{},
+		rpcs:   map[string]*apidoc.RPC{},
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, ef := range p.Errors {
+		for _, e := range ef.Codes {
+			if e.Code == 0 {
+				continue
+			}
+			s.errors[ef.Package] = append(s.errors[ef.Package], e)
+			s.codes[e.Name] = e
+			s.codes[strconv.Itoa(e.Code)] = e
+		}
+	}
+	for _, g := range p.Groups {
+		for _, api := range g.APIs {
+			s.routes[api.Path] = append(s.routes[api.Path], api)
+		}
+	}
+	for _, svc := range services {
+		for _, rpc := range svc.RPCs {
+			s.rpcs[rpc.FullMethod()] = rpc
+		}
+	}
+	return s
+}
+
+// match 路由与 gen 注册的一致 只有静态路径
+func (s *Server) match(method, path string) (api *apidoc.API, found bool) {
+	apis := s.routes[path]
+	for _, a := range apis {
+		if a.Method == method || a.Method == "ANY" {
+			return a, true
+		}
+	}
+	return nil, len(apis) > 0
+}
+
+// delay 按配置与请求指定的延迟等待 请求取消时提前返回
+func (s *Server) delay(done <-chan struct{}, override string) {
+	d := s.opt.Latency
+	if s.opt.Jitter > 0 {
+		s.mu.Lock()
+		d += time.Duration(s.rand.Int63n(int64(s.opt.Jitter)))
+		s.mu.Unlock()
+	}
+	if v, err := time.ParseDuration(override); err == nil {
+		d = v
+	}
+	if d <= 0 {
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(d):
+	}
+}
+
+// inject 本次请求要返回的错误 override 为请求中指定的错误码或名称
+// 随机注入时从接口 @error 中标注的错误码中选择 没有时使用所在包的错误码
+func (s *Server) inject(pkg string, marked []*apidoc.ErrorCode, override string) *apidoc.ErrorCode {
+	if override != "" {
+		if e, ok := s.codes[override]; ok {
+			return e
+		}
+		if code, err := strconv.Atoi(override); err == nil {
+			return &apidoc.ErrorCode{Name: override, Code: code, Msg: "mock 指定的错误"}
+		}
+		return ErrMock
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.opt.ErrorRate <= 0 || s.rand.Float64() >= s.opt.ErrorRate {
+		return nil
+	}
+	var candidates []*apidoc.ErrorCode
+	for _, e := range marked {
+		if e.Package != "" {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = s.errors[pkg]
+	}
+	if len(candidates) == 0 {
+		return ErrMock
+	}
+	return candidates[s.rand.Intn(len(candidates))]
+}
+
+// fixture 读取 mocks 目录中的固定响应 每次请求重新读取 修改后立即生效
+func (s *Server) fixture(owner, name string) (json.RawMessage, error) {
+	if s.opt.Fixtures == "" {
+		return nil, nil
+	}
+	body, err := ioutil.ReadFile(filepath.Join(s.opt.Fixtures, owner, name+".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("%s/%s.json 不是合法的 json", owner, name)
+	}
+	return bytes.TrimSpace(body), nil
+}
+
+// envelope 固定响应是否为包含 err_code 的完整返回结构
+func envelope(raw json.RawMessage) (map[string]json.RawMessage, bool) {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal(raw, &obj) != nil {
+		return nil, false
+	}
+	_, ok := obj["err_code"]
+	return obj, ok
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.opt.Log != nil {
+		_, _ = fmt.Fprintf(s.opt.Log, format, args...)
+	}
+}
+
+// ServeHTTP 按路由组的接口返回框架的返回结构 允许跨域请求
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	// 只有带 Access-Control-Request-Method 的才是跨域预检 其他 OPTIONS 请求按 @method: OPTIONS 的接口处理
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	status, source := s.serveHTTP(w, r)
+	s.logf("%-7s %s %d %s %s\n", r.Method, r.URL.Path, status, source, time.Since(start).Round(time.Millisecond))
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) (int, string) {
+	api, found := s.match(r.Method, r.URL.Path)
+	if api == nil {
+		status, msg := http.StatusNotFound, "mock 中没有接口 "+r.URL.Path
+		if found {
+			status, msg = http.StatusMethodNotAllowed, "接口不支持 "+r.Method
+		}
+		writeJSON(w, status, &apidoc.ErrorCode{Code: status, Msg: msg}, nil)
+		return status, "-"
+	}
+	s.delay(r.Context().Done(), r.Header.Get(HeaderLatency))
+	if e := s.inject(api.Group.Package, api.Errors, r.Header.Get(HeaderError)); e != nil {
+		status := http.StatusOK
+		if e.HTTP != 0 {
+			status = e.HTTP
+		}
+		writeJSON(w, status, e, nil)
+		return status, fmt.Sprintf("error %d", e.Code)
+	}
+	raw, err := s.fixture(api.Group.Name, api.Name)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, &apidoc.ErrorCode{Code: http.StatusInternalServerError, Msg: err.Error()}, nil)
+		return http.StatusInternalServerError, "fixture"
+	}
+	if raw == nil {
+		writeJSON(w, http.StatusOK, nil, apidoc.Sample(api.Resp))
+		return http.StatusOK, "sample"
+	}
+	if _, ok := envelope(raw); ok {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(raw)
+		return http.StatusOK, "fixture"
+	}
+	writeJSON(w, http.StatusOK, nil, raw)
+	return http.StatusOK, "fixture"
+}
+
+// writeJSON 写入框架的返回结构 e 不为空时为错误
+func writeJSON(w http.ResponseWriter, status int, e *apidoc.ErrorCode, data interface{}) {
+	var body []byte
+	if e != nil {
+		body, _ = json.Marshal(map[string]interface{}{"err_code": e.Code, "err_msg": e.Msg})
+	} else {
+		body, _ = json.Marshal(apidoc.Result(data))
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
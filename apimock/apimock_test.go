@@ -0,0 +1,199 @@
+package apimock
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/actorbuf/iotaer/internal/testproject"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func newServer(t *testing.T, opt Option) *Server {
+	p := testproject.Load(t)
+	services, err := p.Services()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || services[0].Name != "user.UserRpc" || !services[0].RPCs[1].StreamResp {
+		t.Fatalf("unexpected services %+v", services)
+	}
+	return New(p, services, opt)
+}
+
+type result struct {
+	ErrCode int             `json:"err_code"`
+	ErrMsg  string          `json:"err_msg"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func get(t *testing.T, s *Server, method, path string, header map[string]string) (int, result) {
+	r := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	var ret result
+	if w.Code != http.StatusNoContent {
+		if err := json.Unmarshal(w.Body.Bytes(), &ret); err != nil {
+			t.Fatalf("%s %s: %v\n%s", method, path, err, w.Body)
+		}
+	}
+	return w.Code, ret
+}
+
+func TestHTTP(t *testing.T) {
+	dir := t.TempDir()
+	s := newServer(t, Option{Fixtures: dir})
+
+	code, ret := get(t, s, "GET", "/api/user/get_user", nil)
+	want := `{"user":{"uid":1,"nick_name":"昵称","level":1,"friends":[{}],"tags":{"key":{"name":"name"}},"avatar":"","labels":["labels"],"scores":{"key":1},"delta":1,"rate":1}}`
+	if code != 200 || ret.ErrCode != 0 || string(ret.Data) != want {
+		t.Fatalf("unexpected sample %d %+v %s", code, ret, ret.Data)
+	}
+
+	// 固定响应可以只有 data 也可以是完整的返回结构
+	_ = os.MkdirAll(filepath.Join(dir, "UserApi"), 0755)
+	_ = ioutil.WriteFile(filepath.Join(dir, "UserApi", "ListUser.json"), []byte(`{"totalCount": 7}`), 0644)
+	if _, ret := get(t, s, "POST", "/api/user/list_user", nil); string(ret.Data) != `{"totalCount":7}` {
+		t.Fatalf("unexpected fixture %s", ret.Data)
+	}
+	_ = ioutil.WriteFile(filepath.Join(dir, "UserApi", "ListUser.json"), []byte(`{"err_code": 20002, "err_msg": "没有权限"}`), 0644)
+	if _, ret := get(t, s, "POST", "/api/user/list_user", nil); ret.ErrCode != 20002 {
+		t.Fatalf("unexpected fixture %+v", ret)
+	}
+
+	if code, ret := get(t, s, "GET", "/api/user/get_user", map[string]string{HeaderError: "ErrCodeUserNotFound"}); code != 404 || ret.ErrCode != 20001 || ret.ErrMsg != "用户不存在" {
+		t.Fatalf("unexpected error %d %+v", code, ret)
+	}
+	if code, _ := get(t, s, "POST", "/api/user/get_user", nil); code != http.StatusMethodNotAllowed {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if code, _ := get(t, s, "GET", "/api/order", nil); code != http.StatusNotFound {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if code, _ := get(t, s, "OPTIONS", "/api/user/get_user", map[string]string{"Access-Control-Request-Method": "GET"}); code != http.StatusNoContent {
+		t.Fatalf("unexpected status %d", code)
+	}
+	if code, _ := get(t, s, "OPTIONS", "/api/user/get_user", nil); code != http.StatusMethodNotAllowed {
+		t.Fatalf("unexpected status %d", code)
+	}
+
+	// 随机注入时使用接口 @error 中标注的错误码
+	s = newServer(t, Option{ErrorRate: 1})
+	if _, ret := get(t, s, "GET", "/api/user/get_user", nil); ret.ErrCode != 20001 {
+		t.Fatalf("unexpected injected error %+v", ret)
+	}
+}
+
+// fields protobuf 中的顶层字段 字段号 => 原始值
+func fields(t *testing.T, b []byte) map[protowire.Number][]interface{} {
+	out := map[protowire.Number][]interface{}{}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		b = b[n:]
+		var v interface{}
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			v, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		b = b[n:]
+		out[num] = append(out[num], v)
+	}
+	return out
+}
+
+func TestEncode(t *testing.T) {
+	s := newServer(t, Option{})
+	user := s.rpcs["/user.UserRpc/Sync"].Resp.Fields[0].Message
+	b, err := encode(user, map[string]interface{}{
+		"uid": json.Number("9007199254740993"), "nick_name": "bob", "level": "LevelVip", "labels": []interface{}{"a", "b"},
+		"scores": map[string]interface{}{"math": json.Number("90")}, "delta": json.Number("-1"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := fields(t, b)
+	entry := fields(t, got[8][0].([]byte))
+	if got[1][0] != uint64(9007199254740993) || string(got[2][0].([]byte)) != "bob" || got[3][0] != uint64(1) ||
+		len(got[7]) != 2 || string(entry[1][0].([]byte)) != "math" || entry[2][0] != uint64(90) || got[9][0] != uint64(1) {
+		t.Fatalf("unexpected fields %v", got)
+	}
+	if _, err := encode(user, map[string]interface{}{"uid": "abc"}); err == nil {
+		t.Fatal("expected invalid integer error")
+	}
+}
+
+func TestGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newServer(t, Option{}).GRPC()
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithDefaultCallOptions(grpc.ForceCodec(rawCodec{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+
+	req := protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 1)
+	var resp []byte
+	if err := conn.Invoke(ctx, "/user.UserRpc/Sync", &req, &resp); err != nil {
+		t.Fatal(err)
+	}
+	user := fields(t, fields(t, resp)[1][0].([]byte))
+	if user[1][0] != uint64(1) || string(user[2][0].([]byte)) != "昵称" || !reflect.DeepEqual(user[7], []interface{}{[]byte("labels")}) {
+		t.Fatalf("unexpected response %v", user)
+	}
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, "/user.UserRpc/Watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendMsg(&req); err != nil {
+		t.Fatal(err)
+	}
+	_ = stream.CloseSend()
+	if err := stream.RecvMsg(&resp); err != nil || len(resp) == 0 {
+		t.Fatalf("unexpected stream response %v", err)
+	}
+
+	ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(HeaderError), "20001")
+	err = conn.Invoke(ctx, "/user.UserRpc/Sync", &req, &resp)
+	if st := status.Convert(err); st.Code() != codes.Unknown || st.Message() != "20001: 用户不存在" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := conn.Invoke(context.Background(), "/user.UserRpc/Missing", &req, &resp); status.Code(err) != codes.Unimplemented {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
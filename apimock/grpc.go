@@ -0,0 +1,142 @@
+package apimock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/actorbuf/iotaer/apidoc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// rawCodec 不解析请求 响应为已编码的 protobuf
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	if b, ok := v.(*[]byte); ok {
+		return *b, nil
+	}
+	return nil, fmt.Errorf("unexpected message %T", v)
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	b, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message %T", v)
+	}
+	*b = append((*b)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// GRPC 返回所有 grpc service 的 mock 服务 不需要生成的 pb 代码
+func (s *Server) GRPC() *grpc.Server {
+	return grpc.NewServer(grpc.ForceServerCodec(rawCodec{}), grpc.UnknownServiceHandler(s.handleStream))
+}
+
+// handleStream 普通方法与服务端流返回一个响应 客户端流读完请求后返回一个响应 双向流每个请求返回一个响应
+func (s *Server) handleStream(_ interface{}, stream grpc.ServerStream) error {
+	start := time.Now()
+	name, _ := grpc.MethodFromServerStream(stream)
+	rpc, ok := s.rpcs[name]
+	if !ok {
+		s.logf("%-7s %s %s\n", "GRPC", name, codes.Unimplemented)
+		return status.Errorf(codes.Unimplemented, "mock 中没有方法 %s", name)
+	}
+	err := s.serveRPC(rpc, stream)
+	s.logf("%-7s %s %s %s\n", "GRPC", name, status.Code(err), time.Since(start).Round(time.Millisecond))
+	return err
+}
+
+func (s *Server) serveRPC(rpc *apidoc.RPC, stream grpc.ServerStream) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	get := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	resp, err := s.rpcResponse(rpc)
+	if err != nil {
+		if st, ok := status.FromError(err); ok {
+			return st.Err()
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+	reply := func() error {
+		s.delay(stream.Context().Done(), get(HeaderLatency))
+		if e := s.inject(pkg(rpc.Service), nil, get(HeaderError)); e != nil {
+			return status.Errorf(codes.Unknown, "%d: %s", e.Code, e.Msg)
+		}
+		return stream.SendMsg(&resp)
+	}
+
+	var req []byte
+	for {
+		err := stream.RecvMsg(&req)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !rpc.StreamReq {
+			break
+		}
+		if rpc.StreamResp {
+			if err := reply(); err != nil {
+				return err
+			}
+		}
+	}
+	if rpc.StreamReq && rpc.StreamResp {
+		return nil
+	}
+	return reply()
+}
+
+// rpcResponse 编码后的响应 固定响应可以为响应消息或包含 err_code 与 data 的返回结构
+func (s *Server) rpcResponse(rpc *apidoc.RPC) ([]byte, error) {
+	raw, err := s.fixture(rpc.Service.Short, rpc.Name)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		if raw, err = json.Marshal(apidoc.Sample(rpc.Resp)); err != nil {
+			return nil, err
+		}
+	} else if obj, ok := envelope(raw); ok {
+		var e apidoc.ErrorCode
+		_ = json.Unmarshal(obj["err_code"], &e.Code)
+		_ = json.Unmarshal(obj["err_msg"], &e.Msg)
+		if e.Code != 0 {
+			return nil, status.Errorf(codes.Unknown, "%d: %s", e.Code, e.Msg)
+		}
+		raw = obj["data"]
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if len(raw) > 0 {
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+	}
+	return encode(rpc.Resp, v)
+}
+
+// pkg service 所在的包名
+func pkg(svc *apidoc.Service) string {
+	if len(svc.Name) > len(svc.Short) {
+		return svc.Name[:len(svc.Name)-len(svc.Short)-1]
+	}
+	return ""
+}
@@ -0,0 +1,175 @@
+package apimock
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/actorbuf/iotaer/apidoc"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// encode 按消息定义把 json 解码后的值编码为 protobuf 二进制 字段按 json 名称或 proto 字段名查找
+func encode(m *apidoc.Message, v interface{}) ([]byte, error) {
+	obj, _ := v.(map[string]interface{})
+	var b []byte
+	for _, f := range m.Fields {
+		fv, ok := obj[f.Name]
+		if !ok {
+			fv, ok = obj[f.Proto]
+		}
+		if !ok || fv == nil {
+			continue
+		}
+		var err error
+		num := protowire.Number(f.Number)
+		switch {
+		case f.Kind == apidoc.KindMap:
+			entries, ok := fv.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s.%s 应为对象", m.Short, f.Name)
+			}
+			keys := make([]string, 0, len(entries))
+			for k := range entries {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				var entry []byte
+				if entry, err = scalar(entry, 1, f.KeyType, k); err != nil {
+					return nil, fmt.Errorf("%s.%s: %v", m.Short, f.Name, err)
+				}
+				if entry, err = element(entry, f, 2, entries[k]); err != nil {
+					return nil, fmt.Errorf("%s.%s: %v", m.Short, f.Name, err)
+				}
+				b = protowire.AppendTag(b, num, protowire.BytesType)
+				b = protowire.AppendBytes(b, entry)
+			}
+		case f.Repeated:
+			items, ok := fv.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s.%s 应为数组", m.Short, f.Name)
+			}
+			// 不使用 packed 编码 proto3 的解析方需要同时支持两种编码
+			for _, item := range items {
+				if b, err = element(b, f, num, item); err != nil {
+					return nil, fmt.Errorf("%s.%s: %v", m.Short, f.Name, err)
+				}
+			}
+		default:
+			if b, err = element(b, f, num, fv); err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", m.Short, f.Name, err)
+			}
+		}
+	}
+	return b, nil
+}
+
+// element 字段单个元素的编码 map 字段为值的编码
+func element(b []byte, f *apidoc.Field, num protowire.Number, v interface{}) ([]byte, error) {
+	switch {
+	case f.Message != nil:
+		sub, err := encode(f.Message, v)
+		if err != nil {
+			return nil, err
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, sub), nil
+	case f.Enum != nil:
+		if s, ok := v.(string); ok {
+			for _, ev := range f.Enum.Values {
+				if ev.Name == s {
+					v = json.Number(strconv.Itoa(ev.Number))
+				}
+			}
+		}
+		return scalar(b, num, "int32", v)
+	}
+	return scalar(b, num, f.Type, v)
+}
+
+// scalar 标量的编码 与 protojson 一致 64 位整数与 map 的键可以为字符串 bytes 为 base64
+func scalar(b []byte, num protowire.Number, typ string, v interface{}) ([]byte, error) {
+	switch typ {
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v 不是字符串", v)
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendString(b, s), nil
+	case "bytes":
+		s, _ := v.(string)
+		raw, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q 不是 base64", s)
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, raw), nil
+	case "bool":
+		var x bool
+		switch t := v.(type) {
+		case bool:
+			x = t
+		case string:
+			var err error
+			if x, err = strconv.ParseBool(t); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%v 不是布尔值", v)
+		}
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeBool(x)), nil
+	case "double", "float":
+		f, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+		if err != nil {
+			return nil, err
+		}
+		if typ == "float" {
+			b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+			return protowire.AppendFixed32(b, math.Float32bits(float32(f))), nil
+		}
+		b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(f)), nil
+	}
+
+	n, err := integer(v)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case "int32", "int64", "uint32", "uint64":
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(n)), nil
+	case "sint32", "sint64":
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, protowire.EncodeZigZag(n)), nil
+	case "fixed32", "sfixed32":
+		b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+		return protowire.AppendFixed32(b, uint32(n)), nil
+	case "fixed64", "sfixed64":
+		b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, uint64(n)), nil
+	}
+	return nil, fmt.Errorf("不支持的类型 %s", typ)
+}
+
+// integer json 中的整数 可以为数字或字符串 uint64 超过 int64 范围时按位保留
+func integer(v interface{}) (int64, error) {
+	s := fmt.Sprint(v)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return int64(n), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f != math.Trunc(f) {
+		return 0, fmt.Errorf("%v 不是整数", v)
+	}
+	return int64(f), nil
+}
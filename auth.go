@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/actorbuf/iotaer/credential"
+	"github.com/actorbuf/iotaer/gitter"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// defaultHosts 不指定 --host 时各类凭据使用的域名
+var defaultHosts = map[string]string{
+	credential.KindGitLab: "gitlab.com",
+	credential.KindGitHub: "github.com",
+	credential.KindGitea:  "gitea.com",
+	credential.KindGitter: "webhook",
+	credential.KindSlack:  "slack.com",
+}
+
+func authCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "管理访问 gitlab/github/gitea、gitter 与 slack 的凭据",
+		Long: fmt.Sprintf("管理访问 git 仓库与聊天服务的凭据, 凭据保存在本地文件 %s (权限 0600, 可用 age/gpg 加密), "+
+			"不使用系统钥匙串, 可通过 IOTAER_<KIND>_TOKEN 环境变量覆盖", credential.DefaultPath()),
+	}
+	cmd.AddCommand(authLoginCommand())
+	cmd.AddCommand(authLogoutCommand())
+	cmd.AddCommand(authStatusCommand())
+	return cmd
+}
+
+func authLoginCommand() *cobra.Command {
+	var kind = credential.KindGitLab
+	var host = ""
+	var user = ""
+	var token = ""
+	var encrypt = ""
+	cmd := &cobra.Command{
+		Use:     "login",
+		Short:   "保存一个访问凭据",
+		Long:    "保存一个访问凭据, 不指定 --token 时从标准输入读取, 终端下输入不回显",
+		Example: "builder auth login --kind gitlab --host gitlab.example.com\necho $TOKEN | builder auth login --kind gitter\nbuilder auth login --kind gitlab --encrypt age",
+		Run: func(cmd *cobra.Command, args []string) {
+			if host == "" {
+				host = defaultHosts[kind]
+			}
+			if token == "" {
+				var err error
+				if token, err = readToken(fmt.Sprintf("%s token for %s: ", kind, host)); err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "读取token失败: %+v\n", err)
+					os.Exit(1)
+				}
+			}
+			store := credential.NewStore()
+			if encrypt != "" {
+				crypto, err := credential.NewCrypto(encrypt)
+				if err != nil {
+					_, _ = fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				store.Crypto = crypto
+			}
+			err := store.Set(credential.Credential{Kind: kind, Host: host, User: user, Token: token})
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "已保存 %s %s 的凭据到 %s\n", kind, host, store.Path)
+		},
+	}
+	cmd.Flags().StringVar(&kind, "kind", kind, fmt.Sprintf("凭据类型%v", credential.Kinds))
+	cmd.Flags().StringVar(&host, "host", "", "服务域名，默认为对应类型的公共域名，gitter 为 webhook")
+	cmd.Flags().StringVar(&user, "user", "", "用户名，http拉取代码时使用，默认 oauth2")
+	cmd.Flags().StringVar(&token, "token", "", "token，不建议在命令行中传入，会留在 shell 历史中")
+	cmd.Flags().StringVar(&encrypt, "encrypt", "", "凭据文件加密方式[plain,age,gpg]，默认沿用文件当前方式")
+	return cmd
+}
+
+func authLogoutCommand() *cobra.Command {
+	var kind = ""
+	var host = ""
+	var all bool
+	cmd := &cobra.Command{
+		Use:     "logout",
+		Short:   "删除访问凭据",
+		Example: "builder auth logout --kind gitlab --host gitlab.example.com\nbuilder auth logout --all",
+		Run: func(cmd *cobra.Command, args []string) {
+			if kind == "" && !all {
+				_, _ = fmt.Fprintln(os.Stderr, "请指定 --kind 或 --all")
+				os.Exit(1)
+			}
+			if all {
+				kind, host = "", ""
+			}
+			n, err := credential.NewStore().Delete(kind, host)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "已删除 %d 条凭据\n", n)
+		},
+	}
+	cmd.Flags().StringVar(&kind, "kind", "", fmt.Sprintf("凭据类型%v", credential.Kinds))
+	cmd.Flags().StringVar(&host, "host", "", "服务域名，不指定时删除该类型的所有凭据")
+	cmd.Flags().BoolVar(&all, "all", false, "删除所有凭据")
+	return cmd
+}
+
+func authStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "查看已保存的凭据",
+		Run: func(cmd *cobra.Command, args []string) {
+			store := credential.NewStore()
+			encryption, err := store.Encryption()
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if encryption == "" {
+				_, _ = fmt.Fprintf(os.Stdout, "凭据文件: %s (不存在)\n", store.Path)
+			} else {
+				_, _ = fmt.Fprintf(os.Stdout, "凭据文件: %s (%s)\n", store.Path, encryption)
+			}
+			list, err := store.List()
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			list = append(list, credential.Env()...)
+			if len(list) == 0 {
+				_, _ = fmt.Fprintln(os.Stdout, "没有凭据, 请执行 iotaer auth login")
+				return
+			}
+			for _, c := range list {
+				user := c.User
+				if user == "" {
+					user = "-"
+				}
+				_, _ = fmt.Fprintf(os.Stdout, "%-7s %-24s %-12s %-14s %s\n", c.Kind, c.Host, user, c.Masked(), c.Source)
+			}
+		},
+	}
+	return cmd
+}
+
+// readToken 终端下不回显读取 管道输入时读取第一行
+func readToken(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		_, _ = fmt.Fprint(os.Stderr, prompt)
+		b, err := term.ReadPassword(fd)
+		_, _ = fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// gitterSecret 依次从环境变量 配置 凭据文件中读取 webhook 密钥
+func gitterSecret(c *gitter.Config) string {
+	if secret := os.Getenv(gitter.SecretEnv); secret != "" {
+		return secret
+	}
+	if c.Secret != "" {
+		return c.Secret
+	}
+	return credential.NewStore().Token(credential.KindGitter, "")
+}
+
+// gitterAuth 拉取私有仓库时按平台与域名读取凭据
+func gitterAuth(store *credential.Store) gitter.AuthFunc {
+	return func(provider, host string) *gitter.Auth {
+		c, err := store.Get(provider, host)
+		if err != nil {
+			return nil
+		}
+		return &gitter.Auth{User: c.User, Token: c.Token}
+	}
+}
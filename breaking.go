@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/actorbuf/iotaer/breaking"
+	"github.com/actorbuf/iotaer/protodef"
+	"github.com/spf13/cobra"
+)
+
+func breakingCommand() *cobra.Command {
+	pbPath, _ := os.Getwd()
+	var against string
+	var format = "text"
+	cmd := &cobra.Command{
+		Use:   "breaking",
+		Short: "检查 proto 文件中不兼容的变更",
+		Long: "与 --against 指定的 git 版本或目录对比, 检查字段删除与改号、类型变更、rpc 与接口删除、路由请求方式与路径变更、错误码改值. " +
+			"存在不兼容变更时返回非0",
+		Example: "builder breaking --against origin/master\nbuilder breaking --path model --against v1.2.0\nbuilder breaking --against ../old/model",
+		Run: func(cmd *cobra.Command, args []string) {
+			if against == "" {
+				_, _ = fmt.Fprintf(os.Stderr, "对比的版本 --against 不能为空\n")
+				os.Exit(1)
+			}
+			cur, err := loadProtos(pbPath)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			old, err := loadAgainst(against, pbPath)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			changes := breaking.Compare(old, cur)
+			if err := breaking.Write(os.Stdout, format, changes); err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if len(changes) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&pbPath, "path", pbPath, "proto文件地址,支持传入目录")
+	cmd.Flags().StringVar(&against, "against", "", "对比的 git 版本(分支/tag/commit) 或旧版本 proto 所在目录")
+	cmd.Flags().StringVar(&format, "format", format, "输出格式 [text,json]")
+	return cmd
+}
+
+// loadAgainst against 为已存在的目录时直接解析 否则作为 git 版本读取 pbPath 下的文件
+func loadAgainst(against, pbPath string) ([]*protodef.File, error) {
+	if info, err := os.Stat(against); err == nil && info.IsDir() {
+		return loadProtos(against)
+	}
+	wd, _ := os.Getwd()
+	abs, err := filepath.Abs(pbPath)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil {
+		return nil, err
+	}
+	return breaking.LoadGit(against, rel)
+}
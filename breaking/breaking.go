@@ -0,0 +1,264 @@
+package breaking
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/actorbuf/iotaer/protodef"
+)
+
+// 变更类型
+const (
+	MessageRemoved    = "message-removed"
+	FieldRemoved      = "field-removed"
+	FieldRenumbered   = "field-renumbered"
+	FieldType         = "field-type"
+	EnumRemoved       = "enum-removed"
+	EnumValueRemoved  = "enum-value-removed"
+	EnumValueChanged  = "enum-value-renumbered"
+	ErrCodeRemoved    = "errcode-removed"
+	ErrCodeRenumbered = "errcode-renumbered"
+	RPCRemoved        = "rpc-removed"
+	RPCType           = "rpc-type"
+	RouteRemoved      = "route-removed"
+	RouteChanged      = "route-changed"
+)
+
+// errCodeEnum 错误码枚举 与 addErrorCodeFile 创建的 error_code.proto 一致
+const errCodeEnum = "ErrCode"
+
+// Change 一个不兼容的变更 Pos 为新版本中的位置 删除时为旧版本中的位置
+type Change struct {
+	Kind    string            `json:"kind"`
+	Message string            `json:"message"`
+	Pos     protodef.Position `json:"pos"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s (%s)", c.Pos, c.Message, c.Kind)
+}
+
+type checker struct {
+	changes []Change
+}
+
+func (c *checker) report(kind string, pos protodef.Position, format string, args ...interface{}) {
+	c.changes = append(c.changes, Change{Kind: kind, Message: fmt.Sprintf(format, args...), Pos: pos})
+}
+
+// Compare 对比新旧两个版本的 proto 文件 消息与服务按包名加名称匹配 文件移动不算变更
+func Compare(old, cur []*protodef.File) []Change {
+	c := &checker{}
+	newMsgs, newEnums, newSvcs := messages(cur), enums(cur), services(cur)
+	for _, f := range old {
+		for _, m := range f.Messages {
+			name := qualify(f, m.Name)
+			if nm, ok := newMsgs[name]; ok {
+				c.compareMessage(name, &message{file: f, msg: m}, nm)
+			} else {
+				c.report(MessageRemoved, m.Pos, "message %s 被删除", name)
+			}
+		}
+		for _, e := range f.Enums {
+			name := qualify(f, e.Name)
+			if ne, ok := newEnums[name]; ok {
+				c.compareEnum(name, &enum{file: f, enum: e}, ne)
+			} else {
+				c.report(EnumRemoved, e.Pos, "enum %s 被删除", name)
+			}
+		}
+		for _, s := range f.Services {
+			name := qualify(f, s.Name)
+			c.compareService(name, &service{file: f, svc: s}, newSvcs[name])
+		}
+	}
+
+	sort.SliceStable(c.changes, func(i, j int) bool {
+		a, b := c.changes[i].Pos, c.changes[j].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return c.changes
+}
+
+func (c *checker) compareMessage(name string, om, nm *message) {
+	byName := map[string]*protodef.Field{}
+	byNumber := map[int]*protodef.Field{}
+	for _, f := range nm.msg.Fields {
+		byName[f.Name] = f
+		byNumber[f.Number] = f
+	}
+	for _, of := range om.msg.Fields {
+		nf, ok := byName[of.Name]
+		if !ok {
+			if reuse, ok := byNumber[of.Number]; ok {
+				c.report(FieldRemoved, reuse.Pos, "%s.%s 被删除, 字段号 %d 被 %s 复用", name, of.Name, of.Number, reuse.Name)
+			} else {
+				c.report(FieldRemoved, nm.msg.Pos, "%s.%s 被删除", name, of.Name)
+			}
+			continue
+		}
+		if of.Number != nf.Number {
+			c.report(FieldRenumbered, nf.Pos, "%s.%s 的字段号由 %d 变为 %d", name, of.Name, of.Number, nf.Number)
+		}
+		if ot, nt := fieldType(om.file, of), fieldType(nm.file, nf); ot != nt {
+			c.report(FieldType, nf.Pos, "%s.%s 的类型由 %s 变为 %s", name, of.Name, ot, nt)
+		}
+	}
+}
+
+func (c *checker) compareEnum(name string, oe, ne *enum) {
+	removed, renumbered := EnumValueRemoved, EnumValueChanged
+	// ErrCode 是返回给客户端的错误码
+	if baseName(name) == errCodeEnum {
+		removed, renumbered = ErrCodeRemoved, ErrCodeRenumbered
+	}
+	values := map[string]*protodef.EnumValue{}
+	for _, v := range ne.enum.Values {
+		values[v.Name] = v
+	}
+	for _, ov := range oe.enum.Values {
+		nv, ok := values[ov.Name]
+		if !ok {
+			c.report(removed, ne.enum.Pos, "%s.%s(%d) 被删除", name, ov.Name, ov.Number)
+			continue
+		}
+		if ov.Number != nv.Number {
+			c.report(renumbered, nv.Pos, "%s.%s 的值由 %d 变为 %d", name, ov.Name, ov.Number, nv.Number)
+		}
+	}
+}
+
+func (c *checker) compareService(name string, old, cur *service) {
+	methods := map[string]*protodef.Method{}
+	if cur != nil {
+		for _, m := range cur.svc.Methods {
+			methods[m.Name] = m
+		}
+	}
+	for _, om := range old.svc.Methods {
+		nm, ok := methods[om.Name]
+		route := old.svc.Kind == protodef.KindRoute
+		if !ok {
+			pos := om.Pos
+			if cur != nil {
+				pos = cur.svc.Pos
+			}
+			if route {
+				c.report(RouteRemoved, pos, "接口 %s.%s(%s %s) 被删除", name, om.Name, om.HTTPMethod, old.svc.FullPath(om))
+			} else {
+				c.report(RPCRemoved, pos, "rpc %s.%s 被删除", name, om.Name)
+			}
+			continue
+		}
+		if ot, nt := rpcType(old.file, om.Req, om.StreamReq), rpcType(cur.file, nm.Req, nm.StreamReq); ot != nt {
+			c.report(RPCType, nm.Pos, "%s.%s 的请求由 %s 变为 %s", name, om.Name, ot, nt)
+		}
+		if ot, nt := rpcType(old.file, om.Resp, om.StreamResp), rpcType(cur.file, nm.Resp, nm.StreamResp); ot != nt {
+			c.report(RPCType, nm.Pos, "%s.%s 的响应由 %s 变为 %s", name, om.Name, ot, nt)
+		}
+		if !route {
+			continue
+		}
+		oldRoute := om.HTTPMethod + " " + old.svc.FullPath(om)
+		if cur.svc.Kind != protodef.KindRoute {
+			c.report(RouteRemoved, nm.Pos, "%s 不再是路由组, 接口 %s 被删除", name, oldRoute)
+			continue
+		}
+		newRoute := nm.HTTPMethod + " " + cur.svc.FullPath(nm)
+		// 改为 ANY 仍能兼容原来的请求方式
+		if oldRoute != newRoute && !(nm.HTTPMethod == "ANY" && old.svc.FullPath(om) == cur.svc.FullPath(nm)) {
+			c.report(RouteChanged, nm.Pos, "接口 %s.%s 由 %s 变为 %s", name, om.Name, oldRoute, newRoute)
+		}
+	}
+}
+
+type message struct {
+	file *protodef.File
+	msg  *protodef.Message
+}
+
+type enum struct {
+	file *protodef.File
+	enum *protodef.Enum
+}
+
+type service struct {
+	file *protodef.File
+	svc  *protodef.Service
+}
+
+func qualify(f *protodef.File, name string) string {
+	if f.Package == "" {
+		return name
+	}
+	return f.Package + "." + name
+}
+
+func messages(files []*protodef.File) map[string]*message {
+	res := map[string]*message{}
+	for _, f := range files {
+		for _, m := range f.Messages {
+			res[qualify(f, m.Name)] = &message{file: f, msg: m}
+		}
+	}
+	return res
+}
+
+func enums(files []*protodef.File) map[string]*enum {
+	res := map[string]*enum{}
+	for _, f := range files {
+		for _, e := range f.Enums {
+			res[qualify(f, e.Name)] = &enum{file: f, enum: e}
+		}
+	}
+	return res
+}
+
+func services(files []*protodef.File) map[string]*service {
+	res := map[string]*service{}
+	for _, f := range files {
+		for _, s := range f.Services {
+			res[qualify(f, s.Name)] = &service{file: f, svc: s}
+		}
+	}
+	return res
+}
+
+// typeName 同包下的类型统一为全名 User 与 pkg.User 视为相同
+func typeName(f *protodef.File, name string) string {
+	name = strings.TrimPrefix(name, ".")
+	if f.Package == "" || strings.HasPrefix(name, f.Package+".") {
+		return name
+	}
+	if f.Message(name) != nil || f.Enum(name) != nil {
+		return f.Package + "." + name
+	}
+	return name
+}
+
+func fieldType(f *protodef.File, fd *protodef.Field) string {
+	t := typeName(f, fd.Type)
+	switch {
+	case fd.KeyType != "":
+		return fmt.Sprintf("map<%s, %s>", fd.KeyType, t)
+	case fd.Repeated:
+		return "repeated " + t
+	}
+	return t
+}
+
+func rpcType(f *protodef.File, name string, stream bool) string {
+	t := typeName(f, name)
+	if stream {
+		return "stream " + t
+	}
+	return t
+}
+
+func baseName(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,164 @@
+package breaking
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/actorbuf/iotaer/protodef"
+)
+
+const before = `syntax = "proto3";
+package user;
+
+message User {
+    string name = 1;
+    int64 id = 2;
+    repeated string tags = 3;
+    string email = 4;
+}
+
+message Removed {}
+
+// @route_group: true
+// @route_api: /api/user
+service UserApi {
+    rpc GetUser (GetUserReq) returns (User);
+    // @method: GET
+    rpc ListUser (ListUserReq) returns (ListUserResp);
+    rpc DelUser (DelUserReq) returns (DelUserResp);
+    // @method: GET
+    rpc Count (CountReq) returns (CountResp);
+}
+
+service UserRpc {
+    rpc Sync (SyncReq) returns (SyncResp);
+}
+`
+
+const after = `syntax = "proto3";
+package user;
+
+message User {
+    string name = 1;
+    int64 id = 5;
+    string tags = 3;
+    int64 phone = 4;
+}
+
+// @route_group: true
+// @route_api: /api/v2/user
+service UserApi {
+    rpc GetUser (GetUserReq) returns (user.User);
+    // @method: POST
+    // @api: /list_user
+    rpc ListUser (ListUserReq) returns (ListUserResp);
+    // @method: ANY
+    rpc Count (CountReq) returns (stream CountResp);
+}
+`
+
+const codeBefore = `syntax = "proto3";
+package user;
+enum ErrCode {
+    ErrCodeNil = 0;
+    ErrCodeNotFound = 1001;
+    ErrCodeExpired = 1002;
+}
+enum Status {
+    StatusNil = 0;
+    StatusOn = 1;
+}
+`
+
+const codeAfter = `syntax = "proto3";
+package user;
+enum ErrCode {
+    ErrCodeNil = 0;
+    ErrCodeNotFound = 1003;
+}
+enum Status {
+    StatusNil = 0;
+    StatusOn = 2;
+}
+`
+
+func parse(t *testing.T, name, src string) *protodef.File {
+	f, err := protodef.Parse(name, []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestCompare(t *testing.T) {
+	old := []*protodef.File{parse(t, "user.proto", before), parse(t, "error_code.proto", codeBefore)}
+	// 文件改名不影响比较
+	cur := []*protodef.File{parse(t, "model/user.proto", after), parse(t, "model/error_code.proto", codeAfter)}
+	got := map[string]int{}
+	for _, c := range Compare(old, cur) {
+		got[c.Kind]++
+		t.Log(c)
+	}
+	want := map[string]int{
+		MessageRemoved:    1, // Removed
+		FieldRenumbered:   1, // User.id
+		FieldType:         1, // User.tags
+		FieldRemoved:      1, // User.email 字段号被 phone 复用
+		RouteRemoved:      1, // DelUser
+		RouteChanged:      3, // 前缀变更影响 GetUser ListUser Count
+		RPCType:           1, // Count 改为 stream
+		RPCRemoved:        1, // UserRpc.Sync
+		ErrCodeRenumbered: 1,
+		ErrCodeRemoved:    1,
+		EnumValueChanged:  1,
+	}
+	for kind, n := range want {
+		if got[kind] != n {
+			t.Fatalf("%s: want %d got %d", kind, n, got[kind])
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected kinds %v", got)
+	}
+	if changes := Compare(cur, cur); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+}
+
+func TestLoadGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v %s", args, err, out)
+		}
+	}
+	_ = os.MkdirAll(filepath.Join(dir, "model"), 0755)
+	_ = ioutil.WriteFile(filepath.Join(dir, "model", "user.proto"), []byte(before), 0644)
+	run("init", "-q")
+	run("add", "-A")
+	run("commit", "-qm", "init")
+	_ = ioutil.WriteFile(filepath.Join(dir, "model", "user.proto"), []byte(after), 0644)
+
+	wd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(wd) }()
+	_ = os.Chdir(filepath.Join(dir, "model"))
+	files, err := LoadGit("HEAD", ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "HEAD:user.proto" || files[0].Message("Removed") == nil {
+		t.Fatalf("unexpected files %+v", files)
+	}
+	if _, err := LoadGit("HEAD", "missing"); err == nil {
+		t.Fatal("expected no proto error")
+	}
+}
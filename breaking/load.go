@@ -0,0 +1,74 @@
+package breaking
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/actorbuf/iotaer/protodef"
+)
+
+// LoadGit 读取 git 版本 ref 中 path 下的 proto 文件 path 为相对当前目录的路径
+// 文件位置记为 ref:path 便于与工作区中的文件区分
+func LoadGit(ref, path string) ([]*protodef.File, error) {
+	out, err := git("ls-tree", "-r", "--name-only", ref, "--", path)
+	if err != nil {
+		return nil, err
+	}
+	var files []*protodef.File
+	for _, name := range strings.Split(string(out), "\n") {
+		if !strings.HasSuffix(name, ".proto") {
+			continue
+		}
+		src, err := git("show", ref+":./"+name)
+		if err != nil {
+			return nil, err
+		}
+		f, err := protodef.Parse(ref+":"+name, src)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s 中 %s 下没有 proto 文件", ref, path)
+	}
+	return files, nil
+}
+
+func git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// Write 输出变更 format 为 text 或 json
+func Write(w io.Writer, format string, changes []Change) error {
+	switch format {
+	case "text", "":
+		for _, c := range changes {
+			if _, err := fmt.Fprintln(w, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		if changes == nil {
+			changes = []Change{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(changes)
+	default:
+		return fmt.Errorf("不支持的输出格式 %s, 可选 text,json", format)
+	}
+}
@@ -0,0 +1,212 @@
+package catalog
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/actorbuf/iotaer/protodef"
+)
+
+// Item 路由、rpc 或定时任务
+type Item struct {
+	Service     string `json:"service"`
+	Name        string `json:"name"`
+	Method      string `json:"method,omitempty"` // 路由的请求方式
+	Path        string `json:"path,omitempty"`   // 路由的完整路径
+	Spec        string `json:"spec,omitempty"`   // 定时任务的时间配置
+	Desc        string `json:"desc,omitempty"`
+	Proto       string `json:"proto"`             // 定义位置
+	GenTo       string `json:"gen_to,omitempty"`  // @gen_to
+	Handler     string `json:"handler,omitempty"` // go 实现的位置
+	Implemented bool   `json:"implemented"`
+}
+
+// ErrCode ErrCode 枚举中的错误码
+type ErrCode struct {
+	Name  string `json:"name"`
+	Code  int    `json:"code"`
+	Msg   string `json:"msg"`
+	Proto string `json:"proto"`
+}
+
+// Catalog 项目中的 proto 定义与 go 实现
+type Catalog struct {
+	Files   []*protodef.File
+	root    string
+	methods map[string][]protodef.Position // Recv.Name
+	funcs   map[string][]protodef.Position // Name
+}
+
+// New 扫描 root 下的 go 代码 查找 proto 定义对应的实现
+// 跳过隐藏目录、vendor、pb 代码与测试文件
+func New(files []*protodef.File, root string) (*Catalog, error) {
+	c := &Catalog{Files: files, root: root, methods: map[string][]protodef.Position{}, funcs: map[string][]protodef.Position{}}
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor" || d.Name() == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(p, ".go") || strings.HasSuffix(p, "_test.go") || strings.HasSuffix(p, ".pb.go") {
+			return nil
+		}
+		fset := token.NewFileSet()
+		f, err := parser.ParseFile(fset, p, nil, 0)
+		if err != nil {
+			return nil // 无法解析的文件不影响其他结果
+		}
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			pos := fset.Position(fd.Name.Pos())
+			at := protodef.Position{File: p, Line: pos.Line, Column: pos.Column}
+			if recv := recvName(fd); recv != "" {
+				c.methods[recv+"."+fd.Name.Name] = append(c.methods[recv+"."+fd.Name.Name], at)
+			} else if fd.Recv == nil {
+				c.funcs[fd.Name.Name] = append(c.funcs[fd.Name.Name], at)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func recvName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) != 1 {
+		return ""
+	}
+	t := fd.Recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	if id, ok := t.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// find 优先返回 prefer 文件中的实现 strict 为 true 时只在 prefer 中查找
+func find(list []protodef.Position, prefer []string, strict bool) (protodef.Position, bool) {
+	for _, pos := range list {
+		for _, p := range prefer {
+			if filepath.Clean(pos.File) == filepath.Clean(p) {
+				return pos, true
+			}
+		}
+	}
+	if len(list) > 0 && !strict {
+		return list[0], true
+	}
+	return protodef.Position{}, false
+}
+
+func (c *Catalog) items(kinds []string, fill func(f *protodef.File, s *protodef.Service, m *protodef.Method, item *Item)) []Item {
+	var items []Item
+	for _, f := range c.Files {
+		for _, s := range f.Services {
+			if !contains(kinds, s.Kind) {
+				continue
+			}
+			for _, m := range s.Methods {
+				item := Item{Service: s.Name, Name: m.Name, Desc: m.Desc, Proto: m.Pos.String(), GenTo: s.GenTo}
+				fill(f, s, m, &item)
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// method 配置了 gen_to 时只接受 gen_to 文件所在包目录中的实现, 其他包中同名类型的同名方法不是该 service 的实现
+func (c *Catalog) method(s *protodef.Service, m *protodef.Method, item *Item) {
+	list := c.methods[s.Name+"."+m.Name]
+	var prefer []string
+	if s.GenTo != "" {
+		genTo := filepath.Join(c.root, s.GenTo)
+		prefer = append(prefer, genTo)
+		var local []protodef.Position
+		for _, pos := range list {
+			if filepath.Dir(filepath.Clean(pos.File)) == filepath.Dir(genTo) {
+				local = append(local, pos)
+			}
+		}
+		list = local
+	}
+	if pos, ok := find(list, prefer, false); ok {
+		item.Handler, item.Implemented = pos.String(), true
+	}
+}
+
+// Routes 路由组中的接口 实现为路由组结构体上的同名方法
+func (c *Catalog) Routes() []Item {
+	return c.items([]string{protodef.KindRoute}, func(f *protodef.File, s *protodef.Service, m *protodef.Method, item *Item) {
+		item.Method, item.Path = m.HTTPMethod, s.FullPath(m)
+		c.method(s, m, item)
+	})
+}
+
+// RPCs rpc 服务与普通 service 中的 rpc 实现为 service 结构体上的同名方法
+func (c *Catalog) RPCs() []Item {
+	return c.items([]string{protodef.KindRPC, protodef.KindPlain}, func(f *protodef.File, s *protodef.Service, m *protodef.Method, item *Item) {
+		c.method(s, m, item)
+	})
+}
+
+// Tasks 定时任务 gen 把任务函数生成到 proto 所在目录下以 gen_to 文件名命名的文件中
+func (c *Catalog) Tasks() []Item {
+	return c.items([]string{protodef.KindTask}, func(f *protodef.File, s *protodef.Service, m *protodef.Method, item *Item) {
+		item.Spec = m.Spec
+		if s.GenTo == "" {
+			return
+		}
+		name := filepath.Base(s.GenTo)
+		if !strings.HasSuffix(name, ".go") {
+			name += ".go"
+		}
+		prefer := []string{filepath.Join(filepath.Dir(f.Path), name), filepath.Join(c.root, s.GenTo)}
+		if pos, ok := find(c.funcs[m.Name], prefer, true); ok {
+			item.Handler, item.Implemented = pos.String(), true
+		}
+	})
+}
+
+// Errors 名为 ErrCode 的枚举 说明取注释 没有注释时与 gen 一致使用名称
+func (c *Catalog) Errors() []ErrCode {
+	var codes []ErrCode
+	for _, f := range c.Files {
+		e := f.Enum("ErrCode")
+		if e == nil {
+			continue
+		}
+		for _, v := range e.Values {
+			msg := protodef.Describe(v.Comment)
+			if msg == "" {
+				msg = v.Name
+			}
+			codes = append(codes, ErrCode{Name: v.Name, Code: v.Number, Msg: msg, Proto: v.Pos.String()})
+		}
+	}
+	return codes
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
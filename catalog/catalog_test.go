@@ -0,0 +1,155 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/actorbuf/iotaer/protodef"
+)
+
+const userProto = `syntax = "proto3";
+package user;
+
+// @route_group: true
+// @route_api: /api/user
+// @gen_to: ./internal/controller/user_api.go
+service UserApi {
+    // @desc: 获取用户
+    // @method: GET
+    rpc GetUser (GetUserReq) returns (GetUserResp);
+    rpc DelUser (DelUserReq) returns (DelUserResp);
+}
+
+service UserRpc {
+    rpc Sync (SyncReq) returns (SyncResp);
+}
+
+// @task: true
+// @gen_to: ./infra/task/user_task.go
+service UserTask {
+    // @t: 0 */5 * * * *
+    rpc Clean (CleanReq) returns (CleanResp);
+}
+
+enum ErrCode {
+    ErrCodeNil = 0;
+    ErrCodeNotFound = 1001; // 用户不存在
+}
+`
+
+const controller = `package controller
+
+type UserApi struct{}
+
+func (receiver *UserApi) GetUser() {}
+`
+
+// 其他包中的同名类型不是路由组的实现
+const admin = `package admin
+
+type UserApi struct{}
+
+func (receiver *UserApi) DelUser() {}
+`
+
+// 没有 gen_to 时与 proto 同名的方法在其他文件中也能找到
+const services = `package services
+
+type UserRpc struct{}
+
+func (s UserRpc) Sync() {}
+
+func Clean() {}
+`
+
+func setup(t *testing.T) *Catalog {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"model/user.proto":                 userProto,
+		"internal/controller/user_api.go":  controller,
+		"internal/services/user_rpc.go":    services,
+		"internal/admin/user_api.go":       admin,
+		"internal/controller/broken.go":    "package controller\nfunc {",
+		"vendor/example.com/x/user_api.go": controller,
+	} {
+		file := filepath.Join(dir, name)
+		_ = os.MkdirAll(filepath.Dir(file), 0755)
+		if err := ioutil.WriteFile(file, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	_ = os.Chdir(dir)
+	f, err := protodef.ParseFile(filepath.Join("model", "user.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New([]*protodef.File{f}, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCatalog(t *testing.T) {
+	c := setup(t)
+	routes := c.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+	get, del := routes[0], routes[1]
+	if get.Method != "GET" || get.Path != "/api/user/get_user" || !get.Implemented ||
+		get.Handler != filepath.Join("internal", "controller", "user_api.go")+":5:26" {
+		t.Fatalf("unexpected route %+v", get)
+	}
+	if del.Method != "POST" || del.Implemented {
+		t.Fatalf("unexpected route %+v", del)
+	}
+	if rpcs := c.RPCs(); len(rpcs) != 1 || !rpcs[0].Implemented {
+		t.Fatalf("unexpected rpcs %+v", rpcs)
+	}
+	// 任务函数只在 gen 生成的位置查找
+	if tasks := c.Tasks(); len(tasks) != 1 || tasks[0].Spec != "0 */5 * * * *" || tasks[0].Implemented {
+		t.Fatalf("unexpected tasks %+v", tasks)
+	}
+	codes := c.Errors()
+	if len(codes) != 2 || codes[1].Code != 1001 || codes[1].Msg != "用户不存在" || codes[0].Msg != "ErrCodeNil" {
+		t.Fatalf("unexpected codes %+v", codes)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	c := setup(t)
+	list, err := c.List("routes", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, "routes", "text", list); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "SERVICE") || !strings.Contains(lines[1], "DelUser") ||
+		!strings.Contains(lines[1], "未实现 (./internal/controller/user_api.go)") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	list, _ = c.List("errors", false)
+	if err := Write(&buf, "errors", "json", list); err != nil {
+		t.Fatal(err)
+	}
+	var codes []ErrCode
+	if err := json.Unmarshal(buf.Bytes(), &codes); err != nil || len(codes) != 2 {
+		t.Fatalf("unexpected json %s", buf.String())
+	}
+	if _, err := c.List("models", false); err == nil {
+		t.Fatal("expected unsupported kind error")
+	}
+}
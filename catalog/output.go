@@ -0,0 +1,100 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Kinds ls 支持的类型
+var Kinds = []string{"routes", "rpcs", "tasks", "errors"}
+
+// List 按类型列出 missing 为 true 时只保留未实现的路由、rpc 与任务
+func (c *Catalog) List(kind string, missing bool) (interface{}, error) {
+	var items []Item
+	switch kind {
+	case "routes":
+		items = c.Routes()
+	case "rpcs":
+		items = c.RPCs()
+	case "tasks":
+		items = c.Tasks()
+	case "errors":
+		codes := c.Errors()
+		if codes == nil || missing {
+			codes = []ErrCode{}
+		}
+		return codes, nil
+	default:
+		return nil, fmt.Errorf("不支持的类型 %s, 可选 %v", kind, Kinds)
+	}
+	list := []Item{}
+	for _, item := range items {
+		if !missing || !item.Implemented {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+// Write 输出 List 的结果 format 为 text 或 json
+func Write(w io.Writer, kind, format string, list interface{}) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	case "text", "":
+	default:
+		return fmt.Errorf("不支持的输出格式 %s", format)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	row := func(cols ...string) {
+		for i, col := range cols {
+			if col == "" {
+				col = "-"
+			}
+			if i > 0 {
+				_, _ = fmt.Fprint(tw, "\t")
+			}
+			_, _ = fmt.Fprint(tw, col)
+		}
+		_, _ = fmt.Fprintln(tw)
+	}
+	switch v := list.(type) {
+	case []ErrCode:
+		row("NAME", "CODE", "MSG", "PROTO")
+		for _, c := range v {
+			row(c.Name, strconv.Itoa(c.Code), c.Msg, c.Proto)
+		}
+	case []Item:
+		switch kind {
+		case "routes":
+			row("SERVICE", "NAME", "METHOD", "PATH", "PROTO", "HANDLER")
+		case "tasks":
+			row("SERVICE", "NAME", "SPEC", "PROTO", "HANDLER")
+		default:
+			row("SERVICE", "NAME", "PROTO", "HANDLER")
+		}
+		for _, item := range v {
+			handler := item.Handler
+			if !item.Implemented {
+				handler = "未实现"
+				if item.GenTo != "" {
+					handler += " (" + item.GenTo + ")"
+				}
+			}
+			switch kind {
+			case "routes":
+				row(item.Service, item.Name, item.Method, item.Path, item.Proto, handler)
+			case "tasks":
+				row(item.Service, item.Name, item.Spec, item.Proto, handler)
+			default:
+				row(item.Service, item.Name, item.Proto, handler)
+			}
+		}
+	}
+	return tw.Flush()
+}
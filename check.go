@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	ose "os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// snapshot 生成前的 go 文件内容与目录 用于 --check 比较并还原
+type snapshot struct {
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+// snapshotGoFiles 记录目录下所有 go 文件的内容 重叠的目录只记录一次
+func snapshotGoFiles(dirs ...string) *snapshot {
+	s := &snapshot{files: map[string][]byte{}, dirs: map[string]bool{}}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		dir, _ = filepath.Abs(dir)
+		_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != dir && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor") {
+					return filepath.SkipDir
+				}
+				s.dirs[path] = true
+				return nil
+			}
+			if !strings.HasSuffix(path, ".go") {
+				return nil
+			}
+			if body, err := ioutil.ReadFile(path); err == nil {
+				s.files[path] = body
+			}
+			return nil
+		})
+	}
+	return s
+}
+
+// diffSnapshot 新增 修改 删除的文件 按路径排序
+func diffSnapshot(before, after *snapshot) []string {
+	var changed []string
+	for path, body := range after.files {
+		if old, ok := before.files[path]; !ok || !bytes.Equal(old, body) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range before.files {
+		if _, ok := after.files[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// restore 把变化的文件还原为生成前的内容 删除新生成的文件与因此创建的空目录
+func (s *snapshot) restore(changed []string) error {
+	for _, path := range changed {
+		if body, ok := s.files[path]; ok {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(path, body, 0644); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for dir := filepath.Dir(path); !s.dirs[dir] && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// checkGen 在子进程中执行不带 --check 的 gen, 比较生成前后的 go 文件后还原工作区
+// 生成代码会写到 @gen_to 等项目内任意位置 所以检查整个项目根目录, 子进程中途退出时同样会还原
+func checkGen(dirs ...string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	var args []string
+	for _, arg := range os.Args[1:] {
+		if arg != "--check" && !strings.HasPrefix(arg, "--check=") {
+			args = append(args, arg)
+		}
+	}
+	before := snapshotGoFiles(dirs...)
+	cmd := ose.Command(self, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	genErr := cmd.Run()
+
+	changed := diffSnapshot(before, snapshotGoFiles(dirs...))
+	if err := before.restore(changed); err != nil {
+		return fmt.Errorf("还原生成前的文件失败: %v", err)
+	}
+	if genErr != nil {
+		return fmt.Errorf("生成代码失败: %v", genErr)
+	}
+	if len(changed) != 0 {
+		return fmt.Errorf("生成代码不是最新, 以下文件需要重新生成(已还原, 请执行 iotaer gen):\n  %s", strings.Join(changed, "\n  "))
+	}
+	return nil
+}
+
+// moduleRoot 向上查找 go.mod 所在目录 找不到时返回空
+func moduleRoot(dir string) string {
+	dir, _ = filepath.Abs(dir)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
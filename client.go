@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/actorbuf/iotaer/apiclient"
+	"github.com/actorbuf/iotaer/apidoc"
+)
+
+// outputClient 为 path 下的每个路由组生成指定语言的客户端 include 中的 . 为 protoc 的默认路径 不作为依赖解析
+func outputClient(path string, include []string, lang, out string) error {
+	var gen func(*apidoc.Project) ([]apidoc.Page, error)
+	switch lang {
+	case apiclient.LangGo:
+		gen = apiclient.Go
+	case apiclient.LangTS:
+		gen = apiclient.TypeScript
+	default:
+		return fmt.Errorf("不支持的客户端语言 %s, 可选 %s/%s", lang, apiclient.LangGo, apiclient.LangTS)
+	}
+	var deps []string
+	for _, p := range include {
+		if p != "." {
+			deps = append(deps, p)
+		}
+	}
+	includes, err := includeFiles(deps)
+	if err != nil {
+		return err
+	}
+	p, err := loadProject(path, includes)
+	if err != nil {
+		return err
+	}
+	if len(p.Groups) == 0 {
+		return fmt.Errorf("%s 中没有路由组", path)
+	}
+	pages, err := gen(p)
+	if err != nil {
+		return err
+	}
+	return writePages(out, pages)
+}
@@ -0,0 +1,146 @@
+package credential
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// 加密方式
+const (
+	CryptoPlain = "plain"
+	CryptoAge   = "age"
+	CryptoGPG   = "gpg"
+)
+
+const (
+	ageHeader       = "-----BEGIN AGE ENCRYPTED FILE-----"
+	ageBinaryHeader = "age-encryption.org/"
+	gpgHeader       = "-----BEGIN PGP MESSAGE-----"
+)
+
+// 加密相关的环境变量
+const (
+	EnvAgeIdentity  = "IOTAER_AGE_IDENTITY"  // age 私钥文件 默认为凭据目录下的 age.key
+	EnvAgeRecipient = "IOTAER_AGE_RECIPIENT" // age 公钥 为空时由私钥推导
+	EnvGPGRecipient = "IOTAER_GPG_RECIPIENT" // gpg 收件人 为空时使用 default-key
+)
+
+// Crypto 凭据文件的加解密 age/gpg 通过调用本机命令实现
+type Crypto interface {
+	Name() string
+	Encrypt(plain []byte) ([]byte, error)
+	Decrypt(body []byte) ([]byte, error)
+}
+
+// NewCrypto 按名称创建加密方式 参数从环境变量读取
+func NewCrypto(name string) (Crypto, error) {
+	switch name {
+	case "", CryptoPlain:
+		return Plain{}, nil
+	case CryptoAge:
+		return newAge(), nil
+	case CryptoGPG:
+		return GPG{Recipient: os.Getenv(EnvGPGRecipient)}, nil
+	}
+	return nil, fmt.Errorf("不支持的加密方式: %s, 可选 [%s,%s,%s]", name, CryptoPlain, CryptoAge, CryptoGPG)
+}
+
+// detect 根据文件头识别加密方式
+func detect(body []byte) Crypto {
+	switch {
+	case bytes.HasPrefix(body, []byte(ageHeader)), bytes.HasPrefix(body, []byte(ageBinaryHeader)):
+		return newAge()
+	case bytes.HasPrefix(body, []byte(gpgHeader)):
+		return GPG{Recipient: os.Getenv(EnvGPGRecipient)}
+	}
+	return Plain{}
+}
+
+// Plain 不加密 仅依赖文件权限保护
+type Plain struct{}
+
+// Name 名称
+func (Plain) Name() string { return CryptoPlain }
+
+// Encrypt 原样返回
+func (Plain) Encrypt(plain []byte) ([]byte, error) { return plain, nil }
+
+// Decrypt 原样返回
+func (Plain) Decrypt(body []byte) ([]byte, error) { return body, nil }
+
+// Age 使用 age 加密 https://age-encryption.org
+type Age struct {
+	Identity  string // 私钥文件
+	Recipient string // 公钥 为空时通过 age-keygen -y 从私钥推导
+}
+
+func newAge() Age {
+	identity := os.Getenv(EnvAgeIdentity)
+	if identity == "" {
+		identity = filepath.Join(filepath.Dir(DefaultPath()), "age.key")
+	}
+	return Age{Identity: identity, Recipient: os.Getenv(EnvAgeRecipient)}
+}
+
+// Name 名称
+func (Age) Name() string { return CryptoAge }
+
+// Encrypt 加密为 armor 格式
+func (a Age) Encrypt(plain []byte) ([]byte, error) {
+	recipient := a.Recipient
+	if recipient == "" {
+		out, err := run(nil, "age-keygen", "-y", a.Identity)
+		if err != nil {
+			return nil, fmt.Errorf("从私钥 %s 推导公钥失败, 可设置 %s: %w", a.Identity, EnvAgeRecipient, err)
+		}
+		recipient = strings.TrimSpace(string(out))
+	}
+	return run(plain, "age", "--encrypt", "--armor", "--recipient", recipient)
+}
+
+// Decrypt 使用私钥解密
+func (a Age) Decrypt(body []byte) ([]byte, error) {
+	return run(body, "age", "--decrypt", "--identity", a.Identity)
+}
+
+// GPG 使用 gpg 加密 解密依赖 gpg-agent
+type GPG struct {
+	Recipient string
+}
+
+// Name 名称
+func (GPG) Name() string { return CryptoGPG }
+
+// Encrypt 加密为 armor 格式
+func (g GPG) Encrypt(plain []byte) ([]byte, error) {
+	args := []string{"--batch", "--yes", "--quiet", "--armor", "--encrypt"}
+	if g.Recipient != "" {
+		args = append(args, "--recipient", g.Recipient)
+	} else {
+		args = append(args, "--default-recipient-self")
+	}
+	return run(plain, "gpg", args...)
+}
+
+// Decrypt 解密
+func (GPG) Decrypt(body []byte) ([]byte, error) {
+	return run(body, "gpg", "--batch", "--quiet", "--decrypt")
+}
+
+func run(stdin []byte, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,306 @@
+package credential
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 凭据类型
+const (
+	KindGitLab = "gitlab" // gitlab personal access token
+	KindGitHub = "github" // github personal access token
+	KindGitea  = "gitea"  // gitea access token
+	KindGitter = "gitter" // gitter webhook 密钥
+	KindSlack  = "slack"  // slack bot token
+)
+
+// Kinds 支持的凭据类型
+var Kinds = []string{KindGitLab, KindGitHub, KindGitea, KindGitter, KindSlack}
+
+// 凭据来源
+const (
+	SourceFile = "file"
+	SourceEnv  = "env"
+)
+
+// ErrNotFound 没有找到凭据
+var ErrNotFound = errors.New("没有找到凭据")
+
+// Credential 一条凭据 同一类型可以为不同的 host 各保存一条
+type Credential struct {
+	Kind      string    `yaml:"kind" json:"kind"`
+	Host      string    `yaml:"host" json:"host"`
+	User      string    `yaml:"user,omitempty" json:"user,omitempty"`
+	Token     string    `yaml:"token" json:"token"`
+	UpdatedAt time.Time `yaml:"updated_at" json:"updated_at"`
+	Source    string    `yaml:"-" json:"source"` // file/env 不落盘
+}
+
+// Masked 用于展示的 token 只保留前4位
+func (c Credential) Masked() string {
+	if len(c.Token) <= 8 {
+		return "********"
+	}
+	return c.Token[:4] + "********"
+}
+
+// EnvName 覆盖某类凭据的环境变量 如 IOTAER_GITLAB_TOKEN IOTAER_GITLAB_HOST IOTAER_GITLAB_USER
+func EnvName(kind, field string) string {
+	return fmt.Sprintf("IOTAER_%s_%s", strings.ToUpper(kind), strings.ToUpper(field))
+}
+
+// file 凭据文件内容
+type file struct {
+	Credentials []Credential `yaml:"credentials"`
+}
+
+// Store 凭据文件 默认为 ~/.config/iotaer/credentials, 权限必须为 0600
+// 文件可以用 age 或 gpg 加密 读取时根据文件头自动识别
+type Store struct {
+	Path   string
+	Crypto Crypto // 写入时使用的加密方式 为空时沿用文件原有方式
+}
+
+// DefaultPath 默认凭据文件路径 优先使用 XDG_CONFIG_HOME
+func DefaultPath() string {
+	if p := os.Getenv("IOTAER_CREDENTIALS"); p != "" {
+		return p
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "iotaer", "credentials")
+}
+
+// NewStore 打开默认凭据文件
+func NewStore() *Store {
+	return &Store{Path: DefaultPath()}
+}
+
+// Encryption 当前文件的加密方式 文件不存在时为空
+func (s *Store) Encryption() (string, error) {
+	body, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return detect(body).Name(), nil
+}
+
+// List 文件中的所有凭据 按类型与host排序
+func (s *Store) List() ([]Credential, error) {
+	f, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	list := f.Credentials
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Kind != list[j].Kind {
+			return list[i].Kind < list[j].Kind
+		}
+		return list[i].Host < list[j].Host
+	})
+	return list, nil
+}
+
+// Get 查找凭据 环境变量优先于文件 host 为空时返回该类型的第一条
+func (s *Store) Get(kind, host string) (Credential, error) {
+	if c, ok := fromEnv(kind, host); ok {
+		return c, nil
+	}
+	list, err := s.List()
+	if err != nil {
+		return Credential{}, err
+	}
+	for _, c := range list {
+		if c.Kind == kind && (host == "" || c.Host == host) {
+			return c, nil
+		}
+	}
+	if host == "" {
+		return Credential{}, fmt.Errorf("%w: %s, 请执行 iotaer auth login --kind %s 或设置 %s", ErrNotFound, kind, kind, EnvName(kind, "token"))
+	}
+	return Credential{}, fmt.Errorf("%w: %s %s, 请执行 iotaer auth login --kind %s --host %s", ErrNotFound, kind, host, kind, host)
+}
+
+// Token 便捷方法 只返回 token 没有时返回空
+func (s *Store) Token(kind, host string) string {
+	c, err := s.Get(kind, host)
+	if err != nil {
+		return ""
+	}
+	return c.Token
+}
+
+// Set 新增或覆盖同类型同host的凭据
+func (s *Store) Set(c Credential) error {
+	if !validKind(c.Kind) {
+		return fmt.Errorf("不支持的凭据类型: %s, 可选 %v", c.Kind, Kinds)
+	}
+	if c.Token == "" {
+		return errors.New("token 不能为空")
+	}
+	f, err := s.load()
+	if err != nil {
+		return err
+	}
+	c.UpdatedAt = time.Now()
+	c.Source = ""
+	replaced := false
+	for i, old := range f.Credentials {
+		if old.Kind == c.Kind && old.Host == c.Host {
+			f.Credentials[i] = c
+			replaced = true
+		}
+	}
+	if !replaced {
+		f.Credentials = append(f.Credentials, c)
+	}
+	return s.save(f)
+}
+
+// Delete 删除凭据 host 为空时删除该类型的全部凭据 kind 也为空时删除所有
+func (s *Store) Delete(kind, host string) (int, error) {
+	f, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+	var keep []Credential
+	for _, c := range f.Credentials {
+		if (kind == "" || c.Kind == kind) && (host == "" || c.Host == host) {
+			continue
+		}
+		keep = append(keep, c)
+	}
+	removed := len(f.Credentials) - len(keep)
+	if removed == 0 {
+		return 0, nil
+	}
+	f.Credentials = keep
+	return removed, s.save(f)
+}
+
+// Env 当前环境变量中设置的凭据
+func Env() []Credential {
+	var list []Credential
+	for _, kind := range Kinds {
+		if c, ok := fromEnv(kind, ""); ok {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+func fromEnv(kind, host string) (Credential, bool) {
+	token := os.Getenv(EnvName(kind, "token"))
+	if token == "" {
+		return Credential{}, false
+	}
+	envHost := os.Getenv(EnvName(kind, "host"))
+	// 指定了 host 的环境变量只覆盖对应 host
+	if host != "" && envHost != "" && envHost != host {
+		return Credential{}, false
+	}
+	if envHost == "" {
+		envHost = host
+	}
+	return Credential{
+		Kind:   kind,
+		Host:   envHost,
+		User:   os.Getenv(EnvName(kind, "user")),
+		Token:  token,
+		Source: SourceEnv,
+	}, true
+}
+
+func validKind(kind string) bool {
+	for _, k := range Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// load 读取并解密凭据文件 文件不存在时为空
+func (s *Store) load() (*file, error) {
+	f := &file{}
+	info, err := os.Stat(s.Path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 与 ssh 私钥一致 其他用户可读时拒绝使用
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return nil, fmt.Errorf("凭据文件 %s 权限为 %#o, 过于宽松, 请执行 chmod 600 %s", s.Path, perm, s.Path)
+	}
+	body, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := detect(body).Decrypt(body)
+	if err != nil {
+		return nil, fmt.Errorf("解密凭据文件失败: %w", err)
+	}
+	if err := yaml.Unmarshal(plain, f); err != nil {
+		return nil, fmt.Errorf("解析凭据文件失败: %w", err)
+	}
+	for i := range f.Credentials {
+		f.Credentials[i].Source = SourceFile
+	}
+	return f, nil
+}
+
+// save 加密后以 0600 权限写入 先写临时文件再重命名
+func (s *Store) save(f *file) error {
+	crypto := s.Crypto
+	if crypto == nil {
+		body, err := ioutil.ReadFile(s.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		crypto = detect(body)
+	}
+	plain, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	body, err := crypto.Encrypt(plain)
+	if err != nil {
+		return fmt.Errorf("加密凭据文件失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.Path), ".credentials-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0600); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(body); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
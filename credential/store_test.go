@@ -0,0 +1,126 @@
+package credential
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "credential")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Store{Path: filepath.Join(dir, "iotaer", "credentials")}, func() { os.RemoveAll(dir) }
+}
+
+func TestStore(t *testing.T) {
+	s, clean := newTestStore(t)
+	defer clean()
+
+	if _, err := s.Get(KindGitLab, ""); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("want not found, got %v", err)
+	}
+	for _, c := range []Credential{
+		{Kind: KindGitLab, Host: "gitlab.example.com", User: "alice", Token: "glpat-old"},
+		{Kind: KindGitLab, Host: "gitlab.example.com", User: "alice", Token: "glpat-new"},
+		{Kind: KindGitLab, Host: "gitlab.com", Token: "glpat-public"},
+		{Kind: KindGitter, Host: "webhook", Token: "secret"},
+	} {
+		if err := s.Set(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Set(Credential{Kind: "svn", Token: "x"}); err == nil {
+		t.Errorf("want unsupported kind error")
+	}
+
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("credentials perm: %#o", info.Mode().Perm())
+	}
+	list, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("want 3 credentials, got %d", len(list))
+	}
+	c, err := s.Get(KindGitLab, "gitlab.example.com")
+	if err != nil || c.Token != "glpat-new" || c.Source != SourceFile {
+		t.Errorf("get: %+v %v", c, err)
+	}
+
+	// 环境变量优先
+	os.Setenv(EnvName(KindGitLab, "token"), "glpat-env")
+	os.Setenv(EnvName(KindGitLab, "host"), "gitlab.com")
+	defer os.Unsetenv(EnvName(KindGitLab, "token"))
+	defer os.Unsetenv(EnvName(KindGitLab, "host"))
+	if c, _ := s.Get(KindGitLab, "gitlab.com"); c.Token != "glpat-env" || c.Source != SourceEnv {
+		t.Errorf("env override: %+v", c)
+	}
+	if c, _ := s.Get(KindGitLab, "gitlab.example.com"); c.Token != "glpat-new" {
+		t.Errorf("env for another host should not override: %+v", c)
+	}
+
+	n, err := s.Delete(KindGitLab, "")
+	if err != nil || n != 2 {
+		t.Errorf("delete: %d %v", n, err)
+	}
+	if s.Token(KindGitter, "") != "secret" {
+		t.Errorf("gitter secret should be kept")
+	}
+
+	// 权限过宽时拒绝读取
+	if err := os.Chmod(s.Path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.List(); err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Errorf("want permission error, got %v", err)
+	}
+}
+
+func TestStoreGPG(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not found")
+	}
+	s, clean := newTestStore(t)
+	defer clean()
+	home := filepath.Join(filepath.Dir(filepath.Dir(s.Path)), "gnupg")
+	if err := os.MkdirAll(home, 0700); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("GNUPGHOME", home)
+	defer os.Unsetenv("GNUPGHOME")
+	if _, err := run(nil, "gpg", "--batch", "--passphrase", "", "--quick-gen-key", "iotaer-test@example.com", "default", "default", "never"); err != nil {
+		t.Skipf("gpg key generation unavailable: %v", err)
+	}
+	defer func() { _, _ = run(nil, "gpgconf", "--kill", "gpg-agent") }()
+
+	s.Crypto = GPG{Recipient: "iotaer-test@example.com"}
+	if err := s.Set(Credential{Kind: KindGitLab, Host: "gitlab.com", Token: "glpat-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "glpat-secret") || !strings.HasPrefix(string(body), gpgHeader) {
+		t.Fatalf("credentials not encrypted:\n%s", body)
+	}
+	// 不指定加密方式时根据文件头识别
+	reader := &Store{Path: s.Path}
+	if enc, _ := reader.Encryption(); enc != CryptoGPG {
+		t.Errorf("encryption: %s", enc)
+	}
+	if reader.Token(KindGitLab, "gitlab.com") != "glpat-secret" {
+		t.Errorf("decrypt failed")
+	}
+}
@@ -1,5 +1,16 @@
 package main
 
+import (
+	"io/ioutil"
+
+	"github.com/actorbuf/iotaer/docker"
+	"github.com/actorbuf/iotaer/errcode"
+	"github.com/actorbuf/iotaer/gitter"
+	"github.com/actorbuf/iotaer/k8s"
+	"github.com/actorbuf/iotaer/lint"
+	"gopkg.in/yaml.v3"
+)
+
 // ProjectName 项目名称的字符画
 const ProjectName = ` _           _ _     _           
 | |__  _   _(_) | __| | ___ _ __ 
@@ -13,5 +24,23 @@ var NeedUpdateFlag bool
 
 // Config 接管项目时 解析项目根下的配置项
 type Config struct {
-	FreqTo string `yaml:"freq_to" json:"freq_to"`
+	FreqTo  string           `yaml:"freq_to" json:"freq_to"`
+	Deploy  k8s.DeployConfig `yaml:"deploy" json:"deploy"`   // k8s布署配置
+	Docker  docker.Config    `yaml:"docker" json:"docker"`   // 镜像与本地compose配置
+	Gitter  gitter.Config    `yaml:"gitter" json:"gitter"`   // webhook布署服务配置
+	Lint    lint.Config      `yaml:"lint" json:"lint"`       // proto 检查规则
+	ErrCode errcode.Config   `yaml:"errcode" json:"errcode"` // 错误码号段
+}
+
+// parseConfig 解析项目下的builder配置 文件不存在或格式不正确时返回零值
+func parseConfig() Config {
+	var c Config
+	body, err := ioutil.ReadFile("./.builderc")
+	if err != nil {
+		return c
+	}
+	if err = yaml.Unmarshal(body, &c); err != nil {
+		return c
+	}
+	return c
 }
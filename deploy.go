@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/actorbuf/iotaer/docker"
+	"github.com/actorbuf/iotaer/k8s"
+	"github.com/actorbuf/iotaer/toolkit"
+	"github.com/spf13/cobra"
+)
+
+// projectDeployConfig 读取 .builderc 的 deploy 配置 未指定服务名时使用 go module 名
+func projectDeployConfig() (k8s.DeployConfig, error) {
+	c := parseConfig().Deploy
+	if c.Name != "" {
+		return c, nil
+	}
+	if !toolkit.IsCurrentDirHasModfile() {
+		return c, fmt.Errorf("请在项目根目录下执行")
+	}
+	modName, err := toolkit.GetCurrentModuleName()
+	if err != nil {
+		return c, err
+	}
+	c.Name = toolkit.Calm2Case(path.Base(modName))
+	return c, nil
+}
+
+func generateK8sCommand() *cobra.Command {
+	var env = ""
+	var output = ""
+	var outFormat = k8s.FormatRaw
+	cmd := &cobra.Command{
+		Use:     "gen-k8s",
+		Short:   "按项目配置生成k8s布署文件",
+		Long:    "读取 .builderc 中的 deploy 配置, 结合 config_<env>.yaml 中的端口与 cmd/ 下的入口, 生成指定环境的 deployment/service/ingress",
+		Example: "builder gen-k8s --env prod --out deploy/prod.yaml\nbuilder gen-k8s --format helm --out deploy/chart",
+		Run: func(cmd *cobra.Command, args []string) {
+			if env == "" {
+				env = os.Getenv("OMEGA_ENV")
+			}
+			if env == "" {
+				env = "local"
+			}
+			c, err := projectDeployConfig()
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			dir, _ := os.Getwd()
+			if outFormat != k8s.FormatRaw {
+				// helm/kustomize 一次生成所有环境
+				bundle, err := k8s.BuildBundle(dir, c)
+				if err != nil {
+					_, _ = fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				if err := k8s.Export(os.Stdout, outFormat, output, bundle); err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "写入失败: %+v\n", err)
+					os.Exit(1)
+				}
+				return
+			}
+			project := &k8s.Project{Dir: dir, Env: env, Config: c}
+			objects, err := project.Build()
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if err := k8s.WriteFile(os.Stdout, output, objects...); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "写入yml失败: %+v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&env, "env", "", "布署环境[local,dev,test,prod], 没有指定时读取 `OMEGA_ENV` 环境变量, 默认 `local`")
+	cmd.Flags().StringVar(&output, "out", "", "yml输出文件，默认输出到标准输出；helm/kustomize格式时为输出目录，默认 deploy/服务名")
+	cmd.Flags().StringVar(&outFormat, "format", outFormat, "输出格式[raw,helm,kustomize], helm/kustomize 会忽略 --env 生成全部环境")
+	return cmd
+}
+
+func generateDockerCommand() *cobra.Command {
+	var env = "local"
+	var output = "."
+	var runtime = ""
+	var goVersion = ""
+	var deps []string
+	var force bool
+	cmd := &cobra.Command{
+		Use:     "gen-docker",
+		Short:   "生成 Dockerfile 与 docker-compose",
+		Long:    "生成多阶段构建的 Dockerfile、.dockerignore, 以及挂载 config_<env>.yaml 并带上 .builderc 中声明的依赖(mdbc→mongo, gdbc→mysql, redis)的 docker-compose.yml",
+		Example: "builder gen-docker\nbuilder gen-docker --runtime alpine --deps mdbc,redis --force",
+		Run: func(cmd *cobra.Command, args []string) {
+			c, err := projectDeployConfig()
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			dc := parseConfig().Docker
+			if runtime != "" {
+				dc.Runtime = runtime
+			}
+			if goVersion != "" {
+				dc.GoVersion = goVersion
+			}
+			if cmd.Flags().Changed("deps") {
+				dc.Deps = deps
+			}
+			dir, _ := os.Getwd()
+			project := &docker.Project{Dir: dir, Env: env, Config: dc, Deploy: c}
+			if err := docker.Generate(output, project, force); err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&env, "env", env, "docker-compose 使用的环境[local,dev,test,prod]")
+	cmd.Flags().StringVar(&output, "out", output, "输出目录，默认为当前目录")
+	cmd.Flags().StringVar(&runtime, "runtime", "", "运行时镜像[distroless,alpine]，覆盖 .builderc 中的 docker.runtime，默认 distroless")
+	cmd.Flags().StringVar(&goVersion, "goVersion", "", "构建镜像的go版本，默认读取 go.mod")
+	cmd.Flags().StringSliceVar(&deps, "deps", nil, "依赖的存储[mdbc,gdbc,redis]，覆盖 .builderc 中的 docker.deps")
+	cmd.Flags().BoolVar(&force, "force", false, "文件已存在时覆盖")
+	return cmd
+}
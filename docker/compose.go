@@ -0,0 +1,149 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/actorbuf/iotaer/k8s"
+	"gopkg.in/yaml.v3"
+)
+
+// Compose docker-compose.yml
+type Compose struct {
+	Version  string                    `yaml:"version"`
+	Services map[string]ComposeService `yaml:"services"`
+	Volumes  map[string]struct{}       `yaml:"volumes,omitempty"`
+}
+
+// ComposeService compose 中的一个服务
+type ComposeService struct {
+	Build       string            `yaml:"build,omitempty"`
+	Image       string            `yaml:"image,omitempty"`
+	Command     []string          `yaml:"command,omitempty"`
+	Environment map[string]string `yaml:"environment,omitempty"`
+	Ports       []string          `yaml:"ports,omitempty"`
+	Volumes     []string          `yaml:"volumes,omitempty"`
+	DependsOn   []string          `yaml:"depends_on,omitempty"`
+	Restart     string            `yaml:"restart,omitempty"`
+}
+
+// depService 依赖在 compose 中的服务名与定义
+type depService struct {
+	name    string
+	service func(p *Project) ComposeService
+	volume  string
+}
+
+// depServices 本地环境用于替代线上存储的服务
+var depServices = map[string]depService{
+	DepMdbc: {
+		name: "mongo",
+		service: func(p *Project) ComposeService {
+			return ComposeService{
+				Image:   "mongo:4.4",
+				Ports:   []string{"27017:27017"},
+				Volumes: []string{"mongo-data:/data/db"},
+			}
+		},
+		volume: "mongo-data",
+	},
+	DepGdbc: {
+		name: "mysql",
+		service: func(p *Project) ComposeService {
+			return ComposeService{
+				Image: "mysql:8.0",
+				Environment: map[string]string{
+					"MYSQL_ROOT_PASSWORD": "root",
+					"MYSQL_DATABASE":      strings.ReplaceAll(p.Deploy.Name, "-", "_"),
+				},
+				Ports:   []string{"3306:3306"},
+				Volumes: []string{"mysql-data:/var/lib/mysql"},
+			}
+		},
+		volume: "mysql-data",
+	},
+	DepRedis: {
+		name: "redis",
+		service: func(p *Project) ComposeService {
+			return ComposeService{
+				Image: "redis:6-alpine",
+				Ports: []string{"6379:6379"},
+			}
+		},
+	},
+}
+
+// Compose 生成本地联调用的 docker-compose
+// 每个入口一个服务 挂载 config_<env>.yaml, 依赖以服务名访问 如 mongo:27017
+func (p *Project) Compose() (*Compose, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	env := p.composeEnv()
+	project := p.env(env)
+	entries, err := project.Entries()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s 下没有找到可布署的入口", filepath.Join(p.Dir, "cmd"))
+	}
+	ports, err := project.Ports()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Compose{Version: "3.8", Services: map[string]ComposeService{}}
+	var deps []string
+	for _, dep := range p.Config.Deps {
+		d := depServices[dep]
+		if _, ok := c.Services[d.name]; ok {
+			continue
+		}
+		c.Services[d.name] = d.service(p)
+		deps = append(deps, d.name)
+		if d.volume != "" {
+			if c.Volumes == nil {
+				c.Volumes = map[string]struct{}{}
+			}
+			c.Volumes[d.volume] = struct{}{}
+		}
+	}
+	sort.Strings(deps)
+
+	config := project.ConfigFile()
+	for _, entry := range entries {
+		svc := ComposeService{
+			Build:       ".",
+			Image:       fmt.Sprintf("%s:%s", p.Deploy.Name, env),
+			Command:     k8s.SplitCommand(project.StartCommand(entry)),
+			Environment: map[string]string{"OMEGA_ENV": env},
+			Volumes:     []string{fmt.Sprintf("./%s:/app/%s:ro", config, config)},
+			DependsOn:   deps,
+			Restart:     "unless-stopped",
+		}
+		if port := ports[entry]; port != 0 {
+			svc.Ports = []string{fmt.Sprintf("%d:%d", port, port)}
+		}
+		c.Services[project.AppName(entry, entries)] = svc
+	}
+	return c, nil
+}
+
+// Marshal 输出 yml
+func (c *Compose) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# 由 builder gen-docker 生成, 容器内访问依赖时配置文件中的地址需使用服务名, 如 mongo:27017 redis:6379\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,203 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"text/template"
+
+	"github.com/actorbuf/iotaer/k8s"
+)
+
+// 运行时基础镜像
+const (
+	RuntimeDistroless = "distroless" // gcr.io/distroless/static 没有shell 体积最小
+	RuntimeAlpine     = "alpine"     // 带shell 方便进入容器排查
+)
+
+// 项目可以声明的依赖 与 gen --db 的驱动类型一致
+const (
+	DepMdbc  = "mdbc"  // mongodb
+	DepGdbc  = "gdbc"  // mysql
+	DepRedis = "redis" // redis
+)
+
+// 生成的文件
+const (
+	FileDockerfile   = "Dockerfile"
+	FileDockerignore = ".dockerignore"
+	FileCompose      = "docker-compose.yml"
+)
+
+// defaultGoVersion go.mod 中没有 go 指令时使用的构建版本
+const defaultGoVersion = "1.17"
+
+// Config .builderc 中的 docker 配置块
+type Config struct {
+	GoVersion string   `yaml:"go_version" json:"go_version"` // 构建镜像的go版本 默认读取 go.mod
+	Runtime   string   `yaml:"runtime" json:"runtime"`       // 运行时镜像 [distroless,alpine] 默认 distroless
+	Deps      []string `yaml:"deps" json:"deps"`             // 依赖的存储 [mdbc,gdbc,redis] compose 会启动对应的本地服务
+}
+
+// Project 生成 docker 文件需要的项目信息
+// 服务名 入口 启动命令与 k8s 布署保持一致 都来自 .builderc 的 deploy 配置
+type Project struct {
+	Dir    string           // 项目根目录
+	Env    string           // docker-compose 使用的环境 默认 local
+	Config Config           // .builderc 中的 docker 配置
+	Deploy k8s.DeployConfig // .builderc 中的 deploy 配置 Name 需已填充
+}
+
+// Validate 校验参数
+func (p *Project) Validate() error {
+	if p.Deploy.Name == "" {
+		return fmt.Errorf("无法确定服务名, 请在 .builderc 的 deploy.name 中指定")
+	}
+	switch p.Config.Runtime {
+	case "", RuntimeDistroless, RuntimeAlpine:
+	default:
+		return fmt.Errorf("不支持的运行时镜像: %s, 可选 [%s,%s]", p.Config.Runtime, RuntimeDistroless, RuntimeAlpine)
+	}
+	for _, dep := range p.Config.Deps {
+		if _, ok := depServices[dep]; !ok {
+			return fmt.Errorf("不支持的依赖: %s, 可选 [%s,%s,%s]", dep, DepMdbc, DepGdbc, DepRedis)
+		}
+	}
+	return nil
+}
+
+// env 返回某个环境下的 k8s 项目描述 用于复用入口 端口与启动命令的推导
+func (p *Project) env(env string) *k8s.Project {
+	return &k8s.Project{Dir: p.Dir, Env: env, Config: p.Deploy}
+}
+
+// composeEnv docker-compose 使用的环境
+func (p *Project) composeEnv() string {
+	if p.Env == "" {
+		return "local"
+	}
+	return p.Env
+}
+
+// defaultEntry 镜像默认启动的入口 有 api 时优先 api
+func defaultEntry(entries []string) string {
+	for _, entry := range entries {
+		if entry == "api" {
+			return entry
+		}
+	}
+	return entries[0]
+}
+
+var goVersionReg = regexp.MustCompile(`(?m)^go\s+(\d+\.\d+)`)
+
+// GoVersion 构建镜像使用的go版本
+func (p *Project) GoVersion() string {
+	if p.Config.GoVersion != "" {
+		return p.Config.GoVersion
+	}
+	body, err := ioutil.ReadFile(filepath.Join(p.Dir, "go.mod"))
+	if err != nil {
+		return defaultGoVersion
+	}
+	if res := goVersionReg.FindSubmatch(body); len(res) == 2 {
+		return string(res[1])
+	}
+	return defaultGoVersion
+}
+
+// dockerfileData Dockerfile 模板参数
+type dockerfileData struct {
+	Name      string
+	GoVersion string
+	Alpine    bool
+	Ports     []int
+	Cmd       string
+}
+
+// Dockerfile 生成多阶段构建的 Dockerfile 默认启动 prod 环境
+func (p *Project) Dockerfile() ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	prod := p.env("prod")
+	entries, err := prod.Entries()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s 下没有找到可布署的入口", filepath.Join(p.Dir, "cmd"))
+	}
+	ports, err := prod.Ports()
+	if err != nil {
+		return nil, err
+	}
+	cmd, _ := json.Marshal(k8s.SplitCommand(prod.StartCommand(defaultEntry(entries))))
+	data := &dockerfileData{
+		Name:      p.Deploy.Name,
+		GoVersion: p.GoVersion(),
+		Alpine:    p.Config.Runtime == RuntimeAlpine,
+		Cmd:       string(cmd),
+	}
+	for _, entry := range entries {
+		if port := ports[entry]; port != 0 {
+			data.Ports = append(data.Ports, port)
+		}
+	}
+	sort.Ints(data.Ports)
+
+	t, err := template.New(FileDockerfile).Parse(dockerfileTpl)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Generate 在 dir 下生成 Dockerfile .dockerignore docker-compose.yml
+// 文件已存在时跳过 force 为 true 时覆盖
+func Generate(dir string, p *Project, force bool) error {
+	dockerfile, err := p.Dockerfile()
+	if err != nil {
+		return err
+	}
+	compose, err := p.Compose()
+	if err != nil {
+		return err
+	}
+	composeBody, err := compose.Marshal()
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		name string
+		body []byte
+	}{
+		{FileDockerfile, dockerfile},
+		{FileDockerignore, []byte(dockerignoreTpl)},
+		{FileCompose, composeBody},
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, f := range files {
+		file := filepath.Join(dir, f.name)
+		if _, err := os.Stat(file); err == nil && !force {
+			_, _ = fmt.Fprintf(os.Stdout, "skip     %s 已存在, 使用 --force 覆盖\n", file)
+			continue
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "create   %s \n", file)
+		if err := ioutil.WriteFile(file, f.body, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
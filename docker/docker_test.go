@@ -0,0 +1,132 @@
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/actorbuf/iotaer/k8s"
+)
+
+func newTestProject(t *testing.T) *Project {
+	dir, err := ioutil.TempDir("", "docker_project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"go.mod":            "module github.com/actor/user\n\ngo 1.16\n",
+		"cmd/api.go":        "package cmd",
+		"cmd/grpc.go":       "package cmd",
+		"cmd/exec.go":       "package cmd",
+		"config_prod.yaml":  "http:\n  addr: \":8080\"\ngrpc:\n  port: 9090\n",
+		"config_local.yaml": "http:\n  addr: \":8081\"\n",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Project{
+		Dir:    dir,
+		Config: Config{Deps: []string{DepMdbc, DepRedis, DepMdbc}},
+		Deploy: k8s.DeployConfig{Name: "user"},
+	}
+}
+
+func TestDockerfile(t *testing.T) {
+	p := newTestProject(t)
+	defer os.RemoveAll(p.Dir)
+
+	body, err := p.Dockerfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(body)
+	for _, want := range []string{
+		"FROM golang:1.16-alpine AS builder",
+		"go build -trimpath",
+		"FROM gcr.io/distroless/static:nonroot",
+		"USER nonroot:nonroot",
+		"EXPOSE 8080\nEXPOSE 9090",
+		`CMD ["./user","api","--config","config_prod.yaml"]`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Dockerfile missing %q:\n%s", want, out)
+		}
+	}
+
+	p.Config.Runtime = RuntimeAlpine
+	p.Config.GoVersion = "1.18"
+	body, err = p.Dockerfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"FROM golang:1.18-alpine", "FROM alpine:", "USER app"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("alpine Dockerfile missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestCompose(t *testing.T) {
+	p := newTestProject(t)
+	defer os.RemoveAll(p.Dir)
+
+	c, err := p.Compose()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Services) != 4 {
+		t.Fatalf("want user-api, user-grpc, mongo, redis, got %v", c.Services)
+	}
+	api := c.Services["user-api"]
+	if strings.Join(api.Ports, ",") != "8081:8081" {
+		t.Errorf("api ports: %v", api.Ports)
+	}
+	if strings.Join(api.DependsOn, ",") != "mongo,redis" {
+		t.Errorf("api depends_on: %v", api.DependsOn)
+	}
+	if api.Volumes[0] != "./config_local.yaml:/app/config_local.yaml:ro" {
+		t.Errorf("api volumes: %v", api.Volumes)
+	}
+	if _, ok := c.Volumes["mongo-data"]; !ok {
+		t.Errorf("missing mongo volume: %v", c.Volumes)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	p := newTestProject(t)
+	defer os.RemoveAll(p.Dir)
+
+	if err := Generate(p.Dir, p, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{FileDockerfile, FileDockerignore, FileCompose} {
+		if _, err := os.Stat(filepath.Join(p.Dir, name)); err != nil {
+			t.Errorf("%s not generated: %v", name, err)
+		}
+	}
+
+	// 已存在的文件不覆盖
+	file := filepath.Join(p.Dir, FileDockerfile)
+	if err := ioutil.WriteFile(file, []byte("FROM scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Generate(p.Dir, p, false); err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadFile(file); string(body) != "FROM scratch\n" {
+		t.Errorf("existing Dockerfile overwritten without --force")
+	}
+
+	p.Config.Deps = []string{"postgres"}
+	if err := Generate(p.Dir, p, true); err == nil {
+		t.Errorf("want unsupported dep error")
+	}
+}
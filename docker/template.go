@@ -0,0 +1,51 @@
+package docker
+
+// dockerfileTpl 多阶段构建 依赖下载与编译缓存使用 BuildKit 的 cache mount
+const dockerfileTpl = `# syntax=docker/dockerfile:1
+# 由 builder gen-docker 生成, 需要开启 BuildKit: DOCKER_BUILDKIT=1 docker build -t {{.Name}} .
+
+FROM golang:{{.GoVersion}}-alpine AS builder
+ARG GOPROXY=https://goproxy.cn,direct
+ARG GOPRIVATE
+ENV CGO_ENABLED=0 GOPROXY=${GOPROXY} GOPRIVATE=${GOPRIVATE}
+WORKDIR /src
+
+# go.mod 不变时复用依赖缓存
+COPY go.mod go.sum* ./
+RUN --mount=type=cache,target=/go/pkg/mod go mod download
+
+COPY . .
+RUN --mount=type=cache,target=/go/pkg/mod \
+    --mount=type=cache,target=/root/.cache/go-build \
+    go build -trimpath -ldflags "-s -w" -o /out/{{.Name}} .
+{{if .Alpine}}
+FROM alpine:3.15
+RUN apk add --no-cache ca-certificates tzdata \
+    && adduser -D -H -u 10001 app
+USER app
+{{- else}}
+FROM gcr.io/distroless/static:nonroot
+USER nonroot:nonroot
+{{- end}}
+WORKDIR /app
+COPY --from=builder /out/{{.Name}} ./{{.Name}}
+COPY config_*.yaml ./
+{{- range .Ports}}
+EXPOSE {{.}}
+{{- end}}
+CMD {{.Cmd}}
+`
+
+// dockerignoreTpl 构建上下文中不需要的文件
+const dockerignoreTpl = `.git
+.gitignore
+.idea
+.vscode
+.DS_Store
+*.log
+bin/
+deploy/
+Dockerfile
+.dockerignore
+docker-compose*.yml
+`
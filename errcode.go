@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/actorbuf/iotaer/errcode"
+	"github.com/actorbuf/iotaer/protodef"
+	"github.com/actorbuf/iotaer/toolkit"
+	"github.com/spf13/cobra"
+)
+
+func errcodeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "errcode",
+		Short: "管理 error_code.proto 中的错误码",
+		Long: "在 ErrCode 枚举中添加错误码并生成辅助代码, 列出与检查错误码. " +
+			"号段在 .builderc 的 errcode.range 与 errcode.ranges 中配置, ranges 按 proto 所在目录配置, 多个服务可以共用同一号段",
+	}
+	cmd.AddCommand(errcodeAddCommand())
+	cmd.AddCommand(errcodeListCommand())
+	cmd.AddCommand(errcodeCheckCommand())
+	cmd.AddCommand(errcodeGenCommand())
+	return cmd
+}
+
+// errcodeConfig 读取并校验 .builderc 中的号段配置
+func errcodeConfig() errcode.Config {
+	c := parseConfig().ErrCode
+	if err := c.Validate(); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return c
+}
+
+// errcodeFile 目录下只有一个 ErrCode 枚举时返回它所在的文件
+func errcodeFile(path string) (string, error) {
+	files, err := loadProtos(path)
+	if err != nil {
+		return "", err
+	}
+	var found []string
+	for _, f := range files {
+		if f.Enum(errcode.EnumName) != nil {
+			found = append(found, f.Path)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("%s 中没有 %s 枚举, 请先执行 builder addErrorCodeFile", path, errcode.EnumName)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("%s 中有多个 %s 枚举 %v, 请通过 --path 指定文件", path, errcode.EnumName, found)
+}
+
+// writeHelper 生成 proto 同目录下的错误码辅助代码
+func writeHelper(pbFile string) error {
+	f, err := protodef.ParseFile(pbFile)
+	if err != nil {
+		return err
+	}
+	src, err := errcode.Generate(f)
+	if err != nil {
+		return err
+	}
+	return writeFile(errcode.HelperFile(pbFile), src)
+}
+
+func errcodeAddCommand() *cobra.Command {
+	pbPath, _ := os.Getwd()
+	var opt errcode.Option
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "添加错误码",
+		Long: "在 ErrCode 枚举末尾添加错误码, 名称自动加上 ErrCode 前缀, 编号取号段内未被共用该号段的服务与框架内置错误码占用的最小值, " +
+			"未配置号段时从 10000 开始. 说明写在行尾注释, HTTP 状态码与多语言说明写在 @http 与 @msg_<lang> 标记中, 添加后重新生成辅助代码",
+		Example: "builder errcode add --name UserNotFound --msg \"用户不存在\" --http 404\n" +
+			"builder errcode add --path model/user --name UserBanned --msg \"用户已被封禁\" --i18n en=\"user is banned\"",
+		Run: func(cmd *cobra.Command, args []string) {
+			if opt.Name == "" {
+				_, _ = fmt.Fprintf(os.Stderr, "errcode add --name 不能为空\n")
+				os.Exit(1)
+			}
+			if opt.Msg == "" {
+				_, _ = fmt.Fprintf(os.Stderr, "errcode add --msg 不能为空\n")
+				os.Exit(1)
+			}
+			c := errcodeConfig()
+			pbFile := pbPath
+			if toolkit.IsDir(pbPath) {
+				var err error
+				if pbFile, err = errcodeFile(pbPath); err != nil {
+					_, _ = fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+			}
+			// 号段可能被项目中其他服务共用 需要扫描整个项目
+			files, err := loadProtos(".")
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			code, err := errcode.Add(pbFile, files, c, opt)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "update   %s %s = %d\n", pbFile, code.Name, code.Code)
+			if err := writeHelper(pbFile); err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			_, _ = fmt.Fprintln(os.Stdout, "proto已更新, 请执行 builder gen 重新生成pb代码")
+		},
+	}
+	cmd.Flags().StringVar(&pbPath, "path", pbPath, "error_code.proto 文件地址, 传入目录时使用目录下唯一的 ErrCode 枚举")
+	cmd.Flags().StringVar(&opt.Name, "name", "", "错误码名称, 如 UserNotFound")
+	cmd.Flags().StringVar(&opt.Msg, "msg", "", "错误说明")
+	cmd.Flags().IntVar(&opt.HTTP, "http", 0, "对应的 HTTP 状态码")
+	cmd.Flags().StringToStringVar(&opt.I18n, "i18n", nil, "多语言说明 lang=msg, 可多次指定")
+	return cmd
+}
+
+func errcodeListCommand() *cobra.Command {
+	pbPath, _ := os.Getwd()
+	var format = errcode.FormatText
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "列出错误码",
+		Example: "builder errcode list\nbuilder errcode list --format json",
+		Run: func(cmd *cobra.Command, args []string) {
+			files, err := loadProtos(pbPath)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			var codes []*errcode.Code
+			for _, f := range files {
+				codes = append(codes, errcode.Codes(f)...)
+			}
+			if err := errcode.Write(os.Stdout, format, codes); err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&pbPath, "path", pbPath, "proto文件地址,支持传入目录")
+	cmd.Flags().StringVar(&format, "format", format, "输出格式 [text,json]")
+	return cmd
+}
+
+func errcodeCheckCommand() *cobra.Command {
+	pbPath, _ := os.Getwd()
+	var format = errcode.FormatText
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "检查错误码",
+		Long: "检查 --path 下所有 ErrCode 枚举: 共用同一号段的服务之间错误码重复、错误码不在配置的号段内、与框架内置错误码冲突以及号段部分重叠, " +
+			"存在问题时返回非0",
+		Example: "builder errcode check\nbuilder errcode check --format json",
+		Run: func(cmd *cobra.Command, args []string) {
+			c := errcodeConfig()
+			files, err := loadProtos(pbPath)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			issues := errcode.Check(files, c)
+			if err := errcode.WriteIssues(os.Stdout, format, issues); err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if len(issues) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&pbPath, "path", pbPath, "proto文件地址,支持传入目录")
+	cmd.Flags().StringVar(&format, "format", format, "输出格式 [text,json]")
+	return cmd
+}
+
+func errcodeGenCommand() *cobra.Command {
+	pbPath, _ := os.Getwd()
+	cmd := &cobra.Command{
+		Use:     "gen",
+		Short:   "重新生成错误码辅助代码",
+		Long:    "手动修改 ErrCode 枚举后, 为 --path 下每个 ErrCode 枚举重新生成同目录下的 <proto名>_helper.go",
+		Example: "builder errcode gen --path model",
+		Run: func(cmd *cobra.Command, args []string) {
+			files, err := loadProtos(pbPath)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			for _, f := range files {
+				if f.Enum(errcode.EnumName) == nil {
+					continue
+				}
+				if err := writeHelper(f.Path); err != nil {
+					_, _ = fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVar(&pbPath, "path", pbPath, "proto文件地址,支持传入目录")
+	return cmd
+}
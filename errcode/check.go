@@ -0,0 +1,87 @@
+package errcode
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/actorbuf/iotaer/protodef"
+)
+
+// 问题类型
+const (
+	Duplicate  = "duplicate"    // 共用号段的错误码重复
+	OutOfRange = "out-of-range" // 错误码不在所在文件配置的号段内
+	Builtin    = "builtin"      // 与框架内置错误码冲突
+	Overlap    = "overlap"      // 配置的号段部分重叠
+)
+
+// Issue check 发现的问题
+type Issue struct {
+	Kind    string            `json:"kind"`
+	Message string            `json:"message"`
+	Pos     protodef.Position `json:"pos"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s (%s)", i.Pos, i.Message, i.Kind)
+}
+
+// Check 检查项目中所有 ErrCode 枚举
+// 共用同一号段的服务之间错误码不能重复, 配置了号段时错误码需要在号段内, 号段之间只能完全相同或不相交
+func Check(files []*protodef.File, c Config) []Issue {
+	var issues []Issue
+	report := func(kind string, pos protodef.Position, format string, args ...interface{}) {
+		issues = append(issues, Issue{Kind: kind, Message: fmt.Sprintf(format, args...), Pos: pos})
+	}
+
+	seen := map[Range]map[int]*Code{}
+	var ranges []Range // 配置的号段 没有配置的文件共用默认号段 不参与重叠检查
+	first := map[Range]protodef.Position{}
+	for _, f := range files {
+		codes := Codes(f)
+		if codes == nil {
+			continue
+		}
+		r := c.RangeOf(f.Path)
+		configured := c.lookup(f.Path) != nil
+		if seen[r] == nil {
+			seen[r] = map[int]*Code{}
+			if configured {
+				ranges = append(ranges, r)
+				first[r] = f.Pos
+			}
+		}
+		for _, code := range codes {
+			if code.Code == 0 {
+				continue
+			}
+			if name, ok := Reserved[code.Code]; ok {
+				report(Builtin, code.Proto, "错误码 %s = %d 与框架内置错误码 %s 冲突", code.Name, code.Code, name)
+			}
+			if configured && !r.Contains(code.Code) {
+				report(OutOfRange, code.Proto, "错误码 %s = %d 不在号段 %s 内", code.Name, code.Code, r)
+			}
+			if prev, ok := seen[r][code.Code]; ok {
+				report(Duplicate, code.Proto, "错误码 %s = %d 与 %s (%s) 重复", code.Name, code.Code, prev.Name, prev.Proto)
+				continue
+			}
+			seen[r][code.Code] = code
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].Min != ranges[j].Min {
+			return ranges[i].Min < ranges[j].Min
+		}
+		return ranges[i].Max < ranges[j].Max
+	})
+	for i := range ranges {
+		for j := i + 1; j < len(ranges); j++ {
+			a, b := ranges[i], ranges[j]
+			if b.Min <= a.Max {
+				report(Overlap, first[b], "号段 %s 与 %s 部分重叠, 共用号段时请配置完全相同的号段", b, a)
+			}
+		}
+	}
+	return issues
+}
@@ -0,0 +1,287 @@
+package errcode
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/actorbuf/iotaer/protodef"
+	"github.com/actorbuf/iotaer/protofmt"
+	"github.com/actorbuf/iotaer/toolkit"
+	"github.com/emicklei/proto"
+)
+
+// EnumName gen 只处理名为 ErrCode 的枚举 与 addErrorCodeFile 创建的 error_code.proto 一致
+const EnumName = "ErrCode"
+
+// Prefix 错误码名称的前缀
+const Prefix = "ErrCode"
+
+// DefaultRange 没有配置号段时使用的号段 避开框架内置的错误码
+var DefaultRange = Range{Min: 10000, Max: math.MaxInt32}
+
+// Reserved 框架内置的错误码 业务错误码不能使用
+var Reserved = map[int]string{
+	-10:  "ErrProcessPanic",
+	-1:   "ErrSystemError",
+	1:    "业务错误",
+	1001: "ErrInvalidArg",
+	1002: "ErrRecordNotFound",
+	1003: "ErrConnectTimeout",
+	1004: "ErrFreqLimit",
+	1005: "ErrRequestBroken",
+	1006: "ErrRequestRateLimit",
+	1007: "ErrParamEmpty",
+}
+
+// Range 号段 包含两端
+type Range struct {
+	Min int
+	Max int
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("[%d,%d]", r.Min, r.Max)
+}
+
+// Contains 错误码是否在号段内
+func (r Range) Contains(n int) bool {
+	return n >= r.Min && n <= r.Max
+}
+
+// Config .builderc 中的 errcode 配置
+type Config struct {
+	Range  []int            `yaml:"range" json:"range"`   // 默认号段 [起始,结束]
+	Ranges map[string][]int `yaml:"ranges" json:"ranges"` // 按 proto 所在目录或文件配置号段 多个服务可以共用同一号段
+}
+
+// Validate 校验号段格式
+func (c *Config) Validate() error {
+	check := func(name string, r []int) error {
+		if len(r) != 2 || r[0] > r[1] {
+			return fmt.Errorf("errcode %s 号段 %v 格式不正确, 应为 [起始,结束]", name, r)
+		}
+		return nil
+	}
+	if c.Range != nil {
+		if err := check("range", c.Range); err != nil {
+			return err
+		}
+	}
+	for path, r := range c.Ranges {
+		if err := check("ranges."+path, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RangeOf proto 文件使用的号段 ranges 中按最长匹配的目录或文件查找 都没有配置时为默认号段
+func (c *Config) RangeOf(path string) Range {
+	if r := c.lookup(path); r != nil {
+		return Range{Min: r[0], Max: r[1]}
+	}
+	return DefaultRange
+}
+
+func (c *Config) lookup(path string) []int {
+	path = relPath(path)
+	best := -1
+	var r []int
+	for p, v := range c.Ranges {
+		p = strings.TrimSuffix(relPath(p), "/")
+		if (path == p || strings.HasPrefix(path, p+"/") || p == ".") && len(p) > best {
+			best, r = len(p), v
+		}
+	}
+	if r == nil {
+		r = c.Range
+	}
+	if len(r) != 2 {
+		return nil
+	}
+	return r
+}
+
+// relPath ranges 中的路径相对工作目录 --path 可能是绝对路径或 ./model 这样的写法, 统一转换后再比较
+func relPath(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if abs, err := filepath.Abs(path); err == nil {
+			if rel, err := filepath.Rel(wd, abs); err == nil {
+				path = rel
+			}
+		}
+	}
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// Code 错误码 说明为行尾注释 多语言说明与 HTTP 状态码为前置注释中的 @msg_<lang> 与 @http 标记
+type Code struct {
+	Name  string            `json:"name"`
+	Code  int               `json:"code"`
+	Msg   string            `json:"msg"`
+	HTTP  int               `json:"http,omitempty"`
+	I18n  map[string]string `json:"i18n,omitempty"`
+	Proto protodef.Position `json:"proto"`
+}
+
+var msgTagReg = regexp.MustCompile(`^\s*@msg_([a-z_]+)\s*:\s*(.*?)\s*$`)
+
+// Codes 文件中 ErrCode 枚举的错误码 没有该枚举时返回 nil
+func Codes(f *protodef.File) []*Code {
+	e := f.Enum(EnumName)
+	if e == nil {
+		return nil
+	}
+	var codes []*Code
+	for _, v := range e.Values {
+		c := &Code{Name: v.Name, Code: v.Number, Msg: protodef.Describe(v.Comment), Proto: v.Pos}
+		if c.Msg == "" {
+			c.Msg = v.Name
+		}
+		c.HTTP, _ = strconv.Atoi(protodef.Tag(v.Comment, "http"))
+		for _, line := range v.Comment {
+			if res := msgTagReg.FindStringSubmatch(line); res != nil {
+				if c.I18n == nil {
+					c.I18n = map[string]string{}
+				}
+				c.I18n[res[1]] = res[2]
+			}
+		}
+		codes = append(codes, c)
+	}
+	return codes
+}
+
+// Lang 语言统一为小写下划线形式 zh-TW 与 zh_tw 相同
+func Lang(lang string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(lang)), "-", "_")
+}
+
+// Option errcode add 的参数
+type Option struct {
+	Name string
+	Msg  string
+	HTTP int
+	I18n map[string]string // 语言 => 说明
+}
+
+var (
+	nameReg = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+	langReg = regexp.MustCompile(`^[a-z_]+$`)
+)
+
+// Name 错误码名称统一加上 ErrCode 前缀 与 ErrCodeNil 一致
+func Name(name string) string {
+	name = toolkit.FirstUpper(name)
+	if strings.HasPrefix(name, Prefix) {
+		return name
+	}
+	return Prefix + name
+}
+
+// Add 在 pbFile 的 ErrCode 枚举末尾追加错误码
+// 编号取号段内没有被 files 中同号段的错误码以及框架内置错误码占用的最小值
+func Add(pbFile string, files []*protodef.File, c Config, opt Option) (*Code, error) {
+	if !nameReg.MatchString(opt.Name) {
+		return nil, fmt.Errorf("错误码名称 %s 不正确, 只能包含字母数字下划线并以字母开头", opt.Name)
+	}
+	if strings.TrimSpace(opt.Msg) == "" {
+		return nil, fmt.Errorf("错误码 %s 缺少说明", opt.Name)
+	}
+	if opt.HTTP != 0 && (opt.HTTP < 100 || opt.HTTP > 599) {
+		return nil, fmt.Errorf("HTTP 状态码 %d 不正确", opt.HTTP)
+	}
+	def, err := protofmt.Load(pbFile)
+	if err != nil {
+		return nil, err
+	}
+	var enum *proto.Enum
+	for _, e := range def.Elements {
+		if v, ok := e.(*proto.Enum); ok && v.Name == EnumName {
+			enum = v
+		}
+	}
+	if enum == nil {
+		return nil, fmt.Errorf("%s 中没有 %s 枚举, 请先执行 builder addErrorCodeFile", pbFile, EnumName)
+	}
+	name := Name(opt.Name)
+	for _, e := range enum.Elements {
+		if v, ok := e.(*proto.EnumField); ok && v.Name == name {
+			return nil, fmt.Errorf("%s 中已存在错误码 %s = %d", pbFile, name, v.Integer)
+		}
+	}
+
+	r := c.RangeOf(pbFile)
+	used := map[int]bool{}
+	for k := range Reserved {
+		used[k] = true
+	}
+	for _, f := range files {
+		if samePath(f.Path, pbFile) || c.RangeOf(f.Path) == r {
+			for _, code := range Codes(f) {
+				used[code.Code] = true
+			}
+		}
+	}
+	// 当前文件以磁盘上的内容为准
+	for _, e := range enum.Elements {
+		if v, ok := e.(*proto.EnumField); ok {
+			used[v.Integer] = true
+		}
+	}
+	number := -1
+	for n := r.Min; n <= r.Max; n++ {
+		if !used[n] {
+			number = n
+			break
+		}
+	}
+	if number < 0 {
+		return nil, fmt.Errorf("号段 %s 已用完", r)
+	}
+
+	field := &proto.EnumField{Name: name, Integer: number, InlineComment: &proto.Comment{Lines: []string{" " + opt.Msg}}}
+	var doc []string
+	if opt.HTTP != 0 {
+		doc = append(doc, fmt.Sprintf(" @http: %d", opt.HTTP))
+	}
+	langs := make([]string, 0, len(opt.I18n))
+	i18n := map[string]string{}
+	for lang, msg := range opt.I18n {
+		lang = Lang(lang)
+		if !langReg.MatchString(lang) {
+			return nil, fmt.Errorf("语言 %s 不正确", lang)
+		}
+		langs = append(langs, lang)
+		i18n[lang] = msg
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		doc = append(doc, fmt.Sprintf(" @msg_%s: %s", lang, i18n[lang]))
+	}
+	if len(doc) > 0 {
+		field.Comment = &proto.Comment{Lines: doc}
+	}
+	field.Parent = enum
+	enum.Elements = append(enum.Elements, field)
+	if err := protofmt.Save(pbFile, def); err != nil {
+		return nil, err
+	}
+	code := &Code{Name: name, Code: number, Msg: opt.Msg, HTTP: opt.HTTP, Proto: protodef.Position{File: pbFile}}
+	if len(i18n) > 0 {
+		code.I18n = i18n
+	}
+	return code, nil
+}
+
+func samePath(a, b string) bool {
+	x, _ := filepath.Abs(a)
+	y, _ := filepath.Abs(b)
+	return x == y
+}
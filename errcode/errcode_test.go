@@ -0,0 +1,196 @@
+package errcode
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/actorbuf/iotaer/protodef"
+)
+
+const userProto = `syntax = "proto3";
+
+package user;
+
+option go_package = "example.com/demo/model/user";
+
+enum ErrCode {
+    ErrCodeNil = 0;
+    ErrCodeUserNotFound = 20000; // 用户不存在
+}
+`
+
+const orderProto = `syntax = "proto3";
+
+package order;
+
+option go_package = "example.com/demo/model/order;order";
+
+enum ErrCode {
+    ErrCodeNil = 0;
+    ErrCodeOrderNotFound = 20001; // 订单不存在
+}
+`
+
+func write(t *testing.T, dir, name, src string) string {
+	p := filepath.Join(dir, name)
+	_ = os.MkdirAll(filepath.Dir(p), 0755)
+	if err := ioutil.WriteFile(p, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func parse(t *testing.T, paths ...string) []*protodef.File {
+	var files []*protodef.File
+	for _, p := range paths {
+		f, err := protodef.ParseFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+func TestRangeOf(t *testing.T) {
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	_ = os.Chdir(dir)
+	// ranges 中为相对项目根目录的路径 --path 可能是绝对路径
+	c := Config{Range: []int{10000, 19999}, Ranges: map[string][]int{"model/user": {20000, 29999}, "model/user/admin.proto": {30000, 30999}}}
+	for path, want := range map[string]Range{
+		filepath.Join(dir, "model", "user", "error_code.proto"): {20000, 29999},
+		"./model/user/../user/admin.proto":                      {30000, 30999},
+		filepath.Join(dir, "model", "users", "x.proto"):         {10000, 19999},
+	} {
+		if got := c.RangeOf(path); got != want {
+			t.Fatalf("%s: unexpected range %v", path, got)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	dir := t.TempDir()
+	user := write(t, dir, "user/error_code.proto", userProto)
+	order := write(t, dir, "order/error_code.proto", orderProto)
+	c := Config{Ranges: map[string][]int{filepath.Join(dir, "user"): {20000, 29999}, filepath.Join(dir, "order"): {20000, 29999}}}
+
+	// 共用号段 跳过其他服务已使用的 20001
+	code, err := Add(user, parse(t, user, order), c, Option{Name: "userBanned", Msg: "用户已被封禁", HTTP: 403, I18n: map[string]string{"en-US": "user is banned"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code.Name != "ErrCodeUserBanned" || code.Code != 20002 {
+		t.Fatalf("unexpected code %+v", code)
+	}
+	src, _ := ioutil.ReadFile(user)
+	for _, want := range []string{"// @http: 403\n", "// @msg_en_us: user is banned\n", "ErrCodeUserBanned = 20002; // 用户已被封禁\n"} {
+		if !strings.Contains(string(src), want) {
+			t.Fatalf("expected %q in\n%s", want, src)
+		}
+	}
+	codes := Codes(parse(t, user)[0])
+	want := &Code{Name: "ErrCodeUserBanned", Code: 20002, Msg: "用户已被封禁", HTTP: 403, I18n: map[string]string{"en_us": "user is banned"}}
+	got := codes[len(codes)-1]
+	got.Proto = protodef.Position{}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected codes %+v", got)
+	}
+
+	// 没有配置号段时从默认号段开始 与其他号段互不影响
+	order2 := write(t, dir, "order2/error_code.proto", strings.ReplaceAll(orderProto, "20001", "10000"))
+	code, err = Add(order2, parse(t, user, order, order2), Config{}, Option{Name: "ErrCodeOrderPaid", Msg: "订单已支付"})
+	if err != nil || code.Code != 10001 {
+		t.Fatalf("unexpected code %+v %v", code, err)
+	}
+
+	for _, opt := range []Option{
+		{Name: "UserNotFound", Msg: "重复"},
+		{Name: "bad-name", Msg: "x"},
+		{Name: "Empty"},
+		{Name: "Status", Msg: "x", HTTP: 999},
+	} {
+		if _, err := Add(user, nil, c, opt); err == nil {
+			t.Fatalf("%+v: expected error", opt)
+		}
+	}
+	full := Config{Range: []int{20000, 20000}}
+	if _, err := Add(user, nil, full, Option{Name: "Full", Msg: "x"}); err == nil || !strings.Contains(err.Error(), "已用完") {
+		t.Fatalf("expected range exhausted, got %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	user := write(t, dir, "user/error_code.proto", userProto)
+	order := write(t, dir, "order/error_code.proto", strings.ReplaceAll(orderProto, "20001", "20000"))
+	pay := write(t, dir, "pay/error_code.proto", strings.ReplaceAll(orderProto, "20001", "1001"))
+	files := parse(t, user, order, pay)
+
+	// 不同号段的服务可以使用相同的错误码
+	c := Config{Ranges: map[string][]int{filepath.Join(dir, "user"): {20000, 29999}, filepath.Join(dir, "order"): {20000, 20999}}}
+	issues := Check(files, c)
+	var kinds []string
+	for _, i := range issues {
+		kinds = append(kinds, i.Kind)
+	}
+	if !reflect.DeepEqual(kinds, []string{Builtin, Overlap}) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+
+	c = Config{Range: []int{20000, 29999}}
+	issues = Check(files, c)
+	kinds = nil
+	for _, i := range issues {
+		kinds = append(kinds, i.Kind)
+	}
+	if !reflect.DeepEqual(kinds, []string{Duplicate, Builtin, OutOfRange}) {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	if !strings.Contains(issues[0].Message, "ErrCodeOrderNotFound = 20000 与 ErrCodeUserNotFound") {
+		t.Fatalf("unexpected message %s", issues[0])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteIssues(&buf, FormatText, issues); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(buf.String(), "\n") != 3 {
+		t.Fatalf("unexpected output %s", buf.String())
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	user := write(t, dir, "user/error_code.proto", userProto)
+	if _, err := Add(user, nil, Config{}, Option{Name: "Banned", Msg: "已封禁 \"x\"", HTTP: 403, I18n: map[string]string{"en": "banned"}}); err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate(parse(t, user)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"package user\n",
+		"ErrUserNotFound = &core.ErrMsg{ErrCode: 20000, ErrMsg: \"用户不存在\"}",
+		"ErrBanned = &core.ErrMsg{ErrCode: 10000, ErrMsg: \"已封禁 \\\"x\\\"\"}",
+		"\"en\": {\n\t\t10000: \"banned\",\n\t},",
+		"var ErrCodeHTTPStatus = map[int32]int{\n\t10000: 403,\n}",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Fatalf("expected %q in\n%s", want, src)
+		}
+	}
+	if got := HelperFile(user); got != filepath.Join(dir, "user", "error_code_helper.go") {
+		t.Fatalf("unexpected helper file %s", got)
+	}
+	if got := GoPackage(parse(t, write(t, dir, "order.proto", orderProto))[0]); got != "order" {
+		t.Fatalf("unexpected package %s", got)
+	}
+}
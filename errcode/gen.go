@@ -0,0 +1,152 @@
+package errcode
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/actorbuf/iotaer/protodef"
+)
+
+// helperTpl 与 gen 生成的 autogen_errcode_*.go 在同一个包中
+// 不能重复定义 gen 已经生成的错误码常量、errCodeMap 与 RegisterError
+const helperTpl = `// Code generated by iotaer errcode. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/actorbuf/iota/core"
+)
+
+// 错误码对应的错误 logic 中直接返回, 调用方可以通过 errors.Is 判断
+// 错误是共用的 不要修改其中的字段
+var ({{range .Codes}}
+	// {{.Var}} {{.Msg}}
+	{{.Var}} = &core.ErrMsg{ErrCode: {{.Code}}, ErrMsg: {{printf "%q" .Msg}}}{{end}}
+)
+
+// ErrCodeMessages 错误码对应的默认说明
+var ErrCodeMessages = map[int32]string{ {{range .Codes}}
+	{{.Code}}: {{printf "%q" .Msg}},{{end}}
+}
+
+// ErrCodeI18n 错误码的多语言说明 语言为小写下划线形式 如 en, zh_tw
+var ErrCodeI18n = map[string]map[int32]string{ {{range $lang, $msgs := .I18n}}
+	{{printf "%q" $lang}}: { {{range $msgs}}
+		{{.Code}}: {{printf "%q" .Msg}},{{end}}
+	},{{end}}
+}
+
+// ErrCodeHTTPStatus 错误码对应的 HTTP 状态码
+var ErrCodeHTTPStatus = map[int32]int{ {{range .Codes}}{{if .HTTP}}
+	{{.Code}}: {{.HTTP}},{{end}}{{end}}
+}
+
+// IsErrCode err 是否为指定错误码的 *core.ErrMsg 支持 core.CreateError 创建的错误与 fmt.Errorf 的 %w 包装
+func IsErrCode(err error, code int32) bool {
+	var e *core.ErrMsg
+	return errors.As(err, &e) && e.ErrCode == code
+}
+
+// ErrCodeMsg 按语言返回错误说明 zh-TW 依次查找 zh_tw、zh, 都没有时返回默认说明
+func ErrCodeMsg(code int32, lang string) string {
+	lang = strings.ReplaceAll(strings.ToLower(lang), "-", "_")
+	for lang != "" {
+		if msg, ok := ErrCodeI18n[lang][code]; ok {
+			return msg
+		}
+		i := strings.LastIndex(lang, "_")
+		if i < 0 {
+			break
+		}
+		lang = lang[:i]
+	}
+	if msg, ok := ErrCodeMessages[code]; ok {
+		return msg
+	}
+	return core.GetErrMsg(code)
+}
+
+// HTTPStatus err 对应的 HTTP 状态码 与框架一致 没有配置时为 200
+func HTTPStatus(err error) int {
+	var e *core.ErrMsg
+	if errors.As(err, &e) {
+		if status, ok := ErrCodeHTTPStatus[e.ErrCode]; ok {
+			return status
+		}
+	}
+	return http.StatusOK
+}
+`
+
+type helperCode struct {
+	Var  string
+	Code int
+	Msg  string
+	HTTP int
+}
+
+// HelperFile 错误码辅助代码的位置 与 proto 在同一目录
+func HelperFile(pbFile string) string {
+	name := strings.TrimSuffix(filepath.Base(pbFile), filepath.Ext(pbFile))
+	return filepath.Join(filepath.Dir(pbFile), name+"_helper.go")
+}
+
+// GoPackage go_package 中的包名 没有时使用 proto 包名
+func GoPackage(f *protodef.File) string {
+	pkg := f.GoPackage
+	if i := strings.LastIndex(pkg, ";"); i >= 0 {
+		return pkg[i+1:]
+	}
+	if pkg != "" {
+		return filepath.Base(pkg)
+	}
+	return strings.ReplaceAll(f.Package, ".", "_")
+}
+
+// Generate 生成错误码辅助代码: 错误变量、说明、多语言说明、HTTP 状态码映射与 errors.Is 判断
+func Generate(f *protodef.File) ([]byte, error) {
+	codes := Codes(f)
+	if codes == nil {
+		return nil, fmt.Errorf("%s 中没有 %s 枚举", f.Path, EnumName)
+	}
+	names := map[string]bool{}
+	for _, c := range codes {
+		names[c.Name] = true
+	}
+	data := struct {
+		Package string
+		Codes   []helperCode
+		I18n    map[string][]helperCode
+	}{Package: GoPackage(f), I18n: map[string][]helperCode{}}
+	for _, c := range codes {
+		if c.Code == 0 {
+			continue
+		}
+		// gen 以枚举值名称生成常量 变量名去掉 ErrCode 前缀避免重名
+		v := "Err" + strings.TrimPrefix(c.Name, Prefix)
+		if names[v] {
+			v += "Error"
+		}
+		data.Codes = append(data.Codes, helperCode{Var: v, Code: c.Code, Msg: c.Msg, HTTP: c.HTTP})
+		for lang, msg := range c.I18n {
+			data.I18n[lang] = append(data.I18n[lang], helperCode{Code: c.Code, Msg: msg})
+		}
+	}
+	for _, list := range data.I18n {
+		sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	}
+	var buf bytes.Buffer
+	if err := template.Must(template.New("errcode").Parse(helperTpl)).Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,76 @@
+package errcode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// 输出格式
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Write 输出错误码列表
+func Write(w io.Writer, format string, codes []*Code) error {
+	if codes == nil {
+		codes = []*Code{}
+	}
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, codes)
+	case FormatText, "":
+	default:
+		return fmt.Errorf("不支持的输出格式 %s", format)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tCODE\tHTTP\tMSG\tI18N\tPROTO")
+	for _, c := range codes {
+		status := "-"
+		if c.HTTP != 0 {
+			status = strconv.Itoa(c.HTTP)
+		}
+		langs := make([]string, 0, len(c.I18n))
+		for lang := range c.I18n {
+			langs = append(langs, lang)
+		}
+		sort.Strings(langs)
+		i18n := strings.Join(langs, ",")
+		if i18n == "" {
+			i18n = "-"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\n", c.Name, c.Code, status, c.Msg, i18n, c.Proto)
+	}
+	return tw.Flush()
+}
+
+// WriteIssues 输出 check 的结果 文本格式每行一个问题
+func WriteIssues(w io.Writer, format string, issues []Issue) error {
+	if issues == nil {
+		issues = []Issue{}
+	}
+	switch format {
+	case FormatJSON:
+		return writeJSON(w, issues)
+	case FormatText, "":
+	default:
+		return fmt.Errorf("不支持的输出格式 %s", format)
+	}
+	for _, i := range issues {
+		if _, err := fmt.Fprintln(w, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/actorbuf/iotaer/apidoc"
+	"github.com/actorbuf/iotaer/apiexport"
+	"github.com/spf13/cobra"
+)
+
+func exportCommand() *cobra.Command {
+	pbPath, _ := os.Getwd()
+	var include []string
+	var format = apiexport.FormatPostman
+	var out, configDir = "", "."
+	var host = "localhost"
+	var baseURLs map[string]string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "导出路由组的Postman集合或.http文件",
+		Long: "为 --path 下的每个路由组导出一个可以直接发送请求的集合, 请求体与 query 参数按字段类型与注释合成示例值(@example 优先), " +
+			"每个 config_<env>.yaml 生成一个环境, 服务地址 baseUrl 为 http://--host:配置中的api端口. " +
+			"postman 格式可以导入 Postman、Bruno、Apifox, http 格式可以在 JetBrains HTTP Client 与 VS Code REST Client 中使用",
+		Example: "builder export --path model --format postman\n" +
+			"builder export --path model --format http --out docs/http --base-url dev=https://dev.example.com",
+		Run: func(cmd *cobra.Command, args []string) {
+			if out == "" {
+				out = "docs/" + format
+			}
+			if err := outputExport(pbPath, include, format, out, configDir, host, baseURLs); err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&pbPath, "path", pbPath, "proto文件或目录")
+	cmd.Flags().StringSliceVar(&include, "include", include, "路由组依赖到的其他proto文件列表,支持目录")
+	cmd.Flags().StringVar(&format, "format", format, "导出格式 postman/http")
+	cmd.Flags().StringVar(&out, "out", out, "输出目录, 默认为 docs/<format>")
+	cmd.Flags().StringVar(&configDir, "config", configDir, "config_<env>.yaml 所在目录")
+	cmd.Flags().StringVar(&host, "host", host, "环境中服务地址的主机名")
+	cmd.Flags().StringToStringVar(&baseURLs, "base-url", baseURLs, "指定环境的服务地址, 如 dev=https://dev.example.com, 环境不存在时新增")
+	return cmd
+}
+
+// outputExport 导出路由组的集合与环境文件 内容没有变化的文件不重写
+func outputExport(path string, include []string, format, out, configDir, host string, baseURLs map[string]string) error {
+	var export func(*apidoc.Project, []apiexport.Env) ([]apidoc.Page, error)
+	switch format {
+	case apiexport.FormatPostman:
+		export = apiexport.Postman
+	case apiexport.FormatHTTP:
+		export = apiexport.HTTP
+	default:
+		return fmt.Errorf("不支持的格式 %s, 可选 %s/%s", format, apiexport.FormatPostman, apiexport.FormatHTTP)
+	}
+	envs, err := apiexport.Envs(configDir, host)
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(baseURLs))
+	for name := range baseURLs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		found := false
+		for i := range envs {
+			if envs[i].Name == name {
+				envs[i].BaseURL, found = baseURLs[name], true
+			}
+		}
+		if !found {
+			envs = append(envs, apiexport.Env{Name: name, BaseURL: baseURLs[name]})
+		}
+	}
+	includes, err := includeFiles(include)
+	if err != nil {
+		return err
+	}
+	p, err := loadProject(path, includes)
+	if err != nil {
+		return err
+	}
+	if len(p.Groups) == 0 {
+		return fmt.Errorf("%s 中没有路由组", path)
+	}
+	pages, err := export(p, envs)
+	if err != nil {
+		return err
+	}
+	return writePages(out, pages)
+}
@@ -0,0 +1,140 @@
+package githost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// 访问级别 与 gitlab 的 access level 对应
+const (
+	AccessNoOne      = "no_one"
+	AccessDeveloper  = "developer"
+	AccessMaintainer = "maintainer"
+)
+
+// 仓库可见性
+const (
+	VisibilityPrivate  = "private"
+	VisibilityInternal = "internal"
+	VisibilityPublic   = "public"
+)
+
+// ErrNotFound 仓库或资源不存在
+var ErrNotFound = errors.New("资源不存在")
+
+// Repo 仓库信息
+type Repo struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"` // namespace/name
+	Description   string `json:"description"`
+	Visibility    string `json:"visibility"`
+	DefaultBranch string `json:"default_branch"`
+	SSHURL        string `json:"ssh_url"`
+	HTTPURL       string `json:"http_url"`
+	WebURL        string `json:"web_url"`
+}
+
+// CreateRepoOption 创建仓库参数
+type CreateRepoOption struct {
+	Name        string // 仓库名
+	Namespace   string // 所属组 为空时创建在当前用户下
+	Description string
+	Visibility  string // private/internal/public 默认 private
+	InitReadme  bool   // 是否初始化 README 推送已有项目时不要开启
+}
+
+// ProtectOption 保护分支参数
+type ProtectOption struct {
+	Branch         string
+	PushAccess     string // 允许推送的级别 默认 maintainer
+	MergeAccess    string // 允许合并的级别 默认 developer
+	AllowForcePush bool
+}
+
+// Variable CI变量
+type Variable struct {
+	Key         string
+	Value       string
+	Protected   bool   // 只在保护分支与tag的流水线中可用
+	Masked      bool   // 在日志中隐藏
+	Environment string // 生效的环境 默认 *
+}
+
+// Client git托管平台的仓库操作
+type Client interface {
+	// CreateRepo 创建仓库
+	CreateRepo(ctx context.Context, opt *CreateRepoOption) (*Repo, error)
+	// GetRepo 按全名查询仓库 不存在时返回 ErrNotFound
+	GetRepo(ctx context.Context, fullName string) (*Repo, error)
+	// ProtectBranch 保护分支 已保护的分支按新参数覆盖
+	ProtectBranch(ctx context.Context, fullName string, opt *ProtectOption) error
+	// SetVariable 新增或更新CI变量
+	SetVariable(ctx context.Context, fullName string, v *Variable) error
+}
+
+// Factory 创建某个平台的客户端 baseURL 如 https://gitlab.example.com
+type Factory func(baseURL, token string, hc *http.Client) Client
+
+var factories = map[string]Factory{}
+
+// Register 注册一个平台的实现 gitea/github 实现后在各自文件的 init 中注册
+func Register(kind string, f Factory) {
+	factories[kind] = f
+}
+
+// Kinds 已支持的平台
+func Kinds() []string {
+	var kinds []string
+	for k := range factories {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// New 创建客户端 host 可以是域名也可以是完整地址 hc 为空时使用 http.DefaultClient
+func New(kind, host, token string, hc *http.Client) (Client, error) {
+	f, ok := factories[kind]
+	if !ok {
+		return nil, fmt.Errorf("暂不支持 %s, 可选 %v", kind, Kinds())
+	}
+	if host == "" {
+		return nil, errors.New("git 平台地址不能为空")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("%s 的 token 为空, 请执行 iotaer auth login --kind %s --host %s", host, kind, host)
+	}
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return f(BaseURL(host), token, hc), nil
+}
+
+// BaseURL 没有协议时默认使用 https
+func BaseURL(host string) string {
+	host = strings.TrimSuffix(host, "/")
+	if strings.HasPrefix(host, "http://") || strings.HasPrefix(host, "https://") {
+		return host
+	}
+	return "https://" + host
+}
+
+// APIError 平台返回的错误
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("git 平台返回错误 %d: %s", e.Code, e.Message)
+}
+
+// Is 404 视为 ErrNotFound
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.Code == http.StatusNotFound
+}
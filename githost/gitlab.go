@@ -0,0 +1,248 @@
+package githost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/guonaihong/gout"
+)
+
+// KindGitLab gitlab v4 api
+const KindGitLab = "gitlab"
+
+func init() {
+	Register(KindGitLab, func(baseURL, token string, hc *http.Client) Client {
+		return &GitLab{baseURL: baseURL, token: token, hc: hc}
+	})
+}
+
+// gitlabAccess 访问级别对应的数值
+var gitlabAccess = map[string]int{
+	AccessNoOne:      0,
+	AccessDeveloper:  30,
+	AccessMaintainer: 40,
+}
+
+// GitLab gitlab 客户端 使用 personal access token 需要 api 权限
+type GitLab struct {
+	baseURL string
+	token   string
+	hc      *http.Client
+}
+
+type gitlabProject struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	Description       string `json:"description"`
+	Visibility        string `json:"visibility"`
+	DefaultBranch     string `json:"default_branch"`
+	SSHURLToRepo      string `json:"ssh_url_to_repo"`
+	HTTPURLToRepo     string `json:"http_url_to_repo"`
+	WebURL            string `json:"web_url"`
+}
+
+func (p *gitlabProject) repo() *Repo {
+	return &Repo{
+		ID:            p.ID,
+		Name:          p.Name,
+		FullName:      p.PathWithNamespace,
+		Description:   p.Description,
+		Visibility:    p.Visibility,
+		DefaultBranch: p.DefaultBranch,
+		SSHURL:        p.SSHURLToRepo,
+		HTTPURL:       p.HTTPURLToRepo,
+		WebURL:        p.WebURL,
+	}
+}
+
+// CreateRepo 创建项目 指定组时先查询组的 namespace_id
+func (g *GitLab) CreateRepo(ctx context.Context, opt *CreateRepoOption) (*Repo, error) {
+	if opt.Name == "" {
+		return nil, errors.New("仓库名不能为空")
+	}
+	visibility := opt.Visibility
+	if visibility == "" {
+		visibility = VisibilityPrivate
+	}
+	body := map[string]interface{}{
+		"name":                   opt.Name,
+		"path":                   opt.Name,
+		"description":            opt.Description,
+		"visibility":             visibility,
+		"initialize_with_readme": opt.InitReadme,
+	}
+	if opt.Namespace != "" {
+		var ns struct {
+			ID int `json:"id"`
+		}
+		if err := g.do(ctx, http.MethodGet, "/namespaces/"+url.PathEscape(opt.Namespace), nil, &ns); err != nil {
+			return nil, fmt.Errorf("查询组 %s 失败: %w", opt.Namespace, err)
+		}
+		body["namespace_id"] = ns.ID
+	}
+	var p gitlabProject
+	if err := g.do(ctx, http.MethodPost, "/projects", body, &p); err != nil {
+		return nil, err
+	}
+	return p.repo(), nil
+}
+
+// GetRepo 查询项目
+func (g *GitLab) GetRepo(ctx context.Context, fullName string) (*Repo, error) {
+	var p gitlabProject
+	if err := g.do(ctx, http.MethodGet, projectPath(fullName), nil, &p); err != nil {
+		return nil, err
+	}
+	return p.repo(), nil
+}
+
+// ProtectBranch gitlab 不支持修改已保护分支的级别 先取消保护再重新保护
+func (g *GitLab) ProtectBranch(ctx context.Context, fullName string, opt *ProtectOption) error {
+	if opt.Branch == "" {
+		return errors.New("分支名不能为空")
+	}
+	push, err := accessLevel(opt.PushAccess, AccessMaintainer)
+	if err != nil {
+		return err
+	}
+	merge, err := accessLevel(opt.MergeAccess, AccessDeveloper)
+	if err != nil {
+		return err
+	}
+	path := projectPath(fullName) + "/protected_branches"
+	branch := path + "/" + url.PathEscape(opt.Branch)
+	// 接口不能直接修改访问级别 需要删除后重新创建, 创建失败时恢复原来的保护 避免分支处于未保护状态
+	var old gitlabProtected
+	err = g.do(ctx, http.MethodGet, branch, nil, &old)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	existed := err == nil
+	if existed {
+		if err := g.do(ctx, http.MethodDelete, branch, nil, nil); err != nil {
+			return err
+		}
+	}
+	err = g.do(ctx, http.MethodPost, path, map[string]interface{}{
+		"name":               opt.Branch,
+		"push_access_level":  push,
+		"merge_access_level": merge,
+		"allow_force_push":   opt.AllowForcePush,
+	}, nil)
+	if err == nil || !existed {
+		return err
+	}
+	if restoreErr := g.do(ctx, http.MethodPost, path, old.body(opt.Branch), nil); restoreErr != nil {
+		return fmt.Errorf("%v, 恢复原来的分支保护失败, 分支 %s 当前未保护: %v", err, opt.Branch, restoreErr)
+	}
+	return err
+}
+
+// gitlabProtected 已保护分支的设置 恢复时只能恢复按角色授予的访问级别
+type gitlabProtected struct {
+	PushAccessLevels  []gitlabAccessLevel `json:"push_access_levels"`
+	MergeAccessLevels []gitlabAccessLevel `json:"merge_access_levels"`
+	AllowForcePush    bool                `json:"allow_force_push"`
+}
+
+type gitlabAccessLevel struct {
+	AccessLevel int `json:"access_level"`
+}
+
+func (p *gitlabProtected) body(branch string) map[string]interface{} {
+	level := func(levels []gitlabAccessLevel) int {
+		if len(levels) == 0 {
+			return gitlabAccess[AccessNoOne]
+		}
+		return levels[0].AccessLevel
+	}
+	return map[string]interface{}{
+		"name":               branch,
+		"push_access_level":  level(p.PushAccessLevels),
+		"merge_access_level": level(p.MergeAccessLevels),
+		"allow_force_push":   p.AllowForcePush,
+	}
+}
+
+// SetVariable 先尝试更新 不存在时创建
+func (g *GitLab) SetVariable(ctx context.Context, fullName string, v *Variable) error {
+	if v.Key == "" {
+		return errors.New("变量名不能为空")
+	}
+	scope := v.Environment
+	if scope == "" {
+		scope = "*"
+	}
+	body := map[string]interface{}{
+		"key":               v.Key,
+		"value":             v.Value,
+		"protected":         v.Protected,
+		"masked":            v.Masked,
+		"environment_scope": scope,
+	}
+	path := projectPath(fullName) + "/variables"
+	// 同名变量在不同环境下各有一份 更新时需要按环境过滤
+	err := g.do(ctx, http.MethodPut, path+"/"+url.PathEscape(v.Key)+"?filter[environment_scope]="+url.QueryEscape(scope), body, nil)
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return g.do(ctx, http.MethodPost, path, body, nil)
+}
+
+// projectPath gitlab 的项目路径需要整体 url 编码
+func projectPath(fullName string) string {
+	return "/projects/" + url.PathEscape(strings.Trim(fullName, "/"))
+}
+
+func accessLevel(name, def string) (int, error) {
+	if name == "" {
+		name = def
+	}
+	level, ok := gitlabAccess[name]
+	if !ok {
+		return 0, fmt.Errorf("不支持的访问级别: %s, 可选 [%s,%s,%s]", name, AccessNoOne, AccessDeveloper, AccessMaintainer)
+	}
+	return level, nil
+}
+
+// do 调用 /api/v4 接口 非2xx时返回 APIError
+func (g *GitLab) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var code int
+	var resp []byte
+	flow := gout.New(g.hc).SetMethod(method).SetURL(g.baseURL + "/api/v4" + path).
+		WithContext(ctx).
+		SetHeader(gout.H{"PRIVATE-TOKEN": g.token}).
+		BindBody(&resp).
+		Code(&code)
+	if body != nil {
+		flow = flow.SetJSON(body)
+	}
+	if err := flow.Do(); err != nil {
+		return err
+	}
+	if code < 200 || code >= 300 {
+		var e struct {
+			Message interface{} `json:"message"`
+			Error   string      `json:"error"`
+		}
+		_ = json.Unmarshal(resp, &e)
+		msg := e.Error
+		if e.Message != nil {
+			msg = fmt.Sprint(e.Message)
+		}
+		if msg == "" {
+			msg = http.StatusText(code)
+		}
+		return &APIError{Code: code, Message: msg}
+	}
+	if out == nil || len(resp) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp, out)
+}
@@ -0,0 +1,238 @@
+package githost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGitLab 只实现 repo 命令用到的几个接口
+type fakeGitLab struct {
+	mu        sync.Mutex
+	projects  map[string]map[string]interface{}
+	protected map[string]map[string]interface{}
+	variables map[string]map[string]interface{}
+	requests  []string
+	failNext  bool // 下一次创建保护分支时返回错误
+}
+
+func newFakeGitLab() *fakeGitLab {
+	return &fakeGitLab{
+		projects:  map[string]map[string]interface{}{},
+		protected: map[string]map[string]interface{}{},
+		variables: map[string]map[string]interface{}{},
+	}
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.Header.Get("PRIVATE-TOKEN") != "glpat-x" {
+		reply(w, http.StatusUnauthorized, map[string]string{"message": "401 Unauthorized"})
+		return
+	}
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4")
+	f.requests = append(f.requests, r.Method+" "+path)
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && parts[0] == "namespaces" && len(parts) == 2:
+		if parts[1] != "backend%2Fsvc" {
+			reply(w, http.StatusNotFound, map[string]string{"message": "404 Namespace Not Found"})
+			return
+		}
+		reply(w, http.StatusOK, map[string]interface{}{"id": 7, "full_path": "backend/svc"})
+	case r.Method == http.MethodPost && path == "/projects":
+		ns := "root"
+		if body["namespace_id"] != nil {
+			ns = "backend/svc"
+		}
+		full := ns + "/" + body["path"].(string)
+		if _, ok := f.projects[full]; ok {
+			reply(w, http.StatusBadRequest, map[string]interface{}{"message": map[string][]string{"name": {"has already been taken"}}})
+			return
+		}
+		p := map[string]interface{}{
+			"id":                  len(f.projects) + 1,
+			"name":                body["name"],
+			"path_with_namespace": full,
+			"visibility":          body["visibility"],
+			"default_branch":      "main",
+			"ssh_url_to_repo":     "git@gitlab.example.com:" + full + ".git",
+			"http_url_to_repo":    "https://gitlab.example.com/" + full + ".git",
+		}
+		f.projects[full] = p
+		reply(w, http.StatusCreated, p)
+	case parts[0] == "projects" && len(parts) >= 2:
+		full := strings.ReplaceAll(parts[1], "%2F", "/")
+		p, ok := f.projects[full]
+		if !ok {
+			reply(w, http.StatusNotFound, map[string]string{"message": "404 Project Not Found"})
+			return
+		}
+		f.project(w, r, full, p, parts[2:], body)
+	default:
+		reply(w, http.StatusNotFound, map[string]string{"error": "404 Not Found"})
+	}
+}
+
+func (f *fakeGitLab) project(w http.ResponseWriter, r *http.Request, full string, p map[string]interface{}, parts []string, body map[string]interface{}) {
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		reply(w, http.StatusOK, p)
+	case len(parts) == 1 && parts[0] == "protected_branches" && r.Method == http.MethodPost:
+		if f.failNext {
+			f.failNext = false
+			reply(w, http.StatusUnprocessableEntity, map[string]string{"message": "invalid access level"})
+			return
+		}
+		key := full + "@" + body["name"].(string)
+		if _, ok := f.protected[key]; ok {
+			reply(w, http.StatusConflict, map[string]string{"message": "Protected branch already exists"})
+			return
+		}
+		f.protected[key] = body
+		reply(w, http.StatusCreated, body)
+	case len(parts) == 2 && parts[0] == "protected_branches" && r.Method == http.MethodGet:
+		b, ok := f.protected[full+"@"+parts[1]]
+		if !ok {
+			reply(w, http.StatusNotFound, map[string]string{"message": "404 Not found"})
+			return
+		}
+		reply(w, http.StatusOK, map[string]interface{}{
+			"name":                b["name"],
+			"push_access_levels":  []map[string]interface{}{{"access_level": b["push_access_level"]}},
+			"merge_access_levels": []map[string]interface{}{{"access_level": b["merge_access_level"]}},
+			"allow_force_push":    b["allow_force_push"],
+		})
+	case len(parts) == 2 && parts[0] == "protected_branches" && r.Method == http.MethodDelete:
+		key := full + "@" + parts[1]
+		if _, ok := f.protected[key]; !ok {
+			reply(w, http.StatusNotFound, map[string]string{"message": "404 Not found"})
+			return
+		}
+		delete(f.protected, key)
+		w.WriteHeader(http.StatusNoContent)
+	case len(parts) == 1 && parts[0] == "variables" && r.Method == http.MethodPost:
+		key := full + "@" + body["key"].(string) + "@" + body["environment_scope"].(string)
+		f.variables[key] = body
+		reply(w, http.StatusCreated, body)
+	case len(parts) == 2 && parts[0] == "variables" && r.Method == http.MethodPut:
+		key := full + "@" + parts[1] + "@" + r.URL.Query().Get("filter[environment_scope]")
+		if _, ok := f.variables[key]; !ok {
+			reply(w, http.StatusNotFound, map[string]string{"message": "404 Variable Not Found"})
+			return
+		}
+		f.variables[key] = body
+		reply(w, http.StatusOK, body)
+	default:
+		reply(w, http.StatusNotFound, map[string]string{"error": "404 Not Found"})
+	}
+}
+
+func reply(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestGitLab(t *testing.T) {
+	fake := newFakeGitLab()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	ctx := context.Background()
+
+	c, err := New(KindGitLab, srv.URL, "glpat-x", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := c.CreateRepo(ctx, &CreateRepoOption{Name: "user", Namespace: "backend/svc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.FullName != "backend/svc/user" || repo.Visibility != VisibilityPrivate {
+		t.Fatalf("unexpected repo %+v", repo)
+	}
+	if repo.SSHURL != "git@gitlab.example.com:backend/svc/user.git" {
+		t.Fatalf("unexpected ssh url %s", repo.SSHURL)
+	}
+	_, err = c.CreateRepo(ctx, &CreateRepoOption{Name: "user", Namespace: "backend/svc"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest || !strings.Contains(apiErr.Message, "has already been taken") {
+		t.Fatalf("expected duplicate error, got %v", err)
+	}
+	if _, err = c.CreateRepo(ctx, &CreateRepoOption{Name: "user", Namespace: "nobody"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected namespace not found, got %v", err)
+	}
+
+	got, err := c.GetRepo(ctx, "backend/svc/user")
+	if err != nil || got.ID != repo.ID {
+		t.Fatalf("get repo: %+v %v", got, err)
+	}
+	if _, err = c.GetRepo(ctx, "backend/svc/none"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// 第二次保护同一分支时覆盖原来的设置
+	for _, force := range []bool{false, true} {
+		err = c.ProtectBranch(ctx, "backend/svc/user", &ProtectOption{Branch: "main", MergeAccess: AccessMaintainer, AllowForcePush: force})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	p := fake.protected["backend/svc/user@main"]
+	if p["push_access_level"] != float64(40) || p["merge_access_level"] != float64(40) || p["allow_force_push"] != true {
+		t.Fatalf("unexpected protected branch %+v", p)
+	}
+	if err = c.ProtectBranch(ctx, "backend/svc/user", &ProtectOption{Branch: "main", PushAccess: "owner"}); err == nil {
+		t.Fatal("expected invalid access level error")
+	}
+	// 重新创建失败时恢复原来的保护
+	fake.failNext = true
+	if err = c.ProtectBranch(ctx, "backend/svc/user", &ProtectOption{Branch: "main", PushAccess: AccessNoOne}); err == nil {
+		t.Fatal("expected protect error")
+	}
+	p = fake.protected["backend/svc/user@main"]
+	if p["push_access_level"] != float64(40) || p["merge_access_level"] != float64(40) || p["allow_force_push"] != true {
+		t.Fatalf("protection should be restored, got %+v", p)
+	}
+
+	for _, v := range []string{"v1", "v2"} {
+		if err = c.SetVariable(ctx, "backend/svc/user", &Variable{Key: "DB_PASS", Value: v, Masked: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = c.SetVariable(ctx, "backend/svc/user", &Variable{Key: "DB_PASS", Value: "p", Environment: "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.variables) != 2 {
+		t.Fatalf("expected 2 variables, got %+v", fake.variables)
+	}
+	if v := fake.variables["backend/svc/user@DB_PASS@*"]; v["value"] != "v2" || v["masked"] != true {
+		t.Fatalf("unexpected variable %+v", v)
+	}
+
+	bad, _ := New(KindGitLab, srv.URL, "wrong", srv.Client())
+	if _, err = bad.GetRepo(ctx, "backend/svc/user"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected 401, got %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("svn", "example.com", "x", nil); err == nil {
+		t.Fatal("expected unsupported kind error")
+	}
+	if _, err := New(KindGitLab, "gitlab.com", "", nil); err == nil {
+		t.Fatal("expected empty token error")
+	}
+	if u := BaseURL("gitlab.example.com/"); u != "https://gitlab.example.com" {
+		t.Fatalf("unexpected base url %s", u)
+	}
+}
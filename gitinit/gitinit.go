@@ -0,0 +1,98 @@
+// Package gitinit 为项目初始化 git 仓库并安装 pre-commit 钩子
+package gitinit
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// PreCommitHook 提交前在暂存区的内容上检查 proto 格式与生成代码是否最新
+// proto 目录取 .builderc 中的 proto_dir 默认为 model, 没有安装 iotaer 或 iotaer 版本不支持 --check 时跳过对应的检查
+const PreCommitHook = `#!/bin/sh
+# 由 iotaer create --git 生成, 跳过检查请使用 git commit --no-verify
+if ! command -v iotaer >/dev/null 2>&1; then
+	echo "pre-commit: 未找到 iotaer, 跳过检查" >&2
+	exit 0
+fi
+# 导出暂存区 检查的是将要提交的内容而不是工作区
+tmp=$(mktemp -d) || exit 1
+trap 'rm -rf "$tmp"' EXIT
+git checkout-index -a --prefix="$tmp/" || exit 1
+dir=$(sed -n 's/^proto_dir:[[:space:]]*//p' "$tmp/.builderc" 2>/dev/null | tr -d "\"' \r")
+dir=${dir:-model}
+[ -d "$tmp/$dir" ] || exit 0
+cd "$tmp/$dir" || exit 1
+if iotaer fmt --help 2>&1 | grep -q -- "--check"; then
+	iotaer fmt --check || {
+		echo "pre-commit: proto 格式不正确, 请执行 iotaer fmt 后重新 git add" >&2
+		exit 1
+	}
+fi
+if iotaer gen --help 2>&1 | grep -q -- "--check"; then
+	iotaer gen --check || {
+		echo "pre-commit: 生成代码不是最新, 请执行 iotaer gen 后重新 git add" >&2
+		exit 1
+	}
+fi
+`
+
+// Option 初始化 git 仓库的参数
+type Option struct {
+	Name string // 项目名称 用于首次提交的说明
+	Path string // 项目目录
+	SSH  string // 设置为 origin 的仓库地址
+}
+
+// Init 初始化git仓库 设置 origin 并完成首次提交, 提交后再安装 pre-commit 钩子
+// 首次提交不经过钩子 避免新项目因工具链未安装而提交失败
+func Init(o *Option) error {
+	if _, err := os.Stat(filepath.Join(o.Path, ".git")); err == nil {
+		return fmt.Errorf("%s 已经是git仓库", o.Path)
+	}
+	if _, err := o.git("init", "-q"); err != nil {
+		return err
+	}
+	fmt.Printf("create   %s \n", filepath.Join(o.Path, ".git"))
+	if o.SSH != "" {
+		if _, err := o.git("remote", "add", "origin", o.SSH); err != nil {
+			return err
+		}
+		fmt.Printf("remote   origin %s \n", o.SSH)
+	}
+	if _, err := o.git("add", "-A"); err != nil {
+		return err
+	}
+	if _, err := o.git("commit", "-q", "--allow-empty", "-m", "init "+o.Name); err != nil {
+		return fmt.Errorf("%v, 请检查 git config user.name 与 user.email 是否已设置", err)
+	}
+	dir, err := o.git("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return err
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(o.Path, dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	hook := filepath.Join(dir, "pre-commit")
+	fmt.Printf("create   %s \n", hook)
+	return ioutil.WriteFile(hook, []byte(PreCommitHook), 0755)
+}
+
+func (o *Option) git(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = o.Path
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,94 @@
+package gitter
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"time"
+)
+
+// SecretEnv 未在配置中指定 secret 时读取的环境变量
+const SecretEnv = "IOTAER_GITTER_SECRET"
+
+// 默认配置
+const (
+	DefaultListen  = "127.0.0.1:8686"
+	DefaultTimeout = 10 * time.Minute
+)
+
+// DefaultRefs 默认只有推送tag才触发布署
+var DefaultRefs = []string{"refs/tags/*"}
+
+// Config .builderc 中的 gitter 配置块
+type Config struct {
+	Listen   string        `yaml:"listen" json:"listen"`     // 监听地址 默认 127.0.0.1:8686
+	WorkDir  string        `yaml:"work_dir" json:"work_dir"` // 代码拉取与布署历史的目录 每个仓库一个子目录
+	Secret   string        `yaml:"secret" json:"secret"`     // webhook 密钥 不建议写在配置中 优先使用环境变量
+	Repos    []string      `yaml:"repos" json:"repos"`       // 允许布署的仓库全名 为空时不限制
+	Refs     []string      `yaml:"refs" json:"refs"`         // 触发布署的引用 支持通配 默认 refs/tags/*
+	Timeout  time.Duration `yaml:"timeout" json:"timeout"`   // 单次布署超时 默认10分钟
+	Pipeline []Step        `yaml:"pipeline" json:"pipeline"` // 拉取代码后依次执行的步骤
+}
+
+// Step 布署流水线中的一步 在代码目录下通过 sh -c 执行
+// 可以使用 GITTER_REPO GITTER_REF GITTER_TAG GITTER_BRANCH GITTER_COMMIT GITTER_PUSHER 环境变量
+type Step struct {
+	Name string            `yaml:"name" json:"name"`
+	Run  string            `yaml:"run" json:"run"`
+	Env  map[string]string `yaml:"env" json:"env"`
+}
+
+// Validate 校验配置并填充默认值
+func (c *Config) Validate() error {
+	if c.Listen == "" {
+		c.Listen = DefaultListen
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if len(c.Refs) == 0 {
+		c.Refs = DefaultRefs
+	}
+	if c.WorkDir == "" {
+		return errors.New("gitter 工作目录 work_dir 不能为空")
+	}
+	if c.Secret == "" {
+		return fmt.Errorf("未配置 webhook 密钥, 请执行 iotaer auth login --kind gitter 或设置 %s 环境变量", SecretEnv)
+	}
+	for _, ref := range c.Refs {
+		if _, err := path.Match(ref, ""); err != nil {
+			return fmt.Errorf("引用匹配规则 %s 不正确: %+v", ref, err)
+		}
+	}
+	for i, s := range c.Pipeline {
+		if s.Run == "" {
+			return fmt.Errorf("流水线第 %d 步 %s 没有指定 run", i+1, s.Name)
+		}
+	}
+	return nil
+}
+
+// Accept 事件是否需要布署
+func (c *Config) Accept(e *Event) bool {
+	if e.Deleted {
+		return false
+	}
+	if len(c.Repos) != 0 && !contains(c.Repos, e.Repo) {
+		return false
+	}
+	for _, ref := range c.Refs {
+		if ok, _ := path.Match(ref, e.Ref); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package gitter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// 布署状态
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// maxHistory 历史文件中最多保留的记录数
+const maxHistory = 200
+
+// maxOutput 每一步保留的输出长度 只保留末尾
+const maxOutput = 8 << 10
+
+// Deployment 一次布署记录
+type Deployment struct {
+	ID         int          `json:"id"`
+	Event      Event        `json:"event"`
+	Commit     string       `json:"commit"` // 实际检出的提交
+	Status     string       `json:"status"`
+	Error      string       `json:"error,omitempty"`
+	Steps      []StepResult `json:"steps,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	FinishedAt time.Time    `json:"finished_at,omitempty"`
+}
+
+// StepResult 流水线中一步的执行结果
+type StepResult struct {
+	Name     string        `json:"name"`
+	Output   string        `json:"output"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// clone 复制一份 避免读取时与执行中的更新冲突
+func (d *Deployment) clone() Deployment {
+	c := *d
+	c.Steps = append([]StepResult(nil), d.Steps...)
+	return c
+}
+
+// History 布署历史 持久化为工作目录下的 history.json
+type History struct {
+	file string
+	mu   sync.Mutex
+	list []*Deployment
+}
+
+// OpenHistory 读取工作目录下的布署历史 文件不存在时为空
+func OpenHistory(workDir string) (*History, error) {
+	h := &History{file: filepath.Join(workDir, "history.json")}
+	body, err := ioutil.ReadFile(h.file)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(body, &h.list); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Add 新增一条待执行的记录
+func (h *History) Add(e Event) (*Deployment, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	id := 1
+	if n := len(h.list); n != 0 {
+		id = h.list[n-1].ID + 1
+	}
+	d := &Deployment{ID: id, Event: e, Status: StatusPending, CreatedAt: time.Now()}
+	h.list = append(h.list, d)
+	if len(h.list) > maxHistory {
+		h.list = h.list[len(h.list)-maxHistory:]
+	}
+	return d, h.save()
+}
+
+// Update 修改记录后落盘
+func (h *History) Update(d *Deployment, fn func(d *Deployment)) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fn(d)
+	return h.save()
+}
+
+// List 按时间倒序返回最近 limit 条记录 limit 为0时返回全部
+func (h *History) List(limit int) []Deployment {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out []Deployment
+	for i := len(h.list) - 1; i >= 0; i-- {
+		if limit > 0 && len(out) == limit {
+			break
+		}
+		out = append(out, h.list[i].clone())
+	}
+	return out
+}
+
+// Get 按ID查询记录
+func (h *History) Get(id int) (Deployment, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, d := range h.list {
+		if d.ID == id {
+			return d.clone(), true
+		}
+	}
+	return Deployment{}, false
+}
+
+// interrupt 将未执行完的记录标记为失败
+func (h *History) interrupt(reason string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var changed bool
+	for _, d := range h.list {
+		if d.Status == StatusPending || d.Status == StatusRunning {
+			d.Status, d.Error, d.FinishedAt = StatusFailed, reason, time.Now()
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return h.save()
+}
+
+func (h *History) save() error {
+	body, err := json.MarshalIndent(h.list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.file), 0755); err != nil {
+		return err
+	}
+	tmp := h.file + ".tmp"
+	if err := ioutil.WriteFile(tmp, body, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.file)
+}
@@ -0,0 +1,69 @@
+package gitter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// secretEnvPrefix 本机凭据与 gitter 密钥的环境变量前缀 不传给流水线步骤 避免输出到布署历史中
+const secretEnvPrefix = "IOTAER_"
+
+// stepEnv 流水线步骤可以使用的环境变量 需要的凭据通过步骤的 env 显式指定
+func stepEnv(e *Event, commit string, extra map[string]string) []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, secretEnvPrefix) {
+			env = append(env, kv)
+		}
+	}
+	env = append(env,
+		"GITTER_PROVIDER="+e.Provider,
+		"GITTER_REPO="+e.Repo,
+		"GITTER_REF="+e.Ref,
+		"GITTER_TAG="+e.Tag(),
+		"GITTER_BRANCH="+e.Branch(),
+		"GITTER_COMMIT="+commit,
+		"GITTER_PUSHER="+e.Pusher,
+	)
+	for k, v := range extra {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return env
+}
+
+// runStep 在代码目录下执行一步 输出合并 stdout 与 stderr
+func runStep(ctx context.Context, dir string, s Step, e *Event, commit string) StepResult {
+	start := time.Now()
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.Run)
+	cmd.Dir = dir
+	cmd.Env = stepEnv(e, commit, s.Env)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+
+	name := s.Name
+	if name == "" {
+		name = s.Run
+	}
+	res := StepResult{Name: name, Output: tail(out.String(), maxOutput), Duration: time.Since(start)}
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		res.Error = err.Error()
+	}
+	return res
+}
+
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return "...\n" + s[len(s)-n:]
+}
@@ -0,0 +1,241 @@
+package gitter
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Server 接收 webhook 并依次执行布署
+// 布署在后台串行执行 webhook 请求立即返回 202 与布署记录
+type Server struct {
+	config  *Config
+	history *History
+	auth    AuthFunc
+
+	mu     sync.Mutex // 同一时间只执行一个布署
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewServer 校验配置并读取布署历史
+func NewServer(c *Config) (*Server, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	history, err := OpenHistory(c.WorkDir)
+	if err != nil {
+		return nil, err
+	}
+	// 上次退出时没有执行完的布署不会再继续
+	if err := history.interrupt("gitter 重启, 布署中断"); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{config: c, history: history, ctx: ctx, cancel: cancel}, nil
+}
+
+// SetAuth 设置拉取私有仓库时查找凭据的方法
+func (s *Server) SetAuth(fn AuthFunc) {
+	s.auth = fn
+}
+
+// History 布署历史
+func (s *Server) History() *History {
+	return s.history
+}
+
+// TokenHeader 查询布署历史时携带 webhook 密钥的请求头 也可以使用 Authorization: Bearer <密钥>
+const TokenHeader = "X-Gitter-Token"
+
+// Handler 路由 POST /webhook 接收push事件, GET /deployments[/{id}] 查询布署历史
+// 布署历史中包含步骤的输出 需要携带 webhook 密钥
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook", s.handleWebhook)
+	mux.HandleFunc("/deployments", s.authorized(s.handleList))
+	mux.HandleFunc("/deployments/", s.authorized(s.handleGet))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	return mux
+}
+
+// ListenAndServe 启动服务 ctx 结束时等待执行中的布署完成后退出
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{Addr: s.config.Listen, Handler: s.Handler()}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	logrus.Infof("gitter listen on %s, work dir: %s", s.config.Listen, s.config.WorkDir)
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = srv.Shutdown(shutdownCtx)
+	s.Wait()
+	return nil
+}
+
+// Wait 等待所有已接收的布署执行完成
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// Close 中断执行中的布署
+func (s *Server) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	e, err := ParseEvent(r, s.config.Secret)
+	switch {
+	case errors.Is(err, ErrSignature):
+		logrus.Warnf("gitter: %+v, remote: %s", err, r.RemoteAddr)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case errors.Is(err, ErrUnsupportedEvent):
+		// ping 等事件直接忽略 避免平台标记 webhook 失败
+		writeJSON(w, http.StatusOK, map[string]string{"message": "ignored"})
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.config.Accept(e) {
+		writeJSON(w, http.StatusOK, map[string]string{"message": "ignored", "ref": e.Ref})
+		return
+	}
+	d, err := s.Deploy(e)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, d)
+}
+
+// authorized 校验请求中的密钥
+func (s *Server) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(TokenHeader)
+		if auth := r.Header.Get("Authorization"); token == "" && strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Secret)) != 1 {
+			logrus.Warnf("gitter: unauthorized %s %s, remote: %s", r.Method, r.URL.Path, r.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 20
+	}
+	writeJSON(w, http.StatusOK, s.history.List(limit))
+}
+
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/deployments/"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	d, ok := s.history.Get(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// Deploy 记录并在后台执行一次布署
+func (s *Server) Deploy(e *Event) (Deployment, error) {
+	d, err := s.history.Add(*e)
+	if err != nil {
+		return Deployment{}, err
+	}
+	snapshot := d.clone()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(d)
+	}()
+	return snapshot, nil
+}
+
+// run 拉取代码并执行流水线 任一步失败即停止
+func (s *Server) run(d *Deployment) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.config.Timeout)
+	defer cancel()
+	e := d.Event
+	logrus.Infof("gitter: deploy #%d %s %s by %s", d.ID, e.Repo, e.Ref, e.Pusher)
+	_ = s.history.Update(d, func(d *Deployment) { d.Status = StatusRunning })
+
+	fail := func(err error) {
+		logrus.Errorf("gitter: deploy #%d failed: %+v", d.ID, err)
+		_ = s.history.Update(d, func(d *Deployment) {
+			d.Status, d.Error, d.FinishedAt = StatusFailed, err.Error(), time.Now()
+		})
+	}
+	dir := repoDir(s.config.WorkDir, e.Repo)
+	commit, err := Fetch(ctx, e.CloneURL, e.Ref, dir, s.lookupAuth(&e))
+	if err != nil {
+		fail(err)
+		return
+	}
+	_ = s.history.Update(d, func(d *Deployment) { d.Commit = commit })
+	for _, step := range s.config.Pipeline {
+		res := runStep(ctx, dir, step, &e, commit)
+		_ = s.history.Update(d, func(d *Deployment) { d.Steps = append(d.Steps, res) })
+		if res.Error != "" {
+			fail(errors.New(res.Name + ": " + res.Error))
+			return
+		}
+	}
+	_ = s.history.Update(d, func(d *Deployment) { d.Status, d.FinishedAt = StatusSuccess, time.Now() })
+	logrus.Infof("gitter: deploy #%d %s %s success", d.ID, e.Repo, commit)
+}
+
+// lookupAuth 只有 http(s) 地址需要凭据 ssh 地址使用本机的 ssh key
+func (s *Server) lookupAuth(e *Event) *Auth {
+	if s.auth == nil {
+		return nil
+	}
+	u, err := url.Parse(e.CloneURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+	return s.auth(e.Provider, u.Hostname())
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
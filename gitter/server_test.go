@@ -0,0 +1,162 @@
+package gitter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newBareRepo 创建一个带 v1.0.0 tag 的本地裸仓库 返回仓库路径
+func newBareRepo(t *testing.T, dir string) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	src := filepath.Join(dir, "src")
+	bare := filepath.Join(dir, "user.git")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=gitter", "-c", "user.email=gitter@example.com", "commit", "-q", "-m", "init"},
+		{"tag", "v1.0.0"},
+		{"clone", "-q", "--bare", src, bare},
+	} {
+		if _, err := git(ctx, src, nil, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return bare
+}
+
+func TestServerDeploy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	bare := newBareRepo(t, dir)
+	_ = os.Setenv(SecretEnv, testSecret)
+	defer os.Unsetenv(SecretEnv)
+
+	s, err := NewServer(&Config{
+		WorkDir: filepath.Join(dir, "work"),
+		Secret:  testSecret,
+		Pipeline: []Step{
+			{Name: "check", Run: "test -f main.go && echo deploy $GITTER_REPO $GITTER_TAG $NAME secret=$IOTAER_GITTER_SECRET", Env: map[string]string{"NAME": "user"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	post := func(provider string, body []byte, secret string) *http.Response {
+		r := newWebhookRequest(provider, body, secret)
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/webhook", bytes.NewReader(body))
+		req.Header = r.Header
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post(ProviderGitHub, githubPayload(bare, "refs/tags/v1.0.0"), "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("bad signature: got %d", resp.StatusCode)
+	}
+	if resp := post(ProviderGitHub, githubPayload(bare, "refs/heads/master"), testSecret); resp.StatusCode != http.StatusOK {
+		t.Errorf("branch push should be ignored: got %d", resp.StatusCode)
+	}
+	if resp := post(ProviderGitea, githubPayload(bare, "refs/tags/v1.0.0"), testSecret); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("tag push: got %d", resp.StatusCode)
+	}
+	// 不存在的tag 拉取失败
+	if resp := post(ProviderGitHub, githubPayload(bare, "refs/tags/v9.9.9"), testSecret); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("tag push: got %d", resp.StatusCode)
+	}
+	s.Wait()
+
+	// 布署历史需要携带密钥
+	if resp, err := http.Get(ts.URL + "/deployments"); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("history without token: %v %v", resp, err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/deployments", nil)
+	req.Header.Set(TokenHeader, testSecret)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list []Deployment
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("want 2 deployments, got %d", len(list))
+	}
+	failed, ok := list[0], list[1]
+	if ok.Status != StatusSuccess || len(ok.Steps) != 1 || !strings.Contains(ok.Steps[0].Output, "deploy actor/user v1.0.0 user secret=\n") {
+		t.Errorf("unexpected deployment: %+v", ok)
+	}
+	if ok.Commit == "" {
+		t.Errorf("commit not recorded")
+	}
+	if failed.Status != StatusFailed || failed.Error == "" {
+		t.Errorf("missing tag should fail: %+v", failed)
+	}
+
+	// 历史落盘 重启后可以读取
+	h, err := OpenHistory(filepath.Join(dir, "work"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, found := h.Get(ok.ID); !found || d.Status != StatusSuccess {
+		t.Errorf("history not persisted: %+v", d)
+	}
+}
+
+func TestFetchAuth(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir, err := ioutil.TempDir("", "gitter_auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	headers := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Get("Authorization")
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	_, err = Fetch(context.Background(), ts.URL+"/actor/user.git", "refs/tags/v1.0.0", dir, &Auth{Token: "glpat-x"})
+	if err == nil {
+		t.Fatal("want fetch error")
+	}
+	if got := <-headers; got != "Basic b2F1dGgyOmdscGF0LXg=" {
+		t.Errorf("authorization header: %q", got)
+	}
+	if body, _ := ioutil.ReadFile(filepath.Join(dir, ".git", "config")); strings.Contains(string(body), "glpat-x") {
+		t.Errorf("token leaked into .git/config")
+	}
+}
@@ -0,0 +1,205 @@
+package gitter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// 支持的git托管平台
+const (
+	ProviderGitHub = "github"
+	ProviderGitLab = "gitlab"
+	ProviderGitea  = "gitea"
+)
+
+const (
+	tagPrefix    = "refs/tags/"
+	branchPrefix = "refs/heads/"
+	zeroCommit   = "0000000000000000000000000000000000000000"
+)
+
+// maxPayloadSize webhook 请求体上限
+const maxPayloadSize = 5 << 20
+
+var (
+	// ErrSignature 签名校验失败
+	ErrSignature = errors.New("webhook 签名校验失败")
+	// ErrUnsupportedEvent 不是 push 事件
+	ErrUnsupportedEvent = errors.New("不支持的 webhook 事件")
+)
+
+// Event 从各平台的 push 事件中提取的布署信息
+type Event struct {
+	Provider string `json:"provider"`  // github/gitlab/gitea
+	Repo     string `json:"repo"`      // 仓库全名 如 actor/user
+	CloneURL string `json:"clone_url"` // 拉取地址
+	Ref      string `json:"ref"`       // 完整引用 如 refs/tags/v1.0.0
+	Commit   string `json:"commit"`    // 推送后的提交
+	Pusher   string `json:"pusher"`    // 推送人
+	Deleted  bool   `json:"deleted"`   // 是否是删除分支或tag
+}
+
+// Tag tag名 不是tag推送时为空
+func (e *Event) Tag() string {
+	if strings.HasPrefix(e.Ref, tagPrefix) {
+		return strings.TrimPrefix(e.Ref, tagPrefix)
+	}
+	return ""
+}
+
+// Branch 分支名 不是分支推送时为空
+func (e *Event) Branch() string {
+	if strings.HasPrefix(e.Ref, branchPrefix) {
+		return strings.TrimPrefix(e.Ref, branchPrefix)
+	}
+	return ""
+}
+
+// githubPush github 与 gitea 的 push 事件
+type githubPush struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	Repository struct {
+		FullName string `json:"full_name"`
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+	Pusher struct {
+		Name     string `json:"name"`
+		Username string `json:"username"`
+	} `json:"pusher"`
+}
+
+// gitlabPush gitlab 的 Push Hook 与 Tag Push Hook
+type gitlabPush struct {
+	Ref          string `json:"ref"`
+	After        string `json:"after"`
+	CheckoutSha  string `json:"checkout_sha"`
+	UserUsername string `json:"user_username"`
+	Project      struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+		GitHTTPURL        string `json:"git_http_url"`
+	} `json:"project"`
+}
+
+// DetectProvider 根据请求头判断来源平台
+// gitea 为兼容会同时带上 X-GitHub-Event 需要先判断
+func DetectProvider(h http.Header) string {
+	switch {
+	case h.Get("X-Gitea-Event") != "":
+		return ProviderGitea
+	case h.Get("X-Gitlab-Event") != "":
+		return ProviderGitLab
+	case h.Get("X-GitHub-Event") != "":
+		return ProviderGitHub
+	}
+	return ""
+}
+
+// ParseEvent 校验签名并解析 push 事件
+// github/gitea 使用 hmac-sha256 签名, gitlab 只支持在 X-Gitlab-Token 中携带明文 token
+func ParseEvent(r *http.Request, secret string) (*Event, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxPayloadSize))
+	if err != nil {
+		return nil, fmt.Errorf("读取 webhook 请求体失败: %w", err)
+	}
+	provider := DetectProvider(r.Header)
+	switch provider {
+	case ProviderGitHub:
+		if !verifyHMAC(secret, body, strings.TrimPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")) {
+			return nil, ErrSignature
+		}
+		if r.Header.Get("X-GitHub-Event") != "push" {
+			return nil, ErrUnsupportedEvent
+		}
+		return parseGithubPush(provider, body)
+	case ProviderGitea:
+		if !verifyHMAC(secret, body, r.Header.Get("X-Gitea-Signature")) {
+			return nil, ErrSignature
+		}
+		if r.Header.Get("X-Gitea-Event") != "push" {
+			return nil, ErrUnsupportedEvent
+		}
+		return parseGithubPush(provider, body)
+	case ProviderGitLab:
+		token := r.Header.Get("X-Gitlab-Token")
+		if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return nil, ErrSignature
+		}
+		switch r.Header.Get("X-Gitlab-Event") {
+		case "Push Hook", "Tag Push Hook":
+		default:
+			return nil, ErrUnsupportedEvent
+		}
+		return parseGitlabPush(body)
+	}
+	return nil, fmt.Errorf("无法识别 webhook 来源, 仅支持 %s/%s/%s", ProviderGitHub, ProviderGitLab, ProviderGitea)
+}
+
+// Sign 计算 github/gitea 格式的签名 hex(hmac-sha256(body))
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func verifyHMAC(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	want, _ := hex.DecodeString(Sign(secret, body))
+	return hmac.Equal(got, want)
+}
+
+func parseGithubPush(provider string, body []byte) (*Event, error) {
+	var p githubPush
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("解析 %s push 事件失败: %w", provider, err)
+	}
+	pusher := p.Pusher.Name
+	if pusher == "" {
+		pusher = p.Pusher.Username
+	}
+	return &Event{
+		Provider: provider,
+		Repo:     p.Repository.FullName,
+		CloneURL: p.Repository.CloneURL,
+		Ref:      p.Ref,
+		Commit:   p.After,
+		Pusher:   pusher,
+		Deleted:  p.Deleted || p.After == zeroCommit,
+	}, nil
+}
+
+func parseGitlabPush(body []byte) (*Event, error) {
+	var p gitlabPush
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, fmt.Errorf("解析 gitlab push 事件失败: %w", err)
+	}
+	commit := p.CheckoutSha
+	if commit == "" {
+		commit = p.After
+	}
+	return &Event{
+		Provider: ProviderGitLab,
+		Repo:     p.Project.PathWithNamespace,
+		CloneURL: p.Project.GitHTTPURL,
+		Ref:      p.Ref,
+		Commit:   commit,
+		Pusher:   p.UserUsername,
+		Deleted:  p.After == zeroCommit,
+	}, nil
+}
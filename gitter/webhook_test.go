@@ -0,0 +1,87 @@
+package gitter
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testSecret = "s3cret"
+
+func newWebhookRequest(provider string, body []byte, secret string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+	switch provider {
+	case ProviderGitHub:
+		r.Header.Set("X-GitHub-Event", "push")
+		r.Header.Set("X-Hub-Signature-256", "sha256="+Sign(secret, body))
+	case ProviderGitea:
+		// gitea 同时带有 github 的头
+		r.Header.Set("X-GitHub-Event", "push")
+		r.Header.Set("X-Gitea-Event", "push")
+		r.Header.Set("X-Gitea-Signature", Sign(secret, body))
+	case ProviderGitLab:
+		r.Header.Set("X-Gitlab-Event", "Tag Push Hook")
+		r.Header.Set("X-Gitlab-Token", secret)
+	}
+	return r
+}
+
+func githubPayload(cloneURL, ref string) []byte {
+	return []byte(`{"ref":"` + ref + `","after":"1111111111111111111111111111111111111111","deleted":false,` +
+		`"repository":{"full_name":"actor/user","clone_url":"` + cloneURL + `"},"pusher":{"name":"alice"}}`)
+}
+
+func TestParseEvent(t *testing.T) {
+	gitlab := []byte(`{"ref":"refs/tags/v1.0.0","after":"2222222222222222222222222222222222222222","checkout_sha":"3333333333333333333333333333333333333333",` +
+		`"user_username":"bob","project":{"path_with_namespace":"actor/user","git_http_url":"https://gitlab.example.com/actor/user.git"}}`)
+	cases := []struct {
+		provider string
+		body     []byte
+		pusher   string
+	}{
+		{ProviderGitHub, githubPayload("https://github.com/actor/user.git", "refs/tags/v1.0.0"), "alice"},
+		{ProviderGitea, githubPayload("https://gitea.example.com/actor/user.git", "refs/tags/v1.0.0"), "alice"},
+		{ProviderGitLab, gitlab, "bob"},
+	}
+	for _, c := range cases {
+		e, err := ParseEvent(newWebhookRequest(c.provider, c.body, testSecret), testSecret)
+		if err != nil {
+			t.Fatalf("%s: %v", c.provider, err)
+		}
+		if e.Provider != c.provider || e.Repo != "actor/user" || e.Tag() != "v1.0.0" || e.Pusher != c.pusher {
+			t.Errorf("%s: unexpected event %+v", c.provider, e)
+		}
+		if _, err := ParseEvent(newWebhookRequest(c.provider, c.body, "wrong"), testSecret); !errors.Is(err, ErrSignature) {
+			t.Errorf("%s: want signature error, got %v", c.provider, err)
+		}
+	}
+	if e, _ := ParseEvent(newWebhookRequest(ProviderGitLab, gitlab, testSecret), testSecret); e.Commit != "3333333333333333333333333333333333333333" {
+		t.Errorf("gitlab should use checkout_sha, got %s", e.Commit)
+	}
+
+	ping := newWebhookRequest(ProviderGitHub, []byte(`{"zen":"hi"}`), testSecret)
+	ping.Header.Set("X-GitHub-Event", "ping")
+	if _, err := ParseEvent(ping, testSecret); !errors.Is(err, ErrUnsupportedEvent) {
+		t.Errorf("ping: want unsupported event, got %v", err)
+	}
+}
+
+func TestConfigAccept(t *testing.T) {
+	c := &Config{WorkDir: "/tmp", Secret: testSecret, Repos: []string{"actor/user"}}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[*Event]bool{
+		{Repo: "actor/user", Ref: "refs/tags/v1.0.0"}:                true,
+		{Repo: "actor/user", Ref: "refs/heads/master"}:               false,
+		{Repo: "actor/order", Ref: "refs/tags/v1.0.0"}:               false,
+		{Repo: "actor/user", Ref: "refs/tags/v1.0.0", Deleted: true}: false,
+	}
+	for e, want := range cases {
+		if got := c.Accept(e); got != want {
+			t.Errorf("%+v: got %v want %v", e, got, want)
+		}
+	}
+}
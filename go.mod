@@ -5,9 +5,14 @@ go 1.16
 require (
 	github.com/actorbuf/proto-format v0.0.0-20220211085837-e558658686a6
 	github.com/actorbuf/proto-parser v0.0.0-20220214035251-4ae3a17066c3
+	github.com/antonfisher/nested-logrus-formatter v1.3.1
 	github.com/elliotchance/pie v1.39.0
+	github.com/emicklei/proto v1.9.1
 	github.com/guonaihong/gout v0.2.11
 	github.com/sirupsen/logrus v1.8.1
 	github.com/spf13/cobra v1.3.0
+	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211
+	google.golang.org/grpc v1.44.0
+	google.golang.org/protobuf v1.27.1
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/actorbuf/iotaer/apigen"
+	"github.com/actorbuf/iotaer/apiimport"
+	"github.com/actorbuf/iotaer/protodef"
+	"github.com/actorbuf/iotaer/toolkit"
+	proto "github.com/actorbuf/proto-parser"
+	"github.com/spf13/cobra"
+)
+
+func importCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "从其他格式的接口定义导入路由组",
+		Long:  "从其他格式的接口定义导入路由组, 导入后执行 builder gen 生成代码",
+	}
+	cmd.AddCommand(importOpenAPICommand())
+	return cmd
+}
+
+func importOpenAPICommand() *cobra.Command {
+	var pbPath, group, prefix, genTo string
+	var logicDir = apigen.DirLogic
+	var code = true
+	cmd := &cobra.Command{
+		Use:   "openapi <spec>",
+		Short: "从OpenAPI/Swagger文档导入路由组",
+		Long: "按 OpenAPI 3 或 Swagger 2 文档(yaml/json)在 proto 中新增路由组与接口, 与 addroute/addapi 相同: " +
+			"query 参数与请求体的属性为 Req 的字段, 成功响应的属性为 Resp 的字段, 引用的 schema 与内联对象生成消息, " +
+			"属性名转换为下划线形式, 与原名称不同时通过 @json 保持原名称. 默认同时生成 controller、logic 与测试代码. " +
+			"gen 只注册由字母、数字、_ 与 / 组成的静态路由, 文档中有路径参数或其他字符时拒绝导入",
+		Example: "builder import openapi spec.yaml --path model/partner.proto\n" +
+			"builder import openapi swagger.json --path model/partner.proto --name PartnerApi --prefix /api/partner --code=false",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if pbPath == "" {
+				_, _ = fmt.Fprintf(os.Stderr, "import openapi --path 不能为空\n")
+				os.Exit(1)
+			}
+			if group == "" {
+				group = apiimport.GroupName(pbPath)
+			}
+			spec, err := apiimport.Load(args[0])
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			plan, err := apiimport.Convert(spec, apiimport.Option{Group: group, Prefix: prefix})
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if err := importPlan(pbPath, plan, genTo, logicDir, code); err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			_, _ = fmt.Fprintln(os.Stdout, "proto已更新, 请执行 builder gen 重新生成pb代码")
+		},
+	}
+	cmd.Flags().StringVar(&pbPath, "path", pbPath, "要导入到的proto文件, 不存在时创建")
+	cmd.Flags().StringVar(&group, "name", group, "路由组名称, 默认为proto文件名的大驼峰形式加Api")
+	cmd.Flags().StringVar(&prefix, "prefix", prefix, "路由组前缀, 默认为所有接口路径的公共前缀")
+	cmd.Flags().StringVar(&genTo, "gento", genTo, "路由组的controller文件, 默认为./internal/controller/路由组_controller.go")
+	cmd.Flags().StringVar(&logicDir, "logic", logicDir, "logic层目录 相对项目根目录")
+	cmd.Flags().BoolVar(&code, "code", code, "生成controller、logic与测试代码, --code=false 只修改proto")
+	return cmd
+}
+
+// importPlan 与 addroute/addapi 相同的方式写入路由组、接口与消息 写入前检查重名避免只导入一部分
+func importPlan(pbPath string, plan *apiimport.Plan, genTo, logicDir string, code bool) error {
+	if _, err := os.Stat(pbPath); os.IsNotExist(err) {
+		if err := createProto(pbPath); err != nil {
+			return err
+		}
+	}
+	f, err := protodef.ParseFile(pbPath)
+	if err != nil {
+		return err
+	}
+	for _, s := range f.Services {
+		if s.Name == plan.Group {
+			return fmt.Errorf("%s 中已存在 service %s, 请通过 --name 指定其他名称", pbPath, plan.Group)
+		}
+	}
+	names := map[string]bool{}
+	for _, m := range plan.Messages {
+		names[m.Name] = true
+	}
+	for _, api := range plan.APIs {
+		names[api.Name+"Req"], names[api.Name+"Resp"] = true, true
+	}
+	for _, m := range f.Messages {
+		if names[m.Name] {
+			return fmt.Errorf("%s 中已存在 message %s", pbPath, m.Name)
+		}
+	}
+
+	if genTo == "" {
+		genTo = fmt.Sprintf("./internal/controller/%s_controller.go", toolkit.Calm2Case(plan.Group))
+	}
+	routeAPI := plan.Prefix
+	if routeAPI == "" {
+		routeAPI = "/"
+	}
+	if err := proto.AddRoute(pbPath, plan.Group, routeAPI, genTo); err != nil {
+		return fmt.Errorf("add route err: %+v", err)
+	}
+	if err := apigen.AddMessages(pbPath, plan.Messages); err != nil {
+		return err
+	}
+	for _, api := range plan.APIs {
+		if err := proto.AddAPI(pbPath, plan.Group, api.Name, api.Method); err != nil {
+			return fmt.Errorf("%s: %v", api.Name, err)
+		}
+		o := &apigen.Option{Proto: pbPath, Service: plan.Group, Name: api.Name, Desc: api.Desc, Path: api.Path,
+			Fields: api.Fields, RespFields: api.RespFields, Logic: logicDir}
+		if err := apigen.UpdateProto(pbPath, o); err != nil {
+			return fmt.Errorf("%s 写入字段失败: %v", api.Name, err)
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "%-8s %s %s %s %s\n", "update", pbPath, api.Name, api.Method, routeAPI+api.Path)
+		if !code {
+			continue
+		}
+		if err := apigen.Generate(o); err != nil {
+			return fmt.Errorf("proto已更新, %s 生成代码失败: %v", api.Name, err)
+		}
+	}
+	return nil
+}
+
+// createProto 与 addroute 相同 包名为所在目录名
+func createProto(pbPath string) error {
+	abs, err := filepath.Abs(pbPath)
+	if err != nil {
+		return err
+	}
+	pkgName := toolkit.Calm2Case(strings.ReplaceAll(filepath.Base(filepath.Dir(abs)), "-", "_"))
+	if err := os.MkdirAll(filepath.Dir(pbPath), 0755); err != nil {
+		return err
+	}
+	content := fmt.Sprintf("syntax = \"proto3\";\npackage %s;\n", pkgName)
+	if err := ioutil.WriteFile(pbPath, []byte(content), 0644); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "%-8s %s \n", "create", pbPath)
+	return nil
+}
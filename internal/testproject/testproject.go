@@ -0,0 +1,57 @@
+package testproject
+
+import (
+	"embed"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/actorbuf/iotaer/apidoc"
+	"github.com/actorbuf/iotaer/protodef"
+)
+
+// testdata 各包测试共用的 proto 项目 model 为项目中的 proto common 为引用的公共 proto
+//
+//go:embed testdata
+var testdata embed.FS
+
+// parse 按项目内的路径解析 testdata 中的 proto
+func parse(t testing.TB, path string) *protodef.File {
+	src, err := testdata.ReadFile("testdata/" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := protodef.Parse(path, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// Files 测试项目的 proto 与其引用的公共 proto 供 apidoc 自身的测试使用
+func Files(t testing.TB) (files, includes []*protodef.File) {
+	return []*protodef.File{parse(t, "model/user.proto")}, []*protodef.File{parse(t, "common/page.proto")}
+}
+
+// Load 加载测试项目的接口文档
+func Load(t testing.TB) *apidoc.Project {
+	p, err := apidoc.Load(Files(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// WriteFiles 在 dir 下写入文件 文件名使用 / 分隔 自动创建上级目录
+func WriteFiles(t testing.TB, dir string, files map[string]string) {
+	for name, body := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ProbeOption 健康检查参数
+// 有http端口时使用 httpGet 探测 Path, 否则使用 tcpSocket 探测端口
+// 脚手架没有注册健康检查路由 http 端口需要指定服务中实际存在的 Path
+type ProbeOption struct {
+	Path                string `yaml:"path" json:"path"`                                   // 健康检查路径 http 端口必填
+	InitialDelaySeconds int    `yaml:"initial_delay_seconds" json:"initial_delay_seconds"` // 首次探测延迟
+	PeriodSeconds       int    `yaml:"period_seconds" json:"period_seconds"`               // 探测间隔
+	TimeoutSeconds      int    `yaml:"timeout_seconds" json:"timeout_seconds"`             // 探测超时
+	FailureThreshold    int    `yaml:"failure_threshold" json:"failure_threshold"`         // 失败阈值
+	Startup             bool   `yaml:"startup" json:"startup"`                             // 是否生成 startupProbe 适用于启动慢的服务
+}
+
+// HPAOption 水平自动扩缩容参数
+type HPAOption struct {
+	MinReplicas    int32 `yaml:"min_replicas" json:"min_replicas"`       // 最小pod数
+	MaxReplicas    int32 `yaml:"max_replicas" json:"max_replicas"`       // 最大pod数
+	CPUUtilization int32 `yaml:"cpu_utilization" json:"cpu_utilization"` // cpu平均使用率阈值 百分比
+	MemUtilization int32 `yaml:"mem_utilization" json:"mem_utilization"` // memory平均使用率阈值 百分比
+}
+
+// PDBOption 中断预算参数 二选一 可以是数字也可以为百分比
+type PDBOption struct {
+	MinAvailable   string `yaml:"min_available" json:"min_available"`
+	MaxUnavailable string `yaml:"max_unavailable" json:"max_unavailable"`
+}
+
+// validate 校验健康检查参数 http 为 true 时使用 httpGet 探测
+func (p *ProbeOption) validate(v Version, http bool) error {
+	if http && p.Path == "" {
+		return errors.New("http 端口的健康检查需要指定路径 (probe.path/--probePath), 脚手架没有默认的健康检查接口")
+	}
+	if p.Path != "" && p.Path[0] != '/' {
+		return fmt.Errorf("健康检查路径需以 / 开头: %s", p.Path)
+	}
+	if p.InitialDelaySeconds < 0 || p.PeriodSeconds < 0 || p.TimeoutSeconds < 0 || p.FailureThreshold < 0 {
+		return errors.New("健康检查的时间参数不能为负数")
+	}
+	if p.Startup && !v.AtLeast(1, 18) {
+		return fmt.Errorf("startupProbe 需要 k8s v1.18 及以上版本, 当前: %s", v)
+	}
+	return nil
+}
+
+// build 生成 liveness/readiness/startup 探针 port 为0时不生成
+func (p *ProbeOption) build(port int, http bool) (liveness, readiness, startup *Probe) {
+	if port == 0 {
+		return nil, nil, nil
+	}
+	newProbe := func() *Probe {
+		probe := &Probe{
+			InitialDelaySeconds: p.InitialDelaySeconds,
+			PeriodSeconds:       p.PeriodSeconds,
+			TimeoutSeconds:      p.TimeoutSeconds,
+			FailureThreshold:    p.FailureThreshold,
+		}
+		if http {
+			probe.HTTPGet = &HTTPGetAction{Path: p.Path, Port: FromInt(port)}
+		} else {
+			probe.TCPSocket = &TCPSocketAction{Port: FromInt(port)}
+		}
+		return probe
+	}
+	liveness, readiness = newProbe(), newProbe()
+	if p.Startup {
+		// 启动阶段允许更长时间 探测成功前不会执行 liveness
+		startup = newProbe()
+		startup.InitialDelaySeconds = 0
+		startup.FailureThreshold = 30
+	}
+	return liveness, readiness, startup
+}
+
+// validate 校验扩缩容参数
+func (h *HPAOption) validate(v Version) error {
+	if h.MinReplicas < 1 {
+		return fmt.Errorf("hpa 最小pod数需大于0: %d", h.MinReplicas)
+	}
+	if h.MaxReplicas < h.MinReplicas {
+		return fmt.Errorf("hpa 最大pod数 %d 小于最小pod数 %d", h.MaxReplicas, h.MinReplicas)
+	}
+	if h.CPUUtilization == 0 && h.MemUtilization == 0 {
+		return errors.New("hpa 至少需要指定 cpu 或 memory 使用率阈值")
+	}
+	if h.CPUUtilization < 0 || h.MemUtilization < 0 {
+		return errors.New("hpa 使用率阈值不能为负数")
+	}
+	if h.MemUtilization != 0 && !v.AtLeast(1, 12) {
+		return fmt.Errorf("按 memory 扩缩容需要 k8s v1.12 及以上版本, 当前: %s", v)
+	}
+	return nil
+}
+
+// validate 校验中断预算参数
+func (p *PDBOption) validate() error {
+	if (p.MinAvailable == "") == (p.MaxUnavailable == "") {
+		return errors.New("pdb 的 minAvailable 与 maxUnavailable 需要且只能指定一个")
+	}
+	if p.MinAvailable != "" {
+		_, err := ParseIntOrPercent("pdb minAvailable", p.MinAvailable)
+		return err
+	}
+	_, err := ParseIntOrPercent("pdb maxUnavailable", p.MaxUnavailable)
+	return err
+}
+
+// BuildHPA 生成针对 Deployment 的 HPA
+func BuildHPA(name, namespace string, h *HPAOption, v Version) (*HorizontalPodAutoscaler, error) {
+	if err := h.validate(v); err != nil {
+		return nil, err
+	}
+	hpa := &HorizontalPodAutoscaler{
+		TypeMeta: TypeMeta{APIVersion: v.hpaAPIVersion(), Kind: "HorizontalPodAutoscaler"},
+		Metadata: ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": name}},
+		Spec: HPASpec{
+			ScaleTargetRef: CrossVersionObjectReference{
+				APIVersion: v.deploymentAPIVersion(),
+				Kind:       "Deployment",
+				Name:       name,
+			},
+			MinReplicas: h.MinReplicas,
+			MaxReplicas: h.MaxReplicas,
+		},
+	}
+	if hpa.APIVersion == "autoscaling/v1" {
+		hpa.Spec.TargetCPUUtilizationPercentage = h.CPUUtilization
+		return hpa, nil
+	}
+	for _, m := range []struct {
+		name  string
+		value int32
+	}{{"cpu", h.CPUUtilization}, {"memory", h.MemUtilization}} {
+		if m.value == 0 {
+			continue
+		}
+		hpa.Spec.Metrics = append(hpa.Spec.Metrics, MetricSpec{
+			Type: "Resource",
+			Resource: ResourceMetricSource{
+				Name:   m.name,
+				Target: MetricTarget{Type: "Utilization", AverageUtilization: m.value},
+			},
+		})
+	}
+	return hpa, nil
+}
+
+// BuildPDB 生成选中 labels 的 pod 的中断预算
+func BuildPDB(name, namespace string, labels map[string]string, p *PDBOption, v Version) (*PodDisruptionBudget, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	pdb := &PodDisruptionBudget{
+		TypeMeta: TypeMeta{APIVersion: v.pdbAPIVersion(), Kind: "PodDisruptionBudget"},
+		Metadata: ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": name}},
+		Spec:     PDBSpec{Selector: LabelSelector{MatchLabels: labels}},
+	}
+	if p.MinAvailable != "" {
+		min, _ := ParseIntOrPercent("pdb minAvailable", p.MinAvailable)
+		pdb.Spec.MinAvailable = &min
+	} else {
+		max, _ := ParseIntOrPercent("pdb maxUnavailable", p.MaxUnavailable)
+		pdb.Spec.MaxUnavailable = &max
+	}
+	return pdb, nil
+}
@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestBuildAutoscale(t *testing.T) {
+	cases := []struct {
+		version Version
+		want    []string
+	}{
+		{Version{1, 11}, []string{"autoscaling/v1", "targetCPUUtilizationPercentage: 70", "policy/v1beta1"}},
+		{Version{1, 20}, []string{"autoscaling/v2beta2", "averageUtilization: 80", "policy/v1beta1"}},
+		{Version{1, 25}, []string{"autoscaling/v2", "name: memory", "policy/v1", "startupProbe:"}},
+	}
+	for _, c := range cases {
+		o := newTestOption()
+		o.Version = c.version
+		o.Probe = &ProbeOption{Path: "/health", PeriodSeconds: 10}
+		o.HPA = &HPAOption{MinReplicas: 2, MaxReplicas: 10, CPUUtilization: 70}
+		if c.version.AtLeast(1, 12) {
+			o.HPA.MemUtilization = 80
+		}
+		if c.version.AtLeast(1, 18) {
+			o.Probe.Startup = true
+		}
+		o.PDB = &PDBOption{MinAvailable: "50%"}
+		objects, err := BuildDeployment(o)
+		if err != nil {
+			t.Fatalf("%s: %v", c.version, err)
+		}
+		if len(objects) != 4 {
+			t.Fatalf("%s: want 4 objects, got %d", c.version, len(objects))
+		}
+		var buf bytes.Buffer
+		if err := Render(&buf, objects...); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		for _, want := range append(c.want, "path: /health", "minAvailable: 50%") {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s: output missing %q:\n%s", c.version, want, out)
+			}
+		}
+	}
+}
+
+func TestGrpcProbe(t *testing.T) {
+	o := newTestOption()
+	o.PortName = "grpc"
+	o.Probe = &ProbeOption{}
+	objects, err := BuildDeployment(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := objects[0].(*Deployment).Spec.Template.Spec.Containers[0]
+	if c.LivenessProbe.HTTPGet != nil || c.LivenessProbe.TCPSocket == nil {
+		t.Errorf("grpc port should use tcpSocket probe: %+v", c.LivenessProbe)
+	}
+}
+
+func TestAutoscaleValidate(t *testing.T) {
+	cases := map[string]func(o *DeploymentOption){
+		"hpa min zero":      func(o *DeploymentOption) { o.HPA = &HPAOption{MaxReplicas: 2, CPUUtilization: 50} },
+		"hpa max below min": func(o *DeploymentOption) { o.HPA = &HPAOption{MinReplicas: 3, MaxReplicas: 2, CPUUtilization: 50} },
+		"hpa no metric":     func(o *DeploymentOption) { o.HPA = &HPAOption{MinReplicas: 1, MaxReplicas: 2} },
+		"hpa old memory": func(o *DeploymentOption) {
+			o.HPA = &HPAOption{MinReplicas: 1, MaxReplicas: 2, MemUtilization: 50}
+			o.Version = Version{1, 11}
+		},
+		"pdb both":      func(o *DeploymentOption) { o.PDB = &PDBOption{MinAvailable: "1", MaxUnavailable: "1"} },
+		"pdb none":      func(o *DeploymentOption) { o.PDB = &PDBOption{} },
+		"probe path":    func(o *DeploymentOption) { o.Probe = &ProbeOption{Path: "health"} },
+		"probe no path": func(o *DeploymentOption) { o.Probe = &ProbeOption{} },
+		"old startup": func(o *DeploymentOption) {
+			o.Probe = &ProbeOption{Path: "/health", Startup: true}
+			o.Version = Version{1, 17}
+		},
+		"probe negative": func(o *DeploymentOption) { o.Probe = &ProbeOption{Path: "/health", PeriodSeconds: -1} },
+	}
+	for name, mutate := range cases {
+		o := newTestOption()
+		mutate(o)
+		if err := o.Validate(); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var validProtocols = []string{"TCP", "UDP", "SCTP"}
+
+// DeploymentOption 生成 Deployment 与 Service 需要的参数
+type DeploymentOption struct {
+	Name           string       // 服务名
+	Namespace      string       // 命名空间
+	Image          string       // 镜像 默认为 服务名:latest
+	StartCommand   string       // 启动命令
+	Replicas       int32        // pod数
+	MaxSurge       string       // 滚动更新时可以额外创建的pod 数字或百分比
+	MaxUnavailable string       // 滚动更新时可以不可用的pod 数字或百分比
+	CPULimit       string       // cpu最大使用资源
+	MemLimit       string       // memory最大使用资源
+	CPURequest     string       // cpu预划资源
+	MemRequest     string       // memory预划资源
+	Port           int          // 容器启动端口 为0时不生成 Service
+	TargetPort     int          // Service对外端口 为0时与 Port 一致
+	Protocol       string       // 端口协议
+	PortName       string       // 端口名称
+	AppProtocol    string       // Service的appProtocol字段
+	NeedVersion    bool         // 是否给 Deployment 加上版本号 用于istio按版本分流
+	Version        Version      // k8s版本
+	Probe          *ProbeOption // 健康检查 为空时不生成探针
+	HPA            *HPAOption   // 水平自动扩缩容 为空时不生成
+	PDB            *PDBOption   // 中断预算 为空时不生成
+}
+
+// Labels pod标签
+func (o *DeploymentOption) Labels() map[string]string {
+	labels := map[string]string{"app": o.Name}
+	if o.NeedVersion {
+		labels["version"] = "v1"
+	}
+	return labels
+}
+
+// Validate 校验参数
+func (o *DeploymentOption) Validate() error {
+	if o.Name == "" {
+		return errors.New("服务名 --svc 不能为空")
+	}
+	if o.StartCommand == "" {
+		return errors.New("启动命令 --startCommand 不能为空")
+	}
+	if o.Replicas < 0 {
+		return fmt.Errorf("replicas 不能为负数: %d", o.Replicas)
+	}
+	surge, err := ParseIntOrPercent("maxSurge", o.MaxSurge)
+	if err != nil {
+		return err
+	}
+	unavailable, err := ParseIntOrPercent("maxUnavailable", o.MaxUnavailable)
+	if err != nil {
+		return err
+	}
+	if surge.isZero() && unavailable.isZero() {
+		return errors.New("maxSurge 与 maxUnavailable 不能同时为 0")
+	}
+	if err := checkResourcePair("cpu", o.CPURequest, o.CPULimit); err != nil {
+		return err
+	}
+	if err := checkResourcePair("memory", o.MemRequest, o.MemLimit); err != nil {
+		return err
+	}
+	if o.Port < 0 || o.Port > 65535 {
+		return fmt.Errorf("启动端口不正确: %d", o.Port)
+	}
+	if o.TargetPort < 0 || o.TargetPort > 65535 {
+		return fmt.Errorf("服务对外端口不正确: %d", o.TargetPort)
+	}
+	if o.Protocol != "" && !contains(validProtocols, o.Protocol) {
+		return fmt.Errorf("协议 %s 不支持, 可选 %v", o.Protocol, validProtocols)
+	}
+	if o.AppProtocol != "" && !o.Version.AtLeast(1, 19) {
+		return fmt.Errorf("appProtocol 需要 k8s v1.19 及以上版本, 当前: %s", o.Version)
+	}
+	if o.Probe != nil {
+		if err := o.Probe.validate(o.Version, o.Port > 0 && o.httpProbe()); err != nil {
+			return err
+		}
+	}
+	if o.HPA != nil {
+		if err := o.HPA.validate(o.Version); err != nil {
+			return err
+		}
+	}
+	if o.PDB != nil {
+		if err := o.PDB.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// httpProbe 是否使用 httpGet 探测 grpc 端口无法用 httpGet 探测
+func (o *DeploymentOption) httpProbe() bool {
+	return o.PortName != "grpc" && o.AppProtocol != "grpc"
+}
+
+// BuildDeployment 生成 Deployment 如果指定了端口会同时生成 Service, 按需生成 HPA 与 PDB
+func BuildDeployment(o *DeploymentOption) ([]interface{}, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	surge, _ := ParseIntOrPercent("maxSurge", o.MaxSurge)
+	unavailable, _ := ParseIntOrPercent("maxUnavailable", o.MaxUnavailable)
+
+	name := o.Name
+	if o.NeedVersion {
+		name = fmt.Sprintf("%s-%s", o.Name, o.Labels()["version"])
+	}
+	image := o.Image
+	if image == "" {
+		image = fmt.Sprintf("%s:latest", o.Name)
+	}
+
+	container := Container{
+		Name:            o.Name,
+		Image:           image,
+		ImagePullPolicy: "IfNotPresent",
+		Command:         SplitCommand(o.StartCommand),
+		Resources: ResourceRequirements{
+			Limits:   resourceList(o.CPULimit, o.MemLimit),
+			Requests: resourceList(o.CPURequest, o.MemRequest),
+		},
+	}
+	if o.Port > 0 {
+		container.Ports = []ContainerPort{{
+			Name:          o.PortName,
+			ContainerPort: o.Port,
+			Protocol:      o.Protocol,
+		}}
+	}
+	if o.Probe != nil {
+		container.LivenessProbe, container.ReadinessProbe, container.StartupProbe = o.Probe.build(o.Port, o.httpProbe())
+	}
+
+	deployment := &Deployment{
+		TypeMeta: TypeMeta{APIVersion: o.Version.deploymentAPIVersion(), Kind: "Deployment"},
+		Metadata: ObjectMeta{Name: name, Namespace: o.Namespace, Labels: o.Labels()},
+		Spec: DeploymentSpec{
+			Replicas: o.Replicas,
+			Selector: LabelSelector{MatchLabels: o.Labels()},
+			Strategy: DeploymentStrategy{
+				Type: "RollingUpdate",
+				RollingUpdate: &RollingUpdateDeployment{
+					MaxSurge:       surge,
+					MaxUnavailable: unavailable,
+				},
+			},
+			Template: PodTemplateSpec{
+				Metadata: ObjectMeta{Labels: o.Labels()},
+				Spec:     PodSpec{Containers: []Container{container}},
+			},
+		},
+	}
+
+	objects := []interface{}{deployment}
+	if o.Port != 0 {
+		objects = append(objects, o.buildService())
+	}
+	if o.HPA != nil {
+		hpa, err := BuildHPA(name, o.Namespace, o.HPA, o.Version)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, hpa)
+	}
+	if o.PDB != nil {
+		pdb, err := BuildPDB(name, o.Namespace, o.Labels(), o.PDB, o.Version)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, pdb)
+	}
+	return objects, nil
+}
+
+// buildService 生成 Service 不是网络服务时不需要
+func (o *DeploymentOption) buildService() *Service {
+	servicePort := o.TargetPort
+	if servicePort == 0 {
+		servicePort = o.Port
+	}
+	return &Service{
+		TypeMeta: TypeMeta{APIVersion: "v1", Kind: "Service"},
+		Metadata: ObjectMeta{Name: o.Name, Namespace: o.Namespace, Labels: map[string]string{"app": o.Name}},
+		Spec: ServiceSpec{
+			Type:     "ClusterIP",
+			Selector: map[string]string{"app": o.Name},
+			Ports: []ServicePort{{
+				Name:        o.PortName,
+				Protocol:    o.Protocol,
+				AppProtocol: o.AppProtocol,
+				Port:        servicePort,
+				TargetPort:  FromInt(o.Port),
+			}},
+		},
+	}
+}
+
+func resourceList(cpu, mem string) ResourceList {
+	list := ResourceList{}
+	if cpu != "" {
+		list["cpu"] = cpu
+	}
+	if mem != "" {
+		list["memory"] = mem
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
+
+// SplitCommand 按空白切分启动命令 支持单双引号包裹含空格的参数
+func SplitCommand(s string) []string {
+	var args []string
+	var buf strings.Builder
+	var quote rune
+	var inArg bool
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+				continue
+			}
+			buf.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, buf.String())
+				buf.Reset()
+				inArg = false
+			}
+		default:
+			buf.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, buf.String())
+	}
+	return args
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package k8s
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func newTestOption() *DeploymentOption {
+	return &DeploymentOption{
+		Name:           "user",
+		Namespace:      "actor",
+		StartCommand:   `./user api --config "config prod.yaml"`,
+		Replicas:       2,
+		MaxSurge:       "100%",
+		MaxUnavailable: "0%",
+		CPULimit:       "400m",
+		MemLimit:       "512Mi",
+		CPURequest:     "200m",
+		MemRequest:     "200Mi",
+		Port:           8080,
+		Protocol:       "TCP",
+		PortName:       "http",
+		Version:        DefaultVersion,
+	}
+}
+
+func TestBuildDeployment(t *testing.T) {
+	objects, err := BuildDeployment(newTestOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 {
+		t.Fatalf("want deployment and service, got %d objects", len(objects))
+	}
+	var buf bytes.Buffer
+	if err := Render(&buf, objects...); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"apiVersion: apps/v1",
+		"kind: Service",
+		"maxSurge: 100%",
+		"targetPort: 8080",
+		"- config prod.yaml",
+		"\n---\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDeploymentValidate(t *testing.T) {
+	cases := map[string]func(o *DeploymentOption){
+		"bad quantity":       func(o *DeploymentOption) { o.CPULimit = "4 cores" },
+		"request over limit": func(o *DeploymentOption) { o.MemRequest = "1Gi" },
+		"bad percent":        func(o *DeploymentOption) { o.MaxSurge = "120%" },
+		"both zero":          func(o *DeploymentOption) { o.MaxSurge = "0" },
+		"old appProtocol":    func(o *DeploymentOption) { o.AppProtocol = "http"; o.Version = Version{1, 18} },
+		"bad protocol":       func(o *DeploymentOption) { o.Protocol = "HTTP" },
+	}
+	for name, mutate := range cases {
+		o := newTestOption()
+		mutate(o)
+		if err := o.Validate(); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	cases := map[string]float64{
+		"400m":  0.4,
+		"1":     1,
+		"0.5":   0.5,
+		"512Mi": 512 * 1024 * 1024,
+		"1e3":   1000,
+		"2Ei":   2 * (1 << 60),
+	}
+	for in, want := range cases {
+		got, err := ParseQuantity(in)
+		if err != nil {
+			t.Errorf("%s: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: got %v want %v", in, got, want)
+		}
+	}
+}
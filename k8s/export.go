@@ -0,0 +1,106 @@
+package k8s
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// 输出格式
+const (
+	FormatRaw       = "raw"       // 原始yml
+	FormatHelm      = "helm"      // helm chart
+	FormatKustomize = "kustomize" // kustomize base+overlays
+)
+
+// Envs 与 run 命令一致的环境列表
+var Envs = []string{"local", "dev", "test", "prod"}
+
+// baseEnv 生成 helm 默认values 与 kustomize base 时使用的环境
+const baseEnv = "prod"
+
+// Bundle 同一份输入生成的资源
+// 通过命令行参数生成时只有 Base, 通过 .builderc 生成时每个环境各有一份
+type Bundle struct {
+	Name    string                   // chart名 服务名
+	Version Version                  // k8s版本
+	Base    []interface{}            // 缺省资源
+	Envs    map[string][]interface{} // 按环境生成的资源
+}
+
+// NewBundle 用一组资源构造 Bundle
+func NewBundle(name string, version Version, objects ...interface{}) *Bundle {
+	return &Bundle{Name: name, Version: version, Base: objects}
+}
+
+// BuildBundle 为有 config_<env>.yaml 的环境生成资源
+func BuildBundle(dir string, c DeployConfig) (*Bundle, error) {
+	version, err := ParseVersion(c.Version)
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{Name: c.Name, Version: version, Envs: map[string][]interface{}{}}
+	for _, env := range Envs {
+		project := &Project{Dir: dir, Env: env, Config: c}
+		// 没有配置文件的环境不生成 基础环境必须存在
+		if _, err := os.Stat(filepath.Join(dir, project.ConfigFile())); os.IsNotExist(err) && env != baseEnv {
+			continue
+		}
+		objects, err := project.Build()
+		if err != nil {
+			return nil, fmt.Errorf("环境 %s: %w", env, err)
+		}
+		b.Envs[env] = objects
+	}
+	b.Base = b.Envs[baseEnv]
+	return b, nil
+}
+
+// Export 按格式输出 raw 格式时 out 为文件 其他格式时 out 为目录
+func Export(w io.Writer, format, out string, b *Bundle) error {
+	switch format {
+	case "", FormatRaw:
+		return WriteFile(w, out, b.Base...)
+	case FormatHelm:
+		return exportHelm(outDir(out, b), b)
+	case FormatKustomize:
+		return exportKustomize(outDir(out, b), b)
+	}
+	return fmt.Errorf("不支持的输出格式: %s, 可选 [%s,%s,%s]", format, FormatRaw, FormatHelm, FormatKustomize)
+}
+
+func outDir(out string, b *Bundle) string {
+	if out == "" {
+		return filepath.Join("deploy", b.Name)
+	}
+	return out
+}
+
+// writeObjects 渲染资源写入文件 自动创建目录
+func writeObjects(file string, objects ...interface{}) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(os.Stdout, "create   %s \n", file)
+	return WriteFile(nil, file, objects...)
+}
+
+// sortedEnvs 按 Envs 的顺序返回 bundle 中存在的环境
+func (b *Bundle) sortedEnvs() []string {
+	var envs []string
+	for _, env := range Envs {
+		if _, ok := b.Envs[env]; ok {
+			envs = append(envs, env)
+		}
+	}
+	var extra []string
+	for env := range b.Envs {
+		if !contains(Envs, env) {
+			extra = append(extra, env)
+		}
+	}
+	sort.Strings(extra)
+	return append(envs, extra...)
+}
@@ -0,0 +1,102 @@
+package k8s
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k8s_export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	objects, err := BuildDeployment(newTestOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	prodOption := newTestOption()
+	prodOption.HPA = &HPAOption{MinReplicas: 2, MaxReplicas: 10, CPUUtilization: 70}
+	prod, err := BuildDeployment(prodOption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prod[0].(*Deployment).Spec.Replicas = 5
+	ingress, err := BuildIngress(&IngressOption{Name: "user", Port: 8080, Hosts: []string{"user.example.com"}, Version: DefaultVersion})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Bundle{
+		Name:    "user",
+		Version: DefaultVersion,
+		Base:    objects,
+		Envs:    map[string][]interface{}{"local": objects, "prod": append(prod, ingress)},
+	}
+
+	if err := Export(nil, FormatHelm, filepath.Join(dir, "helm"), b); err != nil {
+		t.Fatal(err)
+	}
+	if err := Export(nil, FormatKustomize, filepath.Join(dir, "kustomize"), b); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]string{
+		"helm/Chart.yaml":                             "name: user",
+		"helm/values-prod.yaml":                       "replicas: 5",
+		"helm/templates/ingress.yaml":                 "networking.k8s.io/v1",
+		"kustomize/base/kustomization.yaml":           "- deployment.yaml",
+		"kustomize/overlays/prod/kustomization.yaml":  "- resources.yaml",
+		"kustomize/overlays/prod/patch.yaml":          "replicas: 5",
+		"kustomize/overlays/prod/resources.yaml":      "kind: Ingress",
+		"kustomize/overlays/local/kustomization.yaml": "env: local",
+		"helm/templates/hpa.yaml":                     "autoscaling/v2beta2",
+	}
+	for file, want := range expect {
+		body, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Errorf("read %s: %v", file, err)
+			continue
+		}
+		if !strings.Contains(string(body), want) {
+			t.Errorf("%s missing %q:\n%s", file, want, body)
+		}
+	}
+
+	if err := Export(nil, "pulumi", dir, b); err == nil {
+		t.Errorf("want unsupported format error")
+	}
+}
+
+func TestKustomizeDeletePatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k8s_kustomize")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	o := newTestOption()
+	o.HPA = &HPAOption{MinReplicas: 2, MaxReplicas: 10, CPUUtilization: 70}
+	prod, err := BuildDeployment(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := BuildDeployment(newTestOption())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &Bundle{Name: "user", Version: DefaultVersion, Base: prod, Envs: map[string][]interface{}{"local": local, "prod": prod}}
+	if err := Export(nil, FormatKustomize, dir, b); err != nil {
+		t.Fatal(err)
+	}
+	body, err := ioutil.ReadFile(filepath.Join(dir, "overlays", "local", "patch.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), "$patch: delete") || !strings.Contains(string(body), "kind: HorizontalPodAutoscaler") {
+		t.Errorf("local overlay should delete hpa:\n%s", body)
+	}
+}
@@ -0,0 +1,463 @@
+package k8s
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Chart Chart.yaml
+type Chart struct {
+	APIVersion  string `yaml:"apiVersion"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Type        string `yaml:"type"`
+	Version     string `yaml:"version"`
+	AppVersion  string `yaml:"appVersion"`
+}
+
+// ChartValues values.yaml 由生成的资源反推而来
+type ChartValues struct {
+	Apps    []AppValues    `yaml:"apps"`
+	Ingress *IngressValues `yaml:"ingress,omitempty"`
+}
+
+// AppValues 一个 Deployment 及其 Service
+type AppValues struct {
+	Name            string                  `yaml:"name"`
+	Container       string                  `yaml:"container"`
+	Labels          map[string]string       `yaml:"labels"`
+	Replicas        int32                   `yaml:"replicas"`
+	Image           string                  `yaml:"image"`
+	ImagePullPolicy string                  `yaml:"imagePullPolicy"`
+	Command         []string                `yaml:"command,omitempty"`
+	Strategy        RollingUpdateDeployment `yaml:"strategy"`
+	Resources       ResourceRequirements    `yaml:"resources"`
+	Port            *PortValues             `yaml:"port,omitempty"`
+	Probes          *ProbeValues            `yaml:"probes,omitempty"`
+	HPA             *HPAValues              `yaml:"hpa,omitempty"`
+	PDB             *PDBValues              `yaml:"pdb,omitempty"`
+}
+
+// ProbeValues 健康检查
+type ProbeValues struct {
+	Liveness  *Probe `yaml:"liveness,omitempty"`
+	Readiness *Probe `yaml:"readiness,omitempty"`
+	Startup   *Probe `yaml:"startup,omitempty"`
+}
+
+// HPAValues 扩缩容
+type HPAValues struct {
+	MinReplicas                    int32        `yaml:"minReplicas"`
+	MaxReplicas                    int32        `yaml:"maxReplicas"`
+	TargetCPUUtilizationPercentage int32        `yaml:"targetCPUUtilizationPercentage,omitempty"`
+	Metrics                        []MetricSpec `yaml:"metrics,omitempty"`
+}
+
+// PDBValues 中断预算 selector 与 Deployment 一致
+type PDBValues struct {
+	MinAvailable   *IntOrString `yaml:"minAvailable,omitempty"`
+	MaxUnavailable *IntOrString `yaml:"maxUnavailable,omitempty"`
+}
+
+// PortValues 容器端口与服务端口
+type PortValues struct {
+	Name          string `yaml:"name"`
+	Protocol      string `yaml:"protocol"`
+	AppProtocol   string `yaml:"appProtocol,omitempty"`
+	ContainerPort int    `yaml:"containerPort"`
+	ServicePort   int    `yaml:"servicePort"`
+}
+
+// IngressValues ingress配置
+type IngressValues struct {
+	Enabled     bool                `yaml:"enabled"`
+	Name        string              `yaml:"name"`
+	ClassName   string              `yaml:"className"`
+	Annotations map[string]string   `yaml:"annotations,omitempty"`
+	Service     string              `yaml:"service"`
+	Port        int                 `yaml:"port"`
+	Hosts       []IngressHostValues `yaml:"hosts"`
+	TLS         []IngressTLS        `yaml:"tls,omitempty"`
+}
+
+// IngressHostValues 域名及其路径
+type IngressHostValues struct {
+	Host  string   `yaml:"host"`
+	Paths []string `yaml:"paths"`
+}
+
+// NewChartValues 从资源列表生成 values
+func NewChartValues(objects []interface{}) *ChartValues {
+	values := &ChartValues{}
+	services := map[string]*Service{}
+	hpas := map[string]*HorizontalPodAutoscaler{}
+	pdbs := map[string]*PodDisruptionBudget{}
+	for _, obj := range objects {
+		switch v := obj.(type) {
+		case *Service:
+			services[v.Metadata.Name] = v
+		case *HorizontalPodAutoscaler:
+			hpas[v.Metadata.Name] = v
+		case *PodDisruptionBudget:
+			pdbs[v.Metadata.Name] = v
+		}
+	}
+	for _, obj := range objects {
+		switch v := obj.(type) {
+		case *Deployment:
+			app := newAppValues(v, services)
+			if hpa, ok := hpas[v.Metadata.Name]; ok {
+				app.HPA = &HPAValues{
+					MinReplicas:                    hpa.Spec.MinReplicas,
+					MaxReplicas:                    hpa.Spec.MaxReplicas,
+					TargetCPUUtilizationPercentage: hpa.Spec.TargetCPUUtilizationPercentage,
+					Metrics:                        hpa.Spec.Metrics,
+				}
+			}
+			if pdb, ok := pdbs[v.Metadata.Name]; ok {
+				app.PDB = &PDBValues{MinAvailable: pdb.Spec.MinAvailable, MaxUnavailable: pdb.Spec.MaxUnavailable}
+			}
+			values.Apps = append(values.Apps, app)
+		case *Ingress:
+			values.Ingress = newIngressValues(v)
+		}
+	}
+	return values
+}
+
+func newAppValues(d *Deployment, services map[string]*Service) AppValues {
+	c := d.Spec.Template.Spec.Containers[0]
+	app := AppValues{
+		Name:            d.Metadata.Name,
+		Container:       c.Name,
+		Labels:          d.Spec.Template.Metadata.Labels,
+		Replicas:        d.Spec.Replicas,
+		Image:           c.Image,
+		ImagePullPolicy: c.ImagePullPolicy,
+		Command:         c.Command,
+		Resources:       c.Resources,
+	}
+	if d.Spec.Strategy.RollingUpdate != nil {
+		app.Strategy = *d.Spec.Strategy.RollingUpdate
+	}
+	if c.LivenessProbe != nil || c.ReadinessProbe != nil || c.StartupProbe != nil {
+		app.Probes = &ProbeValues{Liveness: c.LivenessProbe, Readiness: c.ReadinessProbe, Startup: c.StartupProbe}
+	}
+	if len(c.Ports) != 0 {
+		port := &PortValues{
+			Name:          c.Ports[0].Name,
+			Protocol:      c.Ports[0].Protocol,
+			ContainerPort: c.Ports[0].ContainerPort,
+			ServicePort:   c.Ports[0].ContainerPort,
+		}
+		// Service 以容器名命名
+		if svc, ok := services[c.Name]; ok && len(svc.Spec.Ports) != 0 {
+			port.ServicePort = svc.Spec.Ports[0].Port
+			port.AppProtocol = svc.Spec.Ports[0].AppProtocol
+		}
+		app.Port = port
+	}
+	return app
+}
+
+func newIngressValues(ing *Ingress) *IngressValues {
+	values := &IngressValues{
+		Enabled:     true,
+		Name:        ing.Metadata.Name,
+		ClassName:   ing.Spec.IngressClassName,
+		Annotations: map[string]string{},
+		TLS:         ing.Spec.TLS,
+	}
+	for k, v := range ing.Metadata.Annotations {
+		if k == AnnotationIngressClass {
+			values.ClassName = v
+			continue
+		}
+		values.Annotations[k] = v
+	}
+	for _, rule := range ing.Spec.Rules {
+		host := IngressHostValues{Host: rule.Host}
+		for _, path := range rule.HTTP.Paths {
+			host.Paths = append(host.Paths, path.Path)
+			if b := path.Backend; b.Service != nil {
+				values.Service, values.Port = b.Service.Name, b.Service.Port.Number
+			} else if b.ServicePort != nil {
+				values.Service, values.Port = b.ServiceName, b.ServicePort.IntVal
+			}
+		}
+		values.Hosts = append(values.Hosts, host)
+	}
+	return values
+}
+
+// exportHelm 输出 chart 目录 每个环境额外生成 values-<env>.yaml
+func exportHelm(dir string, b *Bundle) error {
+	chart := &Chart{
+		APIVersion:  "v2",
+		Name:        b.Name,
+		Description: fmt.Sprintf("A Helm chart for %s", b.Name),
+		Type:        "application",
+		Version:     "0.1.0",
+		AppVersion:  "1.0.0",
+	}
+	if err := writeObjects(filepath.Join(dir, "Chart.yaml"), chart); err != nil {
+		return err
+	}
+	if err := writeObjects(filepath.Join(dir, "values.yaml"), NewChartValues(b.Base)); err != nil {
+		return err
+	}
+	for _, env := range b.sortedEnvs() {
+		file := filepath.Join(dir, fmt.Sprintf("values-%s.yaml", env))
+		if err := writeObjects(file, NewChartValues(b.Envs[env])); err != nil {
+			return err
+		}
+	}
+
+	ingressTpl := helmIngressLegacyTpl
+	if b.Version.AtLeast(1, 19) {
+		ingressTpl = helmIngressTpl
+	}
+	templates := map[string]string{
+		"_helpers.tpl":    helmHelpersTpl,
+		"deployment.yaml": strings.Replace(helmDeploymentTpl, "{{DEPLOYMENT_API_VERSION}}", b.Version.deploymentAPIVersion(), 1),
+		"service.yaml":    helmServiceTpl,
+		"ingress.yaml":    strings.Replace(ingressTpl, "{{INGRESS_API_VERSION}}", b.Version.ingressAPIVersion(), 1),
+		"hpa.yaml": strings.NewReplacer(
+			"{{HPA_API_VERSION}}", b.Version.hpaAPIVersion(),
+			"{{DEPLOYMENT_API_VERSION}}", b.Version.deploymentAPIVersion(),
+		).Replace(helmHPATpl),
+		"pdb.yaml": strings.Replace(helmPDBTpl, "{{PDB_API_VERSION}}", b.Version.pdbAPIVersion(), 1),
+	}
+	for name, content := range templates {
+		file := filepath.Join(dir, "templates", name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "create   %s \n", file)
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 以下为helm模板 由helm在安装时渲染
+
+const helmHelpersTpl = `{{- define "chart.labels" -}}
+helm.sh/chart: {{ .Chart.Name }}-{{ .Chart.Version }}
+app.kubernetes.io/managed-by: {{ .Release.Service }}
+{{- end }}
+`
+
+const helmDeploymentTpl = `{{- range .Values.apps }}
+---
+apiVersion: {{DEPLOYMENT_API_VERSION}}
+kind: Deployment
+metadata:
+  name: {{ .name }}
+  namespace: {{ $.Release.Namespace }}
+  labels:
+    {{- include "chart.labels" $ | nindent 4 }}
+    {{- toYaml .labels | nindent 4 }}
+spec:
+  replicas: {{ .replicas }}
+  selector:
+    matchLabels:
+      {{- toYaml .labels | nindent 6 }}
+  strategy:
+    type: RollingUpdate
+    rollingUpdate:
+      {{- toYaml .strategy | nindent 6 }}
+  template:
+    metadata:
+      labels:
+        {{- toYaml .labels | nindent 8 }}
+    spec:
+      containers:
+        - name: {{ .container }}
+          image: {{ .image | quote }}
+          imagePullPolicy: {{ .imagePullPolicy }}
+          {{- with .command }}
+          command:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+          {{- with .port }}
+          ports:
+            - name: {{ .name }}
+              containerPort: {{ .containerPort }}
+              protocol: {{ .protocol }}
+          {{- end }}
+          resources:
+            {{- toYaml .resources | nindent 12 }}
+          {{- with .probes }}
+          {{- with .liveness }}
+          livenessProbe:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+          {{- with .readiness }}
+          readinessProbe:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+          {{- with .startup }}
+          startupProbe:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+          {{- end }}
+{{- end }}
+`
+
+const helmServiceTpl = `{{- range .Values.apps }}
+{{- if .port }}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{ .container }}
+  namespace: {{ $.Release.Namespace }}
+  labels:
+    {{- include "chart.labels" $ | nindent 4 }}
+    app: {{ .container }}
+spec:
+  type: ClusterIP
+  selector:
+    app: {{ .container }}
+  ports:
+    - name: {{ .port.name }}
+      protocol: {{ .port.protocol }}
+      {{- with .port.appProtocol }}
+      appProtocol: {{ . }}
+      {{- end }}
+      port: {{ .port.servicePort }}
+      targetPort: {{ .port.containerPort }}
+{{- end }}
+{{- end }}
+`
+
+const helmIngressTpl = `{{- with .Values.ingress }}
+{{- if .enabled }}
+apiVersion: {{INGRESS_API_VERSION}}
+kind: Ingress
+metadata:
+  name: {{ .name }}
+  namespace: {{ $.Release.Namespace }}
+  labels:
+    {{- include "chart.labels" $ | nindent 4 }}
+  {{- with .annotations }}
+  annotations:
+    {{- toYaml . | nindent 4 }}
+  {{- end }}
+spec:
+  ingressClassName: {{ .className }}
+  {{- with .tls }}
+  tls:
+    {{- toYaml . | nindent 4 }}
+  {{- end }}
+  rules:
+    {{- $service := .service }}
+    {{- $port := .port }}
+    {{- range .hosts }}
+    - host: {{ .host | quote }}
+      http:
+        paths:
+          {{- range .paths }}
+          - path: {{ . }}
+            pathType: Prefix
+            backend:
+              service:
+                name: {{ $service }}
+                port:
+                  number: {{ $port }}
+          {{- end }}
+    {{- end }}
+{{- end }}
+{{- end }}
+`
+
+const helmIngressLegacyTpl = `{{- with .Values.ingress }}
+{{- if .enabled }}
+apiVersion: {{INGRESS_API_VERSION}}
+kind: Ingress
+metadata:
+  name: {{ .name }}
+  namespace: {{ $.Release.Namespace }}
+  labels:
+    {{- include "chart.labels" $ | nindent 4 }}
+  annotations:
+    kubernetes.io/ingress.class: {{ .className }}
+    {{- with .annotations }}
+    {{- toYaml . | nindent 4 }}
+    {{- end }}
+spec:
+  {{- with .tls }}
+  tls:
+    {{- toYaml . | nindent 4 }}
+  {{- end }}
+  rules:
+    {{- $service := .service }}
+    {{- $port := .port }}
+    {{- range .hosts }}
+    - host: {{ .host | quote }}
+      http:
+        paths:
+          {{- range .paths }}
+          - path: {{ . }}
+            backend:
+              serviceName: {{ $service }}
+              servicePort: {{ $port }}
+          {{- end }}
+    {{- end }}
+{{- end }}
+{{- end }}
+`
+
+const helmHPATpl = `{{- range .Values.apps }}
+{{- if .hpa }}
+---
+apiVersion: {{HPA_API_VERSION}}
+kind: HorizontalPodAutoscaler
+metadata:
+  name: {{ .name }}
+  namespace: {{ $.Release.Namespace }}
+  labels:
+    {{- include "chart.labels" $ | nindent 4 }}
+    app: {{ .name }}
+spec:
+  scaleTargetRef:
+    apiVersion: {{DEPLOYMENT_API_VERSION}}
+    kind: Deployment
+    name: {{ .name }}
+  minReplicas: {{ .hpa.minReplicas }}
+  maxReplicas: {{ .hpa.maxReplicas }}
+  {{- with .hpa.targetCPUUtilizationPercentage }}
+  targetCPUUtilizationPercentage: {{ . }}
+  {{- end }}
+  {{- with .hpa.metrics }}
+  metrics:
+    {{- toYaml . | nindent 4 }}
+  {{- end }}
+{{- end }}
+{{- end }}
+`
+
+const helmPDBTpl = `{{- range .Values.apps }}
+{{- if .pdb }}
+---
+apiVersion: {{PDB_API_VERSION}}
+kind: PodDisruptionBudget
+metadata:
+  name: {{ .name }}
+  namespace: {{ $.Release.Namespace }}
+  labels:
+    {{- include "chart.labels" $ | nindent 4 }}
+    app: {{ .name }}
+spec:
+  {{- toYaml .pdb | nindent 2 }}
+  selector:
+    matchLabels:
+      {{- toYaml .labels | nindent 6 }}
+{{- end }}
+{{- end }}
+`
@@ -0,0 +1,137 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// nginx ingress 相关注解
+const (
+	AnnotationIngressClass     = "kubernetes.io/ingress.class"
+	AnnotationLoadBalance      = "nginx.ingress.kubernetes.io/load-balance"
+	AnnotationSSLRedirect      = "nginx.ingress.kubernetes.io/ssl-redirect"
+	AnnotationProxyReadTimeout = "nginx.ingress.kubernetes.io/proxy-read-timeout"
+	AnnotationProxySendTimeout = "nginx.ingress.kubernetes.io/proxy-send-timeout"
+)
+
+// websocketTimeout websocket长连接的代理超时时间 单位秒
+const websocketTimeout = "3600"
+
+// validLBMethods ingress-nginx 的 load-balance 注解只支持这两种 一致性哈希需要使用 upstream-hash-by
+var validLBMethods = []string{"round_robin", "ewma"}
+
+var hostReg = regexp.MustCompile(`^(\*\.)?[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+// IngressOption 生成 Ingress 需要的参数
+type IngressOption struct {
+	Name         string   // 后端服务名
+	Namespace    string   // 命名空间
+	Port         int      // 后端服务端口
+	Hosts        []string // 域名 每个域名都会转发 Paths 下的所有路径
+	Paths        []string // 转发路径 默认为 /
+	SecretName   string   // 证书名 为空时不开启tls
+	Websocket    bool     // 是否为websocket服务
+	LBMethod     string   // 负载均衡方式
+	IngressClass string   // ingress class 默认为 nginx
+	Version      Version  // k8s版本
+}
+
+// Validate 校验参数
+func (o *IngressOption) Validate() error {
+	if o.Name == "" {
+		return errors.New("服务名 --svc 不能为空")
+	}
+	if o.Port <= 0 || o.Port > 65535 {
+		return fmt.Errorf("服务端口 --port 不正确: %d", o.Port)
+	}
+	if len(o.Hosts) == 0 {
+		return errors.New("host --host 不能为空")
+	}
+	for _, host := range o.Hosts {
+		if !hostReg.MatchString(host) {
+			return fmt.Errorf("host 格式不正确: %s", host)
+		}
+	}
+	for _, path := range o.Paths {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("路径需以 / 开头: %s", path)
+		}
+	}
+	if o.LBMethod != "" && !contains(validLBMethods, o.LBMethod) {
+		return fmt.Errorf("lb方式 %s 不支持, 可选 %v", o.LBMethod, validLBMethods)
+	}
+	return nil
+}
+
+// BuildIngress 生成 Ingress 根据k8s版本选择 extensions/v1beta1 或 networking.k8s.io/v1
+func BuildIngress(o *IngressOption) (*Ingress, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	paths := o.Paths
+	if len(paths) == 0 {
+		paths = []string{"/"}
+	}
+	class := o.IngressClass
+	if class == "" {
+		class = "nginx"
+	}
+	isV1 := o.Version.AtLeast(1, 19)
+
+	annotations := map[string]string{}
+	if o.LBMethod != "" {
+		annotations[AnnotationLoadBalance] = o.LBMethod
+	}
+	if o.Websocket {
+		annotations[AnnotationProxyReadTimeout] = websocketTimeout
+		annotations[AnnotationProxySendTimeout] = websocketTimeout
+	}
+
+	ingress := &Ingress{
+		TypeMeta: TypeMeta{APIVersion: o.Version.ingressAPIVersion(), Kind: "Ingress"},
+		Metadata: ObjectMeta{
+			Name:        o.Name,
+			Namespace:   o.Namespace,
+			Labels:      map[string]string{"app": o.Name},
+			Annotations: annotations,
+		},
+	}
+	if isV1 {
+		ingress.Spec.IngressClassName = class
+	} else {
+		annotations[AnnotationIngressClass] = class
+	}
+	if o.SecretName != "" {
+		annotations[AnnotationSSLRedirect] = "true"
+		ingress.Spec.TLS = []IngressTLS{{Hosts: o.Hosts, SecretName: o.SecretName}}
+	}
+
+	for _, host := range o.Hosts {
+		rule := IngressRule{Host: host}
+		for _, path := range paths {
+			rule.HTTP.Paths = append(rule.HTTP.Paths, o.buildPath(path, isV1))
+		}
+		ingress.Spec.Rules = append(ingress.Spec.Rules, rule)
+	}
+	return ingress, nil
+}
+
+func (o *IngressOption) buildPath(path string, isV1 bool) HTTPIngressPath {
+	if isV1 {
+		return HTTPIngressPath{
+			Path:     path,
+			PathType: "Prefix",
+			Backend: IngressBackend{Service: &IngressServiceBackend{
+				Name: o.Name,
+				Port: ServiceBackendPort{Number: o.Port},
+			}},
+		}
+	}
+	port := FromInt(o.Port)
+	return HTTPIngressPath{
+		Path:    path,
+		Backend: IngressBackend{ServiceName: o.Name, ServicePort: &port},
+	}
+}
@@ -0,0 +1,53 @@
+package k8s
+
+import "testing"
+
+func TestBuildIngress(t *testing.T) {
+	o := &IngressOption{
+		Name:       "user",
+		Port:       8080,
+		Hosts:      []string{"a.example.com", "b.example.com"},
+		Paths:      []string{"/api", "/ws"},
+		SecretName: "example-tls",
+		Websocket:  true,
+		LBMethod:   "ewma",
+		Version:    Version{1, 20},
+	}
+	ingress, err := BuildIngress(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ingress.APIVersion != "networking.k8s.io/v1" || ingress.Spec.IngressClassName != "nginx" {
+		t.Errorf("unexpected v1 ingress: %+v", ingress.TypeMeta)
+	}
+	if len(ingress.Spec.Rules) != 2 || len(ingress.Spec.Rules[1].HTTP.Paths) != 2 {
+		t.Errorf("want 2 hosts x 2 paths, got %+v", ingress.Spec.Rules)
+	}
+	if ingress.Metadata.Annotations[AnnotationProxyReadTimeout] != websocketTimeout {
+		t.Errorf("missing websocket timeout annotation")
+	}
+
+	o.Version = Version{1, 18}
+	legacy, err := BuildIngress(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := legacy.Spec.Rules[0].HTTP.Paths[0].Backend
+	if legacy.APIVersion != "extensions/v1beta1" || backend.ServiceName != "user" || backend.Service != nil {
+		t.Errorf("unexpected legacy ingress: %+v", legacy)
+	}
+	if legacy.Metadata.Annotations[AnnotationIngressClass] != "nginx" {
+		t.Errorf("legacy ingress should use class annotation")
+	}
+
+	o.LBMethod = "least_conn"
+	if _, err := BuildIngress(o); err == nil {
+		t.Errorf("want invalid lb method error")
+	}
+
+	o.LBMethod = ""
+	o.Hosts = []string{"Bad_Host"}
+	if _, err := BuildIngress(o); err == nil {
+		t.Errorf("want invalid host error")
+	}
+}
@@ -0,0 +1,193 @@
+package k8s
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Kustomization kustomization.yaml
+type Kustomization struct {
+	APIVersion            string            `yaml:"apiVersion"`
+	Kind                  string            `yaml:"kind"`
+	Namespace             string            `yaml:"namespace,omitempty"`
+	CommonLabels          map[string]string `yaml:"commonLabels,omitempty"`
+	Resources             []string          `yaml:"resources"`
+	PatchesStrategicMerge []string          `yaml:"patchesStrategicMerge,omitempty"`
+}
+
+func newKustomization() *Kustomization {
+	return &Kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization"}
+}
+
+// deploymentPatch 只覆盖随环境变化的字段
+type deploymentPatch struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta `yaml:"metadata"`
+	Spec     struct {
+		Replicas int32 `yaml:"replicas"`
+		Template struct {
+			Spec PodSpec `yaml:"spec"`
+		} `yaml:"template"`
+	} `yaml:"spec"`
+}
+
+// exportKustomize 输出 base 与每个环境的 overlay
+// 按环境生成时 base 不包含 ingress, ingress 的host随环境变化 直接放在 overlay 中
+func exportKustomize(dir string, b *Bundle) error {
+	base := newKustomization()
+	var baseObjects []interface{}
+	baseKeys := map[string]bool{}
+	for _, obj := range b.Base {
+		if _, ok := obj.(*Ingress); ok && len(b.Envs) != 0 {
+			continue
+		}
+		baseObjects = append(baseObjects, obj)
+		baseKeys[objectKey(obj)] = true
+	}
+	for _, obj := range baseObjects {
+		base.Resources = appendUnique(base.Resources, resourceFile(obj))
+	}
+	for _, file := range base.Resources {
+		if err := writeObjects(filepath.Join(dir, "base", file), objectsOfFile(baseObjects, file)...); err != nil {
+			return err
+		}
+	}
+	if err := writeObjects(filepath.Join(dir, "base", "kustomization.yaml"), base); err != nil {
+		return err
+	}
+
+	envs := b.sortedEnvs()
+	if len(envs) == 0 {
+		envs = Envs
+	}
+	for _, env := range envs {
+		overlay := newKustomization()
+		overlay.Resources = []string{"../../base"}
+		overlay.CommonLabels = map[string]string{"env": env}
+		objects, ok := b.Envs[env]
+		if !ok {
+			// 命令行参数生成的资源各环境一致 overlay 只打上环境标签
+			if err := writeObjects(filepath.Join(dir, "overlays", env, "kustomization.yaml"), overlay); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var patches, resources []interface{}
+		envKeys := map[string]bool{}
+		for _, obj := range objects {
+			envKeys[objectKey(obj)] = true
+		}
+		for _, obj := range baseObjects {
+			if p := newDeletePatch(obj); p != nil && !envKeys[objectKey(obj)] {
+				patches = append(patches, p)
+			}
+		}
+		for _, obj := range objects {
+			if !baseKeys[objectKey(obj)] {
+				resources = append(resources, obj)
+				continue
+			}
+			switch v := obj.(type) {
+			case *Deployment:
+				overlay.Namespace = v.Metadata.Namespace
+				patches = append(patches, newDeploymentPatch(v))
+			default:
+				patches = append(patches, obj)
+			}
+		}
+		overlayDir := filepath.Join(dir, "overlays", env)
+		if len(patches) != 0 {
+			overlay.PatchesStrategicMerge = []string{"patch.yaml"}
+			if err := writeObjects(filepath.Join(overlayDir, "patch.yaml"), patches...); err != nil {
+				return err
+			}
+		}
+		if len(resources) != 0 {
+			overlay.Resources = append(overlay.Resources, "resources.yaml")
+			if err := writeObjects(filepath.Join(overlayDir, "resources.yaml"), resources...); err != nil {
+				return err
+			}
+		}
+		if err := writeObjects(filepath.Join(overlayDir, "kustomization.yaml"), overlay); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deletePatch 删除 base 中存在而当前环境未开启的资源 如只在 prod 开启的 hpa
+type deletePatch struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta `yaml:"metadata"`
+	Patch    string     `yaml:"$patch"`
+}
+
+func newDeletePatch(obj interface{}) *deletePatch {
+	switch v := obj.(type) {
+	case *HorizontalPodAutoscaler:
+		return &deletePatch{TypeMeta: v.TypeMeta, Metadata: ObjectMeta{Name: v.Metadata.Name}, Patch: "delete"}
+	case *PodDisruptionBudget:
+		return &deletePatch{TypeMeta: v.TypeMeta, Metadata: ObjectMeta{Name: v.Metadata.Name}, Patch: "delete"}
+	}
+	return nil
+}
+
+func newDeploymentPatch(d *Deployment) *deploymentPatch {
+	p := &deploymentPatch{TypeMeta: d.TypeMeta}
+	p.Metadata = ObjectMeta{Name: d.Metadata.Name}
+	p.Spec.Replicas = d.Spec.Replicas
+	p.Spec.Template.Spec = d.Spec.Template.Spec
+	return p
+}
+
+// objectKey 资源唯一标识
+func objectKey(obj interface{}) string {
+	switch v := obj.(type) {
+	case *Deployment:
+		return "Deployment/" + v.Metadata.Name
+	case *Service:
+		return "Service/" + v.Metadata.Name
+	case *Ingress:
+		return "Ingress/" + v.Metadata.Name
+	case *HorizontalPodAutoscaler:
+		return "HorizontalPodAutoscaler/" + v.Metadata.Name
+	case *PodDisruptionBudget:
+		return "PodDisruptionBudget/" + v.Metadata.Name
+	}
+	return fmt.Sprintf("%T", obj)
+}
+
+// resourceFile base中资源按类型分文件
+func resourceFile(obj interface{}) string {
+	switch obj.(type) {
+	case *Deployment:
+		return "deployment.yaml"
+	case *Service:
+		return "service.yaml"
+	case *Ingress:
+		return "ingress.yaml"
+	case *HorizontalPodAutoscaler:
+		return "hpa.yaml"
+	case *PodDisruptionBudget:
+		return "pdb.yaml"
+	}
+	return "resources.yaml"
+}
+
+func objectsOfFile(objects []interface{}, file string) []interface{} {
+	var out []interface{}
+	for _, obj := range objects {
+		if resourceFile(obj) == file {
+			out = append(out, obj)
+		}
+	}
+	return out
+}
+
+func appendUnique(list []string, s string) []string {
+	if contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
@@ -0,0 +1,396 @@
+package k8s
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DeployConfig .builderc 中的 deploy 配置块
+type DeployConfig struct {
+	Name         string               `yaml:"name" json:"name"`                   // 服务名 默认为go module名
+	Namespace    string               `yaml:"namespace" json:"namespace"`         // 命名空间
+	Image        string               `yaml:"image" json:"image"`                 // 镜像地址 默认为 服务名:环境
+	Version      string               `yaml:"version" json:"version"`             // k8s版本
+	Entries      []string             `yaml:"entries" json:"entries"`             // 需要布署的 cmd/ 入口 默认为cmd/下除 exec.go 外的所有文件
+	StartCommand string               `yaml:"start_command" json:"start_command"` // 启动命令模板 支持 {name} {entry} {env} {config} 占位
+	Defaults     DeployEnv            `yaml:"defaults" json:"defaults"`           // 所有环境的缺省值
+	Envs         map[string]DeployEnv `yaml:"envs" json:"envs"`                   // 按环境覆盖缺省值
+}
+
+// DeployEnv 某个环境的布署参数 零值表示沿用缺省值
+type DeployEnv struct {
+	Namespace      string         `yaml:"namespace" json:"namespace"`
+	ImageTag       string         `yaml:"image_tag" json:"image_tag"`
+	Replicas       *int32         `yaml:"replicas" json:"replicas"`
+	MaxSurge       string         `yaml:"max_surge" json:"max_surge"`
+	MaxUnavailable string         `yaml:"max_unavailable" json:"max_unavailable"`
+	CPUMax         string         `yaml:"cpu_max" json:"cpu_max"`
+	MemMax         string         `yaml:"mem_max" json:"mem_max"`
+	CPUMin         string         `yaml:"cpu_min" json:"cpu_min"`
+	MemMin         string         `yaml:"mem_min" json:"mem_min"`
+	Ports          map[string]int `yaml:"ports" json:"ports"`             // 按入口指定端口 不填则从 config_<env>.yaml 中读取
+	TargetPort     int            `yaml:"target_port" json:"target_port"` // Service对外端口
+	AppProtocol    string         `yaml:"app_protocol" json:"app_protocol"`
+	Ingress        *IngressConfig `yaml:"ingress" json:"ingress"`
+	Probe          *ProbeOption   `yaml:"probe" json:"probe"` // 健康检查 http 端口需要指定路径
+	HPA            *HPAOption     `yaml:"hpa" json:"hpa"`
+	PDB            *PDBOption     `yaml:"pdb" json:"pdb"`
+}
+
+// IngressConfig 某个环境的ingress配置
+type IngressConfig struct {
+	Entry      string   `yaml:"entry" json:"entry"` // 转发到哪个入口 默认为 api
+	Hosts      []string `yaml:"hosts" json:"hosts"`
+	Paths      []string `yaml:"paths" json:"paths"`
+	SecretName string   `yaml:"secret_name" json:"secret_name"`
+	Websocket  bool     `yaml:"websocket" json:"websocket"`
+	LBMethod   string   `yaml:"lb_method" json:"lb_method"`
+}
+
+// 未配置时使用与 gen-k8s-deployment-yml 一致的缺省值
+var defaultDeployEnv = DeployEnv{
+	MaxSurge:       "100%",
+	MaxUnavailable: "0%",
+	CPUMax:         "400m",
+	MemMax:         "512Mi",
+	CPUMin:         "200m",
+	MemMin:         "200Mi",
+}
+
+const defaultStartCommand = "./{name} {entry} --config {config}"
+
+// merge 用 e 的非零字段覆盖 base
+func (e DeployEnv) merge(base DeployEnv) DeployEnv {
+	out := base
+	setString := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	setString(&out.Namespace, e.Namespace)
+	setString(&out.ImageTag, e.ImageTag)
+	setString(&out.MaxSurge, e.MaxSurge)
+	setString(&out.MaxUnavailable, e.MaxUnavailable)
+	setString(&out.CPUMax, e.CPUMax)
+	setString(&out.MemMax, e.MemMax)
+	setString(&out.CPUMin, e.CPUMin)
+	setString(&out.MemMin, e.MemMin)
+	setString(&out.AppProtocol, e.AppProtocol)
+	if e.Replicas != nil {
+		out.Replicas = e.Replicas
+	}
+	if e.TargetPort != 0 {
+		out.TargetPort = e.TargetPort
+	}
+	if e.Ingress != nil {
+		out.Ingress = e.Ingress
+	}
+	if e.Probe != nil {
+		out.Probe = e.Probe
+	}
+	if e.HPA != nil {
+		out.HPA = e.HPA
+	}
+	if e.PDB != nil {
+		out.PDB = e.PDB
+	}
+	if len(e.Ports) != 0 {
+		ports := map[string]int{}
+		for k, v := range base.Ports {
+			ports[k] = v
+		}
+		for k, v := range e.Ports {
+			ports[k] = v
+		}
+		out.Ports = ports
+	}
+	return out
+}
+
+// Env 合并出某个环境最终的布署参数
+func (c *DeployConfig) Env(env string) DeployEnv {
+	return c.Envs[env].merge(c.Defaults.merge(defaultDeployEnv))
+}
+
+// Project 从项目目录推导出的布署信息
+type Project struct {
+	Dir    string       // 项目根目录
+	Env    string       // 环境 local/dev/test/prod
+	Config DeployConfig // .builderc 中的 deploy 配置 Name 需已填充
+}
+
+// ConfigFile 当前环境的配置文件名
+func (p *Project) ConfigFile() string {
+	return fmt.Sprintf("config_%s.yaml", p.Env)
+}
+
+// Entries 需要布署的入口
+func (p *Project) Entries() ([]string, error) {
+	if len(p.Config.Entries) != 0 {
+		return p.Config.Entries, nil
+	}
+	return DetectEntries(p.Dir)
+}
+
+// StartCommand 某个入口的启动命令
+func (p *Project) StartCommand(entry string) string {
+	tpl := p.Config.StartCommand
+	if tpl == "" {
+		tpl = defaultStartCommand
+	}
+	return strings.NewReplacer(
+		"{name}", p.Config.Name,
+		"{entry}", entry,
+		"{env}", p.Env,
+		"{config}", p.ConfigFile(),
+	).Replace(tpl)
+}
+
+// Image 当前环境使用的镜像
+func (p *Project) Image(env DeployEnv) string {
+	image := p.Config.Image
+	if image == "" {
+		image = p.Config.Name
+	}
+	tag := env.ImageTag
+	if tag == "" {
+		tag = p.Env
+	}
+	// 已经写明tag的不再追加
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image
+	}
+	return fmt.Sprintf("%s:%s", image, tag)
+}
+
+// Ports 各入口的端口 .builderc 中指定的优先 其次读取配置文件
+func (p *Project) Ports() (map[string]int, error) {
+	ports, err := DetectPorts(filepath.Join(p.Dir, p.ConfigFile()))
+	if err != nil {
+		return nil, err
+	}
+	for entry, port := range p.Config.Env(p.Env).Ports {
+		ports[entry] = port
+	}
+	return ports, nil
+}
+
+// AppName 单入口项目直接使用服务名 多入口时带上入口名
+func (p *Project) AppName(entry string, entries []string) string {
+	if len(entries) == 1 {
+		return p.Config.Name
+	}
+	return fmt.Sprintf("%s-%s", p.Config.Name, entry)
+}
+
+// Build 生成某个环境的完整资源列表
+func (p *Project) Build() ([]interface{}, error) {
+	if p.Config.Name == "" {
+		return nil, fmt.Errorf("无法确定服务名, 请在 .builderc 的 deploy.name 中指定")
+	}
+	if !contains(Envs, p.Env) {
+		return nil, fmt.Errorf("环境 %s 不支持, 可选 %v", p.Env, Envs)
+	}
+	version, err := ParseVersion(p.Config.Version)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := p.Entries()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s 下没有找到可布署的入口", filepath.Join(p.Dir, "cmd"))
+	}
+	env := p.Config.Env(p.Env)
+	namespace := env.Namespace
+	if namespace == "" {
+		namespace = p.Config.Namespace
+	}
+	ports, err := p.Ports()
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []interface{}
+	var ingressEntry string
+	var ingressPort int
+	if env.Ingress != nil {
+		ingressEntry = env.Ingress.Entry
+		if ingressEntry == "" {
+			ingressEntry = "api"
+		}
+	}
+	for _, entry := range entries {
+		port := ports[entry]
+		replicas := int32(2)
+		if env.Replicas != nil {
+			replicas = *env.Replicas
+		}
+		items, err := BuildDeployment(&DeploymentOption{
+			Name:           p.AppName(entry, entries),
+			Namespace:      namespace,
+			Image:          p.Image(env),
+			StartCommand:   p.StartCommand(entry),
+			Replicas:       replicas,
+			MaxSurge:       env.MaxSurge,
+			MaxUnavailable: env.MaxUnavailable,
+			CPULimit:       env.CPUMax,
+			MemLimit:       env.MemMax,
+			CPURequest:     env.CPUMin,
+			MemRequest:     env.MemMin,
+			Port:           port,
+			TargetPort:     env.TargetPort,
+			Protocol:       "TCP",
+			PortName:       portName(entry),
+			AppProtocol:    env.AppProtocol,
+			Version:        version,
+			Probe:          env.Probe,
+			HPA:            env.HPA,
+			PDB:            env.PDB,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("入口 %s: %w", entry, err)
+		}
+		objects = append(objects, items...)
+		if entry == ingressEntry {
+			ingressPort = port
+			if env.TargetPort != 0 {
+				ingressPort = env.TargetPort
+			}
+		}
+	}
+
+	if env.Ingress != nil {
+		if ingressPort == 0 {
+			return nil, fmt.Errorf("ingress 转发的入口 %s 不存在或没有端口", ingressEntry)
+		}
+		ingress, err := BuildIngress(&IngressOption{
+			Name:       p.AppName(ingressEntry, entries),
+			Namespace:  namespace,
+			Port:       ingressPort,
+			Hosts:      env.Ingress.Hosts,
+			Paths:      env.Ingress.Paths,
+			SecretName: env.Ingress.SecretName,
+			Websocket:  env.Ingress.Websocket,
+			LBMethod:   env.Ingress.LBMethod,
+			Version:    version,
+		})
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, ingress)
+	}
+	return objects, nil
+}
+
+// portName grpc入口的端口命名为grpc 方便istio识别协议
+func portName(entry string) string {
+	if strings.Contains(entry, "grpc") || strings.Contains(entry, "rpc") {
+		return "grpc"
+	}
+	return "http"
+}
+
+// DetectEntries 从 cmd/ 目录中找出所有入口 exec.go 是公共的执行器不算入口
+func DetectEntries(dir string) ([]string, error) {
+	files, err := ioutil.ReadDir(filepath.Join(dir, "cmd"))
+	if err != nil {
+		return nil, fmt.Errorf("读取cmd目录失败: %+v", err)
+	}
+	var entries []string
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		entry := strings.TrimSuffix(name, ".go")
+		if entry == "exec" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Strings(entries)
+	return entries, nil
+}
+
+// 配置文件中表示监听地址的键
+var listenKeys = []string{"port", "addr", "address", "listen"}
+
+// 配置段名与入口的对应关系
+var entrySections = map[string][]string{
+	"api":  {"api", "http", "server", "web", "gin"},
+	"grpc": {"grpc", "rpc"},
+}
+
+var portReg = regexp.MustCompile(`(?:^|:)(\d{1,5})$`)
+
+// DetectPorts 从 config_<env>.yaml 中读取各入口的监听端口 文件不存在时返回错误
+func DetectPorts(configFile string) (map[string]int, error) {
+	ports := map[string]int{}
+	body, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("配置文件 %s 不存在, 请先创建该环境的配置", configFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %+v", configFile, err)
+	}
+	var found []int
+	walkPorts(doc, nil, func(path []string, port int) {
+		found = append(found, port)
+		for entry, sections := range entrySections {
+			if _, ok := ports[entry]; ok {
+				continue
+			}
+			for _, p := range path {
+				if contains(sections, strings.ToLower(p)) {
+					ports[entry] = port
+				}
+			}
+		}
+	})
+	// 只有一个监听端口时认为是 api 入口
+	if len(ports) == 0 && len(found) == 1 {
+		ports["api"] = found[0]
+	}
+	return ports, nil
+}
+
+func walkPorts(node map[string]interface{}, path []string, fn func(path []string, port int)) {
+	keys := make([]string, 0, len(node))
+	for k := range node {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		switch v := node[k].(type) {
+		case map[string]interface{}:
+			walkPorts(v, append(append([]string{}, path...), k), fn)
+		case int:
+			if contains(listenKeys, strings.ToLower(k)) && v > 0 && v <= 65535 {
+				fn(path, v)
+			}
+		case string:
+			if !contains(listenKeys, strings.ToLower(k)) {
+				continue
+			}
+			if res := portReg.FindStringSubmatch(v); len(res) == 2 {
+				if port, _ := strconv.Atoi(res[1]); port > 0 && port <= 65535 {
+					fn(path, port)
+				}
+			}
+		}
+	}
+}
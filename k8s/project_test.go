@@ -0,0 +1,93 @@
+package k8s
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/actorbuf/iotaer/internal/testproject"
+	"gopkg.in/yaml.v3"
+)
+
+func TestProjectBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "k8s_project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	testproject.WriteFiles(t, dir, map[string]string{
+		"cmd/api.go":       "package cmd",
+		"cmd/grpc.go":      "package cmd",
+		"cmd/exec.go":      "package cmd",
+		"config_prod.yaml": "http:\n  addr: \":8080\"\ngrpc:\n  port: 9090\n",
+	})
+
+	var c DeployConfig
+	err = yaml.Unmarshal([]byte(`
+name: user
+namespace: actor
+image: registry.example.com/user
+defaults:
+  replicas: 1
+envs:
+  prod:
+    replicas: 3
+    cpu_max: "1"
+    ingress:
+      hosts: [user.example.com]
+`), &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project := &Project{Dir: dir, Env: "prod", Config: c}
+	objects, err := project.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// api/grpc 各一组 deployment+service 再加一个 ingress
+	if len(objects) != 5 {
+		t.Fatalf("want 5 objects, got %d", len(objects))
+	}
+	api := objects[0].(*Deployment)
+	if api.Metadata.Name != "user-api" || api.Spec.Replicas != 3 {
+		t.Errorf("unexpected api deployment: %+v", api.Metadata)
+	}
+	container := api.Spec.Template.Spec.Containers[0]
+	if container.Image != "registry.example.com/user:prod" || container.Resources.Limits["cpu"] != "1" {
+		t.Errorf("unexpected container: %+v", container)
+	}
+	if got := container.Command; len(got) != 4 || got[1] != "api" || got[3] != "config_prod.yaml" {
+		t.Errorf("unexpected start command: %v", got)
+	}
+	grpcSvc := objects[3].(*Service)
+	if grpcSvc.Spec.Ports[0].Port != 9090 || grpcSvc.Spec.Ports[0].Name != "grpc" {
+		t.Errorf("unexpected grpc service: %+v", grpcSvc.Spec.Ports)
+	}
+	ingress := objects[4].(*Ingress)
+	if ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Port.Number != 8080 {
+		t.Errorf("ingress should forward to api port")
+	}
+}
+
+func TestProjectBuildEnv(t *testing.T) {
+	dir := t.TempDir()
+	testproject.WriteFiles(t, dir, map[string]string{
+		"cmd/api.go":       "package cmd",
+		"config_prod.yaml": "http:\n  addr: \":8080\"\n",
+	})
+	c := DeployConfig{Name: "user"}
+	// 不支持的环境与缺少配置文件的环境都需要报错
+	for _, env := range []string{"staging", "dev"} {
+		if _, err := (&Project{Dir: dir, Env: env, Config: c}).Build(); err == nil {
+			t.Errorf("%s: want error", env)
+		}
+	}
+	b, err := BuildBundle(dir, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Envs) != 1 || b.Base == nil {
+		t.Errorf("bundle should only contain prod, got %v", b.sortedEnvs())
+	}
+}
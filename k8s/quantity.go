@@ -0,0 +1,116 @@
+package k8s
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var quantityReg = regexp.MustCompile(`^([0-9]+(?:\.[0-9]*)?|\.[0-9]+)(m|k|M|G|T|P|E|Ki|Mi|Gi|Ti|Pi|Ei|[eE][+-]?[0-9]+)?$`)
+
+var quantitySuffix = map[string]float64{
+	"":   1,
+	"m":  1e-3,
+	"k":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"P":  1e15,
+	"E":  1e18,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+	"Pi": 1 << 50,
+	"Ei": 1 << 60,
+}
+
+// ParseQuantity 解析k8s资源数量 如 400m 512Mi 0.5 返回以基础单位计的数值
+func ParseQuantity(q string) (float64, error) {
+	res := quantityReg.FindStringSubmatch(q)
+	if len(res) == 0 {
+		return 0, fmt.Errorf("资源数量格式不正确: %q", q)
+	}
+	num, err := strconv.ParseFloat(res[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("资源数量格式不正确: %q", q)
+	}
+	scale, ok := quantitySuffix[res[2]]
+	if !ok {
+		// 科学计数法 如 1e3
+		exp, _ := strconv.Atoi(res[2][1:])
+		return num * math.Pow10(exp), nil
+	}
+	return num * scale, nil
+}
+
+// checkResourcePair 校验预划资源与最大资源 预划不得超过最大值
+func checkResourcePair(kind, request, limit string) error {
+	var req, lim float64
+	var err error
+	if request != "" {
+		if req, err = ParseQuantity(request); err != nil {
+			return fmt.Errorf("%s 预划资源 %w", kind, err)
+		}
+	}
+	if limit != "" {
+		if lim, err = ParseQuantity(limit); err != nil {
+			return fmt.Errorf("%s 最大资源 %w", kind, err)
+		}
+	}
+	if request != "" && limit != "" && req > lim {
+		return fmt.Errorf("%s 预划资源 %s 大于最大资源 %s", kind, request, limit)
+	}
+	return nil
+}
+
+// IntOrString 对应k8s的 intstr.IntOrString 按原类型输出到yaml
+type IntOrString struct {
+	IsString bool
+	IntVal   int
+	StrVal   string
+}
+
+// FromInt 构造整型值
+func FromInt(i int) IntOrString {
+	return IntOrString{IntVal: i}
+}
+
+// FromString 构造字符串值
+func FromString(s string) IntOrString {
+	return IntOrString{IsString: true, StrVal: s}
+}
+
+// MarshalYAML 实现 yaml.Marshaler
+func (i IntOrString) MarshalYAML() (interface{}, error) {
+	if i.IsString {
+		return i.StrVal, nil
+	}
+	return i.IntVal, nil
+}
+
+// ParseIntOrPercent 解析滚动更新参数 可以是非负整数也可以是 0%-100% 的百分比
+func ParseIntOrPercent(name, v string) (IntOrString, error) {
+	if strings.HasSuffix(v, "%") {
+		p, err := strconv.Atoi(strings.TrimSuffix(v, "%"))
+		if err != nil || p < 0 || p > 100 {
+			return IntOrString{}, fmt.Errorf("%s 百分比格式不正确: %s, 取值范围 0%%-100%%", name, v)
+		}
+		return FromString(v), nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return IntOrString{}, fmt.Errorf("%s 需为非负整数或百分比: %s", name, v)
+	}
+	return FromInt(n), nil
+}
+
+// isZero 数值0或0%
+func (i IntOrString) isZero() bool {
+	if i.IsString {
+		return strings.TrimSuffix(i.StrVal, "%") == "0"
+	}
+	return i.IntVal == 0
+}
@@ -0,0 +1,33 @@
+package k8s
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Render 将多个资源以 --- 分隔输出为yaml
+func Render(w io.Writer, objects ...interface{}) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	for _, obj := range objects {
+		if err := enc.Encode(obj); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// WriteFile 将资源渲染后写入文件 file 为空时输出到 w
+func WriteFile(w io.Writer, file string, objects ...interface{}) error {
+	if file == "" {
+		return Render(w, objects...)
+	}
+	var buf bytes.Buffer
+	if err := Render(&buf, objects...); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, buf.Bytes(), 0644)
+}
@@ -0,0 +1,246 @@
+package k8s
+
+// 以下结构体只覆盖生成器用到的字段 字段命名与k8s api保持一致
+
+// TypeMeta 资源类型
+type TypeMeta struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+}
+
+// ObjectMeta 资源元信息
+type ObjectMeta struct {
+	Name        string            `yaml:"name,omitempty"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// LabelSelector 标签选择器
+type LabelSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+// Deployment 无状态应用
+type Deployment struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta     `yaml:"metadata"`
+	Spec     DeploymentSpec `yaml:"spec"`
+}
+
+// DeploymentSpec Deployment描述
+type DeploymentSpec struct {
+	Replicas int32              `yaml:"replicas"`
+	Selector LabelSelector      `yaml:"selector"`
+	Strategy DeploymentStrategy `yaml:"strategy"`
+	Template PodTemplateSpec    `yaml:"template"`
+}
+
+// DeploymentStrategy 更新策略
+type DeploymentStrategy struct {
+	Type          string                   `yaml:"type"`
+	RollingUpdate *RollingUpdateDeployment `yaml:"rollingUpdate,omitempty"`
+}
+
+// RollingUpdateDeployment 滚动更新参数
+type RollingUpdateDeployment struct {
+	MaxSurge       IntOrString `yaml:"maxSurge"`
+	MaxUnavailable IntOrString `yaml:"maxUnavailable"`
+}
+
+// PodTemplateSpec pod模板
+type PodTemplateSpec struct {
+	Metadata ObjectMeta `yaml:"metadata"`
+	Spec     PodSpec    `yaml:"spec"`
+}
+
+// PodSpec pod描述
+type PodSpec struct {
+	Containers []Container `yaml:"containers"`
+}
+
+// Container 容器
+type Container struct {
+	Name            string               `yaml:"name"`
+	Image           string               `yaml:"image"`
+	ImagePullPolicy string               `yaml:"imagePullPolicy,omitempty"`
+	Command         []string             `yaml:"command,omitempty"`
+	Ports           []ContainerPort      `yaml:"ports,omitempty"`
+	Resources       ResourceRequirements `yaml:"resources"`
+	LivenessProbe   *Probe               `yaml:"livenessProbe,omitempty"`
+	ReadinessProbe  *Probe               `yaml:"readinessProbe,omitempty"`
+	StartupProbe    *Probe               `yaml:"startupProbe,omitempty"`
+}
+
+// Probe 健康检查 HTTPGet 与 TCPSocket 二选一
+type Probe struct {
+	HTTPGet             *HTTPGetAction   `yaml:"httpGet,omitempty"`
+	TCPSocket           *TCPSocketAction `yaml:"tcpSocket,omitempty"`
+	InitialDelaySeconds int              `yaml:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int              `yaml:"periodSeconds,omitempty"`
+	TimeoutSeconds      int              `yaml:"timeoutSeconds,omitempty"`
+	FailureThreshold    int              `yaml:"failureThreshold,omitempty"`
+}
+
+// HTTPGetAction http健康检查
+type HTTPGetAction struct {
+	Path string      `yaml:"path"`
+	Port IntOrString `yaml:"port"`
+}
+
+// TCPSocketAction tcp健康检查
+type TCPSocketAction struct {
+	Port IntOrString `yaml:"port"`
+}
+
+// ContainerPort 容器端口
+type ContainerPort struct {
+	Name          string `yaml:"name,omitempty"`
+	ContainerPort int    `yaml:"containerPort"`
+	Protocol      string `yaml:"protocol,omitempty"`
+}
+
+// ResourceList 资源名到数量的映射 如 cpu: 400m
+type ResourceList map[string]string
+
+// ResourceRequirements 资源限制
+type ResourceRequirements struct {
+	Limits   ResourceList `yaml:"limits,omitempty"`
+	Requests ResourceList `yaml:"requests,omitempty"`
+}
+
+// Service 服务
+type Service struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta  `yaml:"metadata"`
+	Spec     ServiceSpec `yaml:"spec"`
+}
+
+// ServiceSpec 服务描述
+type ServiceSpec struct {
+	Type     string            `yaml:"type,omitempty"`
+	Selector map[string]string `yaml:"selector"`
+	Ports    []ServicePort     `yaml:"ports"`
+}
+
+// ServicePort 服务端口
+type ServicePort struct {
+	Name        string      `yaml:"name,omitempty"`
+	Protocol    string      `yaml:"protocol,omitempty"`
+	AppProtocol string      `yaml:"appProtocol,omitempty"`
+	Port        int         `yaml:"port"`
+	TargetPort  IntOrString `yaml:"targetPort"`
+}
+
+// Ingress 入口
+type Ingress struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta  `yaml:"metadata"`
+	Spec     IngressSpec `yaml:"spec"`
+}
+
+// IngressSpec 入口描述
+type IngressSpec struct {
+	IngressClassName string        `yaml:"ingressClassName,omitempty"`
+	TLS              []IngressTLS  `yaml:"tls,omitempty"`
+	Rules            []IngressRule `yaml:"rules"`
+}
+
+// IngressTLS 证书配置
+type IngressTLS struct {
+	Hosts      []string `yaml:"hosts"`
+	SecretName string   `yaml:"secretName"`
+}
+
+// IngressRule 按host的转发规则
+type IngressRule struct {
+	Host string               `yaml:"host"`
+	HTTP HTTPIngressRuleValue `yaml:"http"`
+}
+
+// HTTPIngressRuleValue 路径列表
+type HTTPIngressRuleValue struct {
+	Paths []HTTPIngressPath `yaml:"paths"`
+}
+
+// HTTPIngressPath 单个路径转发
+type HTTPIngressPath struct {
+	Path     string         `yaml:"path"`
+	PathType string         `yaml:"pathType,omitempty"`
+	Backend  IngressBackend `yaml:"backend"`
+}
+
+// IngressBackend 后端服务
+// networking.k8s.io/v1 使用 Service 字段, extensions/v1beta1 使用 ServiceName/ServicePort 字段
+type IngressBackend struct {
+	Service     *IngressServiceBackend `yaml:"service,omitempty"`
+	ServiceName string                 `yaml:"serviceName,omitempty"`
+	ServicePort *IntOrString           `yaml:"servicePort,omitempty"`
+}
+
+// IngressServiceBackend networking.k8s.io/v1 的后端服务
+type IngressServiceBackend struct {
+	Name string             `yaml:"name"`
+	Port ServiceBackendPort `yaml:"port"`
+}
+
+// ServiceBackendPort 后端服务端口
+type ServiceBackendPort struct {
+	Number int `yaml:"number"`
+}
+
+// HorizontalPodAutoscaler 水平自动扩缩容
+// autoscaling/v1 只支持 TargetCPUUtilizationPercentage, v2beta2 及 v2 使用 Metrics
+type HorizontalPodAutoscaler struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta `yaml:"metadata"`
+	Spec     HPASpec    `yaml:"spec"`
+}
+
+// HPASpec 扩缩容描述
+type HPASpec struct {
+	ScaleTargetRef                 CrossVersionObjectReference `yaml:"scaleTargetRef"`
+	MinReplicas                    int32                       `yaml:"minReplicas"`
+	MaxReplicas                    int32                       `yaml:"maxReplicas"`
+	TargetCPUUtilizationPercentage int32                       `yaml:"targetCPUUtilizationPercentage,omitempty"`
+	Metrics                        []MetricSpec                `yaml:"metrics,omitempty"`
+}
+
+// CrossVersionObjectReference 扩缩容目标
+type CrossVersionObjectReference struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Name       string `yaml:"name"`
+}
+
+// MetricSpec 扩缩容指标
+type MetricSpec struct {
+	Type     string               `yaml:"type"`
+	Resource ResourceMetricSource `yaml:"resource"`
+}
+
+// ResourceMetricSource 资源指标
+type ResourceMetricSource struct {
+	Name   string       `yaml:"name"`
+	Target MetricTarget `yaml:"target"`
+}
+
+// MetricTarget 指标目标值
+type MetricTarget struct {
+	Type               string `yaml:"type"`
+	AverageUtilization int32  `yaml:"averageUtilization"`
+}
+
+// PodDisruptionBudget 中断预算
+type PodDisruptionBudget struct {
+	TypeMeta `yaml:",inline"`
+	Metadata ObjectMeta `yaml:"metadata"`
+	Spec     PDBSpec    `yaml:"spec"`
+}
+
+// PDBSpec 中断预算描述 MinAvailable 与 MaxUnavailable 二选一
+type PDBSpec struct {
+	MinAvailable   *IntOrString  `yaml:"minAvailable,omitempty"`
+	MaxUnavailable *IntOrString  `yaml:"maxUnavailable,omitempty"`
+	Selector       LabelSelector `yaml:"selector"`
+}
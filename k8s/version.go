@@ -0,0 +1,79 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// DefaultVersion 未指定 --version 时使用的k8s版本
+var DefaultVersion = Version{Major: 1, Minor: 20}
+
+var versionReg = regexp.MustCompile(`^v?(\d+)\.(\d+)(\.\d+)?$`)
+
+// Version k8s集群版本 只关心主次版本号
+type Version struct {
+	Major int
+	Minor int
+}
+
+// ParseVersion 解析 v1.20 / 1.20 / v1.20.3 形式的版本号 空字符串返回 DefaultVersion
+func ParseVersion(v string) (Version, error) {
+	if v == "" {
+		return DefaultVersion, nil
+	}
+	res := versionReg.FindStringSubmatch(v)
+	if len(res) == 0 {
+		return Version{}, fmt.Errorf("k8s版本格式不正确: %s, 示例: v1.20", v)
+	}
+	major, _ := strconv.Atoi(res[1])
+	minor, _ := strconv.Atoi(res[2])
+	return Version{Major: major, Minor: minor}, nil
+}
+
+// AtLeast 当前版本是否不低于 major.minor
+func (v Version) AtLeast(major, minor int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	return v.Minor >= minor
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("v%d.%d", v.Major, v.Minor)
+}
+
+// deploymentAPIVersion 1.9 起 Deployment 进入 apps/v1
+func (v Version) deploymentAPIVersion() string {
+	if v.AtLeast(1, 9) {
+		return "apps/v1"
+	}
+	return "extensions/v1beta1"
+}
+
+// ingressAPIVersion 1.19 起 Ingress 进入 networking.k8s.io/v1
+func (v Version) ingressAPIVersion() string {
+	if v.AtLeast(1, 19) {
+		return "networking.k8s.io/v1"
+	}
+	return "extensions/v1beta1"
+}
+
+// hpaAPIVersion 1.23 起 autoscaling/v2 GA, 1.12 起可用 autoscaling/v2beta2
+func (v Version) hpaAPIVersion() string {
+	switch {
+	case v.AtLeast(1, 23):
+		return "autoscaling/v2"
+	case v.AtLeast(1, 12):
+		return "autoscaling/v2beta2"
+	}
+	return "autoscaling/v1"
+}
+
+// pdbAPIVersion 1.21 起 PodDisruptionBudget 进入 policy/v1
+func (v Version) pdbAPIVersion() string {
+	if v.AtLeast(1, 21) {
+		return "policy/v1"
+	}
+	return "policy/v1beta1"
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/actorbuf/iotaer/lint"
+	"github.com/actorbuf/iotaer/protodef"
+	"github.com/actorbuf/iotaer/protofmt"
+	"github.com/spf13/cobra"
+)
+
+func lintCommand() *cobra.Command {
+	pbPath, _ := os.Getwd()
+	var format = lint.FormatText
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "检查 proto 文件的注释与命名规范",
+		Long: "检查 proto 文件: 注释缺失、命名规范、接口 Req/Resp 后缀、保留字段复用、go_package、枚举零值与重复路由. " +
+			"规则级别在 .builderc 的 lint.rules 中配置 [error,warning,off], 存在 error 级别的问题时返回非0",
+		Example: "builder lint --path model\nbuilder lint --format sarif > lint.sarif",
+		Run: func(cmd *cobra.Command, args []string) {
+			files, err := loadProtos(pbPath)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			issues, err := lint.Run(files, parseConfig().Lint)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if err := lint.Write(os.Stdout, format, issues); err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if lint.HasError(issues) {
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&pbPath, "path", pbPath, "proto文件地址,支持传入目录")
+	cmd.Flags().StringVar(&format, "format", format, "输出格式 [text,json,sarif]")
+	return cmd
+}
+
+// loadProtos 解析 path 下的 proto 文件 位置使用相对当前目录的路径
+func loadProtos(path string) ([]*protodef.File, error) {
+	paths, err := protofmt.Files(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []*protodef.File
+	wd, _ := os.Getwd()
+	for _, p := range paths {
+		if rel, err := filepath.Rel(wd, p); err == nil && !strings.HasPrefix(rel, "..") {
+			p = rel
+		}
+		f, err := protodef.ParseFile(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
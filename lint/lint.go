@@ -0,0 +1,330 @@
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/actorbuf/iotaer/protodef"
+)
+
+// 规则级别
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityOff     = "off"
+)
+
+// Rule 一条检查规则
+type Rule struct {
+	ID       string `json:"id"`
+	Desc     string `json:"desc"`
+	Severity string `json:"severity"` // 默认级别
+	check    func(l *linter)
+}
+
+// Rules 全部规则 顺序即检查顺序
+var Rules = []*Rule{
+	{ID: "message-comment", Desc: "message 需要注释", Severity: SeverityWarning, check: checkMessageComment},
+	{ID: "field-comment", Desc: "字段需要注释", Severity: SeverityWarning, check: checkFieldComment},
+	{ID: "rpc-comment", Desc: "rpc 需要注释", Severity: SeverityWarning, check: checkRPCComment},
+	{ID: "message-name", Desc: "message 与 enum 使用大驼峰命名", Severity: SeverityError, check: checkMessageName},
+	{ID: "field-name", Desc: "字段使用下划线命名", Severity: SeverityError, check: checkFieldName},
+	{ID: "api-message-suffix", Desc: "路由组接口的请求与响应以 Req/Resp 结尾", Severity: SeverityError, check: checkAPIMessageSuffix},
+	{ID: "reserved-field", Desc: "不能使用已保留的字段号与字段名", Severity: SeverityError, check: checkReservedField},
+	{ID: "go-package", Desc: "需要设置 option go_package", Severity: SeverityError, check: checkGoPackage},
+	{ID: "enum-zero", Desc: "枚举的零值以 Nil 结尾", Severity: SeverityError, check: checkEnumZero},
+	{ID: "duplicate-route", Desc: "路由组之间不能有重复的 HTTP 路径", Severity: SeverityError, check: checkDuplicateRoute},
+}
+
+// Config .builderc 中的 lint 配置
+type Config struct {
+	Rules   map[string]string `yaml:"rules" json:"rules"`     // 规则级别 [error,warning,off] 未配置时使用默认级别
+	Exclude []string          `yaml:"exclude" json:"exclude"` // 不检查的文件 支持通配符 匹配路径或文件名
+}
+
+// Validate 校验规则名与级别
+func (c *Config) Validate() error {
+	for id, severity := range c.Rules {
+		if FindRule(id) == nil {
+			return fmt.Errorf("未知的 lint 规则: %s", id)
+		}
+		switch severity {
+		case SeverityError, SeverityWarning, SeverityOff:
+		default:
+			return fmt.Errorf("lint 规则 %s 的级别 %s 不正确, 可选 error,warning,off", id, severity)
+		}
+	}
+	for _, pattern := range c.Exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("exclude %s 格式不正确: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// severity 规则的实际级别
+func (c *Config) severity(r *Rule) string {
+	if s, ok := c.Rules[r.ID]; ok {
+		return s
+	}
+	return r.Severity
+}
+
+func (c *Config) excluded(path string) bool {
+	path = filepath.ToSlash(path)
+	for _, pattern := range c.Exclude {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FindRule 按 ID 查找规则
+func FindRule(id string) *Rule {
+	for _, r := range Rules {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// Issue 一个检查结果
+type Issue struct {
+	Rule     string            `json:"rule"`
+	Severity string            `json:"severity"`
+	Message  string            `json:"message"`
+	Pos      protodef.Position `json:"pos"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", i.Pos, i.Severity, i.Message, i.Rule)
+}
+
+// HasError 是否存在 error 级别的问题
+func HasError(issues []Issue) bool {
+	for _, i := range issues {
+		if i.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+type linter struct {
+	files    []*protodef.File
+	severity string
+	rule     *Rule
+	issues   []Issue
+}
+
+func (l *linter) report(pos protodef.Position, format string, args ...interface{}) {
+	l.issues = append(l.issues, Issue{
+		Rule:     l.rule.ID,
+		Severity: l.severity,
+		Message:  fmt.Sprintf(format, args...),
+		Pos:      pos,
+	})
+}
+
+// Run 按配置检查 结果按位置排序
+func Run(files []*protodef.File, c Config) ([]Issue, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	l := &linter{}
+	for _, f := range files {
+		if !c.excluded(f.Path) {
+			l.files = append(l.files, f)
+		}
+	}
+	for _, r := range Rules {
+		if l.severity = c.severity(r); l.severity == SeverityOff {
+			continue
+		}
+		l.rule = r
+		r.check(l)
+	}
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		a, b := l.issues[i].Pos, l.issues[j].Pos
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.issues, nil
+}
+
+func checkMessageComment(l *linter) {
+	for _, f := range l.files {
+		for _, m := range f.Messages {
+			if protodef.Describe(m.Comment) == "" {
+				l.report(m.Pos, "message %s 缺少注释", m.Name)
+			}
+		}
+	}
+}
+
+func checkFieldComment(l *linter) {
+	for _, f := range l.files {
+		for _, m := range f.Messages {
+			for _, fd := range m.Fields {
+				if protodef.Describe(fd.Comment) == "" {
+					l.report(fd.Pos, "字段 %s.%s 缺少注释", m.Name, fd.Name)
+				}
+			}
+		}
+	}
+}
+
+func checkRPCComment(l *linter) {
+	for _, f := range l.files {
+		for _, s := range f.Services {
+			for _, m := range s.Methods {
+				if protodef.Describe(m.Comment) == "" {
+					l.report(m.Pos, "rpc %s.%s 缺少注释", s.Name, m.Name)
+				}
+			}
+		}
+	}
+}
+
+var (
+	pascalReg = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+	snakeReg  = regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`)
+)
+
+func checkMessageName(l *linter) {
+	for _, f := range l.files {
+		for _, m := range f.Messages {
+			if name := baseName(m.Name); !pascalReg.MatchString(name) {
+				l.report(m.Pos, "message %s 应使用大驼峰命名", name)
+			}
+		}
+		for _, e := range f.Enums {
+			if name := baseName(e.Name); !pascalReg.MatchString(name) {
+				l.report(e.Pos, "enum %s 应使用大驼峰命名", name)
+			}
+		}
+	}
+}
+
+func checkFieldName(l *linter) {
+	for _, f := range l.files {
+		for _, m := range f.Messages {
+			for _, fd := range m.Fields {
+				if !snakeReg.MatchString(fd.Name) {
+					l.report(fd.Pos, "字段 %s.%s 应使用下划线命名", m.Name, fd.Name)
+				}
+			}
+		}
+	}
+}
+
+func checkAPIMessageSuffix(l *linter) {
+	for _, f := range l.files {
+		for _, s := range f.Services {
+			if s.Kind != protodef.KindRoute {
+				continue
+			}
+			for _, m := range s.Methods {
+				if !strings.HasSuffix(baseName(m.Req), "Req") {
+					l.report(m.Pos, "接口 %s 的请求 %s 应以 Req 结尾", m.Name, m.Req)
+				}
+				if !strings.HasSuffix(baseName(m.Resp), "Resp") {
+					l.report(m.Pos, "接口 %s 的响应 %s 应以 Resp 结尾", m.Name, m.Resp)
+				}
+			}
+		}
+	}
+}
+
+func checkReservedField(l *linter) {
+	for _, f := range l.files {
+		for _, m := range f.Messages {
+			for _, fd := range m.Fields {
+				if m.Reserved.HasNumber(fd.Number) {
+					l.report(fd.Pos, "字段 %s.%s 使用了已保留的字段号 %d", m.Name, fd.Name, fd.Number)
+				}
+				if m.Reserved.HasName(fd.Name) {
+					l.report(fd.Pos, "字段 %s.%s 使用了已保留的字段名", m.Name, fd.Name)
+				}
+			}
+		}
+	}
+}
+
+func checkGoPackage(l *linter) {
+	for _, f := range l.files {
+		if f.GoPackage == "" {
+			l.report(f.Pos, "缺少 option go_package")
+		}
+	}
+}
+
+func checkEnumZero(l *linter) {
+	for _, f := range l.files {
+		for _, e := range f.Enums {
+			var zero *protodef.EnumValue
+			for _, v := range e.Values {
+				if v.Number == 0 {
+					zero = v
+					break
+				}
+			}
+			switch {
+			case zero == nil:
+				l.report(e.Pos, "enum %s 缺少零值", e.Name)
+			case !strings.HasSuffix(zero.Name, "Nil"):
+				l.report(zero.Pos, "enum %s 的零值 %s 应以 Nil 结尾, 如 %sNil", e.Name, zero.Name, baseName(e.Name))
+			}
+		}
+	}
+}
+
+// checkDuplicateRoute 检查所有文件的路由组 ANY 与任意请求方式冲突
+func checkDuplicateRoute(l *linter) {
+	type route struct {
+		method string
+		name   string
+		pos    protodef.Position
+	}
+	seen := map[string][]route{}
+	for _, f := range l.files {
+		for _, s := range f.Services {
+			if s.Kind != protodef.KindRoute {
+				continue
+			}
+			for _, m := range s.Methods {
+				path := s.FullPath(m)
+				var conflict *route
+				for i, r := range seen[path] {
+					if r.method == m.HTTPMethod || r.method == "ANY" || m.HTTPMethod == "ANY" {
+						conflict = &seen[path][i]
+						break
+					}
+				}
+				if conflict != nil {
+					l.report(m.Pos, "%s %s 与 %s(%s) 重复", m.HTTPMethod, path, conflict.name, conflict.pos)
+					continue
+				}
+				seen[path] = append(seen[path], route{method: m.HTTPMethod, name: s.Name + "." + m.Name, pos: m.Pos})
+			}
+		}
+	}
+}
+
+// baseName 去掉包名与外层 message 的名称
+func baseName(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,165 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/actorbuf/iotaer/protodef"
+)
+
+const userProto = `syntax = "proto3";
+package user;
+
+message user_info {
+    string userName = 1;
+    int64 id = 5; // ID
+    reserved 5;
+}
+
+enum Status {
+    StatusUnknown = 0;
+}
+
+// @route_group: true
+// @route_api: /api
+service UserApi {
+    // @desc: 获取用户
+    rpc GetUser (GetUserReq) returns (GetUserResp);
+    // @method: ANY
+    // @api: /list
+    rpc List (ListRequest) returns (ListResp);
+}
+`
+
+const orderProto = `syntax = "proto3";
+package order;
+option go_package = "example.com/app/model/order";
+
+// @route_group: true
+// @route_api: /api/
+service OrderApi {
+    // @desc: 重复
+    // @method: GET
+    // @api: get_user
+    rpc GetUser (GetUserReq) returns (GetUserResp);
+    // @desc: 与 ANY 冲突
+    // @api: /list
+    rpc ListOrder (ListOrderReq) returns (ListOrderResp);
+}
+`
+
+func parse(t *testing.T) []*protodef.File {
+	var files []*protodef.File
+	for _, v := range [][2]string{{"user.proto", userProto}, {"order.proto", orderProto}} {
+		f, err := protodef.Parse(v[0], []byte(v[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+func rules(issues []Issue) map[string]int {
+	res := map[string]int{}
+	for _, i := range issues {
+		res[i.Rule]++
+	}
+	return res
+}
+
+func TestRun(t *testing.T) {
+	issues, err := Run(parse(t), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{
+		"message-comment":    1,
+		"field-comment":      1,
+		"rpc-comment":        1,
+		"message-name":       1,
+		"field-name":         1,
+		"api-message-suffix": 1,
+		"reserved-field":     1,
+		"go-package":         1,
+		"enum-zero":          1,
+		"duplicate-route":    1,
+	}
+	got := rules(issues)
+	for id, n := range want {
+		if got[id] != n {
+			t.Fatalf("rule %s: want %d issues, got %d\n%v", id, n, got[id], issues)
+		}
+	}
+	// 重复路由报告在后出现的位置 结果按文件排序
+	if issues[0].Pos.File != "order.proto" || issues[0].Rule != "duplicate-route" || !HasError(issues) {
+		t.Fatalf("unexpected order %v", issues)
+	}
+	for _, i := range issues {
+		// POST /api/get_user 与 GET /api/get_user 不冲突 ANY /api/list 与所有方式冲突
+		if i.Rule == "duplicate-route" && !strings.Contains(i.Message, "/api/list") {
+			t.Fatalf("unexpected duplicate %v", i)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	c := Config{Rules: map[string]string{"field-name": SeverityOff, "go-package": SeverityWarning}, Exclude: []string{"order.*"}}
+	issues, err := Run(parse(t), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := rules(issues)
+	if got["field-name"] != 0 || got["duplicate-route"] != 0 {
+		t.Fatalf("unexpected issues %v", issues)
+	}
+	for _, i := range issues {
+		if i.Pos.File != "user.proto" || i.Rule == "go-package" && i.Severity != SeverityWarning {
+			t.Fatalf("unexpected issue %v", i)
+		}
+	}
+	for _, c := range []Config{{Rules: map[string]string{"unknown": SeverityError}}, {Rules: map[string]string{"field-name": "info"}}} {
+		if _, err := Run(nil, c); err == nil {
+			t.Fatalf("expected error for %v", c.Rules)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	issues, _ := Run(parse(t), Config{})
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatText, issues[1:2]); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "user.proto:1:1: error: 缺少 option go_package (go-package)\n" {
+		t.Fatalf("unexpected text %q", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, FormatJSON, nil); err != nil || strings.TrimSpace(buf.String()) != "[]" {
+		t.Fatalf("unexpected json %q %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, FormatSARIF, issues); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	run := log.Runs[0]
+	if log.Version != "2.1.0" || len(run.Results) != len(issues) || len(run.Tool.Driver.Rules) != len(Rules) {
+		t.Fatalf("unexpected sarif %s", buf.String())
+	}
+	r := run.Results[0]
+	if run.Tool.Driver.Rules[r.RuleIndex].ID != r.RuleID || r.Locations[0].PhysicalLocation.ArtifactLocation.URI != "order.proto" {
+		t.Fatalf("unexpected result %+v", r)
+	}
+
+	if err := Write(&buf, "xml", issues); err == nil {
+		t.Fatal("expected unsupported format")
+	}
+}
@@ -0,0 +1,127 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// 输出格式
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+// Write 按格式输出检查结果
+func Write(w io.Writer, format string, issues []Issue) error {
+	switch format {
+	case FormatText, "":
+		for _, i := range issues {
+			if _, err := fmt.Fprintln(w, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatJSON:
+		if issues == nil {
+			issues = []Issue{}
+		}
+		return encode(w, issues)
+	case FormatSARIF:
+		return encode(w, sarif(issues))
+	default:
+		return fmt.Errorf("不支持的输出格式 %s, 可选 text,json,sarif", format)
+	}
+}
+
+func encode(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// sarifLog SARIF 2.1.0 只包含用到的字段 供代码扫描平台展示
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine   int `json:"startLine"`
+			StartColumn int `json:"startColumn,omitempty"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+func sarif(issues []Issue) sarifLog {
+	driver := sarifDriver{Name: "iotaer-lint"}
+	index := map[string]int{}
+	for i, r := range Rules {
+		rule := sarifRule{ID: r.ID, ShortDescription: sarifMessage{Text: r.Desc}}
+		rule.DefaultConfiguration.Level = r.Severity
+		driver.Rules = append(driver.Rules, rule)
+		index[r.ID] = i
+	}
+	results := []sarifResult{}
+	for _, i := range issues {
+		var loc sarifLocation
+		loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(i.Pos.File)
+		loc.PhysicalLocation.Region.StartLine = i.Pos.Line
+		loc.PhysicalLocation.Region.StartColumn = i.Pos.Column
+		results = append(results, sarifResult{
+			RuleID:    i.Rule,
+			RuleIndex: index[i.Rule],
+			Level:     i.Severity,
+			Message:   sarifMessage{Text: i.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+}
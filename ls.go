@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/actorbuf/iotaer/catalog"
+	"github.com/spf13/cobra"
+)
+
+func lsCommand() *cobra.Command {
+	pbPath, _ := os.Getwd()
+	var format = "text"
+	var missing bool
+	cmd := &cobra.Command{
+		Use:   "ls routes|rpcs|tasks|errors",
+		Short: "列出项目中的路由、rpc、定时任务与错误码",
+		Long: "解析 --path 下的 proto 文件与当前目录下的 go 代码, 列出路由组接口的请求方式与完整路径、rpc、定时任务以及 ErrCode 错误码, " +
+			"并给出 go 实现的位置, proto 中定义但没有实现的标记为未实现. --missing 只列出未实现的, 存在时返回非0",
+		Example:   "builder ls routes\nbuilder ls rpcs --format json\nbuilder ls routes --missing",
+		Args:      cobra.ExactValidArgs(1),
+		ValidArgs: catalog.Kinds,
+		Run: func(cmd *cobra.Command, args []string) {
+			files, err := loadProtos(pbPath)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			c, err := catalog.New(files, ".")
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			list, err := c.List(args[0], missing)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if err := catalog.Write(os.Stdout, args[0], format, list); err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if items, ok := list.([]catalog.Item); ok && missing && len(items) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&pbPath, "path", pbPath, "proto文件地址,支持传入目录")
+	cmd.Flags().StringVar(&format, "format", format, "输出格式 [text,json]")
+	cmd.Flags().BoolVar(&missing, "missing", false, "只列出proto中定义但没有实现的 ("+strings.Join(catalog.Kinds[:3], "/")+")")
+	return cmd
+}
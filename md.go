@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/actorbuf/iotaer/apidoc"
+	"github.com/actorbuf/iotaer/protodef"
+	"github.com/actorbuf/iotaer/protofmt"
+)
+
+// includeFiles 展开 --include 中的目录
+func includeFiles(include []string) ([]string, error) {
+	var files []string
+	for _, p := range include {
+		paths, err := protofmt.Files(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, paths...)
+	}
+	return files, nil
+}
+
+// loadProject 解析 path 下的路由组 includes 中的文件只用于查找消息与错误码
+func loadProject(path string, includes []string) (*apidoc.Project, error) {
+	files, err := loadProtos(path)
+	if err != nil {
+		return nil, err
+	}
+	var deps []*protodef.File
+	for _, p := range includes {
+		f, err := protodef.ParseFile(p)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, f)
+	}
+	return apidoc.Load(files, deps)
+}
+
+// outputDocs 生成整个项目的 markdown 文档 内容没有变化的页面不重写
+func outputDocs(path string, includes []string, out string) error {
+	p, err := loadProject(path, includes)
+	if err != nil {
+		return err
+	}
+	if len(p.Groups) == 0 {
+		return fmt.Errorf("%s 中没有路由组", path)
+	}
+	pages, err := apidoc.Markdown(p)
+	if err != nil {
+		return err
+	}
+	return writePages(out, pages)
+}
+
+// writePages 把生成的页面写到 out 目录下 内容没有变化的文件不重写
+func writePages(out string, pages []apidoc.Page) error {
+	for _, page := range pages {
+		target := filepath.Join(out, filepath.FromSlash(page.Path))
+		action := "create"
+		if old, err := ioutil.ReadFile(target); err == nil {
+			if string(old) == string(page.Content) {
+				continue
+			}
+			action = "update"
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(target, page.Content, 0644); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "%-8s %s \n", action, target)
+	}
+	return nil
+}
+
+// writeFile 写入单个文件 与 writePages 相同内容不变时跳过
+func writeFile(target string, content []byte) error {
+	return writePages(filepath.Dir(target), []apidoc.Page{{Path: filepath.Base(target), Content: content}})
+}
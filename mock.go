@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/actorbuf/iotaer/apimock"
+	"github.com/spf13/cobra"
+)
+
+func mockCommand() *cobra.Command {
+	pbPath, _ := os.Getwd()
+	var include []string
+	var httpAddr, grpcAddr = ":8080", ":9090"
+	var opt = apimock.Option{Fixtures: "mocks", Log: os.Stdout}
+	cmd := &cobra.Command{
+		Use:   "mock",
+		Short: "按proto启动返回示例响应的http与grpc服务",
+		Long: "按 --path 下的 proto 启动本地 mock 服务, 不需要后端实现即可联调: http 服务提供所有路由组的接口, grpc 服务提供所有 service 的方法(不需要生成 pb 代码). " +
+			"响应优先使用 --mocks 目录中的 <路由组或service>/<方法名>.json, 内容为响应消息或包含 err_code 的完整返回结构, 修改后立即生效; " +
+			"否则按字段类型与注释合成示例值. --latency/--jitter 模拟延迟, --error-rate 按比例返回接口 @error 中标注的错误码, " +
+			"请求头(grpc 为 metadata) X-Mock-Error 与 X-Mock-Latency 可以指定单个请求返回的错误码与延迟",
+		Example: "builder mock --path model/\n" +
+			"builder mock --path model/ --http :8080 --grpc \"\" --latency 200ms --jitter 300ms --error-rate 0.1",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runMock(pbPath, include, httpAddr, grpcAddr, opt); err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&pbPath, "path", pbPath, "proto文件或目录")
+	cmd.Flags().StringSliceVar(&include, "include", include, "依赖到的其他proto文件列表,支持目录")
+	cmd.Flags().StringVar(&httpAddr, "http", httpAddr, "http服务监听地址, 为空时不启动")
+	cmd.Flags().StringVar(&grpcAddr, "grpc", grpcAddr, "grpc服务监听地址, 为空时不启动")
+	cmd.Flags().StringVar(&opt.Fixtures, "mocks", opt.Fixtures, "固定响应所在目录")
+	cmd.Flags().DurationVar(&opt.Latency, "latency", opt.Latency, "每个请求的延迟")
+	cmd.Flags().DurationVar(&opt.Jitter, "jitter", opt.Jitter, "在延迟上随机增加0到jitter")
+	cmd.Flags().Float64Var(&opt.ErrorRate, "error-rate", opt.ErrorRate, "返回错误的比例 0到1")
+	return cmd
+}
+
+// runMock 启动 mock 服务 直到其中一个服务退出
+func runMock(path string, include []string, httpAddr, grpcAddr string, opt apimock.Option) error {
+	if opt.ErrorRate < 0 || opt.ErrorRate > 1 {
+		return fmt.Errorf("--error-rate 应在 0 到 1 之间")
+	}
+	if httpAddr == "" && grpcAddr == "" {
+		return fmt.Errorf("--http 与 --grpc 不能都为空")
+	}
+	includes, err := includeFiles(include)
+	if err != nil {
+		return err
+	}
+	p, err := loadProject(path, includes)
+	if err != nil {
+		return err
+	}
+	services, err := p.Services()
+	if err != nil {
+		return err
+	}
+	if len(p.Groups) == 0 && len(services) == 0 {
+		return fmt.Errorf("%s 中没有路由组与 service", path)
+	}
+	s := apimock.New(p, services, opt)
+
+	errc := make(chan error, 2)
+	if httpAddr != "" {
+		for _, g := range p.Groups {
+			for _, api := range g.APIs {
+				_, _ = fmt.Fprintf(os.Stdout, "%-7s %s\n", api.Method, api.Path)
+			}
+		}
+		srv := &http.Server{Addr: httpAddr, Handler: s, ReadHeaderTimeout: 10 * time.Second}
+		go func() { errc <- srv.ListenAndServe() }()
+		_, _ = fmt.Fprintf(os.Stdout, "mock http 服务已启动 %s\n", httpAddr)
+	}
+	if grpcAddr != "" {
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			return err
+		}
+		for _, svc := range services {
+			for _, rpc := range svc.RPCs {
+				_, _ = fmt.Fprintf(os.Stdout, "%-7s %s\n", "GRPC", rpc.FullMethod())
+			}
+		}
+		go func() { errc <- s.GRPC().Serve(lis) }()
+		_, _ = fmt.Fprintf(os.Stdout, "mock grpc 服务已启动 %s\n", grpcAddr)
+	}
+	return <-errc
+}
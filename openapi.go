@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/actorbuf/iotaer/apidoc"
+	"github.com/actorbuf/iotaer/toolkit"
+	"github.com/spf13/cobra"
+)
+
+// openapi 文档的输出格式
+const (
+	openapiJSON = "json"
+	openapiYAML = "yaml"
+)
+
+func openapiCommand() *cobra.Command {
+	pbPath, _ := os.Getwd()
+	var include, servers []string
+	var format, out, title string
+	var version = "1.0.0"
+	cmd := &cobra.Command{
+		Use:   "openapi",
+		Short: "为路由组的api生成OpenAPI 3.1文档",
+		Long: "为 --path 下所有路由组的api生成 OpenAPI 3.1 文档, 请求与响应消息(包括嵌套消息、枚举、repeated 与 map 字段)按 gen 注入的 json tag 输出到 components.schemas, " +
+			"响应按框架的返回结构包装, 错误码输出为 schema 并在接口的 x-error-codes 中标注. 未指定 --out 时输出到标准输出",
+		Example: "builder openapi --path model --format json\n" +
+			"builder openapi --path model --include ../common/model --server http://localhost:8080 --out docs/openapi.yaml",
+		Run: func(cmd *cobra.Command, args []string) {
+			if format == "" {
+				format = openapiYAML
+				if strings.EqualFold(filepath.Ext(out), ".json") {
+					format = openapiJSON
+				}
+			}
+			if format != openapiJSON && format != openapiYAML {
+				_, _ = fmt.Fprintf(os.Stderr, "不支持的格式 %s, 可选 json/yaml\n", format)
+				os.Exit(1)
+			}
+			if err := outputOpenAPI(pbPath, include, out, format, apidoc.Info{Title: title, Version: version, Servers: servers}); err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&pbPath, "path", pbPath, "proto文件或目录")
+	cmd.Flags().StringSliceVar(&include, "include", include, "路由组依赖到的其他proto文件列表,支持目录")
+	cmd.Flags().StringVar(&format, "format", format, "输出格式 json/yaml, 默认按 --out 的扩展名, 否则为yaml")
+	cmd.Flags().StringVar(&out, "out", out, "输出文件, 默认输出到标准输出")
+	cmd.Flags().StringVar(&title, "title", title, "文档标题, 默认为go module名称")
+	cmd.Flags().StringVar(&version, "version", version, "文档版本")
+	cmd.Flags().StringSliceVar(&servers, "server", servers, "服务地址, 可以指定多个")
+	return cmd
+}
+
+// outputOpenAPI 生成 openapi 文档 out 为空时输出到标准输出
+func outputOpenAPI(path string, include []string, out, format string, info apidoc.Info) error {
+	includes, err := includeFiles(include)
+	if err != nil {
+		return err
+	}
+	p, err := loadProject(path, includes)
+	if err != nil {
+		return err
+	}
+	if len(p.Groups) == 0 {
+		return fmt.Errorf("%s 中没有路由组", path)
+	}
+	if info.Title == "" {
+		if info.Title, err = toolkit.GetCurrentModuleName(); err != nil || info.Title == "" {
+			abs, _ := filepath.Abs(path)
+			info.Title = filepath.Base(abs)
+		}
+	}
+	doc := apidoc.OpenAPI(p, info)
+	var content []byte
+	if format == openapiJSON {
+		content, err = doc.JSON()
+	} else {
+		content, err = doc.YAML()
+	}
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return writeFile(out, content)
+}
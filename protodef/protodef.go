@@ -0,0 +1,397 @@
+package protodef
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"text/scanner"
+
+	"github.com/actorbuf/iotaer/protofmt"
+	"github.com/actorbuf/iotaer/toolkit"
+	"github.com/emicklei/proto"
+)
+
+// service 的类型 由注释中的标记决定 与 proto-parser 保持一致
+const (
+	KindRoute = "route" // @route_group: true 路由组
+	KindRPC   = "rpc"   // @rpc_gen: true rpc服务
+	KindTask  = "task"  // @task: true 定时任务
+	KindPlain = ""      // 普通 grpc service
+)
+
+// DefaultMethod 路由没有指定 @method 时的请求方式
+const DefaultMethod = "POST"
+
+// Position 元素在文件中的位置
+type Position struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// File 一个 proto 文件
+type File struct {
+	Path      string
+	Package   string
+	GoPackage string
+	Imports   []string
+	Messages  []*Message // 包含嵌套消息 嵌套消息的 Name 为 Outer.Inner
+	Enums     []*Enum
+	Services  []*Service
+	Pos       Position
+}
+
+// Message 消息
+type Message struct {
+	Name     string
+	Comment  []string
+	Fields   []*Field
+	Reserved Reserved
+	Pos      Position
+}
+
+// Reserved 保留的字段号与字段名
+type Reserved struct {
+	Ranges []proto.Range
+	Names  []string
+	Pos    Position
+}
+
+// HasNumber 字段号是否被保留
+func (r *Reserved) HasNumber(n int) bool {
+	for _, rg := range r.Ranges {
+		if n >= rg.From && (rg.Max || n <= rg.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasName 字段名是否被保留
+func (r *Reserved) HasName(name string) bool {
+	for _, v := range r.Names {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Field 字段 map 字段的 KeyType 不为空
+type Field struct {
+	Name     string
+	Type     string
+	KeyType  string
+	Number   int
+	Repeated bool
+	Optional bool
+	Oneof    string   // 所属 oneof
+	Comment  []string // 前置注释与行尾注释
+	Pos      Position
+}
+
+// Enum 枚举 嵌套枚举的 Name 为 Outer.Inner
+type Enum struct {
+	Name    string
+	Comment []string
+	Values  []*EnumValue
+	Pos     Position
+}
+
+// EnumValue 枚举值
+type EnumValue struct {
+	Name    string
+	Number  int
+	Comment []string
+	Pos     Position
+}
+
+// Service service 定义 Kind 区分路由组 rpc服务与定时任务
+type Service struct {
+	Name       string
+	Kind       string
+	Prefix     string // 路由组前缀 @route_api
+	GenTo      string // @gen_to
+	Desc       string
+	Middleware string // @middleware 原始内容
+	Comment    []string
+	Methods    []*Method
+	Pos        Position
+}
+
+// Method rpc 定义 路由组中为一个接口 定时任务中为一个任务
+type Method struct {
+	Name       string
+	Req        string
+	Resp       string
+	StreamReq  bool
+	StreamResp bool
+	Desc       string
+	Author     string
+	HTTPMethod string // 路由组接口的请求方式 默认 POST
+	Path       string // 路由组接口相对前缀的路径 默认为 /方法名的下划线形式
+	Middleware string
+	Spec       string // 定时任务 @t
+	Comment    []string
+	Pos        Position
+}
+
+// FullPath 路由组接口的完整路径
+func (s *Service) FullPath(m *Method) string {
+	prefix := strings.TrimSuffix(s.Prefix, "/")
+	if prefix != "" && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	return prefix + m.Path
+}
+
+// Message 按名称查找消息 支持带包名的全名
+func (f *File) Message(name string) *Message {
+	name = strings.TrimPrefix(name, f.Package+".")
+	for _, m := range f.Messages {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// Enum 按名称查找枚举
+func (f *File) Enum(name string) *Enum {
+	name = strings.TrimPrefix(name, f.Package+".")
+	for _, e := range f.Enums {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// ParseFile 解析文件
+func ParseFile(path string) (*File, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, src)
+}
+
+// Load 解析目录或文件 目录下递归查找 proto 文件
+func Load(path string) ([]*File, error) {
+	paths, err := protofmt.Files(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []*File
+	for _, p := range paths {
+		f, err := ParseFile(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// Parse 解析 proto 源码
+func Parse(path string, src []byte) (*File, error) {
+	parser := proto.NewParser(bytes.NewReader(src))
+	parser.Filename(path)
+	def, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	f := &File{Path: path, Pos: Position{File: path, Line: 1, Column: 1}}
+	for _, e := range def.Elements {
+		switch v := e.(type) {
+		case *proto.Package:
+			f.Package = v.Name
+		case *proto.Import:
+			f.Imports = append(f.Imports, v.Filename)
+		case *proto.Option:
+			if v.Name == "go_package" {
+				f.GoPackage = v.Constant.Source
+			}
+		case *proto.Message:
+			if !v.IsExtend {
+				f.addMessage("", v)
+			}
+		case *proto.Enum:
+			f.addEnum("", v)
+		case *proto.Service:
+			f.Services = append(f.Services, newService(v))
+		}
+	}
+	return f, nil
+}
+
+func (f *File) addMessage(prefix string, m *proto.Message) {
+	msg := &Message{Name: prefix + m.Name, Comment: lines(m.Comment), Pos: position(m.Position)}
+	f.Messages = append(f.Messages, msg)
+	var addField func(oneof string, e proto.Visitee)
+	addField = func(oneof string, e proto.Visitee) {
+		switch v := e.(type) {
+		case *proto.NormalField:
+			fd := newField(v.Field)
+			fd.Repeated, fd.Optional, fd.Oneof = v.Repeated, v.Optional, oneof
+			msg.Fields = append(msg.Fields, fd)
+		case *proto.MapField:
+			fd := newField(v.Field)
+			fd.KeyType = v.KeyType
+			msg.Fields = append(msg.Fields, fd)
+		case *proto.OneOfField:
+			fd := newField(v.Field)
+			fd.Oneof = oneof
+			msg.Fields = append(msg.Fields, fd)
+		case *proto.Oneof:
+			for _, el := range v.Elements {
+				addField(v.Name, el)
+			}
+		case *proto.Reserved:
+			msg.Reserved.Ranges = append(msg.Reserved.Ranges, v.Ranges...)
+			msg.Reserved.Names = append(msg.Reserved.Names, v.FieldNames...)
+			msg.Reserved.Pos = position(v.Position)
+		case *proto.Message:
+			if !v.IsExtend {
+				f.addMessage(msg.Name+".", v)
+			}
+		case *proto.Enum:
+			f.addEnum(msg.Name+".", v)
+		}
+	}
+	for _, e := range m.Elements {
+		addField("", e)
+	}
+}
+
+func (f *File) addEnum(prefix string, e *proto.Enum) {
+	enum := &Enum{Name: prefix + e.Name, Comment: lines(e.Comment), Pos: position(e.Position)}
+	for _, el := range e.Elements {
+		if v, ok := el.(*proto.EnumField); ok {
+			enum.Values = append(enum.Values, &EnumValue{
+				Name:    v.Name,
+				Number:  v.Integer,
+				Comment: append(lines(v.Comment), lines(v.InlineComment)...),
+				Pos:     position(v.Position),
+			})
+		}
+	}
+	f.Enums = append(f.Enums, enum)
+}
+
+func newField(v *proto.Field) *Field {
+	return &Field{
+		Name:    v.Name,
+		Type:    v.Type,
+		Number:  v.Sequence,
+		Comment: append(lines(v.Comment), lines(v.InlineComment)...),
+		Pos:     position(v.Position),
+	}
+}
+
+func newService(v *proto.Service) *Service {
+	doc := lines(v.Comment)
+	s := &Service{Name: v.Name, Comment: doc, Pos: position(v.Position)}
+	switch {
+	case Tag(doc, "route_group") == "true":
+		s.Kind = KindRoute
+	case Tag(doc, "rpc_gen") == "true":
+		s.Kind = KindRPC
+	case Tag(doc, "task") == "true":
+		s.Kind = KindTask
+	}
+	s.Prefix = Tag(doc, "route_api")
+	s.GenTo = Tag(doc, "gen_to")
+	s.Desc = Tag(doc, "desc")
+	s.Middleware = Tag(doc, "middleware")
+	for _, e := range v.Elements {
+		rpc, ok := e.(*proto.RPC)
+		if !ok {
+			continue
+		}
+		doc := append(lines(rpc.Comment), lines(rpc.InlineComment)...)
+		m := &Method{
+			Name:       rpc.Name,
+			Req:        rpc.RequestType,
+			Resp:       rpc.ReturnsType,
+			StreamReq:  rpc.StreamsRequest,
+			StreamResp: rpc.StreamsReturns,
+			Desc:       Tag(doc, "desc"),
+			Author:     Tag(doc, "author"),
+			Middleware: Tag(doc, "middleware"),
+			Spec:       Tag(doc, "t"),
+			Comment:    doc,
+			Pos:        position(rpc.Position),
+		}
+		if s.Kind == KindRoute {
+			m.HTTPMethod = strings.ToUpper(Tag(doc, "method"))
+			if m.HTTPMethod == "" {
+				m.HTTPMethod = DefaultMethod
+			}
+			m.Path = Tag(doc, "api")
+			if m.Path == "" {
+				m.Path = toolkit.Calm2Case(m.Name)
+			}
+			if !strings.HasPrefix(m.Path, "/") {
+				m.Path = "/" + m.Path
+			}
+		}
+		s.Methods = append(s.Methods, m)
+	}
+	return s
+}
+
+var tagReg = regexp.MustCompile(`^\s*@([a-z_]+)\s*:\s*(.*?)\s*$`)
+
+// valueRegs 与 proto-parser 生成路由时使用的字符集一致, 不在其中的字符及之后的内容会被 gen 忽略
+// 如 @api: /users/:id 在 gen 中注册为 /users/
+var valueRegs = map[string]*regexp.Regexp{
+	"api":       regexp.MustCompile(`^[\w|/]*`),
+	"route_api": regexp.MustCompile(`^[\w|/]*`),
+	"gen_to":    regexp.MustCompile(`^[\w|/|\.]*`),
+	"method":    regexp.MustCompile(`^\w*`),
+}
+
+// Tag 读取注释中的 @key: value 标记 没有时返回空
+func Tag(doc []string, key string) string {
+	for _, line := range doc {
+		if res := tagReg.FindStringSubmatch(line); res != nil && res[1] == key {
+			if reg, ok := valueRegs[key]; ok {
+				return reg.FindString(res[2])
+			}
+			return res[2]
+		}
+	}
+	return ""
+}
+
+// Describe 元素的说明 优先使用 @desc, 否则为第一行不是标记的注释
+func Describe(doc []string) string {
+	if desc := Tag(doc, "desc"); desc != "" {
+		return desc
+	}
+	for _, line := range doc {
+		line = strings.TrimSpace(line)
+		if line != "" && !tagReg.MatchString(line) {
+			return line
+		}
+	}
+	return ""
+}
+
+func lines(c *proto.Comment) []string {
+	if c == nil {
+		return nil
+	}
+	return c.Lines
+}
+
+func position(p scanner.Position) Position {
+	return Position{File: p.Filename, Line: p.Line, Column: p.Column}
+}
@@ -0,0 +1,115 @@
+package protodef
+
+import (
+	"testing"
+)
+
+const src = `syntax = "proto3";
+package user;
+option go_package = "example.com/app/model/user";
+
+// @desc: 用户
+message User {
+    string name = 1; // 姓名
+    map<string, int64> score = 2;
+    oneof contact {
+        string email = 3;
+    }
+    reserved 5 to 7, 10 to max;
+    reserved "old";
+    message Extra {
+        int64 id = 1;
+    }
+    enum Level {
+        LevelNil = 0;
+    }
+}
+
+enum ErrCode {
+    ErrCodeNil = 0;
+    ErrCodeNotFound = 1001; // 不存在
+}
+
+// @route_group: true
+// @route_api: /api/user
+// @gen_to: ./controller/user
+service UserApi {
+    // @desc: 获取用户
+    // @author: alice
+    // @method: get
+    rpc GetUser (GetUserReq) returns (GetUserResp);
+    // @api: list
+    rpc ListUser (ListUserReq) returns (ListUserResp);
+}
+
+// @task: true
+service Cron {
+    // @t: */5 * * * *
+    rpc Clean (CleanReq) returns (CleanResp);
+}
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse("user.proto", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Package != "user" || f.GoPackage != "example.com/app/model/user" {
+		t.Fatalf("unexpected file header %+v", f)
+	}
+	if len(f.Messages) != 2 || f.Messages[1].Name != "User.Extra" {
+		t.Fatalf("unexpected messages %+v", f.Messages)
+	}
+	user := f.Message("user.User")
+	if user == nil || Describe(user.Comment) != "用户" || user.Pos.Line != 6 {
+		t.Fatalf("unexpected message %+v", user)
+	}
+	if len(user.Fields) != 3 || user.Fields[0].Comment[0] != " 姓名" || user.Fields[1].KeyType != "string" || user.Fields[2].Oneof != "contact" {
+		t.Fatalf("unexpected fields %+v", user.Fields)
+	}
+	for n, want := range map[int]bool{4: false, 5: true, 7: true, 8: false, 100: true} {
+		if user.Reserved.HasNumber(n) != want {
+			t.Fatalf("reserved %d: want %v", n, want)
+		}
+	}
+	if !user.Reserved.HasName("old") {
+		t.Fatal("expected reserved name")
+	}
+	if len(f.Enums) != 2 || f.Enum("User.Level") == nil || f.Enum("ErrCode").Values[1].Number != 1001 {
+		t.Fatalf("unexpected enums %+v", f.Enums)
+	}
+
+	api := f.Services[0]
+	if api.Kind != KindRoute || api.GenTo != "./controller/user" {
+		t.Fatalf("unexpected service %+v", api)
+	}
+	get, list := api.Methods[0], api.Methods[1]
+	if get.HTTPMethod != "GET" || api.FullPath(get) != "/api/user/get_user" || get.Author != "alice" || get.Desc != "获取用户" {
+		t.Fatalf("unexpected method %+v", get)
+	}
+	if list.HTTPMethod != DefaultMethod || api.FullPath(list) != "/api/user/list" {
+		t.Fatalf("unexpected method %+v", list)
+	}
+	if cron := f.Services[1]; cron.Kind != KindTask || cron.Methods[0].Spec != "*/5 * * * *" {
+		t.Fatalf("unexpected task %+v", cron)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	if d := Describe([]string{" @author: bob", " 说明"}); d != "说明" {
+		t.Fatalf("unexpected %q", d)
+	}
+	if d := Describe([]string{" @author: bob"}); d != "" {
+		t.Fatalf("unexpected %q", d)
+	}
+}
+
+func TestTag(t *testing.T) {
+	doc := []string{" @api: /users/:id", " @route_api: /api/order-items", " @gen_to: ./ctrl/user.go", " @method: get // 查询", " @desc: 用户: 详情"}
+	// 路由与 proto-parser 的字符集一致
+	for key, want := range map[string]string{"api": "/users/", "route_api": "/api/order", "gen_to": "./ctrl/user.go", "method": "get", "desc": "用户: 详情"} {
+		if got := Tag(doc, key); got != want {
+			t.Fatalf("%s: unexpected %q", key, got)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package protofmt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// diffContext 每个变更块前后保留的行数 与 diff -u 一致
+const diffContext = 3
+
+type edit struct {
+	op   byte // ' ' '-' '+'
+	line string
+}
+
+// Diff 生成 unified diff 内容相同时返回 nil
+func Diff(name string, a, b []byte) []byte {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+	edits := diffLines(splitLines(a), splitLines(b))
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- a/%s\n+++ b/%s\n", name, name)
+	// 两个变更块之间的相同行不超过 2*diffContext 时合并为一个块
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for same := 0; end < len(edits); end++ {
+			if edits[end].op != ' ' {
+				same = 0
+				continue
+			}
+			if same++; same > 2*diffContext {
+				break
+			}
+		}
+		// 块尾只保留 diffContext 行相同行
+		for countTail(edits[i:end]) > diffContext {
+			end--
+		}
+		writeHunk(&buf, edits, start, end)
+		i = end
+	}
+	return buf.Bytes()
+}
+
+// countTail 末尾连续相同行的数量
+func countTail(edits []edit) int {
+	n := 0
+	for i := len(edits) - 1; i >= 0 && edits[i].op == ' '; i-- {
+		n++
+	}
+	return n
+}
+
+func writeHunk(buf *bytes.Buffer, edits []edit, start, end int) {
+	// 计算块在新旧文件中的起始行号
+	aLine, bLine := 1, 1
+	for _, e := range edits[:start] {
+		if e.op != '+' {
+			aLine++
+		}
+		if e.op != '-' {
+			bLine++
+		}
+	}
+	var aCount, bCount int
+	for _, e := range edits[start:end] {
+		if e.op != '+' {
+			aCount++
+		}
+		if e.op != '-' {
+			bCount++
+		}
+	}
+	if aCount == 0 {
+		aLine--
+	}
+	if bCount == 0 {
+		bLine--
+	}
+	fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", aLine, aCount, bLine, bCount)
+	for _, e := range edits[start:end] {
+		buf.WriteByte(e.op)
+		buf.WriteString(e.line)
+		if !strings.HasSuffix(e.line, "\n") {
+			buf.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// splitLines 按行切分 保留换行符
+func splitLines(s []byte) []string {
+	var lines []string
+	for len(s) > 0 {
+		i := bytes.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, string(s))
+			break
+		}
+		lines = append(lines, string(s[:i+1]))
+		s = s[i+1:]
+	}
+	return lines
+}
+
+// diffLines 去掉相同的首尾后按最长公共子序列对比 格式化通常只改动少量行
+func diffLines(a, b []string) []edit {
+	var prefix, suffix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var edits []edit
+	for _, l := range a[:prefix] {
+		edits = append(edits, edit{' ', l})
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	// lcs[i][j] 为 ma[i:] 与 mb[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			edits = append(edits, edit{' ', ma[i]})
+			i++
+			j++
+		case i < len(ma) && (j == len(mb) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', ma[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', mb[j]})
+			j++
+		}
+	}
+	for _, l := range a[len(a)-suffix:] {
+		edits = append(edits, edit{' ', l})
+	}
+	return edits
+}
@@ -0,0 +1,139 @@
+package protofmt
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/actorbuf/proto-format/pkg"
+	"github.com/emicklei/proto"
+)
+
+// Indent 与 proto-format 保持一致 使用4个空格缩进
+const Indent = "    "
+
+// Source 格式化 proto 源码 filename 只用于错误信息
+func Source(filename string, src []byte) ([]byte, error) {
+	parser := proto.NewParser(bytes.NewReader(src))
+	parser.Filename(filename)
+	def, err := parser.Parse()
+	if err != nil {
+		return nil, err
+	}
+	return Format(def), nil
+}
+
+// Format 输出修改后的 proto 定义
+func Format(def *proto.Proto) []byte {
+	var buf bytes.Buffer
+	pkg.NewFormatter(&buf, Indent).Format(def)
+	return buf.Bytes()
+}
+
+// Files 列出需要格式化的 proto 文件
+// path 可以是文件、目录(递归查找) 或兼容旧用法的 dir/*.proto
+func Files(path string) ([]string, error) {
+	if strings.HasSuffix(path, "*.proto") || strings.HasSuffix(path, "*") {
+		path = filepath.Dir(path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		if !strings.HasSuffix(path, ".proto") {
+			return nil, fmt.Errorf("%s 不是 proto 文件", path)
+		}
+		return []string{path}, nil
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != path && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(p, ".proto") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Result 单个文件的格式化结果
+type Result struct {
+	File      string
+	Src       []byte
+	Formatted []byte
+}
+
+// Changed 格式化后是否有变化
+func (r *Result) Changed() bool {
+	return !bytes.Equal(r.Src, r.Formatted)
+}
+
+// Diff 格式化前后的 unified diff
+func (r *Result) Diff() []byte {
+	return Diff(r.File, r.Src, r.Formatted)
+}
+
+// File 读取并格式化文件 不会写回
+func File(file string) (*Result, error) {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	formatted, err := Source(file, src)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{File: file, Src: src, Formatted: formatted}, nil
+}
+
+// Load 解析 proto 文件 用于修改后通过 Save 写回
+func Load(file string) (*proto.Proto, error) {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	parser := proto.NewParser(bytes.NewReader(src))
+	parser.Filename(file)
+	return parser.Parse()
+}
+
+// Save 写回修改后的 proto 保留原文件权限
+// 新增的元素没有位置信息 重新解析一次使对齐与 fmt 一致
+func Save(file string, def *proto.Proto) error {
+	out, err := Source(file, Format(def))
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, out, info.Mode().Perm())
+}
+
+// Write 有变化时写回 保留原文件权限
+func (r *Result) Write() error {
+	if !r.Changed() {
+		return nil
+	}
+	info, err := os.Stat(r.File)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.File, r.Formatted, info.Mode().Perm())
+}
@@ -0,0 +1,162 @@
+package protofmt
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const messy = `syntax = "proto3";
+package model;
+message User {
+  string name = 1;
+      int64 id=2; // 用户ID
+}
+`
+
+func TestSource(t *testing.T) {
+	out, err := Source("user.proto", []byte(messy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "    string name = 1;") || !strings.Contains(string(out), "    int64  id   = 2; // 用户ID") {
+		t.Fatalf("unexpected format result:\n%s", out)
+	}
+	again, err := Source("user.proto", out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, again) {
+		t.Fatalf("format is not idempotent:\n%s", Diff("user.proto", out, again))
+	}
+	if _, err := Source("bad.proto", []byte("message {")); err == nil || !strings.Contains(err.Error(), "bad.proto") {
+		t.Fatalf("expected parse error with filename, got %v", err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.proto", "sub/b.proto", "sub/c.go", ".git/d.proto"} {
+		p := filepath.Join(dir, name)
+		_ = os.MkdirAll(filepath.Dir(p), 0755)
+		if err := ioutil.WriteFile(p, []byte(messy), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{filepath.Join(dir, "a.proto"), filepath.Join(dir, "sub", "b.proto")}
+	for _, path := range []string{dir, dir + "/*.proto"} {
+		files, err := Files(path)
+		if err != nil || !reflect.DeepEqual(files, want) {
+			t.Fatalf("%s: unexpected files %v %v", path, files, err)
+		}
+	}
+	if _, err := Files(filepath.Join(dir, "sub", "c.go")); err == nil {
+		t.Fatal("expected not proto error")
+	}
+
+	r, err := File(want[0])
+	if err != nil || !r.Changed() {
+		t.Fatalf("expected changed result %v", err)
+	}
+	if err := r.Write(); err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(want[0])
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("file mode changed to %v", info.Mode())
+	}
+	if r, _ = File(want[0]); r.Changed() || r.Diff() != nil {
+		t.Fatal("expected formatted file")
+	}
+}
+
+// TestDiff 与 diff -u 的输出对比
+func TestDiff(t *testing.T) {
+	if _, err := exec.LookPath("diff"); err != nil {
+		t.Skip("diff not found")
+	}
+	dir := t.TempDir()
+	rnd := rand.New(rand.NewSource(1))
+	gen := func() []byte {
+		var b strings.Builder
+		for i, n := 0, rnd.Intn(30); i < n; i++ {
+			b.WriteString(string(rune('a' + rnd.Intn(4))))
+			b.WriteByte('\n')
+		}
+		if rnd.Intn(5) == 0 {
+			b.WriteString("end")
+		}
+		return []byte(b.String())
+	}
+	for i := 0; i < 200; i++ {
+		a, b := gen(), gen()
+		_ = ioutil.WriteFile(filepath.Join(dir, "a"), a, 0644)
+		_ = ioutil.WriteFile(filepath.Join(dir, "b"), b, 0644)
+		cmd := exec.Command("diff", "-u", "a", "b")
+		cmd.Dir = dir
+		want, _ := cmd.Output()
+		got := Diff("x", a, b)
+		if len(want) == 0 {
+			if got != nil {
+				t.Fatalf("expected no diff, got %s", got)
+			}
+			continue
+		}
+		// 只比较变更块的行数 相同长度的最长公共子序列可能有多种
+		if hunks(got) != hunks(want) || count(got, "\n-") != count(want, "\n-") || count(got, "\n+") != count(want, "\n+") {
+			t.Fatalf("case %d: mismatch\nA:%q\nB:%q\ngot:\n%s\nwant:\n%s", i, a, b, got, want)
+		}
+		if !bytes.Equal(apply(a, got), b) {
+			t.Fatalf("case %d: applying diff does not produce b\n%s", i, got)
+		}
+	}
+}
+
+func hunks(d []byte) int { return count(d, "\n@@ ") }
+
+func count(d []byte, s string) int { return strings.Count("\n"+string(d), s) }
+
+// apply 按 diff 从 a 还原出 b 校验行号与内容
+func apply(a, d []byte) []byte {
+	src := splitLines(a)
+	var out []string
+	pos := 0
+	lines := splitLines(d)[2:]
+	for k := 0; k < len(lines); k++ {
+		l := lines[k]
+		if strings.HasPrefix(l, "@@ ") {
+			var as, ac, bs, bc int
+			_, _ = fmt.Sscanf(l, "@@ -%d,%d +%d,%d @@", &as, &ac, &bs, &bc)
+			if ac == 0 {
+				as++
+			}
+			out = append(out, src[pos:as-1]...)
+			pos = as - 1
+			continue
+		}
+		noEOL := k+1 < len(lines) && strings.HasPrefix(lines[k+1], `\ No newline`)
+		body := l[1:]
+		if noEOL {
+			body = strings.TrimSuffix(body, "\n")
+			k++
+		}
+		switch l[0] {
+		case ' ':
+			out = append(out, body)
+			pos++
+		case '-':
+			pos++
+		case '+':
+			out = append(out, body)
+		}
+	}
+	out = append(out, src[pos:]...)
+	return []byte(strings.Join(out, ""))
+}
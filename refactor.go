@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/actorbuf/iotaer/apigen"
+	"github.com/actorbuf/iotaer/toolkit"
+	"github.com/spf13/cobra"
+)
+
+// targetFlags rmapi/rmrpc/rmroute/rename-api 共用的参数
+func targetFlags(cmd *cobra.Command, t *apigen.Target, name bool) {
+	cmd.Flags().StringVar(&t.Proto, "path", t.Proto, "proto单个文件地址")
+	cmd.Flags().StringVar(&t.Service, "svc", t.Service, "路由组/service名称")
+	if name {
+		cmd.Flags().StringVar(&t.Name, "name", t.Name, "接口/RPC名称")
+	}
+	cmd.Flags().StringVar(&t.Logic, "logic", apigen.DirLogic, "logic层目录 相对项目根目录")
+	cmd.Flags().StringVar(&t.Router, "router", apigen.DirRouter, "注册路由的目录 相对项目根目录")
+}
+
+// checkTarget 检查必填参数 失败时退出
+func checkTarget(t *apigen.Target, name bool) {
+	if t.Service == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "路由组/service名称 --svc 不能为空\n")
+		os.Exit(1)
+	}
+	if name && t.Name == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "接口名称 --name 不能为空\n")
+		os.Exit(1)
+	}
+	t.Name = toolkit.FirstUpper(t.Name)
+}
+
+func runTarget(fn func() error) {
+	if err := fn(); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println("proto已更新, 请执行 builder gen 重新生成pb代码")
+}
+
+func rmAPICommand() *cobra.Command {
+	t := &apigen.Target{}
+	t.Proto, _ = os.Getwd()
+	cmd := &cobra.Command{
+		Use:   "rmapi",
+		Short: "删除路由组中的一个api",
+		Long: "删除proto中的接口与不再被引用的 Req/Resp, 并删除 gen_to 文件中的 controller 方法、logic 层的同名函数与 httptest 测试. " +
+			"修改后不再使用的 import 会一并删除",
+		Example: "builder rmapi --path model/user.proto --svc UserApi --name GetUser",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkTarget(t, true)
+			runTarget(func() error { return apigen.RemoveAPI(t) })
+		},
+	}
+	targetFlags(cmd, t, true)
+	return cmd
+}
+
+func rmRPCCommand() *cobra.Command {
+	t := &apigen.Target{}
+	t.Proto, _ = os.Getwd()
+	cmd := &cobra.Command{
+		Use:     "rmrpc",
+		Short:   "删除service中的一个rpc",
+		Long:    "删除proto中的rpc与不再被引用的 Req/Resp, 并删除 gen_to 文件中的实现方法",
+		Example: "builder rmrpc --path model/user.proto --svc UserService --name SyncUser",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkTarget(t, true)
+			runTarget(func() error { return apigen.RemoveRPC(t) })
+		},
+	}
+	targetFlags(cmd, t, true)
+	return cmd
+}
+
+func rmRouteCommand() *cobra.Command {
+	t := &apigen.Target{}
+	t.Proto, _ = os.Getwd()
+	cmd := &cobra.Command{
+		Use:   "rmroute",
+		Short: "删除一个路由组",
+		Long: "删除proto中的路由组与不再被引用的 Req/Resp, 删除 controller 结构体及其方法、logic 层函数与 httptest 测试, " +
+			"并从 --router 目录中删除 BindRouteMap/RegisterStruct 的注册. 只剩 import 的文件会被删除",
+		Example: "builder rmroute --path model/user.proto --svc UserApi",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkTarget(t, false)
+			runTarget(func() error { return apigen.RemoveRoute(t) })
+		},
+	}
+	targetFlags(cmd, t, false)
+	return cmd
+}
+
+func renameAPICommand() *cobra.Command {
+	t := &apigen.Target{}
+	t.Proto, _ = os.Getwd()
+	var to = ""
+	var renamePath = false
+	cmd := &cobra.Command{
+		Use:   "rename-api",
+		Short: "重命名路由组中的api或service中的rpc",
+		Long: "修改proto中的接口名称, 按 NameReq/NameResp 命名的 message 一并修改. " +
+			"接口的 URL 默认不变, 没有 @api 时写入原来的默认路径, --rename-path 时改为新名称的默认路径. " +
+			"同时修改 controller 方法、logic 层函数、httptest 测试及其文件名, 以及项目中对 pb 类型与 logic 函数的引用",
+		Example: "builder rename-api --path model/user.proto --svc UserApi --name GetUser --to FetchUser",
+		Run: func(cmd *cobra.Command, args []string) {
+			checkTarget(t, true)
+			if to == "" {
+				_, _ = fmt.Fprintf(os.Stderr, "新名称 --to 不能为空\n")
+				os.Exit(1)
+			}
+			runTarget(func() error { return apigen.RenameAPI(t, toolkit.FirstUpper(to), renamePath) })
+		},
+	}
+	targetFlags(cmd, t, true)
+	cmd.Flags().StringVar(&to, "to", to, "新的接口/RPC名称")
+	cmd.Flags().BoolVar(&renamePath, "rename-path", renamePath, "同时把默认的 @api 路径改为新名称, 调用方需要同步修改 URL")
+	return cmd
+}
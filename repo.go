@@ -0,0 +1,395 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	ose "os/exec"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/actorbuf/iotaer/credential"
+	"github.com/actorbuf/iotaer/githost"
+	"github.com/actorbuf/iotaer/gitter"
+	"github.com/actorbuf/iotaer/toolkit"
+	"github.com/spf13/cobra"
+)
+
+// repoTimeout 单次调用git平台接口的超时
+const repoTimeout = 30 * time.Second
+
+// repoContext 每次调用git平台接口使用新的超时 避免推送等耗时操作占用后续调用的时间
+func repoContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), repoTimeout)
+}
+
+// repoTarget repo 子命令共用的平台参数
+type repoTarget struct {
+	kind string
+	host string
+}
+
+func (t *repoTarget) flags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&t.kind, "kind", credential.KindGitLab, fmt.Sprintf("git平台类型%v", githost.Kinds()))
+	cmd.Flags().StringVar(&t.host, "host", "", "git平台地址，默认使用 auth login 保存的第一个该类型凭据的域名")
+}
+
+// credential 按平台与域名读取凭据 未指定域名时使用第一条凭据的域名
+func (t *repoTarget) credential() (credential.Credential, error) {
+	c, err := credential.NewStore().Get(t.kind, t.host)
+	if err != nil {
+		return c, err
+	}
+	if c.Host == "" {
+		c.Host = t.host
+	}
+	if c.Host == "" {
+		c.Host = defaultHosts[t.kind]
+	}
+	return c, nil
+}
+
+func (t *repoTarget) client() (githost.Client, credential.Credential, error) {
+	c, err := t.credential()
+	if err != nil {
+		return nil, c, err
+	}
+	client, err := githost.New(t.kind, c.Host, c.Token, nil)
+	return client, c, err
+}
+
+func repoCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repo",
+		Short: "gitlab仓库操作",
+		Long:  "创建、克隆仓库, 设置保护分支与CI变量, 平台凭据通过 iotaer auth login 保存",
+	}
+	cmd.AddCommand(repoCreateCommand())
+	cmd.AddCommand(repoCloneCommand())
+	cmd.AddCommand(repoProtectCommand())
+	cmd.AddCommand(repoSetVarsCommand())
+	return cmd
+}
+
+func repoCreateCommand() *cobra.Command {
+	var target repoTarget
+	var opt githost.CreateRepoOption
+	var push bool
+	var protect []string
+	var vars []string
+	var varFile string
+	cmd := &cobra.Command{
+		Use:   "create [name]",
+		Short: "创建仓库",
+		Long:  "创建仓库, 在项目目录下执行时默认按 go.mod 的模块名推断域名、组与仓库名, 可同时推送代码、保护分支与设置CI变量",
+		Example: "builder repo create --push --protect main --var-file .env.ci\n" +
+			"builder repo create user --namespace backend --visibility internal",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 1 {
+				opt.Name = args[0]
+			}
+			inferRepo(&target, &opt)
+			if opt.Name == "" {
+				_, _ = fmt.Fprintln(os.Stderr, "请指定仓库名")
+				os.Exit(1)
+			}
+			variables, err := parseVariables(vars, varFile)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			client, _, err := target.client()
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			ctx, cancel := repoContext()
+			repo, err := client.CreateRepo(ctx, &opt)
+			cancel()
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "创建仓库失败: %+v\n", err)
+				os.Exit(1)
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "create   %s %s\n", repo.FullName, repo.WebURL)
+			if push {
+				if err := pushOrigin(repo.SSHURL); err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "推送代码失败: %+v\n", err)
+					os.Exit(1)
+				}
+			}
+			// 保护分支需要分支已存在 放在推送之后
+			for _, branch := range protect {
+				if err := protectBranch(client, repo.FullName, &githost.ProtectOption{Branch: branch}); err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "保护分支 %s 失败: %+v\n", branch, err)
+					os.Exit(1)
+				}
+				_, _ = fmt.Fprintf(os.Stdout, "protect  %s\n", branch)
+			}
+			setVariables(client, repo.FullName, variables)
+		},
+	}
+	target.flags(cmd)
+	cmd.Flags().StringVar(&opt.Namespace, "namespace", "", "所属组，支持子组如 backend/svc，默认按模块名推断，推断不出时创建在当前用户下")
+	cmd.Flags().StringVar(&opt.Visibility, "visibility", githost.VisibilityPrivate, "可见性[private,internal,public]")
+	cmd.Flags().StringVar(&opt.Description, "desc", "", "仓库描述")
+	cmd.Flags().BoolVar(&opt.InitReadme, "readme", false, "初始化README，推送已有项目时不要开启")
+	cmd.Flags().BoolVar(&push, "push", false, "将当前git仓库设置为origin并推送")
+	cmd.Flags().StringSliceVar(&protect, "protect", nil, "推送后保护的分支，如 main")
+	cmd.Flags().StringArrayVar(&vars, "var", nil, "CI变量 KEY=VALUE，可多次指定")
+	cmd.Flags().StringVar(&varFile, "var-file", "", "从文件读取CI变量，每行一个 KEY=VALUE，# 开头为注释")
+	return cmd
+}
+
+func repoCloneCommand() *cobra.Command {
+	var target repoTarget
+	var useHTTP bool
+	cmd := &cobra.Command{
+		Use:     "clone <namespace/name> [dir]",
+		Short:   "克隆仓库",
+		Example: "builder repo clone backend/user\nbuilder repo clone backend/user --http",
+		Args:    cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			client, c, err := target.client()
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			ctx, cancel := repoContext()
+			repo, err := client.GetRepo(ctx, args[0])
+			cancel()
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "查询仓库失败: %+v\n", err)
+				os.Exit(1)
+			}
+			gitArgs := []string{"clone"}
+			var env []string
+			if useHTTP {
+				// token 通过环境变量注入 不会写入 .git/config
+				env = (&gitter.Auth{User: c.User, Token: c.Token}).Env()
+				gitArgs = append(gitArgs, repo.HTTPURL)
+			} else {
+				gitArgs = append(gitArgs, repo.SSHURL)
+			}
+			if len(args) == 2 {
+				gitArgs = append(gitArgs, args[1])
+			}
+			if err := runGit(env, gitArgs...); err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+	target.flags(cmd)
+	cmd.Flags().BoolVar(&useHTTP, "http", false, "使用http地址与已保存的token克隆，默认使用ssh地址")
+	return cmd
+}
+
+func repoProtectCommand() *cobra.Command {
+	var target repoTarget
+	var name string
+	var branches []string
+	var opt githost.ProtectOption
+	cmd := &cobra.Command{
+		Use:     "protect",
+		Short:   "设置保护分支",
+		Long:    "设置保护分支, 已保护的分支按新参数覆盖",
+		Example: "builder repo protect --branch main,release --merge maintainer\nbuilder repo protect --repo backend/user --branch main --push no_one",
+		Run: func(cmd *cobra.Command, args []string) {
+			client, full := repoClient(&target, name)
+			for _, branch := range branches {
+				o := opt
+				o.Branch = branch
+				if err := protectBranch(client, full, &o); err != nil {
+					_, _ = fmt.Fprintf(os.Stderr, "保护分支 %s 失败: %+v\n", branch, err)
+					os.Exit(1)
+				}
+				_, _ = fmt.Fprintf(os.Stdout, "protect  %s %s\n", full, branch)
+			}
+		},
+	}
+	target.flags(cmd)
+	cmd.Flags().StringVar(&name, "repo", "", "仓库全名 namespace/name，默认按模块名推断")
+	cmd.Flags().StringSliceVar(&branches, "branch", []string{"main"}, "保护的分支")
+	cmd.Flags().StringVar(&opt.PushAccess, "push", githost.AccessMaintainer, "允许推送的级别[no_one,developer,maintainer]")
+	cmd.Flags().StringVar(&opt.MergeAccess, "merge", githost.AccessDeveloper, "允许合并的级别[no_one,developer,maintainer]")
+	cmd.Flags().BoolVar(&opt.AllowForcePush, "force-push", false, "允许强制推送")
+	return cmd
+}
+
+func repoSetVarsCommand() *cobra.Command {
+	var target repoTarget
+	var name string
+	var varFile string
+	var tpl githost.Variable
+	cmd := &cobra.Command{
+		Use:     "set-vars [KEY=VALUE...]",
+		Short:   "设置CI变量",
+		Long:    "新增或更新CI变量, 同一批变量使用相同的保护、隐藏与环境设置",
+		Example: "builder repo set-vars REGISTRY=hub.example.com --env prod\nbuilder repo set-vars --var-file .env.ci --masked --protected",
+		Run: func(cmd *cobra.Command, args []string) {
+			variables, err := parseVariables(args, varFile)
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if len(variables) == 0 {
+				_, _ = fmt.Fprintln(os.Stderr, "请指定 KEY=VALUE 或 --var-file")
+				os.Exit(1)
+			}
+			for i := range variables {
+				variables[i].Protected = tpl.Protected
+				variables[i].Masked = tpl.Masked
+				variables[i].Environment = tpl.Environment
+			}
+			client, full := repoClient(&target, name)
+			setVariables(client, full, variables)
+		},
+	}
+	target.flags(cmd)
+	cmd.Flags().StringVar(&name, "repo", "", "仓库全名 namespace/name，默认按模块名推断")
+	cmd.Flags().StringVar(&varFile, "var-file", "", "从文件读取CI变量，每行一个 KEY=VALUE，# 开头为注释")
+	cmd.Flags().BoolVar(&tpl.Protected, "protected", false, "只在保护分支与tag的流水线中可用")
+	cmd.Flags().BoolVar(&tpl.Masked, "masked", false, "在流水线日志中隐藏，gitlab 要求值至少8位")
+	cmd.Flags().StringVar(&tpl.Environment, "env", "", "生效的环境，默认所有环境")
+	return cmd
+}
+
+// repoClient 创建客户端并确定仓库全名 出错时直接退出
+func repoClient(target *repoTarget, name string) (githost.Client, string) {
+	if name == "" {
+		var opt githost.CreateRepoOption
+		inferRepo(target, &opt)
+		if opt.Namespace == "" || opt.Name == "" {
+			_, _ = fmt.Fprintln(os.Stderr, "无法从 go.mod 推断仓库, 请指定 --repo namespace/name")
+			os.Exit(1)
+		}
+		name = opt.Namespace + "/" + opt.Name
+	}
+	client, _, err := target.client()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return client, name
+}
+
+// inferRepo 按模块名推断 如 gitlab.example.com/backend/user 推断为该域名下 backend 组的 user 仓库
+// 已通过参数指定的值不会覆盖 指定了 --host 时仍按模块名推断组
+func inferRepo(target *repoTarget, opt *githost.CreateRepoOption) {
+	if !toolkit.IsExist("go.mod") {
+		return
+	}
+	module, err := toolkit.GetCurrentModuleName()
+	if err != nil {
+		return
+	}
+	module = strings.TrimSpace(module)
+	if opt.Name == "" {
+		opt.Name = path.Base(module)
+	}
+	parts := strings.Split(module, "/")
+	// 第一段不像域名时不推断组
+	if len(parts) < 3 || !strings.Contains(parts[0], ".") {
+		return
+	}
+	if target.host == "" {
+		target.host = parts[0]
+	}
+	if opt.Namespace == "" {
+		opt.Namespace = strings.Join(parts[1:len(parts)-1], "/")
+	}
+}
+
+// parseVariables 解析 KEY=VALUE 参数与变量文件 参数中的同名变量覆盖文件
+func parseVariables(args []string, file string) ([]githost.Variable, error) {
+	var lines []string
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			lines = append(lines, strings.TrimPrefix(line, "export "))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	lines = append(lines, args...)
+
+	var list []githost.Variable
+	index := map[string]int{}
+	for _, line := range lines {
+		kv := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || key == "" {
+			return nil, fmt.Errorf("变量格式不正确: %s, 应为 KEY=VALUE", line)
+		}
+		value := strings.Trim(strings.TrimSpace(kv[1]), `"'`)
+		if i, ok := index[key]; ok {
+			list[i].Value = value
+			continue
+		}
+		index[key] = len(list)
+		list = append(list, githost.Variable{Key: key, Value: value})
+	}
+	return list, nil
+}
+
+func protectBranch(client githost.Client, name string, opt *githost.ProtectOption) error {
+	ctx, cancel := repoContext()
+	defer cancel()
+	return client.ProtectBranch(ctx, name, opt)
+}
+
+func setVariables(client githost.Client, name string, variables []githost.Variable) {
+	for i := range variables {
+		ctx, cancel := repoContext()
+		err := client.SetVariable(ctx, name, &variables[i])
+		cancel()
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "设置变量 %s 失败: %+v\n", variables[i].Key, err)
+			os.Exit(1)
+		}
+		_, _ = fmt.Fprintf(os.Stdout, "variable %s\n", variables[i].Key)
+	}
+}
+
+// pushOrigin 将当前仓库的 origin 指向新仓库并推送当前分支与tag
+func pushOrigin(url string) error {
+	if !toolkit.IsExist(".git") {
+		return errors.New("当前目录不是git仓库, 请先执行 git init 并提交代码")
+	}
+	if ose.Command("git", "remote", "get-url", "origin").Run() == nil {
+		if err := runGit(nil, "remote", "set-url", "origin", url); err != nil {
+			return err
+		}
+	} else if err := runGit(nil, "remote", "add", "origin", url); err != nil {
+		return err
+	}
+	if err := runGit(nil, "push", "-u", "origin", "HEAD"); err != nil {
+		return err
+	}
+	return runGit(nil, "push", "origin", "--tags")
+}
+
+func runGit(env []string, args ...string) error {
+	cmd := ose.Command("git", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), env...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+	}
+	return nil
+}
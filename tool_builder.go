@@ -1,17 +1,40 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/actorbuf/iotaer/toolbox"
 	"github.com/spf13/cobra"
 )
 
 func toolBuilderCommand() *cobra.Command {
+	var opt toolbox.Option
 	cmd := &cobra.Command{
 		Use:   "tool",
 		Short: "将文件构建为任意工具箱",
+		Long: "将目录下的文件(sql、模板、json、脚本等)通过 embed.FS 打包为一个 go 包, 每个文件生成一个访问方法, " +
+			"模板文件(.tmpl/.tpl/.gotmpl)额外生成 RenderXxx, json 文件额外生成 DecodeXxx. 源目录变化后重新执行即可, . 与 _ 开头的文件会被忽略",
+		Example: "builder tool --src resources --pkg assets\nbuilder tool --src sql --pkg sqls --out internal/sqls --gzip",
 		Run: func(cmd *cobra.Command, args []string) {
-
+			files, skipped, err := toolbox.Generate(&opt)
+			for _, name := range skipped {
+				_, _ = fmt.Fprintf(os.Stderr, "skip     %s\n", name)
+			}
+			if err != nil {
+				_, _ = fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			for _, f := range files {
+				_, _ = fmt.Fprintf(os.Stdout, "embed    %s -> %s()\n", f.Name, f.Ident)
+			}
+			_, _ = fmt.Fprintf(os.Stdout, "create   %s \n", filepath.Join(opt.Out, toolbox.FileCode))
 		},
 	}
-
+	cmd.Flags().StringVar(&opt.Src, "src", "", "需要打包的目录")
+	cmd.Flags().StringVar(&opt.Pkg, "pkg", "", "生成的包名")
+	cmd.Flags().StringVar(&opt.Out, "out", "", "生成的包目录，默认为 ./<pkg>")
+	cmd.Flags().BoolVar(&opt.Gzip, "gzip", false, "压缩存储，读取时解压，适合较大的文本文件")
 	return cmd
 }
@@ -0,0 +1,140 @@
+package toolbox
+
+// codeTemplate 生成的访问方法 只依赖标准库
+const codeTemplate = `// Code generated by iotaer tool. DO NOT EDIT.
+
+package {{.Pkg}}
+
+import (
+	"bytes"
+{{- if .Gzip}}
+	"compress/gzip"
+{{- end}}
+	"embed"
+{{- if .HasJSON}}
+	"encoding/json"
+{{- end}}
+	"fmt"
+	"io/fs"
+{{- if .Gzip}}
+	"io/ioutil"
+{{- end}}
+	"sort"
+	"sync"
+	"text/template"
+)
+
+//go:embed {{.Dir}}
+var assets embed.FS
+
+// 文件名
+const (
+{{- range .Files}}
+	File{{.Ident}} = "{{.Name}}"
+{{- end}}
+)
+
+var names = []string{
+{{- range .Files}}
+	File{{.Ident}},
+{{- end}}
+}
+
+// Names 所有文件名
+func Names() []string {
+	list := append([]string(nil), names...)
+	sort.Strings(list)
+	return list
+}
+
+// FS 嵌入的原始文件{{if .Gzip}} 文件以 .gz 压缩存储{{end}}
+func FS() fs.FS {
+	sub, _ := fs.Sub(assets, "{{.Dir}}")
+	return sub
+}
+
+// ReadFile 读取文件内容 name 为相对打包目录的路径
+func ReadFile(name string) ([]byte, error) {
+{{- if .Gzip}}
+	body, err := assets.ReadFile("{{.Dir}}/" + name + ".gz")
+	if err != nil {
+		return nil, err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+{{- else}}
+	body, err := assets.ReadFile("{{.Dir}}/" + name)
+	if err != nil {
+		return nil, err
+	}
+	// 返回副本 避免调用方修改共享数据
+	return append([]byte(nil), body...), nil
+{{- end}}
+}
+
+// MustReadFile 读取文件内容 文件不存在时 panic
+func MustReadFile(name string) []byte {
+	body, err := ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	return body
+}
+
+var templates sync.Map
+
+// Render 将文件作为 text/template 渲染 解析结果会被缓存
+func Render(name string, data interface{}) ([]byte, error) {
+	t, ok := templates.Load(name)
+	if !ok {
+		body, err := ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := template.New(name).Parse(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("解析模板 %s 失败: %w", name, err)
+		}
+		t, _ = templates.LoadOrStore(name, parsed)
+	}
+	var buf bytes.Buffer
+	if err := t.(*template.Template).Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("渲染模板 %s 失败: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+{{- if .HasJSON}}
+
+// Decode 将 json 文件解析到 v
+func Decode(name string, v interface{}) error {
+	body, err := ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+{{- end}}
+{{range .Files}}
+// {{.Ident}} {{.Name}}
+func {{.Ident}}() []byte {
+	return MustReadFile(File{{.Ident}})
+}
+{{- if eq .Kind "template"}}
+
+// Render{{.Ident}} 渲染 {{.Name}}
+func Render{{.Ident}}(data interface{}) ([]byte, error) {
+	return Render(File{{.Ident}}, data)
+}
+{{- end}}
+{{- if eq .Kind "json"}}
+
+// Decode{{.Ident}} 解析 {{.Name}}
+func Decode{{.Ident}}(v interface{}) error {
+	return Decode(File{{.Ident}}, v)
+}
+{{- end}}
+{{end}}`
@@ -0,0 +1,265 @@
+package toolbox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// 生成的文件与目录
+const (
+	FileCode  = "assets.go" // 访问方法
+	DirAssets = "assets"    // 嵌入的文件 每次生成前清空
+)
+
+// 文件类型 决定除 []byte 外额外生成的访问方法
+const (
+	KindRaw      = "raw"
+	KindTemplate = "template" // 额外生成 RenderXxx
+	KindJSON     = "json"     // 额外生成 DecodeXxx
+)
+
+// templateExts 作为模板处理的扩展名
+var templateExts = map[string]bool{".tmpl": true, ".tpl": true, ".gotmpl": true}
+
+// Option 生成参数
+type Option struct {
+	Src  string // 需要打包的目录
+	Out  string // 生成的包目录 默认为 ./<Pkg> 不能在 Src 中
+	Pkg  string // 包名
+	Gzip bool   // 是否压缩存储 读取时解压
+}
+
+// File 一个被嵌入的文件
+type File struct {
+	Name  string // 相对 Src 的路径 使用 / 分隔
+	Ident string // 访问方法名
+	Kind  string
+}
+
+// Validate 校验参数并填充默认值
+func (o *Option) Validate() error {
+	if o.Src == "" {
+		return errors.New("请指定需要打包的目录 --src")
+	}
+	if o.Pkg == "" {
+		return errors.New("请指定包名 --pkg")
+	}
+	if !token.IsIdentifier(o.Pkg) || token.IsKeyword(o.Pkg) {
+		return fmt.Errorf("包名 %s 不是合法的标识符", o.Pkg)
+	}
+	if o.Out == "" {
+		o.Out = o.Pkg
+	}
+	src, err := filepath.Abs(o.Src)
+	if err != nil {
+		return err
+	}
+	out, err := filepath.Abs(o.Out)
+	if err != nil {
+		return err
+	}
+	if out == src || strings.HasPrefix(out, src+string(filepath.Separator)) {
+		return fmt.Errorf("输出目录 %s 不能在源目录 %s 中", o.Out, o.Src)
+	}
+	info, err := os.Stat(o.Src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s 不是目录", o.Src)
+	}
+	return nil
+}
+
+// Scan 列出需要嵌入的文件 go:embed 不支持的文件名会被跳过并返回在 skipped 中
+func Scan(src string) (files []File, skipped []string, err error) {
+	err = filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		// go1.16 的 go:embed 会忽略 . 与 _ 开头的文件
+		if base := d.Name(); strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_") || !validName(base) {
+			skipped = append(skipped, rel)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		files = append(files, File{Name: rel, Kind: kindOf(rel)})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	assignIdents(files)
+	return files, skipped, nil
+}
+
+// Generate 将文件复制到 Out/assets 并生成访问方法 返回嵌入的文件
+func Generate(o *Option) ([]File, []string, error) {
+	if err := o.Validate(); err != nil {
+		return nil, nil, err
+	}
+	files, skipped, err := Scan(o.Src)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(files) == 0 {
+		return nil, skipped, fmt.Errorf("%s 下没有可以嵌入的文件", o.Src)
+	}
+	assets := filepath.Join(o.Out, DirAssets)
+	if err := os.RemoveAll(assets); err != nil {
+		return nil, nil, err
+	}
+	for _, f := range files {
+		body, err := ioutil.ReadFile(filepath.Join(o.Src, filepath.FromSlash(f.Name)))
+		if err != nil {
+			return nil, nil, err
+		}
+		name := f.Name
+		if o.Gzip {
+			if body, err = compress(body); err != nil {
+				return nil, nil, err
+			}
+			name += ".gz"
+		}
+		dst := filepath.Join(assets, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return nil, nil, err
+		}
+		if err := ioutil.WriteFile(dst, body, 0644); err != nil {
+			return nil, nil, err
+		}
+	}
+	code, err := Code(o, files)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(o.Out, FileCode), code, 0644); err != nil {
+		return nil, nil, err
+	}
+	return files, skipped, nil
+}
+
+// Code 生成访问方法的代码
+func Code(o *Option, files []File) ([]byte, error) {
+	var hasJSON bool
+	for _, f := range files {
+		hasJSON = hasJSON || f.Kind == KindJSON
+	}
+	var buf bytes.Buffer
+	err := codeTpl.Execute(&buf, map[string]interface{}{
+		"Pkg":     o.Pkg,
+		"Gzip":    o.Gzip,
+		"Dir":     DirAssets,
+		"Files":   files,
+		"HasJSON": hasJSON,
+	})
+	if err != nil {
+		return nil, err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("格式化生成代码失败: %+v", err)
+	}
+	return code, nil
+}
+
+func compress(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func kindOf(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	switch {
+	case templateExts[ext]:
+		return KindTemplate
+	case ext == ".json":
+		return KindJSON
+	}
+	return KindRaw
+}
+
+// validName go:embed 不允许的文件名字符
+func validName(name string) bool {
+	return !strings.ContainsAny(name, "\"*<>?`'|/\\:")
+}
+
+// assignIdents 按路径生成方法名 如 sql/user.sql 为 SqlUserSql, 重名时追加序号
+// 每个文件还会生成 File<Ident> 常量与 Render<Ident>、Decode<Ident> 方法, 这些名字同样不能重复
+func assignIdents(files []File) {
+	used := map[string]bool{
+		// 生成代码中已有的方法
+		"FS": true, "Names": true, "ReadFile": true, "MustReadFile": true, "Render": true, "Decode": true,
+	}
+	free := func(ident string) bool {
+		return !used[ident] && !used["File"+ident] && !used["Render"+ident] && !used["Decode"+ident]
+	}
+	for i := range files {
+		base := identifier(files[i].Name)
+		ident := base
+		for n := 2; !free(ident); n++ {
+			ident = fmt.Sprintf("%s%d", base, n)
+		}
+		for _, name := range []string{ident, "File" + ident, "Render" + ident, "Decode" + ident} {
+			used[name] = true
+		}
+		files[i].Ident = ident
+	}
+}
+
+// identifier 按非字母数字切分后拼接 每段首字母大写
+func identifier(s string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	ident := b.String()
+	// 数字或没有大小写的文字开头时无法导出
+	if ident == "" || !unicode.IsUpper([]rune(ident)[0]) {
+		ident = "File" + ident
+	}
+	return ident
+}
+
+var codeTpl = template.Must(template.New("toolbox").Parse(codeTemplate))
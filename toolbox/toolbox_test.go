@@ -0,0 +1,160 @@
+package toolbox
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/actorbuf/iotaer/internal/testproject"
+)
+
+func TestScan(t *testing.T) {
+	src := t.TempDir()
+	testproject.WriteFiles(t, src, map[string]string{
+		"sql/user.sql":      "select 1",
+		"sql/user-sql":      "dup",
+		"mail/welcome.tmpl": "hi {{.}}",
+		"fixtures/a.json":   "{}",
+		"1.sh":              "echo",
+		".env":              "skip",
+		"_draft/x.sql":      "skip",
+		"sql/Names":         "reserved",
+		"文档.txt":            "doc",
+	})
+	files, skipped, err := Scan(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, f := range files {
+		got[f.Name] = f.Ident + ":" + f.Kind
+	}
+	want := map[string]string{
+		"1.sh":              "File1Sh:raw",
+		"fixtures/a.json":   "FixturesAJson:json",
+		"mail/welcome.tmpl": "MailWelcomeTmpl:template",
+		"sql/Names":         "SqlNames:raw",
+		"sql/user-sql":      "SqlUserSql:raw",
+		"sql/user.sql":      "SqlUserSql2:raw",
+		"文档.txt":            "File文档Txt:raw",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected files %v", got)
+	}
+	if !reflect.DeepEqual(skipped, []string{".env", "_draft"}) {
+		t.Fatalf("unexpected skipped %v", skipped)
+	}
+}
+
+func TestAssignIdents(t *testing.T) {
+	for _, names := range [][]string{
+		{"a.sql", "file_a.sql", "b.json", "decode_b.json", "c.tmpl", "render_c.tmpl"},
+		{"file_a.sql", "a.sql", "decode_b.json", "b.json", "render_c.tmpl", "c.tmpl"},
+	} {
+		files := make([]File, len(names))
+		for i, name := range names {
+			files[i].Name = name
+		}
+		assignIdents(files)
+		// 文件方法与派生的常量和方法都不能重名
+		seen := map[string]string{}
+		for _, f := range files {
+			for _, ident := range []string{f.Ident, "File" + f.Ident, "Render" + f.Ident, "Decode" + f.Ident} {
+				if other, ok := seen[ident]; ok {
+					t.Fatalf("%s of %s conflicts with %s", ident, f.Name, other)
+				}
+				seen[ident] = f.Name
+			}
+		}
+		if files[0].Ident != identifier(names[0]) {
+			t.Fatalf("unexpected ident %s", files[0].Ident)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	src := t.TempDir()
+	if err := (&Option{Src: src, Out: filepath.Join(src, "box"), Pkg: "box"}).Validate(); err == nil {
+		t.Fatal("expected out inside src error")
+	}
+	if err := (&Option{Src: src, Pkg: "type"}).Validate(); err == nil {
+		t.Fatal("expected keyword package error")
+	}
+	if err := (&Option{Src: src}).Validate(); err == nil {
+		t.Fatal("expected empty package error")
+	}
+	o := &Option{Src: src, Pkg: "box"}
+	if err := o.Validate(); err != nil || o.Out != "box" {
+		t.Fatalf("unexpected defaults %+v %v", o, err)
+	}
+}
+
+// TestGenerate 生成到临时模块中编译运行 校验生成代码可用
+func TestGenerate(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not found")
+	}
+	for _, gz := range []bool{false, true} {
+		mod := t.TempDir()
+		src := filepath.Join(mod, "resources")
+		testproject.WriteFiles(t, mod, map[string]string{
+			"go.mod": "module example.com/demo\n\ngo 1.16\n",
+			"main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/demo/box"
+)
+
+func main() {
+	out, err := box.RenderMailWelcomeTmpl(map[string]string{"Name": "iota"})
+	if err != nil {
+		panic(err)
+	}
+	var v struct{ Port int }
+	if err := box.DecodeConfigJson(&v); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%s|%s|%d|%v", box.SqlUserSql(), out, v.Port, box.Names())
+}
+`,
+			"resources/sql/user.sql":      "select * from user",
+			"resources/mail/welcome.tmpl": "hello {{.Name}}",
+			"resources/config.json":       `{"port": 8080}`,
+		})
+		o := &Option{Src: src, Out: filepath.Join(mod, "box"), Pkg: "box", Gzip: gz}
+		if _, _, err := Generate(o); err != nil {
+			t.Fatal(err)
+		}
+		// 重复生成时清理旧文件
+		if err := os.Remove(filepath.Join(src, "config.json")); err != nil {
+			t.Fatal(err)
+		}
+		testproject.WriteFiles(t, src, map[string]string{"config.json": `{"port": 9090}`})
+		if _, _, err := Generate(o); err != nil {
+			t.Fatal(err)
+		}
+		name := "sql/user.sql"
+		if gz {
+			name += ".gz"
+		}
+		if _, err := os.Stat(filepath.Join(o.Out, DirAssets, filepath.FromSlash(name))); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command("go", "run", ".")
+		cmd.Dir = mod
+		cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GO111MODULE=on")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("gzip=%v: %v: %s", gz, err, out)
+		}
+		want := "select * from user|hello iota|9090|[config.json mail/welcome.tmpl sql/user.sql]"
+		if strings.TrimSpace(string(out)) != want {
+			t.Fatalf("gzip=%v: unexpected output %s", gz, out)
+		}
+	}
+}
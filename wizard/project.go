@@ -0,0 +1,92 @@
+package wizard
+
+import (
+	"path/filepath"
+
+	"github.com/actorbuf/iotaer/protodef"
+	"github.com/actorbuf/iotaer/protofmt"
+)
+
+// Project 项目中已有的 proto 文件
+type Project struct {
+	Files []*protodef.File
+}
+
+// Scan 查找目录下的 proto 文件 解析失败的文件会被忽略
+func Scan(dir string) (*Project, error) {
+	files, err := protofmt.Files(dir)
+	if err != nil {
+		return nil, err
+	}
+	p := &Project{}
+	for _, file := range files {
+		f, err := protodef.ParseFile(file)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, file); err == nil {
+			f.Path = rel
+		}
+		p.Files = append(p.Files, f)
+	}
+	return p, nil
+}
+
+// File 按路径查找已解析的文件
+func (p *Project) File(path string) *protodef.File {
+	for _, f := range p.Files {
+		if filepath.Clean(f.Path) == filepath.Clean(path) {
+			return f
+		}
+	}
+	return nil
+}
+
+// Paths 包含指定类型 service 的文件 kinds 为空时返回全部文件
+func (p *Project) Paths(kinds ...string) []string {
+	var paths []string
+	for _, f := range p.Files {
+		if len(kinds) == 0 || len(services(f, kinds)) > 0 {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths
+}
+
+// Services 文件中指定类型的 service 名称
+func (p *Project) Services(path string, kinds ...string) []string {
+	f := p.File(path)
+	if f == nil {
+		return nil
+	}
+	var names []string
+	for _, s := range services(f, kinds) {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func services(f *protodef.File, kinds []string) []*protodef.Service {
+	var list []*protodef.Service
+	for _, s := range f.Services {
+		for _, kind := range kinds {
+			if s.Kind == kind {
+				list = append(list, s)
+				break
+			}
+		}
+	}
+	return list
+}
+
+// service 查找文件中的 service
+func (p *Project) service(path, name string) *protodef.Service {
+	if f := p.File(path); f != nil {
+		for _, s := range f.Services {
+			if s.Name == name {
+				return s
+			}
+		}
+	}
+	return nil
+}
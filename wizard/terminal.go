@@ -0,0 +1,100 @@
+package wizard
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// Terminal 基于 x/term 的交互输入 支持行编辑、历史与 Tab 补全
+type Terminal struct {
+	fd         int
+	state      *term.State
+	t          *term.Terminal
+	candidates []string
+}
+
+// IsTerminal 标准输入是否为终端 不是终端时只能通过参数执行
+func IsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// NewTerminal 进入 raw 模式 结束后需要调用 Close 恢复终端
+func NewTerminal() (*Terminal, error) {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	t := &Terminal{fd: fd, state: state}
+	t.t = term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "")
+	t.t.AutoCompleteCallback = t.complete
+	return t, nil
+}
+
+// Close 恢复终端状态
+func (t *Terminal) Close() error {
+	return term.Restore(t.fd, t.state)
+}
+
+// Ask 实现 Prompter Ctrl-C/Ctrl-D 取消
+func (t *Terminal) Ask(prompt string, candidates []string) (string, error) {
+	t.candidates = candidates
+	t.t.SetPrompt(prompt)
+	line, err := t.t.ReadLine()
+	if err == io.EOF {
+		return "", ErrCanceled
+	}
+	return line, err
+}
+
+// Println 实现 Prompter
+func (t *Terminal) Println(a ...interface{}) {
+	_, _ = fmt.Fprintln(t.t, a...)
+}
+
+// complete Tab 时补全到所有候选项的公共前缀
+func (t *Terminal) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' || pos != len(line) {
+		return "", 0, false
+	}
+	s, ok := Complete(line, t.candidates)
+	if !ok {
+		return "", 0, false
+	}
+	return s, len(s), true
+}
+
+// Complete 以 prefix 开头的候选项的最长公共前缀 没有候选项或无法补全更多时返回 false
+func Complete(prefix string, candidates []string) (string, bool) {
+	common := ""
+	found := false
+	for _, c := range candidates {
+		if !strings.HasPrefix(c, prefix) {
+			continue
+		}
+		if !found {
+			common, found = c, true
+			continue
+		}
+		i := 0
+		for i < len(common) && i < len(c) && common[i] == c[i] {
+			i++
+		}
+		common = common[:i]
+		for !utf8.ValidString(common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if !found || common == prefix {
+		return "", false
+	}
+	return common, true
+}
@@ -0,0 +1,443 @@
+package wizard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/actorbuf/iotaer/apigen"
+	"github.com/actorbuf/iotaer/protodef"
+	"github.com/actorbuf/iotaer/toolkit"
+)
+
+// ErrCanceled 用户取消了向导
+var ErrCanceled = errors.New("已取消")
+
+// Kind 向导支持生成的内容
+type Kind struct {
+	Name    string // 向导中的名称
+	Desc    string // 说明
+	Command string // 实际执行的命令
+}
+
+// Kinds 与 add* 命令一一对应
+var Kinds = []Kind{
+	{Name: "route", Desc: "路由组", Command: "addroute"},
+	{Name: "api", Desc: "路由组中的接口", Command: "addapi"},
+	{Name: "svc", Desc: "rpc服务", Command: "addsvc"},
+	{Name: "rpc", Desc: "service中的rpc", Command: "addrpc"},
+	{Name: "task", Desc: "定时任务", Command: "addtask"},
+	{Name: "errcode", Desc: "错误码文件", Command: "addErrorCodeFile"},
+}
+
+// Methods addapi 支持的请求方式
+var Methods = []string{"GET", "POST", "PUT", "DELETE", "ANY"}
+
+var nameReg = regexp.MustCompile(`^[A-Z][A-Za-z0-9]*$`)
+
+// Answers 向导收集的参数 非交互模式下由命令行参数填写
+type Answers struct {
+	Kind       string
+	Path       string // proto 文件 errcode 时为目录
+	Svc        string // 路由组/service 名称
+	Name       string // 新增的名称
+	Method     string // 接口请求方式
+	Desc       string // 接口描述
+	Fields     string // Req 的字段
+	RespFields string // Resp 的字段
+	GenTo      string // 代码生成位置
+	API        string // 路由组前缀
+}
+
+// Prompter 读取用户输入
+type Prompter interface {
+	// Ask 读取一行输入 candidates 用于 Tab 补全 取消时返回 ErrCanceled
+	Ask(prompt string, candidates []string) (string, error)
+	// Println 输出提示信息
+	Println(a ...interface{})
+}
+
+// Run 依次询问缺少的参数 确认后返回需要执行的命令参数
+func Run(p Prompter, proj *Project, a *Answers) ([]string, error) {
+	w := &wizard{p: p, proj: proj, a: a}
+	if err := w.ask(); err != nil {
+		return nil, err
+	}
+	args, err := Args(proj, a)
+	if err != nil {
+		return nil, err
+	}
+	p.Println("将执行: builder " + Command(args))
+	ok, err := w.input("确认执行? (Y/n)", "y", nil, func(s string) error {
+		if s = strings.ToLower(s); s != "y" && s != "n" {
+			return fmt.Errorf("请输入 y 或 n")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(ok) != "y" {
+		return nil, ErrCanceled
+	}
+	return args, nil
+}
+
+// Args 校验参数并转换为对应 add* 命令的参数 缺少的可选参数使用默认值
+func Args(proj *Project, a *Answers) ([]string, error) {
+	kind, err := findKind(a.Kind)
+	if err != nil {
+		return nil, err
+	}
+	if a.Path == "" {
+		return nil, fmt.Errorf("--path 不能为空")
+	}
+	if kind.Name == "errcode" {
+		return []string{kind.Command, "--path", a.Path}, nil
+	}
+	if kind.Name == "api" || kind.Name == "rpc" || kind.Name == "task" {
+		if a.Svc == "" {
+			return nil, fmt.Errorf("--svc 不能为空")
+		}
+		a.Svc = toolkit.FirstUpper(a.Svc)
+	}
+	a.Name = toolkit.FirstUpper(a.Name)
+	if err := checkName(proj, a, a.Name); err != nil {
+		return nil, err
+	}
+	cname := toolkit.Calm2Case(a.Name)
+	switch kind.Name {
+	case "route":
+		if a.GenTo == "" {
+			a.GenTo = fmt.Sprintf("./internal/controller/%s_controller.go", cname)
+		}
+		if a.API == "" {
+			a.API = "/api/" + cname
+		}
+		cmd := kind.Command
+		if _, err := os.Stat(a.Path); os.IsNotExist(err) {
+			cmd = "addrouteV2" // 会创建带 go_package 的 proto 文件
+		}
+		return []string{cmd, "--path", a.Path, "--name", a.Name, "--gento", a.GenTo, "--api", a.API}, nil
+	case "svc":
+		if a.GenTo == "" {
+			a.GenTo = fmt.Sprintf("./internal/services/%s_service.go", cname)
+		}
+		return []string{kind.Command, "--path", a.Path, "--name", a.Name, "--gento", a.GenTo}, nil
+	case "api":
+		if a.Method == "" {
+			a.Method = "POST"
+		}
+		a.Method = strings.ToUpper(a.Method)
+		if !contains(Methods, a.Method) {
+			return nil, fmt.Errorf("请求方式 %s 不正确, 可选 %s", a.Method, strings.Join(Methods, "/"))
+		}
+		for _, fields := range []string{a.Fields, a.RespFields} {
+			if _, err := apigen.ParseFields(fields); err != nil {
+				return nil, err
+			}
+		}
+		args := []string{kind.Command, "--path", a.Path, "--svc", a.Svc, "--name", a.Name, "--method", a.Method}
+		return appendFlags(args, "--desc", a.Desc, "--fields", a.Fields, "--resp-fields", a.RespFields), nil
+	case "rpc":
+		return []string{kind.Command, "--path", a.Path, "--svc", a.Svc, "--name", a.Name}, nil
+	default: // task
+		args := []string{kind.Command, "--path", a.Path, "--svc", a.Svc, "--name", a.Name}
+		return appendFlags(args, "--gen", a.GenTo), nil
+	}
+}
+
+// Command 拼接可以直接复制执行的命令
+func Command(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\"'$;&|<>()[]*?") {
+			quoted[i] = strconv.Quote(arg)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+func findKind(name string) (Kind, error) {
+	var names []string
+	for _, k := range Kinds {
+		if k.Name == name {
+			return k, nil
+		}
+		names = append(names, k.Name)
+	}
+	if name == "" {
+		return Kind{}, fmt.Errorf("--kind 不能为空, 可选 %s", strings.Join(names, "/"))
+	}
+	return Kind{}, fmt.Errorf("不支持的类型 %s, 可选 %s", name, strings.Join(names, "/"))
+}
+
+// checkName 名称需为大驼峰 且在文件或 service 中不存在
+func checkName(proj *Project, a *Answers, name string) error {
+	if name == "" {
+		return fmt.Errorf("--name 不能为空")
+	}
+	if !nameReg.MatchString(name) {
+		return fmt.Errorf("名称 %s 只能包含字母与数字, 且以字母开头", name)
+	}
+	switch a.Kind {
+	case "route", "svc":
+		if proj.service(a.Path, name) != nil {
+			return fmt.Errorf("%s 中已存在 %s", a.Path, name)
+		}
+	case "api", "rpc", "task":
+		if s := proj.service(a.Path, a.Svc); s != nil {
+			for _, m := range s.Methods {
+				if m.Name == name {
+					return fmt.Errorf("%s 中已存在 %s", a.Svc, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func appendFlags(args []string, kv ...string) []string {
+	for i := 0; i+1 < len(kv); i += 2 {
+		if kv[i+1] != "" {
+			args = append(args, kv[i], kv[i+1])
+		}
+	}
+	return args
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type wizard struct {
+	p    Prompter
+	proj *Project
+	a    *Answers
+}
+
+// ask 按类型询问 已通过参数填写的不再询问
+func (w *wizard) ask() error {
+	a := w.a
+	var err error
+	if a.Kind == "" {
+		var options []string
+		for _, k := range Kinds {
+			options = append(options, fmt.Sprintf("%-8s %s", k.Name, k.Desc))
+		}
+		if a.Kind, err = w.choose("要添加什么", options, ""); err != nil {
+			return err
+		}
+		a.Kind = strings.Fields(a.Kind)[0]
+	}
+	if _, err := findKind(a.Kind); err != nil {
+		return err
+	}
+	if a.Path == "" {
+		if a.Path, err = w.askPath(); err != nil {
+			return err
+		}
+	}
+	if a.Kind == "errcode" {
+		return nil
+	}
+	if a.Svc == "" && (a.Kind == "api" || a.Kind == "rpc" || a.Kind == "task") {
+		if a.Svc, err = w.askSvc(); err != nil {
+			return err
+		}
+	}
+	if a.Name == "" {
+		label := map[string]string{"route": "路由组名称", "svc": "服务名称", "api": "接口名称", "rpc": "rpc名称", "task": "任务名称"}[a.Kind]
+		a.Name, err = w.input(label, "", nil, func(s string) error {
+			return checkName(w.proj, a, toolkit.FirstUpper(s))
+		})
+		if err != nil {
+			return err
+		}
+		a.Name = toolkit.FirstUpper(a.Name)
+	}
+	cname := toolkit.Calm2Case(a.Name)
+	switch a.Kind {
+	case "route":
+		if a.GenTo == "" {
+			if a.GenTo, err = w.input("controller生成位置", fmt.Sprintf("./internal/controller/%s_controller.go", cname), nil, nil); err != nil {
+				return err
+			}
+		}
+		if a.API == "" {
+			if a.API, err = w.input("路由组前缀", "/api/"+cname, nil, nil); err != nil {
+				return err
+			}
+		}
+	case "svc":
+		if a.GenTo == "" {
+			if a.GenTo, err = w.input("service生成位置", fmt.Sprintf("./internal/services/%s_service.go", cname), nil, nil); err != nil {
+				return err
+			}
+		}
+	case "api":
+		return w.askAPI()
+	case "task":
+		// 任务 service 不存在时才需要生成位置
+		if a.GenTo == "" && w.proj.service(a.Path, a.Svc) == nil {
+			def := fmt.Sprintf("./infra/task/%s.go", toolkit.Calm2Case(a.Svc))
+			if a.GenTo, err = w.input("任务代码生成位置", def, nil, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *wizard) askPath() (string, error) {
+	a := w.a
+	switch a.Kind {
+	case "api":
+		return w.choose("proto文件", w.proj.Paths(protodef.KindRoute), "")
+	case "rpc":
+		return w.choose("proto文件", w.proj.Paths(protodef.KindRPC, protodef.KindPlain), "")
+	case "errcode":
+		def := "model"
+		if paths := w.proj.Paths(); len(paths) > 0 {
+			def = filepath.Dir(paths[0])
+		}
+		return w.input("错误码文件所在目录", def, nil, nil)
+	}
+	paths := w.proj.Paths()
+	def := ""
+	if len(paths) > 0 {
+		def = paths[0]
+	}
+	return w.input("proto文件", def, paths, func(s string) error {
+		if !strings.HasSuffix(s, ".proto") {
+			return fmt.Errorf("%s 不是 proto 文件", s)
+		}
+		if a.Kind == "route" {
+			return nil // 不存在时由 addrouteV2 创建
+		}
+		if _, err := os.Stat(s); err != nil {
+			return fmt.Errorf("%s 不存在", s)
+		}
+		return nil
+	})
+}
+
+func (w *wizard) askSvc() (string, error) {
+	switch w.a.Kind {
+	case "api":
+		return w.choose("路由组", w.proj.Services(w.a.Path, protodef.KindRoute), "")
+	case "rpc":
+		return w.choose("service", w.proj.Services(w.a.Path, protodef.KindRPC, protodef.KindPlain), "")
+	}
+	tasks := w.proj.Services(w.a.Path, protodef.KindTask)
+	def := ""
+	if len(tasks) > 0 {
+		def = tasks[0]
+	}
+	w.p.Println("不存在的任务service会自动创建")
+	return w.input("任务service", def, tasks, func(s string) error {
+		if !nameReg.MatchString(toolkit.FirstUpper(s)) {
+			return fmt.Errorf("名称 %s 只能包含字母与数字, 且以字母开头", s)
+		}
+		return nil
+	})
+}
+
+func (w *wizard) askAPI() error {
+	a := w.a
+	var err error
+	if a.Method == "" {
+		if a.Method, err = w.choose("请求方式", Methods, "POST"); err != nil {
+			return err
+		}
+	}
+	if a.Desc == "" {
+		if a.Desc, err = w.input("接口描述", "", nil, nil); err != nil {
+			return err
+		}
+	}
+	checkFields := func(s string) error {
+		_, err := apigen.ParseFields(s)
+		return err
+	}
+	if a.Fields == "" {
+		w.p.Println("字段格式 name:type 以逗号分隔, 类型前加[]表示repeated, 如 user_id:int64,tags:[]string")
+		if a.Fields, err = w.input("Req字段", "", nil, checkFields); err != nil {
+			return err
+		}
+	}
+	if a.RespFields == "" {
+		if a.RespFields, err = w.input("Resp字段", "", nil, checkFields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// input 读取输入直到校验通过 直接回车时使用默认值
+func (w *wizard) input(label, def string, candidates []string, validate func(string) error) (string, error) {
+	prompt := label + ": "
+	if def != "" {
+		prompt = fmt.Sprintf("%s [%s]: ", label, def)
+	}
+	for {
+		s, err := w.p.Ask(prompt, candidates)
+		if err != nil {
+			return "", err
+		}
+		if s = strings.TrimSpace(s); s == "" {
+			s = def
+		}
+		if validate == nil {
+			return s, nil
+		}
+		if err := validate(s); err != nil {
+			w.p.Println(err)
+			continue
+		}
+		return s, nil
+	}
+}
+
+// choose 从列表中选择 可以输入序号或名称
+func (w *wizard) choose(label string, options []string, def string) (string, error) {
+	if len(options) == 0 {
+		return "", fmt.Errorf("没有可选的%s", label)
+	}
+	w.p.Println(label + ":")
+	for i, opt := range options {
+		w.p.Println(fmt.Sprintf("  %d) %s", i+1, opt))
+	}
+	if def == "" {
+		def = "1"
+	}
+	var names []string
+	for _, opt := range options {
+		names = append(names, strings.Fields(opt)[0])
+	}
+	var chosen string
+	_, err := w.input("请选择", def, names, func(s string) error {
+		if i, err := strconv.Atoi(s); err == nil && i >= 1 && i <= len(options) {
+			chosen = options[i-1]
+			return nil
+		}
+		for i, name := range names {
+			if name == s {
+				chosen = options[i]
+				return nil
+			}
+		}
+		return fmt.Errorf("无效的选择 %s", s)
+	})
+	return chosen, err
+}
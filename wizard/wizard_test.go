@@ -0,0 +1,151 @@
+package wizard
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const userProto = `syntax = "proto3";
+package user;
+
+// @route_group: true
+// @route_api: /api/user
+service UserApi {
+    rpc GetUser (GetUserReq) returns (GetUserResp);
+}
+
+service UserRpc {
+    rpc Sync (SyncReq) returns (SyncResp);
+}
+
+// @task: true
+service UserTask {
+    rpc Clean (CleanReq) returns (CleanResp);
+}
+`
+
+// script 按顺序返回预设的输入
+type script struct {
+	answers []string
+	prompts []string
+	out     []string
+}
+
+func (s *script) Ask(prompt string, candidates []string) (string, error) {
+	s.prompts = append(s.prompts, prompt)
+	if len(s.answers) == 0 {
+		return "", ErrCanceled
+	}
+	a := s.answers[0]
+	s.answers = s.answers[1:]
+	return a, nil
+}
+
+func (s *script) Println(a ...interface{}) {
+	for _, v := range a {
+		if e, ok := v.(error); ok {
+			s.out = append(s.out, e.Error())
+		} else if str, ok := v.(string); ok {
+			s.out = append(s.out, str)
+		}
+	}
+}
+
+func project(t *testing.T) *Project {
+	dir := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(dir, "model"), 0755)
+	if err := ioutil.WriteFile(filepath.Join(dir, "model", "user.proto"), []byte(userProto), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	_ = os.Chdir(dir)
+	proj, err := Scan(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proj
+}
+
+func TestRun(t *testing.T) {
+	proj := project(t)
+	pb := filepath.Join("model", "user.proto")
+	if got := proj.Paths(); !reflect.DeepEqual(got, []string{pb}) {
+		t.Fatalf("unexpected paths %v", got)
+	}
+
+	// 选择 api 默认文件与路由组 名称重复时重新输入
+	s := &script{answers: []string{"2", "", "", "getUser", "getProfile", "1", "获取资料", "uid", "uid:int64", "", ""}}
+	args, err := Run(s, proj, &Answers{})
+	if err != nil {
+		t.Fatal(err, s.out)
+	}
+	want := []string{"addapi", "--path", pb, "--svc", "UserApi", "--name", "GetProfile", "--method", "GET", "--desc", "获取资料", "--fields", "uid:int64"}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("unexpected args %v", args)
+	}
+	if !strings.Contains(strings.Join(s.out, "\n"), "UserApi 中已存在 GetUser") {
+		t.Fatalf("expected duplicate name message, got %v", s.out)
+	}
+
+	// 通过参数指定的不再询问 不存在的任务 service 需要填写生成位置
+	s = &script{answers: []string{"", "y"}}
+	args, err = Run(s, proj, &Answers{Kind: "task", Path: pb, Svc: "orderTask", Name: "Refresh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"addtask", "--path", pb, "--svc", "OrderTask", "--name", "Refresh", "--gen", "./infra/task/order_task.go"}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("unexpected args %v", args)
+	}
+
+	s = &script{answers: []string{"rpc", "", "", "Push", "n"}}
+	if _, err := Run(s, proj, &Answers{}); err != ErrCanceled {
+		t.Fatalf("expected canceled, got %v", err)
+	}
+	if s.prompts[len(s.prompts)-1] != "确认执行? (Y/n) [y]: " {
+		t.Fatalf("unexpected prompts %v", s.prompts)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	proj := project(t)
+	pb := filepath.Join("model", "user.proto")
+	args, err := Args(proj, &Answers{Kind: "route", Path: "model/order.proto", Name: "OrderApi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"addrouteV2", "--path", "model/order.proto", "--name", "OrderApi", "--gento", "./internal/controller/order_api_controller.go", "--api", "/api/order_api"}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("unexpected args %v", args)
+	}
+	for _, a := range []*Answers{
+		{},
+		{Kind: "api", Path: pb, Name: "Foo"},
+		{Kind: "api", Path: pb, Svc: "UserApi", Name: "GetUser"},
+		{Kind: "api", Path: pb, Svc: "UserApi", Name: "Foo", Method: "HEAD"},
+		{Kind: "svc", Path: pb, Name: "UserRpc"},
+		{Kind: "rpc", Path: pb, Svc: "UserRpc", Name: "bad-name"},
+	} {
+		if _, err := Args(proj, a); err == nil {
+			t.Fatalf("%+v: expected error", a)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	candidates := []string{"model/user.proto", "model/user_ext.proto", "api/order.proto"}
+	for prefix, want := range map[string]string{"m": "model/user", "a": "api/order.proto", "model/user": "", "x": ""} {
+		got, ok := Complete(prefix, candidates)
+		if got != want || ok != (want != "") {
+			t.Fatalf("%s: want %q got %q", prefix, want, got)
+		}
+	}
+	if got := Command([]string{"addapi", "--desc", "获取 用户", "--fields", "tags:[]string"}); got != `addapi --desc "获取 用户" --fields "tags:[]string"` {
+		t.Fatalf("unexpected command %s", got)
+	}
+}